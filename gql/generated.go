@@ -1254,6 +1254,154 @@ type FlyctlConfigCurrentReleaseResponse struct {
 // GetApp returns FlyctlConfigCurrentReleaseResponse.App, and is useful for accessing the field via an interface.
 func (v *FlyctlConfigCurrentReleaseResponse) GetApp() FlyctlConfigCurrentReleaseApp { return v.App }
 
+// FlyctlReleasesUnprocessedApp includes the requested fields of the GraphQL type App.
+type FlyctlReleasesUnprocessedApp struct {
+	// Individual releases for this application, without any config processing
+	ReleasesUnprocessed FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnection `json:"releasesUnprocessed"`
+}
+
+// GetReleasesUnprocessed returns FlyctlReleasesUnprocessedApp.ReleasesUnprocessed, and is useful for accessing the field via an interface.
+func (v *FlyctlReleasesUnprocessedApp) GetReleasesUnprocessed() FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnection {
+	return v.ReleasesUnprocessed
+}
+
+// FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnection includes the requested fields of the GraphQL type ReleaseUnprocessedConnection.
+// The GraphQL type's documentation follows.
+//
+// The connection type for ReleaseUnprocessed.
+type FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnection struct {
+	// Information to aid in pagination.
+	PageInfo FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionPageInfo `json:"pageInfo"`
+	// A list of nodes.
+	Nodes []FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed `json:"nodes"`
+}
+
+// GetPageInfo returns FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnection.PageInfo, and is useful for accessing the field via an interface.
+func (v *FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnection) GetPageInfo() FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionPageInfo {
+	return v.PageInfo
+}
+
+// GetNodes returns FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnection.Nodes, and is useful for accessing the field via an interface.
+func (v *FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnection) GetNodes() []FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed {
+	return v.Nodes
+}
+
+// FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed includes the requested fields of the GraphQL type ReleaseUnprocessed.
+type FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed struct {
+	// The version of the release
+	Version int `json:"version"`
+	// A description of the release
+	Description string `json:"description"`
+	// The reason for the release
+	Reason string `json:"reason"`
+	// The status of the release
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Docker image URI
+	ImageRef string `json:"imageRef"`
+	// Docker image
+	Image FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessedImage `json:"image"`
+	// The user who created the release
+	User             FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessedUser `json:"user"`
+	ConfigDefinition interface{}                                                                                            `json:"configDefinition"`
+}
+
+// GetVersion returns FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed.Version, and is useful for accessing the field via an interface.
+func (v *FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed) GetVersion() int {
+	return v.Version
+}
+
+// GetDescription returns FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed.Description, and is useful for accessing the field via an interface.
+func (v *FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed) GetDescription() string {
+	return v.Description
+}
+
+// GetReason returns FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed.Reason, and is useful for accessing the field via an interface.
+func (v *FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed) GetReason() string {
+	return v.Reason
+}
+
+// GetStatus returns FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed.Status, and is useful for accessing the field via an interface.
+func (v *FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed) GetStatus() string {
+	return v.Status
+}
+
+// GetCreatedAt returns FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed.CreatedAt, and is useful for accessing the field via an interface.
+func (v *FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed) GetCreatedAt() time.Time {
+	return v.CreatedAt
+}
+
+// GetImageRef returns FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed.ImageRef, and is useful for accessing the field via an interface.
+func (v *FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed) GetImageRef() string {
+	return v.ImageRef
+}
+
+// GetImage returns FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed.Image, and is useful for accessing the field via an interface.
+func (v *FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed) GetImage() FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessedImage {
+	return v.Image
+}
+
+// GetUser returns FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed.User, and is useful for accessing the field via an interface.
+func (v *FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed) GetUser() FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessedUser {
+	return v.User
+}
+
+// GetConfigDefinition returns FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed.ConfigDefinition, and is useful for accessing the field via an interface.
+func (v *FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed) GetConfigDefinition() interface{} {
+	return v.ConfigDefinition
+}
+
+// FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessedImage includes the requested fields of the GraphQL type Image.
+type FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessedImage struct {
+	Digest string `json:"digest"`
+}
+
+// GetDigest returns FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessedImage.Digest, and is useful for accessing the field via an interface.
+func (v *FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessedImage) GetDigest() string {
+	return v.Digest
+}
+
+// FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessedUser includes the requested fields of the GraphQL type User.
+type FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessedUser struct {
+	// Email address for user (private)
+	Email string `json:"email"`
+}
+
+// GetEmail returns FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessedUser.Email, and is useful for accessing the field via an interface.
+func (v *FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessedUser) GetEmail() string {
+	return v.Email
+}
+
+// FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionPageInfo includes the requested fields of the GraphQL type PageInfo.
+// The GraphQL type's documentation follows.
+//
+// Information about pagination in a connection.
+type FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionPageInfo struct {
+	// When paginating forwards, are there more items?
+	HasNextPage bool `json:"hasNextPage"`
+	// When paginating forwards, the cursor to continue.
+	EndCursor string `json:"endCursor"`
+}
+
+// GetHasNextPage returns FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionPageInfo.HasNextPage, and is useful for accessing the field via an interface.
+func (v *FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionPageInfo) GetHasNextPage() bool {
+	return v.HasNextPage
+}
+
+// GetEndCursor returns FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionPageInfo.EndCursor, and is useful for accessing the field via an interface.
+func (v *FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionPageInfo) GetEndCursor() string {
+	return v.EndCursor
+}
+
+// FlyctlReleasesUnprocessedResponse is returned by FlyctlReleasesUnprocessed on success.
+type FlyctlReleasesUnprocessedResponse struct {
+	// Find an app by name
+	App FlyctlReleasesUnprocessedApp `json:"app"`
+}
+
+// GetApp returns FlyctlReleasesUnprocessedResponse.App, and is useful for accessing the field via an interface.
+func (v *FlyctlReleasesUnprocessedResponse) GetApp() FlyctlReleasesUnprocessedApp { return v.App }
+
 // GetAddOnAddOn includes the requested fields of the GraphQL type AddOn.
 type GetAddOnAddOn struct {
 	AddOnData `json:"-"`
@@ -3247,6 +3395,18 @@ type __FlyctlConfigCurrentReleaseInput struct {
 // GetAppName returns __FlyctlConfigCurrentReleaseInput.AppName, and is useful for accessing the field via an interface.
 func (v *__FlyctlConfigCurrentReleaseInput) GetAppName() string { return v.AppName }
 
+// __FlyctlReleasesUnprocessedInput is used internally by genqlient
+type __FlyctlReleasesUnprocessedInput struct {
+	AppName string `json:"appName"`
+	After   string `json:"after,omitempty"`
+}
+
+// GetAppName returns __FlyctlReleasesUnprocessedInput.AppName, and is useful for accessing the field via an interface.
+func (v *__FlyctlReleasesUnprocessedInput) GetAppName() string { return v.AppName }
+
+// GetAfter returns __FlyctlReleasesUnprocessedInput.After, and is useful for accessing the field via an interface.
+func (v *__FlyctlReleasesUnprocessedInput) GetAfter() string { return v.After }
+
 // __GetAddOnInput is used internally by genqlient
 type __GetAddOnInput struct {
 	Name     string `json:"name"`
@@ -3827,6 +3987,62 @@ func FlyctlConfigCurrentRelease(
 	return data_, err_
 }
 
+// The query executed by FlyctlReleasesUnprocessed.
+const FlyctlReleasesUnprocessed_Operation = `
+query FlyctlReleasesUnprocessed ($appName: String!, $after: String) {
+	app(name: $appName) {
+		releasesUnprocessed(first: 50, after: $after) {
+			pageInfo {
+				hasNextPage
+				endCursor
+			}
+			nodes {
+				version
+				description
+				reason
+				status
+				createdAt
+				imageRef
+				image {
+					digest
+				}
+				user {
+					email
+				}
+				configDefinition
+			}
+		}
+	}
+}
+`
+
+func FlyctlReleasesUnprocessed(
+	ctx_ context.Context,
+	client_ graphql.Client,
+	appName string,
+	after string,
+) (data_ *FlyctlReleasesUnprocessedResponse, err_ error) {
+	req_ := &graphql.Request{
+		OpName: "FlyctlReleasesUnprocessed",
+		Query:  FlyctlReleasesUnprocessed_Operation,
+		Variables: &__FlyctlReleasesUnprocessedInput{
+			AppName: appName,
+			After:   after,
+		},
+	}
+
+	data_ = &FlyctlReleasesUnprocessedResponse{}
+	resp_ := &graphql.Response{Data: data_}
+
+	err_ = client_.MakeRequest(
+		ctx_,
+		req_,
+		resp_,
+	)
+
+	return data_, err_
+}
+
 // The query executed by GetAddOn.
 const GetAddOn_Operation = `
 query GetAddOn ($name: String, $provider: String) {
@@ -116,6 +116,33 @@ type Deploy struct {
 	ReleaseCommandTimeout *fly.Duration `toml:"release_command_timeout,omitempty" json:"release_command_timeout,omitempty"`
 	ReleaseCommandCompute *Compute      `toml:"release_command_vm,omitempty" json:"release_command_vm,omitempty"`
 	SeedCommand           string        `toml:"seed_command,omitempty" json:"seed_command,omitempty"`
+	// ImagePolicy is the path of a policy file the image is checked
+	// against before it's deployed.
+	ImagePolicy string `toml:"image_policy,omitempty" json:"image_policy,omitempty"`
+	// Policy is the path of a policy file of rules the deploy is checked
+	// against before it starts.
+	Policy string `toml:"policy,omitempty" json:"policy,omitempty"`
+	// Notifications are where deploy events are posted to.
+	Notifications *DeployNotifications `toml:"notifications,omitempty" json:"notifications,omitempty"`
+}
+
+// DeployNotifications configures the webhooks that deploys post their
+// start, success and failure to.
+type DeployNotifications struct {
+	Webhooks []DeployWebhook `toml:"webhooks,omitempty" json:"webhooks,omitempty"`
+}
+
+// DeployWebhook is a webhook deploy events are posted to.
+type DeployWebhook struct {
+	// URL can refer to environment variables, like ${SLACK_WEBHOOK_URL},
+	// to keep secret URLs out of fly.toml.
+	URL string `toml:"url,omitempty" json:"url,omitempty"`
+	// Format is the payload format: "json" (the default), "slack" or
+	// "discord".
+	Format string `toml:"format,omitempty" json:"format,omitempty"`
+	// Events are the events to post: "started", "succeeded" and "failed".
+	// All of them are posted if none are set.
+	Events []string `toml:"events,omitempty" json:"events,omitempty"`
 }
 
 type File struct {
@@ -184,6 +211,52 @@ type Build struct {
 	Compose           *BuildCompose     `toml:"compose,omitempty" json:"compose,omitempty"`
 	Compression       string            `toml:"compression,omitempty" json:"compression,omitempty"`
 	CompressionLevel  *int              `toml:"compression_level,omitempty" json:"compression_level,omitempty"`
+	Cache             *BuildCache       `toml:"cache,omitempty" json:"cache,omitempty"`
+	// Platforms lists the platforms to build the image for, such as
+	// "linux/amd64" and "linux/arm64". More than one produces a multi-platform
+	// image. Defaults to linux/amd64.
+	Platforms    []string           `toml:"platforms,omitempty" json:"platforms,omitempty"`
+	Attestations *BuildAttestations `toml:"attestations,omitempty" json:"attestations,omitempty"`
+	// Files are copied on top of Image to build the image without Docker.
+	Files []BuildFile `toml:"files,omitempty" json:"files,omitempty"`
+}
+
+// BuildFile is a local file or directory copied into the image.
+type BuildFile struct {
+	// LocalPath is relative to the directory of fly.toml.
+	LocalPath string `toml:"local_path,omitempty" json:"local_path,omitempty"`
+	// ImagePath is the absolute path of the file in the image.
+	ImagePath string `toml:"image_path,omitempty" json:"image_path,omitempty"`
+}
+
+// BuildAttestations sets which attestations BuildKit builds attach to the
+// image.
+type BuildAttestations struct {
+	// SBOM generates a software bill of materials of the image.
+	SBOM bool `toml:"sbom,omitempty" json:"sbom,omitempty"`
+	// SBOMFormat is "spdx", the default, or "cyclonedx".
+	SBOMFormat string `toml:"sbom_format,omitempty" json:"sbom_format,omitempty"`
+	// Provenance is "min" or "max" to generate SLSA provenance of that
+	// detail.
+	Provenance string `toml:"provenance,omitempty" json:"provenance,omitempty"`
+}
+
+// BuildCache sets where builds import their layer cache from and export it
+// to, so that builds without a long-lived builder, like those on CI runners,
+// can start warm.
+type BuildCache struct {
+	// Inline embeds cache metadata in the image, which later builds can then
+	// import by listing the image in From.
+	Inline bool `toml:"inline,omitempty" json:"inline,omitempty"`
+	// From lists image references to import cache from.
+	From []string `toml:"from,omitempty" json:"from,omitempty"`
+	// To is an image reference to export cache to.
+	To string `toml:"to,omitempty" json:"to,omitempty"`
+	// Dir is a local directory to import cache from and export it to.
+	Dir string `toml:"dir,omitempty" json:"dir,omitempty"`
+	// Mode is "max", the default, to export the layers of every build stage,
+	// or "min" for only those of the final image.
+	Mode string `toml:"mode,omitempty" json:"mode,omitempty"`
 }
 
 type Experimental struct {
@@ -354,7 +427,11 @@ func (c *Config) BuildStrategies() []string {
 	}
 
 	if c.Build.Image != "" {
-		strategies = append(strategies, fmt.Sprintf("the \"%s\" docker image", c.Build.Image))
+		if len(c.Build.Files) > 0 {
+			strategies = append(strategies, fmt.Sprintf("files copied onto the \"%s\" docker image", c.Build.Image))
+		} else {
+			strategies = append(strategies, fmt.Sprintf("the \"%s\" docker image", c.Build.Image))
+		}
 	}
 	if c.Build.Builder != "" || len(c.Build.Buildpacks) > 0 {
 		strategies = append(strategies, "a buildpack")
@@ -176,6 +176,25 @@ func TestToDefinition(t *testing.T) {
 				"param1": "value1",
 				"param2": "value2",
 			},
+			"platforms": []any{"linux/amd64", "linux/arm64"},
+			"cache": map[string]any{
+				"inline": true,
+				"from":   []any{"registry.fly.io/foo:cache"},
+				"to":     "registry.fly.io/foo:cache",
+				"dir":    ".cache/build",
+				"mode":   "min",
+			},
+			"attestations": map[string]any{
+				"sbom":        true,
+				"sbom_format": "cyclonedx",
+				"provenance":  "max",
+			},
+			"files": []any{
+				map[string]any{
+					"local_path": "bin/server",
+					"image_path": "/app/server",
+				},
+			},
 		},
 
 		"restart": []any{
@@ -265,6 +284,14 @@ func TestToDefinition(t *testing.T) {
 				"size":   "performance-2x",
 				"memory": "8g",
 			},
+			"image_policy": "image-policy.toml",
+			"policy":       "deploy-policy.toml",
+			"notifications": map[string]any{
+				"webhooks": []any{
+					map[string]any{"url": "https://example.com/deploys", "events": []any{"failed"}},
+					map[string]any{"url": "${SLACK_WEBHOOK_URL}", "format": "slack"},
+				},
+			},
 		},
 		"env": map[string]any{
 			"FOO": "BAR",
@@ -470,6 +470,23 @@ func TestLoadTOMLAppConfigReferenceFormat(t *testing.T) {
 				"param1": "value1",
 				"param2": "value2",
 			},
+			Platforms: []string{"linux/amd64", "linux/arm64"},
+			Cache: &BuildCache{
+				Inline: true,
+				From:   []string{"registry.fly.io/foo:cache"},
+				To:     "registry.fly.io/foo:cache",
+				Dir:    ".cache/build",
+				Mode:   "min",
+			},
+			Attestations: &BuildAttestations{
+				SBOM:       true,
+				SBOMFormat: "cyclonedx",
+				Provenance: "max",
+			},
+			Files: []BuildFile{{
+				LocalPath: "bin/server",
+				ImagePath: "/app/server",
+			}},
 		},
 
 		Deploy: &Deploy{
@@ -481,6 +498,14 @@ func TestLoadTOMLAppConfigReferenceFormat(t *testing.T) {
 				Size:   "performance-2x",
 				Memory: "8g",
 			},
+			ImagePolicy: "image-policy.toml",
+			Policy:      "deploy-policy.toml",
+			Notifications: &DeployNotifications{
+				Webhooks: []DeployWebhook{
+					{URL: "https://example.com/deploys", Events: []string{"failed"}},
+					{URL: "${SLACK_WEBHOOK_URL}", Format: "slack"},
+				},
+			},
 		},
 
 		Env: map[string]string{
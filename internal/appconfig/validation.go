@@ -1,9 +1,11 @@
 package appconfig
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"path"
 	"slices"
 	"strings"
 	"time"
@@ -20,6 +22,11 @@ import (
 var (
 	ErrInvalidApplicationConfig = errors.New("invalid app configuration")
 	MachinesDeployStrategies    = []string{"canary", "rolling", "immediate", "bluegreen"}
+	// DeployWebhookFormats are the payload formats of deploy notification
+	// webhooks.
+	DeployWebhookFormats = []string{"json", "slack", "discord"}
+	// DeployEvents are the events deploys post to notification webhooks.
+	DeployEvents = []string{"started", "succeeded", "failed"}
 )
 
 func (c *Config) Validate(ctx context.Context) (err error, extra_info string) {
@@ -38,6 +45,10 @@ func (c *Config) Validate(ctx context.Context) (err error, extra_info string) {
 		c.validateMounts,
 		c.validateRestartPolicy,
 		c.validateCompression,
+		c.validateBuildCache,
+		c.validateBuildPlatforms,
+		c.validateBuildAttestations,
+		c.validateBuildFiles,
 	}
 
 	extra_info = fmt.Sprintf("Validating %s\n", c.ConfigFilePath())
@@ -121,6 +132,25 @@ func (c *Config) validateDeploySection() (extraInfo string, err error) {
 		}
 	}
 
+	if c.Deploy.Notifications != nil {
+		for _, webhook := range c.Deploy.Notifications.Webhooks {
+			if webhook.URL == "" {
+				extraInfo += "deploy notification webhooks must set url\n"
+				err = ErrInvalidApplicationConfig
+			}
+			if !slices.Contains(DeployWebhookFormats, cmp.Or(webhook.Format, "json")) {
+				extraInfo += fmt.Sprintf("deploy notification webhook format must be one of %s, not %q\n", strings.Join(DeployWebhookFormats, ", "), webhook.Format)
+				err = ErrInvalidApplicationConfig
+			}
+			for _, event := range webhook.Events {
+				if !slices.Contains(DeployEvents, event) {
+					extraInfo += fmt.Sprintf("deploy notification event must be one of %s, not %q\n", strings.Join(DeployEvents, ", "), event)
+					err = ErrInvalidApplicationConfig
+				}
+			}
+		}
+	}
+
 	return
 }
 
@@ -387,3 +417,88 @@ func (c *Config) validateCompression() (extraInfo string, err error) {
 
 	return
 }
+
+func (c *Config) validateBuildCache() (extraInfo string, err error) {
+	if c.Build == nil || c.Build.Cache == nil {
+		return
+	}
+
+	switch c.Build.Cache.Mode {
+	case "", "min", "max":
+	default:
+		extraInfo += fmt.Sprintf("build cache mode must be \"min\" or \"max\", not %q\n", c.Build.Cache.Mode)
+		err = ErrInvalidApplicationConfig
+	}
+
+	return
+}
+
+// buildPlatforms are the platforms Fly Machines run on.
+var buildPlatforms = []string{"linux/amd64", "linux/arm64"}
+
+func (c *Config) validateBuildPlatforms() (extraInfo string, err error) {
+	if c.Build == nil {
+		return
+	}
+
+	seen := map[string]bool{}
+	for _, platform := range c.Build.Platforms {
+		if !slices.Contains(buildPlatforms, platform) {
+			extraInfo += fmt.Sprintf("build platform %q is not supported; use %s\n", platform, strings.Join(buildPlatforms, " or "))
+			err = ErrInvalidApplicationConfig
+		}
+		if seen[platform] {
+			extraInfo += fmt.Sprintf("build platform %q is listed more than once\n", platform)
+			err = ErrInvalidApplicationConfig
+		}
+		seen[platform] = true
+	}
+
+	return
+}
+
+func (c *Config) validateBuildAttestations() (extraInfo string, err error) {
+	if c.Build == nil || c.Build.Attestations == nil {
+		return
+	}
+
+	switch c.Build.Attestations.SBOMFormat {
+	case "", "spdx", "cyclonedx":
+	default:
+		extraInfo += fmt.Sprintf("build attestations sbom_format must be \"spdx\" or \"cyclonedx\", not %q\n", c.Build.Attestations.SBOMFormat)
+		err = ErrInvalidApplicationConfig
+	}
+
+	switch c.Build.Attestations.Provenance {
+	case "", "min", "max":
+	default:
+		extraInfo += fmt.Sprintf("build attestations provenance must be \"min\" or \"max\", not %q\n", c.Build.Attestations.Provenance)
+		err = ErrInvalidApplicationConfig
+	}
+
+	return
+}
+
+func (c *Config) validateBuildFiles() (extraInfo string, err error) {
+	if c.Build == nil || len(c.Build.Files) == 0 {
+		return
+	}
+
+	if c.Build.Image == "" {
+		extraInfo += "build files need a base image; set image in [build], e.g. \"scratch\"\n"
+		err = ErrInvalidApplicationConfig
+	}
+
+	for _, f := range c.Build.Files {
+		if f.LocalPath == "" {
+			extraInfo += "build files must set local_path\n"
+			err = ErrInvalidApplicationConfig
+		}
+		if !path.IsAbs(f.ImagePath) {
+			extraInfo += fmt.Sprintf("build file image_path must be absolute, not %q\n", f.ImagePath)
+			err = ErrInvalidApplicationConfig
+		}
+	}
+
+	return
+}
@@ -96,6 +96,59 @@ func TestConfig_ValidateMounts(t *testing.T) {
 	require.Contains(t, x, "group 'app' has more than one [[mounts]] section defined")
 }
 
+func TestConfig_ValidateBuildPlatforms(t *testing.T) {
+	cfg := &Config{Build: &Build{Platforms: []string{"linux/amd64", "linux/arm64"}}}
+	_, err := cfg.validateBuildPlatforms()
+	require.NoError(t, err)
+
+	cfg.Build.Platforms = []string{"linux/arm64", "windows/amd64", "linux/arm64"}
+	x, err := cfg.validateBuildPlatforms()
+	require.ErrorIs(t, err, ErrInvalidApplicationConfig)
+	require.Contains(t, x, `build platform "windows/amd64" is not supported`)
+	require.Contains(t, x, `build platform "linux/arm64" is listed more than once`)
+}
+
+func TestConfig_ValidateBuildAttestations(t *testing.T) {
+	cfg := &Config{Build: &Build{Attestations: &BuildAttestations{SBOM: true, SBOMFormat: "cyclonedx", Provenance: "min"}}}
+	_, err := cfg.validateBuildAttestations()
+	require.NoError(t, err)
+
+	cfg.Build.Attestations = &BuildAttestations{SBOMFormat: "syft", Provenance: "full"}
+	x, err := cfg.validateBuildAttestations()
+	require.ErrorIs(t, err, ErrInvalidApplicationConfig)
+	require.Contains(t, x, `sbom_format must be "spdx" or "cyclonedx", not "syft"`)
+	require.Contains(t, x, `provenance must be "min" or "max", not "full"`)
+}
+
+func TestConfig_ValidateBuildFiles(t *testing.T) {
+	cfg := &Config{Build: &Build{Image: "scratch", Files: []BuildFile{{LocalPath: "bin/server", ImagePath: "/app/server"}}}}
+	_, err := cfg.validateBuildFiles()
+	require.NoError(t, err)
+
+	cfg.Build = &Build{Files: []BuildFile{{ImagePath: "app/server"}}}
+	x, err := cfg.validateBuildFiles()
+	require.ErrorIs(t, err, ErrInvalidApplicationConfig)
+	require.Contains(t, x, "build files need a base image")
+	require.Contains(t, x, "build files must set local_path")
+	require.Contains(t, x, `image_path must be absolute, not "app/server"`)
+}
+
+func TestConfig_ValidateDeployNotifications(t *testing.T) {
+	cfg := &Config{Deploy: &Deploy{Notifications: &DeployNotifications{Webhooks: []DeployWebhook{
+		{URL: "https://example.com/deploys"},
+		{URL: "${SLACK_WEBHOOK_URL}", Format: "slack", Events: []string{"failed"}},
+	}}}}
+	_, err := cfg.validateDeploySection()
+	require.NoError(t, err)
+
+	cfg.Deploy.Notifications.Webhooks = []DeployWebhook{{Format: "teams", Events: []string{"finished"}}}
+	x, err := cfg.validateDeploySection()
+	require.ErrorIs(t, err, ErrInvalidApplicationConfig)
+	require.Contains(t, x, "deploy notification webhooks must set url")
+	require.Contains(t, x, `format must be one of json, slack, discord, not "teams"`)
+	require.Contains(t, x, `event must be one of started, succeeded, failed, not "finished"`)
+}
+
 func TestConfig_ValidateServices(t *testing.T) {
 	cfg, err := LoadConfig("./testdata/validate-services.toml")
 	require.NoError(t, err)
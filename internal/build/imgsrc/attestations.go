@@ -0,0 +1,323 @@
+package imgsrc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/containerd/containerd/api/services/content/v1"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/terminal"
+)
+
+// Predicate types of the in-toto attestations BuildKit generates.
+const (
+	spdxPredicateType          = "https://spdx.dev/Document"
+	slsaProvenanceV02Predicate = "https://slsa.dev/provenance/v0.2"
+	slsaProvenanceV1Predicate  = "https://slsa.dev/provenance/v1"
+)
+
+// Annotations BuildKit puts on attestations in an image index.
+const (
+	referenceTypeAnnotation   = "vnd.docker.reference.type"
+	referenceDigestAnnotation = "vnd.docker.reference.digest"
+	predicateTypeAnnotation   = "in-toto.io/predicate-type"
+	attestationManifestType   = "attestation-manifest"
+)
+
+// SBOM formats.
+const (
+	SBOMFormatSPDX      = "spdx"
+	SBOMFormatCycloneDX = "cyclonedx"
+)
+
+// ImageAttestation holds the attestations BuildKit attached to the image of
+// one platform.
+type ImageAttestation struct {
+	Platform   string
+	SBOM       *SBOM
+	Provenance *Provenance
+}
+
+// SBOM is a software bill of materials of an image.
+type SBOM struct {
+	// Format is SBOMFormatSPDX or SBOMFormatCycloneDX.
+	Format   string
+	Document json.RawMessage
+	Packages []SBOMPackage
+}
+
+// SBOMPackage is a package listed in an SBOM.
+type SBOMPackage struct {
+	Name    string
+	Version string
+	PURL    string
+	// License is an SPDX license expression, or "" if it isn't known.
+	License string
+}
+
+// Provenance is the SLSA provenance of an image.
+type Provenance struct {
+	PredicateType string
+	Predicate     json.RawMessage
+	// BaseImages lists the images the build pulled, like node:20-slim.
+	BaseImages []string
+}
+
+// attestationFrontendAttrs returns the BuildKit frontend attributes that
+// request the attestations set in cfg.
+func attestationFrontendAttrs(cfg *appconfig.BuildAttestations) map[string]string {
+	attrs := map[string]string{}
+	if cfg == nil {
+		return attrs
+	}
+
+	if cfg.SBOM {
+		attrs["attest:sbom"] = ""
+	}
+	if cfg.Provenance != "" {
+		attrs["attest:provenance"] = "mode=" + cfg.Provenance
+	}
+
+	return attrs
+}
+
+// warnAttestationsUnsupported warns that builder ignores [build.attestations].
+func warnAttestationsUnsupported(opts ImageOptions, builder string) {
+	if opts.Attestations == nil || (!opts.Attestations.SBOM && opts.Attestations.Provenance == "") {
+		return
+	}
+
+	terminal.Warnf("The %s builder can't generate attestations; use the Depot builder or a BuildKit builder for [build.attestations]\n", builder)
+}
+
+type inTotoStatement struct {
+	PredicateType string          `json:"predicateType"`
+	Predicate     json.RawMessage `json:"predicate"`
+}
+
+type attestationManifest struct {
+	Layers []struct {
+		OCIDescriptor
+		Annotations map[string]string `json:"annotations,omitempty"`
+	} `json:"layers"`
+}
+
+// readAttestations returns the attestations in the image index rawIndex,
+// reading them from contentClient. SBOMs are returned in sbomFormat.
+func readAttestations(ctx context.Context, contentClient content.ContentClient, rawIndex, sbomFormat string) ([]ImageAttestation, error) {
+	var index imageIndex
+	if err := json.Unmarshal([]byte(rawIndex), &index); err != nil {
+		return nil, fmt.Errorf("failed to parse image index: %w", err)
+	}
+
+	platforms := map[string]string{}
+	for _, m := range index.Manifests {
+		if m.Platform != nil && m.Platform.OS != "unknown" {
+			platforms[m.Digest] = m.Platform.String()
+		}
+	}
+
+	var attestations []ImageAttestation
+	for _, m := range index.Manifests {
+		if m.Annotations[referenceTypeAnnotation] != attestationManifestType {
+			continue
+		}
+
+		raw, err := readContent(ctx, contentClient, &Descriptor{Digest: m.Digest})
+		if err != nil {
+			return nil, err
+		}
+		var manifest attestationManifest
+		if err := json.Unmarshal([]byte(raw), &manifest); err != nil {
+			return nil, fmt.Errorf("failed to parse attestation manifest: %w", err)
+		}
+
+		attestation := ImageAttestation{Platform: platforms[m.Annotations[referenceDigestAnnotation]]}
+		for _, layer := range manifest.Layers {
+			predicateType := layer.Annotations[predicateTypeAnnotation]
+			if predicateType == "" {
+				continue
+			}
+
+			raw, err := readContent(ctx, contentClient, &Descriptor{Digest: layer.Digest})
+			if err != nil {
+				return nil, err
+			}
+			var statement inTotoStatement
+			if err := json.Unmarshal([]byte(raw), &statement); err != nil {
+				return nil, fmt.Errorf("failed to parse %s attestation: %w", predicateType, err)
+			}
+
+			switch statement.PredicateType {
+			case spdxPredicateType:
+				if attestation.SBOM, err = parseSPDX(statement.Predicate); err != nil {
+					return nil, err
+				}
+				if sbomFormat == SBOMFormatCycloneDX {
+					if attestation.SBOM, err = attestation.SBOM.CycloneDX(); err != nil {
+						return nil, err
+					}
+				}
+			case slsaProvenanceV02Predicate, slsaProvenanceV1Predicate:
+				if attestation.Provenance, err = parseProvenance(statement.PredicateType, statement.Predicate); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		attestations = append(attestations, attestation)
+	}
+
+	return attestations, nil
+}
+
+type spdxDocument struct {
+	Packages []struct {
+		Name             string `json:"name"`
+		VersionInfo      string `json:"versionInfo"`
+		LicenseConcluded string `json:"licenseConcluded"`
+		LicenseDeclared  string `json:"licenseDeclared"`
+		ExternalRefs     []struct {
+			ReferenceType    string `json:"referenceType"`
+			ReferenceLocator string `json:"referenceLocator"`
+		} `json:"externalRefs"`
+	} `json:"packages"`
+}
+
+func parseSPDX(doc json.RawMessage) (*SBOM, error) {
+	var spdx spdxDocument
+	if err := json.Unmarshal(doc, &spdx); err != nil {
+		return nil, fmt.Errorf("failed to parse SPDX SBOM: %w", err)
+	}
+
+	sbom := &SBOM{Format: SBOMFormatSPDX, Document: doc}
+	for _, p := range spdx.Packages {
+		pkg := SBOMPackage{Name: p.Name, Version: p.VersionInfo, License: spdxLicense(p.LicenseConcluded)}
+		if pkg.License == "" {
+			pkg.License = spdxLicense(p.LicenseDeclared)
+		}
+		for _, ref := range p.ExternalRefs {
+			if ref.ReferenceType == "purl" {
+				pkg.PURL = ref.ReferenceLocator
+				break
+			}
+		}
+		sbom.Packages = append(sbom.Packages, pkg)
+	}
+
+	return sbom, nil
+}
+
+// spdxLicense returns license, or "" if it's one of SPDX's placeholders for
+// an unknown license.
+func spdxLicense(license string) string {
+	switch license {
+	case "", "NOASSERTION", "NONE":
+		return ""
+	default:
+		return license
+	}
+}
+
+type cycloneDXDocument struct {
+	BOMFormat   string               `json:"bomFormat"`
+	SpecVersion string               `json:"specVersion"`
+	Version     int                  `json:"version"`
+	Components  []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXComponent struct {
+	Type     string             `json:"type"`
+	Name     string             `json:"name"`
+	Version  string             `json:"version,omitempty"`
+	PURL     string             `json:"purl,omitempty"`
+	Licenses []cycloneDXLicense `json:"licenses,omitempty"`
+}
+
+type cycloneDXLicense struct {
+	Expression string `json:"expression"`
+}
+
+// CycloneDX returns the SBOM as a CycloneDX document.
+func (s *SBOM) CycloneDX() (*SBOM, error) {
+	if s.Format == SBOMFormatCycloneDX {
+		return s, nil
+	}
+
+	doc := cycloneDXDocument{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.5",
+		Version:     1,
+		Components:  make([]cycloneDXComponent, 0, len(s.Packages)),
+	}
+	for _, p := range s.Packages {
+		component := cycloneDXComponent{Type: "library", Name: p.Name, Version: p.Version, PURL: p.PURL}
+		if p.License != "" {
+			component.Licenses = []cycloneDXLicense{{Expression: p.License}}
+		}
+		doc.Components = append(doc.Components, component)
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SBOM{Format: SBOMFormatCycloneDX, Document: data, Packages: s.Packages}, nil
+}
+
+type slsaMaterial struct {
+	URI string `json:"uri"`
+}
+
+func parseProvenance(predicateType string, predicate json.RawMessage) (*Provenance, error) {
+	var doc struct {
+		// SLSA v0.2
+		Materials []slsaMaterial `json:"materials"`
+		// SLSA v1
+		BuildDefinition struct {
+			ResolvedDependencies []slsaMaterial `json:"resolvedDependencies"`
+		} `json:"buildDefinition"`
+	}
+	if err := json.Unmarshal(predicate, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse provenance: %w", err)
+	}
+
+	provenance := &Provenance{PredicateType: predicateType, Predicate: predicate}
+	for _, m := range append(doc.Materials, doc.BuildDefinition.ResolvedDependencies...) {
+		if image := imageFromPURL(m.URI); image != "" {
+			provenance.BaseImages = append(provenance.BaseImages, image)
+		}
+	}
+
+	return provenance, nil
+}
+
+// imageFromPURL turns the package URL of a Docker image, like
+// pkg:docker/node@20-slim?platform=linux%2Famd64, into an image reference,
+// like node:20-slim. It returns "" for other package URLs.
+func imageFromPURL(purl string) string {
+	name, ok := strings.CutPrefix(purl, "pkg:docker/")
+	if !ok {
+		return ""
+	}
+	name, _, _ = strings.Cut(name, "?")
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+
+	if i := strings.LastIndex(name, "@"); i >= 0 {
+		version := name[i+1:]
+		name = name[:i]
+		if strings.Contains(version, ":") {
+			return name + "@" + version
+		}
+
+		return name + ":" + version
+	}
+
+	return name
+}
@@ -0,0 +1,95 @@
+package imgsrc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/internal/appconfig"
+)
+
+const testSPDX = `{
+  "spdxVersion": "SPDX-2.3",
+  "packages": [
+    {"name": "express", "versionInfo": "4.19.2", "licenseConcluded": "MIT",
+     "externalRefs": [{"referenceType": "purl", "referenceLocator": "pkg:npm/express@4.19.2"}]},
+    {"name": "busybox", "versionInfo": "1.36.1", "licenseConcluded": "NOASSERTION", "licenseDeclared": "GPL-2.0-only"}
+  ]
+}`
+
+const testProvenance = `{
+  "buildType": "https://mobyproject.org/buildkit@v1",
+  "materials": [
+    {"uri": "pkg:docker/node@20-slim?platform=linux%2Famd64", "digest": {"sha256": "abc"}},
+    {"uri": "pkg:docker/ghcr.io/acme/base@sha256:def?platform=linux%2Famd64"},
+    {"uri": "https://github.com/acme/app.git#main"}
+  ]
+}`
+
+func TestReadAttestations(t *testing.T) {
+	index := `{
+  "mediaType": "application/vnd.oci.image.index.v1+json",
+  "manifests": [
+    {"digest": "sha256:img", "platform": {"os": "linux", "architecture": "amd64"}},
+    {"digest": "sha256:att", "platform": {"os": "unknown", "architecture": "unknown"},
+     "annotations": {"vnd.docker.reference.type": "attestation-manifest", "vnd.docker.reference.digest": "sha256:img"}}
+  ]
+}`
+	statement := func(predicateType, predicate string) string {
+		return `{"_type": "https://in-toto.io/Statement/v0.1", "predicateType": "` + predicateType + `", "predicate": ` + predicate + `}`
+	}
+	client := &fakeContentClient{blobs: map[string]string{
+		"sha256:att": `{"layers": [
+  {"digest": "sha256:sbom", "annotations": {"in-toto.io/predicate-type": "https://spdx.dev/Document"}},
+  {"digest": "sha256:prov", "annotations": {"in-toto.io/predicate-type": "https://slsa.dev/provenance/v0.2"}}
+]}`,
+		"sha256:sbom": statement(spdxPredicateType, testSPDX),
+		"sha256:prov": statement(slsaProvenanceV02Predicate, testProvenance),
+	}}
+
+	attestations, err := readAttestations(context.Background(), client, index, "")
+	require.NoError(t, err)
+	require.Len(t, attestations, 1)
+
+	a := attestations[0]
+	assert.Equal(t, "linux/amd64", a.Platform)
+	require.NotNil(t, a.SBOM)
+	assert.Equal(t, SBOMFormatSPDX, a.SBOM.Format)
+	assert.Equal(t, []SBOMPackage{
+		{Name: "express", Version: "4.19.2", PURL: "pkg:npm/express@4.19.2", License: "MIT"},
+		{Name: "busybox", Version: "1.36.1", License: "GPL-2.0-only"},
+	}, a.SBOM.Packages)
+	require.NotNil(t, a.Provenance)
+	assert.Equal(t, []string{"node:20-slim", "ghcr.io/acme/base@sha256:def"}, a.Provenance.BaseImages)
+
+	attestations, err = readAttestations(context.Background(), client, index, SBOMFormatCycloneDX)
+	require.NoError(t, err)
+	assert.Equal(t, SBOMFormatCycloneDX, attestations[0].SBOM.Format)
+}
+
+func TestSBOMCycloneDX(t *testing.T) {
+	sbom, err := parseSPDX(json.RawMessage(testSPDX))
+	require.NoError(t, err)
+
+	cdx, err := sbom.CycloneDX()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "bomFormat": "CycloneDX",
+  "specVersion": "1.5",
+  "version": 1,
+  "components": [
+    {"type": "library", "name": "express", "version": "4.19.2", "purl": "pkg:npm/express@4.19.2", "licenses": [{"expression": "MIT"}]},
+    {"type": "library", "name": "busybox", "version": "1.36.1", "licenses": [{"expression": "GPL-2.0-only"}]}
+  ]
+}`, string(cdx.Document))
+}
+
+func TestAttestationFrontendAttrs(t *testing.T) {
+	assert.Empty(t, attestationFrontendAttrs(nil))
+	assert.Equal(t, map[string]string{
+		"attest:sbom":       "",
+		"attest:provenance": "mode=max",
+	}, attestationFrontendAttrs(&appconfig.BuildAttestations{SBOM: true, Provenance: "max"}))
+}
@@ -125,7 +125,7 @@ func (r *BuildkitBuilder) buildWithBuildkit(ctx context.Context, streams *iostre
 		return nil, err
 	}
 
-	return newDeploymentImage(ctx, buildkitClient, res, opts.Tag)
+	return newDeploymentImage(ctx, buildkitClient, res, opts)
 }
 
 func (r *BuildkitBuilder) connectClient(ctx context.Context, app *flaps.App, appName string) (*client.Client, error) {
@@ -7,6 +7,7 @@ import (
 	"maps"
 	"os"
 
+	"github.com/buildpacks/pack/pkg/cache"
 	packclient "github.com/buildpacks/pack/pkg/client"
 	projectTypes "github.com/buildpacks/pack/pkg/project/types"
 	"github.com/pkg/errors"
@@ -145,12 +146,37 @@ func (*buildpacksBuilder) Run(ctx context.Context, dockerFactory *dockerClientFa
 			attribute.Bool("is_remote", dockerFactory.IsRemote()),
 		),
 	)
+	buildCache, err := buildpacksCache(opts, dockerFactory.IsRemote())
+	if err != nil {
+		build.ImageBuildFinish()
+		build.BuildFinish()
+		buildSpan.End()
+
+		return nil, "", err
+	}
+
+	warnAttestationsUnsupported(opts, "buildpacks")
+
+	// Without platforms, pack builds for the platform of the Docker daemon.
+	var platform string
+	if len(opts.Platforms) > 0 {
+		if platform, err = singlePlatform(opts, "buildpacks"); err != nil {
+			build.ImageBuildFinish()
+			build.BuildFinish()
+			buildSpan.End()
+
+			return nil, "", err
+		}
+	}
+
 	var gid = -1
 	var uid = -1
 	err = packClient.Build(buildCtx, packclient.BuildOptions{
 		AppPath:        opts.WorkingDir,
 		Builder:        builder,
 		ClearCache:     opts.NoCache,
+		Cache:          buildCache,
+		Platform:       platform,
 		Image:          newCacheTag(opts.AppName),
 		DockerHost:     opts.BuildpacksDockerHost,
 		Buildpacks:     buildpacks,
@@ -320,3 +346,30 @@ func (w *fdWrapper) Fd() uintptr {
 
 	return ^(uintptr(0))
 }
+
+// buildpacksCache returns the pack cache options for the build cache
+// settings of opts. The build cache can only be kept in a directory, which
+// is bind mounted and so has to be on the same host as the Docker daemon.
+func buildpacksCache(opts ImageOptions, remote bool) (cache.CacheOpts, error) {
+	var cacheOpts cache.CacheOpts
+	if opts.Cache == nil {
+		return cacheOpts, nil
+	}
+
+	if opts.Cache.Inline || opts.Cache.To != "" || len(opts.Cache.From) > 0 {
+		terminal.Warnf("Buildpacks can't import or export build cache from a registry; use [build.cache] dir with a local Docker daemon instead\n")
+	}
+
+	if dir := cacheDir(opts); dir != "" {
+		if remote {
+			terminal.Warnf("Buildpacks can only use a cache directory with a local Docker daemon; ignoring %s\n", dir)
+			return cacheOpts, nil
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return cacheOpts, fmt.Errorf("failed to create build cache directory: %w", err)
+		}
+		cacheOpts.Build = cache.CacheInfo{Format: cache.CacheBind, Source: dir}
+	}
+
+	return cacheOpts, nil
+}
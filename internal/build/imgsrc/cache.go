@@ -0,0 +1,101 @@
+package imgsrc
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/moby/buildkit/client"
+	"github.com/superfly/flyctl/internal/appconfig"
+)
+
+// Buildkit cache exporters. Docker Engine's embedded Buildkit only supports
+// the inline one.
+const (
+	cacheInline   = "inline"
+	cacheRegistry = "registry"
+	cacheLocal    = "local"
+)
+
+var allCacheExporters = []string{cacheInline, cacheRegistry, cacheLocal}
+
+// cacheDir returns the build cache directory of opts, resolved against the
+// working directory, or "" if it has none.
+func cacheDir(opts ImageOptions) string {
+	if opts.Cache == nil || opts.Cache.Dir == "" {
+		return ""
+	}
+	if filepath.IsAbs(opts.Cache.Dir) {
+		return opts.Cache.Dir
+	}
+
+	return filepath.Join(opts.WorkingDir, opts.Cache.Dir)
+}
+
+func cacheMode(cache *appconfig.BuildCache) string {
+	if cache.Mode == "" {
+		return "max"
+	}
+
+	return cache.Mode
+}
+
+// buildkitCacheImports returns the cache Buildkit should import for opts.
+// --no-cache skips them all.
+func buildkitCacheImports(opts ImageOptions) []client.CacheOptionsEntry {
+	if opts.Cache == nil || opts.NoCache {
+		return nil
+	}
+
+	var imports []client.CacheOptionsEntry
+	for _, ref := range opts.Cache.From {
+		imports = append(imports, client.CacheOptionsEntry{
+			Type:  cacheRegistry,
+			Attrs: map[string]string{"ref": ref},
+		})
+	}
+	// Buildkit skips a directory that holds no cache yet, as on a first build.
+	if dir := cacheDir(opts); dir != "" {
+		imports = append(imports, client.CacheOptionsEntry{
+			Type:  cacheLocal,
+			Attrs: map[string]string{"src": dir},
+		})
+	}
+
+	return imports
+}
+
+// buildkitCacheExports returns the cache Buildkit should export for opts
+// using the given exporters, and a description of each export that had to be
+// skipped because its exporter isn't supported.
+func buildkitCacheExports(opts ImageOptions, supported []string) (exports []client.CacheOptionsEntry, skipped []string) {
+	if opts.Cache == nil {
+		return nil, nil
+	}
+
+	add := func(typ, what string, attrs map[string]string) {
+		if slices.Contains(supported, typ) {
+			exports = append(exports, client.CacheOptionsEntry{Type: typ, Attrs: attrs})
+		} else {
+			skipped = append(skipped, what)
+		}
+	}
+
+	if opts.Cache.Inline {
+		add(cacheInline, "inline cache", map[string]string{})
+	}
+	if opts.Cache.To != "" {
+		add(cacheRegistry, fmt.Sprintf("cache to %s", opts.Cache.To), map[string]string{
+			"ref":  opts.Cache.To,
+			"mode": cacheMode(opts.Cache),
+		})
+	}
+	if dir := cacheDir(opts); dir != "" {
+		add(cacheLocal, fmt.Sprintf("cache to directory %s", dir), map[string]string{
+			"dest": dir,
+			"mode": cacheMode(opts.Cache),
+		})
+	}
+
+	return exports, skipped
+}
@@ -0,0 +1,53 @@
+package imgsrc
+
+import (
+	"testing"
+
+	"github.com/moby/buildkit/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/internal/appconfig"
+)
+
+func TestBuildkitCacheImports(t *testing.T) {
+	opts := ImageOptions{
+		WorkingDir: "/app",
+		Cache: &appconfig.BuildCache{
+			From: []string{"registry.fly.io/foo:cache"},
+			Dir:  ".cache/build",
+		},
+	}
+
+	assert.Equal(t, []client.CacheOptionsEntry{
+		{Type: cacheRegistry, Attrs: map[string]string{"ref": "registry.fly.io/foo:cache"}},
+		{Type: cacheLocal, Attrs: map[string]string{"src": "/app/.cache/build"}},
+	}, buildkitCacheImports(opts))
+
+	opts.NoCache = true
+	assert.Empty(t, buildkitCacheImports(opts))
+
+	assert.Empty(t, buildkitCacheImports(ImageOptions{}))
+}
+
+func TestBuildkitCacheExports(t *testing.T) {
+	opts := ImageOptions{
+		WorkingDir: "/app",
+		Cache: &appconfig.BuildCache{
+			Inline: true,
+			To:     "registry.fly.io/foo:cache",
+			Dir:    "/tmp/cache",
+		},
+	}
+
+	exports, skipped := buildkitCacheExports(opts, allCacheExporters)
+	assert.Empty(t, skipped)
+	assert.Equal(t, []client.CacheOptionsEntry{
+		{Type: cacheInline, Attrs: map[string]string{}},
+		{Type: cacheRegistry, Attrs: map[string]string{"ref": "registry.fly.io/foo:cache", "mode": "max"}},
+		{Type: cacheLocal, Attrs: map[string]string{"dest": "/tmp/cache", "mode": "max"}},
+	}, exports)
+
+	opts.Cache.Mode = "min"
+	exports, skipped = buildkitCacheExports(opts, []string{cacheInline})
+	assert.Equal(t, []client.CacheOptionsEntry{{Type: cacheInline, Attrs: map[string]string{}}}, exports)
+	assert.Equal(t, []string{"cache to registry.fly.io/foo:cache", "cache to directory /tmp/cache"}, skipped)
+}
@@ -0,0 +1,413 @@
+package imgsrc
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/moby/patternmatcher"
+)
+
+// maxReportLargestFiles is the number of largest files a BuildContextReport
+// lists.
+const maxReportLargestFiles = 10
+
+// maxReportRulePaths is the number of excluded paths listed under each ignore
+// rule of a BuildContextReport.
+const maxReportRulePaths = 10
+
+// largeContextFileBytes is the size above which a single file in the build
+// context is suggested for .dockerignore.
+const largeContextFileBytes = 50_000_000
+
+// BuildContextReport describes what CreateArchive would send to the builder
+// for a working directory.
+type BuildContextReport struct {
+	WorkingDir string `json:"working_dir"`
+	Dockerfile string `json:"dockerfile,omitempty"`
+	// IgnoreFile is the .dockerignore that was applied, or "" if there was
+	// none and only the default rules apply.
+	IgnoreFile  string                   `json:"ignore_file,omitempty"`
+	TotalBytes  int64                    `json:"total_bytes"`
+	FileCount   int                      `json:"file_count"`
+	Tree        *BuildContextNode        `json:"tree"`
+	Rules       []BuildContextRule       `json:"rules"`
+	Largest     []BuildContextPath       `json:"largest"`
+	Suggestions []BuildContextSuggestion `json:"suggestions"`
+}
+
+// BuildContextNode is a file or directory of the build context. Directories
+// deeper than the depth the report was made with are folded into their
+// parent.
+type BuildContextNode struct {
+	Name     string              `json:"name"`
+	Path     string              `json:"path"`
+	Dir      bool                `json:"dir"`
+	Bytes    int64               `json:"bytes"`
+	Files    int                 `json:"files"`
+	Children []*BuildContextNode `json:"children,omitempty"`
+}
+
+// BuildContextPath is a path of the working directory along with the size of
+// the files at or beneath it.
+type BuildContextPath struct {
+	Path  string `json:"path"`
+	Dir   bool   `json:"dir"`
+	Bytes int64  `json:"bytes"`
+	Files int    `json:"files"`
+}
+
+// BuildContextRule is an ignore rule and what it kept out of the build
+// context. Paths holds the largest of the excluded paths.
+type BuildContextRule struct {
+	Pattern string             `json:"pattern"`
+	Bytes   int64              `json:"bytes"`
+	Files   int                `json:"files"`
+	Paths   []BuildContextPath `json:"paths"`
+}
+
+// BuildContextSuggestion is a pattern worth adding to .dockerignore.
+type BuildContextSuggestion struct {
+	Pattern string `json:"pattern"`
+	Reason  string `json:"reason"`
+	Bytes   int64  `json:"bytes"`
+	Files   int    `json:"files"`
+}
+
+// dockerignoreCandidates are paths that rarely belong in an image, keyed by
+// their base name.
+var dockerignoreCandidates = map[string]string{
+	".git":          "Git history is not needed to build the image",
+	".hg":           "Mercurial history is not needed to build the image",
+	".svn":          "Subversion metadata is not needed to build the image",
+	"node_modules":  "Dependencies are installed by the Dockerfile",
+	".venv":         "Virtual environments are created by the Dockerfile",
+	"venv":          "Virtual environments are created by the Dockerfile",
+	"__pycache__":   "Python bytecode is regenerated at runtime",
+	".pytest_cache": "Test caches are not needed in the image",
+	".mypy_cache":   "Type checker caches are not needed in the image",
+	".next":         "Next.js build output is rebuilt by the Dockerfile",
+	".nuxt":         "Nuxt build output is rebuilt by the Dockerfile",
+	".terraform":    "Terraform providers are not needed in the image",
+	"coverage":      "Coverage reports are not needed in the image",
+	".DS_Store":     "macOS metadata is not needed in the image",
+	".env":          "Environment files may hold secrets; use fly secrets instead",
+}
+
+// AnalyzeBuildContext walks workingDir applying the .dockerignore rules
+// CreateArchive uses and reports what would be sent to the builder, what each
+// rule excluded and what else could be left out. The tree is folded below
+// depth levels; 0 means no limit.
+func AnalyzeBuildContext(workingDir, dockerfile, ignoreFile string, depth int) (*BuildContextReport, error) {
+	excludes, err := buildContextExcludes(workingDir, dockerfile, ignoreFile)
+	if err != nil {
+		return nil, err
+	}
+	pm, err := patternmatcher.New(excludes)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := newRuleFinder(excludes)
+	if err != nil {
+		return nil, err
+	}
+
+	if ignoreFile == "" {
+		ignoreFile = filepath.Join(workingDir, ".dockerignore")
+	}
+	if _, err := os.Stat(ignoreFile); err != nil {
+		ignoreFile = ""
+	}
+
+	report := &BuildContextReport{
+		WorkingDir: workingDir,
+		Dockerfile: dockerfile,
+		IgnoreFile: ignoreFile,
+		Tree:       &BuildContextNode{Name: ".", Path: ".", Dir: true},
+	}
+	byRule := map[string]*BuildContextRule{}
+	candidates := map[string]*BuildContextSuggestion{}
+	var files []BuildContextPath
+
+	walkErr := filepath.WalkDir(workingDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+
+		rel, relErr := filepath.Rel(workingDir, p)
+		if relErr != nil || rel == "." {
+			return nil
+		}
+		slashRel := filepath.ToSlash(rel)
+
+		if excluded, _ := pm.MatchesOrParentMatches(slashRel); excluded {
+			skip := d.IsDir() && canSkipExcludedDir(pm, rel)
+			if skip || !d.IsDir() {
+				excludedPath := sizePath(p, slashRel, d)
+				pattern := rules.find(slashRel)
+				rule := byRule[pattern]
+				if rule == nil {
+					rule = &BuildContextRule{Pattern: pattern}
+					byRule[pattern] = rule
+				}
+				rule.Bytes += excludedPath.Bytes
+				rule.Files += excludedPath.Files
+				rule.Paths = append(rule.Paths, excludedPath)
+			}
+			if skip {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+
+		pattern, reason := dockerignoreCandidate(slashRel)
+		if pattern != "" && candidates[pattern] == nil {
+			candidates[pattern] = &BuildContextSuggestion{Pattern: pattern, Reason: reason}
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, infoErr := d.Info()
+		if infoErr != nil {
+			return nil
+		}
+
+		report.TotalBytes += info.Size()
+		report.FileCount++
+		report.Tree.add(strings.Split(slashRel, "/"), info.Size(), depth)
+		files = append(files, BuildContextPath{Path: slashRel, Bytes: info.Size(), Files: 1})
+		if c := candidates[pattern]; c != nil {
+			c.Bytes += info.Size()
+			c.Files++
+		}
+
+		return nil
+	})
+	if walkErr != nil {
+		return nil, walkErr
+	}
+
+	report.Tree.sort()
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Bytes != files[j].Bytes {
+			return files[i].Bytes > files[j].Bytes
+		}
+
+		return files[i].Path < files[j].Path
+	})
+	if len(files) > maxReportLargestFiles {
+		report.Largest = files[:maxReportLargestFiles]
+	} else {
+		report.Largest = files
+	}
+
+	report.Rules = make([]BuildContextRule, 0, len(byRule))
+	for _, rule := range byRule {
+		sortContextPaths(rule.Paths)
+		if len(rule.Paths) > maxReportRulePaths {
+			rule.Paths = rule.Paths[:maxReportRulePaths]
+		}
+		report.Rules = append(report.Rules, *rule)
+	}
+	sort.Slice(report.Rules, func(i, j int) bool {
+		if report.Rules[i].Bytes != report.Rules[j].Bytes {
+			return report.Rules[i].Bytes > report.Rules[j].Bytes
+		}
+
+		return report.Rules[i].Pattern < report.Rules[j].Pattern
+	})
+
+	report.Suggestions = suggestDockerignore(candidates, files)
+
+	return report, nil
+}
+
+// suggestDockerignore returns the candidates that were found in the build
+// context, along with any single file large enough to be worth a look,
+// largest first.
+func suggestDockerignore(candidates map[string]*BuildContextSuggestion, files []BuildContextPath) []BuildContextSuggestion {
+	suggestions := make([]BuildContextSuggestion, 0, len(candidates))
+	for _, c := range candidates {
+		suggestions = append(suggestions, *c)
+	}
+
+	for _, f := range files {
+		if f.Bytes < largeContextFileBytes {
+			break // files are sorted largest first
+		}
+		if pattern, _ := dockerignoreCandidate(f.Path); pattern == "" {
+			suggestions = append(suggestions, BuildContextSuggestion{
+				Pattern: f.Path,
+				Reason:  "Large file; leave it out unless the image needs it",
+				Bytes:   f.Bytes,
+				Files:   1,
+			})
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Bytes != suggestions[j].Bytes {
+			return suggestions[i].Bytes > suggestions[j].Bytes
+		}
+
+		return suggestions[i].Pattern < suggestions[j].Pattern
+	})
+
+	return suggestions
+}
+
+// dockerignoreCandidate returns the pattern of the outermost candidate that
+// rel is or is beneath, and why it's a candidate, or "" if there is none.
+func dockerignoreCandidate(rel string) (pattern, reason string) {
+	parts := strings.Split(rel, "/")
+	for i, part := range parts {
+		if reason, ok := dockerignoreCandidates[part]; ok {
+			if i == 0 {
+				return part, reason
+			}
+
+			return "**/" + part, reason
+		}
+	}
+
+	return "", ""
+}
+
+// add counts a file of the given size, at the path made of parts below n,
+// folding directories deeper than depth into their parent.
+func (n *BuildContextNode) add(parts []string, size int64, depth int) {
+	n.Bytes += size
+	n.Files++
+	if len(parts) == 0 || (depth > 0 && n.depth() >= depth) {
+		return
+	}
+
+	var child *BuildContextNode
+	for _, c := range n.Children {
+		if c.Name == parts[0] {
+			child = c
+			break
+		}
+	}
+	if child == nil {
+		childPath := parts[0]
+		if n.Path != "." {
+			childPath = n.Path + "/" + parts[0]
+		}
+		child = &BuildContextNode{Name: parts[0], Path: childPath, Dir: len(parts) > 1}
+		n.Children = append(n.Children, child)
+	}
+	child.add(parts[1:], size, depth)
+}
+
+// depth returns how many levels below the root of the build context n is.
+func (n *BuildContextNode) depth() int {
+	if n.Path == "." {
+		return 0
+	}
+
+	return strings.Count(n.Path, "/") + 1
+}
+
+// sort orders the children of n and everything beneath it largest first.
+func (n *BuildContextNode) sort() {
+	sort.Slice(n.Children, func(i, j int) bool {
+		if n.Children[i].Bytes != n.Children[j].Bytes {
+			return n.Children[i].Bytes > n.Children[j].Bytes
+		}
+
+		return n.Children[i].Name < n.Children[j].Name
+	})
+	for _, c := range n.Children {
+		c.sort()
+	}
+}
+
+func sortContextPaths(paths []BuildContextPath) {
+	sort.Slice(paths, func(i, j int) bool {
+		if paths[i].Bytes != paths[j].Bytes {
+			return paths[i].Bytes > paths[j].Bytes
+		}
+
+		return paths[i].Path < paths[j].Path
+	})
+}
+
+// sizePath returns the size of the file or directory at p.
+func sizePath(p, rel string, d fs.DirEntry) BuildContextPath {
+	out := BuildContextPath{Path: rel, Dir: d.IsDir()}
+	if !d.IsDir() {
+		if info, err := d.Info(); err == nil && d.Type().IsRegular() {
+			out.Bytes = info.Size()
+			out.Files = 1
+		}
+
+		return out
+	}
+
+	_ = filepath.WalkDir(p, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			out.Bytes += info.Size()
+			out.Files++
+		}
+
+		return nil
+	})
+
+	return out
+}
+
+// ruleFinder tells which ignore rule excluded a path.
+type ruleFinder struct {
+	patterns []string
+	// prefixes[i] matches like the first i+1 patterns.
+	prefixes []*patternmatcher.PatternMatcher
+}
+
+func newRuleFinder(patterns []string) (*ruleFinder, error) {
+	f := &ruleFinder{patterns: patterns}
+	for i := range patterns {
+		pm, err := patternmatcher.New(patterns[:i+1])
+		if err != nil {
+			return nil, err
+		}
+		f.prefixes = append(f.prefixes, pm)
+	}
+
+	return f, nil
+}
+
+// find returns the pattern that excluded rel: the last one that turned it
+// from included to excluded, as later patterns override earlier ones.
+func (f *ruleFinder) find(rel string) string {
+	rule := ""
+	matched := false
+	for i, pm := range f.prefixes {
+		m, _ := pm.MatchesOrParentMatches(rel)
+		if m && !matched {
+			rule = f.patterns[i]
+		}
+		matched = m
+	}
+
+	return rule
+}
@@ -0,0 +1,100 @@
+package imgsrc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ruleByPattern(rules []BuildContextRule, pattern string) (BuildContextRule, bool) {
+	for _, r := range rules {
+		if r.Pattern == pattern {
+			return r, true
+		}
+	}
+
+	return BuildContextRule{}, false
+}
+
+func TestAnalyzeBuildContextReport(t *testing.T) {
+	dir := t.TempDir()
+	writeSizedFile(t, dir, "Dockerfile", 100)
+	writeSizedFile(t, dir, "app/models/user.rb", 500)
+	writeSizedFile(t, dir, "app/main.rb", 200)
+	writeSizedFile(t, dir, "node_modules/left-pad/index.js", 3000)
+	writeSizedFile(t, dir, "web/node_modules/react/index.js", 2000)
+	writeSizedFile(t, dir, ".git/objects/pack.bin", 9000)
+	writeSizedFile(t, dir, "logs/big.log", 4000)
+	writeSizedFile(t, dir, "logs/keep.log", 30)
+	writeSizedFile(t, dir, "fly.toml", 10)
+
+	ignore := ".git\nlogs\n!logs/keep.log\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".dockerignore"), []byte(ignore), 0o644))
+
+	report, err := AnalyzeBuildContext(dir, filepath.Join(dir, "Dockerfile"), "", 1)
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(dir, ".dockerignore"), report.IgnoreFile)
+	assert.Equal(t, int64(100+500+200+3000+2000+30+10+int64(len(ignore))), report.TotalBytes)
+	assert.Equal(t, 8, report.FileCount)
+
+	// Depth 1 folds app/models into app.
+	require.NotEmpty(t, report.Tree.Children)
+	assert.Equal(t, "node_modules", report.Tree.Children[0].Name)
+	for _, child := range report.Tree.Children {
+		assert.Empty(t, child.Children, child.Path)
+		if child.Name == "app" {
+			assert.Equal(t, int64(700), child.Bytes)
+			assert.Equal(t, 2, child.Files)
+		}
+	}
+
+	git, ok := ruleByPattern(report.Rules, ".git")
+	require.True(t, ok)
+	assert.Equal(t, int64(9000), git.Bytes)
+	assert.Equal(t, []BuildContextPath{{Path: ".git", Dir: true, Bytes: 9000, Files: 1}}, git.Paths)
+
+	logs, ok := ruleByPattern(report.Rules, "logs")
+	require.True(t, ok)
+	assert.Equal(t, []BuildContextPath{{Path: "logs/big.log", Bytes: 4000, Files: 1}}, logs.Paths)
+
+	require.NotEmpty(t, report.Largest)
+	assert.Equal(t, "node_modules/left-pad/index.js", report.Largest[0].Path)
+
+	assert.Equal(t, []BuildContextSuggestion{
+		{Pattern: "node_modules", Reason: dockerignoreCandidates["node_modules"], Bytes: 3000, Files: 1},
+		{Pattern: "**/node_modules", Reason: dockerignoreCandidates["node_modules"], Bytes: 2000, Files: 1},
+	}, report.Suggestions)
+}
+
+func TestAnalyzeBuildContextDefaultRules(t *testing.T) {
+	dir := t.TempDir()
+	writeSizedFile(t, dir, "Dockerfile", 100)
+	writeSizedFile(t, dir, "fly.toml", 10)
+	writeSizedFile(t, dir, ".env", 20)
+
+	report, err := AnalyzeBuildContext(dir, filepath.Join(dir, "Dockerfile"), "", 0)
+	require.NoError(t, err)
+
+	assert.Empty(t, report.IgnoreFile)
+	assert.Equal(t, []BuildContextRule{{
+		Pattern: "fly.toml",
+		Bytes:   10,
+		Files:   1,
+		Paths:   []BuildContextPath{{Path: "fly.toml", Bytes: 10, Files: 1}},
+	}}, report.Rules)
+	require.Len(t, report.Suggestions, 1)
+	assert.Equal(t, ".env", report.Suggestions[0].Pattern)
+}
+
+func TestRuleFinder(t *testing.T) {
+	f, err := newRuleFinder([]string{"*.log", "logs", "!logs/keep.log", "logs/keep.log"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "*.log", f.find("debug.log"))
+	assert.Equal(t, "logs", f.find("logs/other.txt"))
+	assert.Equal(t, "logs/keep.log", f.find("logs/keep.log"))
+}
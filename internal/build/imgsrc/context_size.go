@@ -137,14 +137,7 @@ func resolveBuildContextWarnBytes(flagValue, envValue string) (int64, bool) {
 func analyzeBuildContext(workingDir, dockerfile, ignoreFile string) (buildContextStats, error) {
 	var stats buildContextStats
 
-	relDockerfile := ""
-	if isPathInRoot(dockerfile, workingDir) {
-		if p, err := filepath.Rel(workingDir, dockerfile); err == nil {
-			relDockerfile = filepath.ToSlash(p)
-		}
-	}
-
-	excludes, err := readDockerignore(workingDir, ignoreFile, relDockerfile)
+	excludes, err := buildContextExcludes(workingDir, dockerfile, ignoreFile)
 	if err != nil {
 		return stats, err
 	}
@@ -228,6 +221,19 @@ func analyzeBuildContext(workingDir, dockerfile, ignoreFile string) (buildContex
 	return stats, nil
 }
 
+// buildContextExcludes returns the .dockerignore patterns CreateArchive
+// applies to workingDir when building with dockerfile.
+func buildContextExcludes(workingDir, dockerfile, ignoreFile string) ([]string, error) {
+	relDockerfile := ""
+	if dockerfile != "" && isPathInRoot(dockerfile, workingDir) {
+		if p, err := filepath.Rel(workingDir, dockerfile); err == nil {
+			relDockerfile = filepath.ToSlash(p)
+		}
+	}
+
+	return readDockerignore(workingDir, ignoreFile, relDockerfile)
+}
+
 // canSkipExcludedDir reports whether an excluded directory can be skipped
 // entirely, i.e. no negated (re-include) pattern could match a file beneath it.
 // This mirrors the directory-skipping logic in
@@ -296,7 +302,8 @@ func formatLargeBuildContextWarning(cs *iostreams.ColorScheme, stats buildContex
 		}
 	}
 
-	fmt.Fprint(&b, "     If some of these don't need to be in the image, add them to .dockerignore:\n")
+	fmt.Fprint(&b, "     If some of these don't need to be in the image, add them to .dockerignore.\n")
+	fmt.Fprint(&b, "     Run 'fly build context' to see what is included and what to leave out:\n")
 	fmt.Fprintf(&b, "     %s\n", cs.Gray("https://docs.docker.com/build/concepts/context/#dockerignore-files"))
 	fmt.Fprintf(&b, "     %s\n", cs.Gray(fmt.Sprintf("(disable with --%s 0)", flag.BuildContextWarnSizeName)))
 
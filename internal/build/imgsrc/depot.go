@@ -6,9 +6,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"maps"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	depotbuild "github.com/depot/depot-go/build"
@@ -154,7 +156,10 @@ func depotBuild(ctx context.Context, streams *iostreams.IOStreams, opts ImageOpt
 	provisionCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 	defer cancel()
 
-	buildkit, build, buildErr := initBuilder(provisionCtx, buildState, opts.AppName, streams, scope)
+	// Depot builds other platforms under emulation, so use a builder of the
+	// first one.
+	arch := platformArch(buildPlatforms(opts)[0])
+	buildkit, build, buildErr := initBuilder(provisionCtx, buildState, opts.AppName, streams, scope, arch)
 	if buildErr != nil {
 		return nil, buildErr
 	}
@@ -188,13 +193,13 @@ func depotBuild(ctx context.Context, streams *iostreams.IOStreams, opts ImageOpt
 	link = streams.CreateLink("Build Summary: ", build.BuildURL)
 	tb.Done(link)
 
-	return newDeploymentImage(ctx, buildkitClient, res, opts.Tag)
+	return newDeploymentImage(ctx, buildkitClient, res, opts)
 }
 
 // initBuilder returns a Depot machine to build a container image.
 // Note that the caller is responsible for passing a context with a resonable timeout.
 // Otherwise, the function cloud block indefinitely.
-func initBuilder(ctx context.Context, buildState *build, appName string, streams *iostreams.IOStreams, builderScope depotBuilderScope) (m *depotmachine.Machine, b *depotbuild.Build, retErr error) {
+func initBuilder(ctx context.Context, buildState *build, appName string, streams *iostreams.IOStreams, builderScope depotBuilderScope, arch string) (m *depotmachine.Machine, b *depotbuild.Build, retErr error) {
 	ctx, span := tracing.GetTracer().Start(ctx, "init_depot_build")
 
 	defer func() {
@@ -229,7 +234,7 @@ func initBuilder(ctx context.Context, buildState *build, appName string, streams
 
 	span.AddEvent("Acquiring Depot machine")
 
-	machine, err := depotmachine.Acquire(ctx, build.ID, build.Token, "amd64")
+	machine, err := depotmachine.Acquire(ctx, build.ID, build.Token, arch)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -271,6 +276,8 @@ func buildImage(ctx context.Context, buildkitClient *client.Client, opts ImageOp
 		return nil, err
 	}
 
+	cacheExports, _ := buildkitCacheExports(opts, allCacheExporters)
+
 	ch := make(chan *client.SolveStatus)
 	eg, ctx := errgroup.WithContext(ctx)
 	eg.Go(func() error {
@@ -279,19 +286,22 @@ func buildImage(ctx context.Context, buildkitClient *client.Client, opts ImageOp
 			FrontendAttrs: map[string]string{
 				"filename": filepath.Base(dockerfilePath),
 				"target":   opts.Target,
-				"platform": "linux/amd64",
+				"platform": strings.Join(buildPlatforms(opts), ","),
 			},
 			LocalMounts: map[string]fsutil.FS{
 				"dockerfile": dockerfileDir,
 				"context":    contextDir,
 			},
-			Exports: []client.ExportEntry{exportEntry},
+			Exports:      []client.ExportEntry{exportEntry},
+			CacheImports: buildkitCacheImports(opts),
+			CacheExports: cacheExports,
 			// Prevent recording the build steps and traces in buildkit as it is _very_ slow.
 			Internal: true,
 		}
 		if opts.NoCache {
 			solverOptions.FrontendAttrs["no-cache"] = ""
 		}
+		maps.Copy(solverOptions.FrontendAttrs, attestationFrontendAttrs(opts.Attestations))
 		for k, v := range opts.Label {
 			solverOptions.FrontendAttrs["label:"+k] = v
 		}
@@ -326,7 +336,7 @@ func buildImage(ctx context.Context, buildkitClient *client.Client, opts ImageOp
 	return res, nil
 }
 
-func newDeploymentImage(ctx context.Context, c *client.Client, res *client.SolveResponse, tag string) (*DeploymentImage, error) {
+func newDeploymentImage(ctx context.Context, c *client.Client, res *client.SolveResponse, opts ImageOptions) (*DeploymentImage, error) {
 	id := res.ExporterResponse["containerimage.digest"]
 	encoded := res.ExporterResponse["containerimage.descriptor"]
 	output, err := base64.StdEncoding.DecodeString(encoded)
@@ -358,11 +368,34 @@ func newDeploymentImage(ctx context.Context, c *client.Client, res *client.Solve
 	}
 	image := &DeploymentImage{
 		ID:        id,
-		Tag:       tag,
+		Tag:       opts.Tag,
 		Size:      descriptor.Bytes(),
 		BuilderID: builderHostname,
 	}
 
+	// A multi-platform image is an index of one image per platform.
+	if isImageIndex(descriptor.MediaType) {
+		image.Platforms, err = platformImages(ctx, c.ContentClient(), descriptor.Annotations.RawManifest)
+		if err != nil {
+			return nil, err
+		}
+		image.Size = 0
+		for _, p := range image.Platforms {
+			image.Size += p.Size
+		}
+
+		// A policy check fails later if it needs an attestation that
+		// couldn't be read.
+		var sbomFormat string
+		if opts.Attestations != nil {
+			sbomFormat = opts.Attestations.SBOMFormat
+		}
+		image.Attestations, err = readAttestations(ctx, c.ContentClient(), descriptor.Annotations.RawManifest, sbomFormat)
+		if err != nil {
+			terminal.Warnf("Failed to read the attestations of the image: %v\n", err)
+		}
+	}
+
 	return image, nil
 }
 
@@ -29,6 +29,6 @@ func TestInitBuilder(t *testing.T) {
 	build := newBuild(1, false)
 
 	// The invocation below doesn't test things much, but it may be better than nothing.
-	_, _, err := initBuilder(ctx, build, "app1", ios, DepotBuilderScopeOrganization)
+	_, _, err := initBuilder(ctx, build, "app1", ios, DepotBuilderScopeOrganization, "amd64")
 	require.ErrorContains(t, err, `unsupported protocol scheme "invalid"`)
 }
@@ -328,10 +328,11 @@ func runClassicBuild(ctx context.Context, streams *iostreams.IOStreams, docker *
 	)
 	defer span.End()
 
-	platform := "linux/amd64"
-	if p := os.Getenv("FLY_DEV_PLATFORM"); p != "" {
-		platform = p
+	platform, err := localDockerPlatform(opts, "classic Docker")
+	if err != nil {
+		return "", err
 	}
+	warnAttestationsUnsupported(opts, "classic Docker")
 
 	options := types.ImageBuildOptions{
 		Tags:        []string{opts.Tag},
@@ -344,6 +345,14 @@ func runClassicBuild(ctx context.Context, streams *iostreams.IOStreams, docker *
 		Labels:      opts.Label,
 	}
 
+	// The classic builder can only use images built with inline cache.
+	if opts.Cache != nil && !opts.NoCache {
+		options.CacheFrom = opts.Cache.From
+	}
+	if opts.Cache != nil && (opts.Cache.Inline || opts.Cache.To != "" || opts.Cache.Dir != "") {
+		terminal.Warnf("The classic Docker builder can't export build cache; enable BuildKit to use [build.cache] inline, to or dir\n")
+	}
+
 	resp, err := docker.ImageBuild(ctx, r, options)
 	if err != nil {
 		return "", errors.Wrap(err, "error building with docker")
@@ -366,12 +375,14 @@ func runClassicBuild(ctx context.Context, streams *iostreams.IOStreams, docker *
 }
 
 func solveOptFromImageOptions(opts ImageOptions, dockerfilePath string, buildArgs map[string]*string) (client.SolveOpt, error) {
-	// Fly.io only supports linux/amd64, but local Docker Engine could be running on ARM,
-	// including Apple Silicon. Use FLY_DEV_PLATFORM to override for local testing.
-	platform := "linux/amd64"
-	if p := os.Getenv("FLY_DEV_PLATFORM"); p != "" {
-		platform = p
+	// Local Docker Engine could be running on ARM, including Apple Silicon, so
+	// the platform is always set. Its "moby" exporter can't store a
+	// multi-platform image. Use FLY_DEV_PLATFORM to override for local testing.
+	platform, err := localDockerPlatform(opts, "Docker Engine")
+	if err != nil {
+		return client.SolveOpt{}, err
 	}
+	warnAttestationsUnsupported(opts, "Docker Engine")
 
 	attrs := map[string]string{
 		"filename": filepath.Base(dockerfilePath),
@@ -403,9 +414,16 @@ func solveOptFromImageOptions(opts ImageOptions, dockerfilePath string, buildArg
 		return client.SolveOpt{}, err
 	}
 
+	cacheExports, skipped := buildkitCacheExports(opts, []string{cacheInline})
+	for _, what := range skipped {
+		terminal.Warnf("Docker Engine's BuildKit can't export %s; only inline cache is supported\n", what)
+	}
+
 	return client.SolveOpt{
 		Frontend:      "dockerfile.v0",
 		FrontendAttrs: attrs,
+		CacheImports:  buildkitCacheImports(opts),
+		CacheExports:  cacheExports,
 		LocalMounts: map[string]fsutil.FS{
 			"dockerfile": dockerfileDir,
 			"context":    contextDir,
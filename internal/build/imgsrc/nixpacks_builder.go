@@ -193,6 +193,18 @@ func (b *nixpacksBuilder) Run(ctx context.Context, dockerFactory *dockerClientFa
 	nixpacksPath := filepath.Join(confDir, "bin", "nixpacks")
 
 	nixpacksArgs := []string{"build", "--name", opts.Tag, opts.WorkingDir}
+	nixpacksArgs = append(nixpacksArgs, nixpacksCacheArgs(opts)...)
+	warnAttestationsUnsupported(opts, "nixpacks")
+	if len(opts.Platforms) > 0 {
+		platform, err := singlePlatform(opts, "nixpacks")
+		if err != nil {
+			build.ImageBuildFinish()
+			build.BuildFinish()
+
+			return nil, "", err
+		}
+		nixpacksArgs = append(nixpacksArgs, "--platform", platform)
+	}
 	for _, kv := range os.Environ() {
 		if strings.HasPrefix(kv, "NIXPACKS_") {
 			nixpacksArgs = append(nixpacksArgs, "--env", kv)
@@ -238,3 +250,28 @@ func (b *nixpacksBuilder) Run(ctx context.Context, dockerFactory *dockerClientFa
 		Size: img.Size,
 	}, "", nil
 }
+
+// nixpacksCacheArgs returns the nixpacks flags for the build cache settings
+// of opts. nixpacks can only import cache from one image, and only export
+// it inline.
+func nixpacksCacheArgs(opts ImageOptions) []string {
+	if opts.Cache == nil {
+		return nil
+	}
+
+	var args []string
+	if opts.Cache.Inline {
+		args = append(args, "--inline-cache")
+	}
+	if len(opts.Cache.From) > 0 && !opts.NoCache {
+		args = append(args, "--cache-from", opts.Cache.From[0])
+		if len(opts.Cache.From) > 1 {
+			terminal.Warnf("nixpacks can only import cache from one image; using %s\n", opts.Cache.From[0])
+		}
+	}
+	if opts.Cache.To != "" || opts.Cache.Dir != "" {
+		terminal.Warnf("nixpacks can't export build cache to a registry or directory; use [build.cache] inline instead\n")
+	}
+
+	return args
+}
@@ -0,0 +1,209 @@
+package imgsrc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/spf13/viper"
+	"github.com/superfly/flyctl/flyctl"
+	"github.com/superfly/flyctl/internal/config"
+	"github.com/superfly/flyctl/internal/oci"
+	"github.com/superfly/flyctl/internal/tracing"
+	"github.com/superfly/flyctl/iostreams"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ociBuilder builds images without Docker by copying the files of
+// [[build.files]] onto the [build] image and pushing the result straight to
+// the registry. It's meant for apps that ship a prebuilt binary, like a
+// static Go or Rust executable, from CI runners that have no Docker daemon.
+type ociBuilder struct {
+	// keychain authenticates to registries; defaults to the Fly registry
+	// token and then the Docker credentials of the user.
+	keychain authn.Keychain
+}
+
+func (*ociBuilder) Name() string {
+	return "Daemonless"
+}
+
+func (b *ociBuilder) Run(ctx context.Context, _ *dockerClientFactory, streams *iostreams.IOStreams, opts ImageOptions, build *build) (*DeploymentImage, string, error) {
+	ctx, span := tracing.GetTracer().Start(ctx, "oci_builder")
+	defer span.End()
+
+	if len(opts.Files) == 0 {
+		return nil, "no build files to copy, skipping", nil
+	}
+
+	keychain := b.keychain
+	if keychain == nil {
+		keychain = authn.NewMultiKeychain(flyRegistryKeychain{token: config.Tokens(ctx).Docker()}, authn.DefaultKeychain)
+	}
+
+	platform, err := singlePlatform(opts, "daemonless")
+	if err != nil {
+		return nil, "", err
+	}
+	warnAttestationsUnsupported(opts, "daemonless")
+
+	build.BuildStart()
+	build.ImageBuildStart()
+	img, err := assembleOCIImage(ctx, opts, platform, keychain)
+	build.ImageBuildFinish()
+	build.BuildFinish()
+	if err != nil {
+		tracing.RecordError(span, err, "failed to assemble image")
+
+		return nil, "", err
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		return nil, "", err
+	}
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, "", err
+	}
+	size := manifest.Config.Size
+	for _, layer := range manifest.Layers {
+		size += layer.Size
+	}
+
+	span.SetAttributes(attribute.String("digest", digest.String()))
+
+	if opts.Publish {
+		ref, err := name.ParseReference(opts.Tag)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid image tag %q: %w", opts.Tag, err)
+		}
+
+		fmt.Fprintf(streams.ErrOut, "Pushing %s\n", opts.Tag)
+		build.PushStart()
+		err = remote.Write(ref, img, remote.WithContext(ctx), remote.WithAuthFromKeychain(keychain))
+		build.PushFinish()
+		if err != nil {
+			tracing.RecordError(span, err, "failed to push image")
+
+			return nil, "", fmt.Errorf("failed to push %s: %w", opts.Tag, err)
+		}
+	}
+
+	return &DeploymentImage{
+		ID:     digest.String(),
+		Tag:    opts.Tag,
+		Digest: digest.String(),
+		Size:   size,
+		Labels: opts.Label,
+	}, "", nil
+}
+
+// assembleOCIImage returns opts.BaseImage for platform with opts.Files and
+// opts.Label added.
+func assembleOCIImage(ctx context.Context, opts ImageOptions, platform string, keychain authn.Keychain) (v1.Image, error) {
+	goos, goarch, _ := strings.Cut(platform, "/")
+
+	var base v1.Image
+	if opts.BaseImage == "scratch" {
+		cfg, err := empty.Image.ConfigFile()
+		if err != nil {
+			return nil, err
+		}
+		cfg = cfg.DeepCopy()
+		cfg.OS = goos
+		cfg.Architecture = goarch
+		if base, err = mutate.ConfigFile(empty.Image, cfg); err != nil {
+			return nil, err
+		}
+	} else {
+		ref, err := name.ParseReference(opts.BaseImage)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base image %q: %w", opts.BaseImage, err)
+		}
+		base, err = remote.Image(ref,
+			remote.WithContext(ctx),
+			remote.WithAuthFromKeychain(keychain),
+			remote.WithPlatform(v1.Platform{OS: goos, Architecture: goarch}),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch base image %s: %w", opts.BaseImage, err)
+		}
+	}
+
+	files := make([]oci.File, 0, len(opts.Files))
+	for _, f := range opts.Files {
+		localPath := f.LocalPath
+		if !filepath.IsAbs(localPath) {
+			localPath = filepath.Join(opts.WorkingDir, localPath)
+		}
+		files = append(files, oci.File{LocalPath: localPath, ImagePath: f.ImagePath})
+	}
+
+	created, err := sourceDateEpoch()
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := oci.AppendFiles(base, files, created)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add build files to the image: %w", err)
+	}
+
+	if len(opts.Label) == 0 {
+		return img, nil
+	}
+
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	cfg = cfg.DeepCopy()
+	if cfg.Config.Labels == nil {
+		cfg.Config.Labels = map[string]string{}
+	}
+	for k, v := range opts.Label {
+		cfg.Config.Labels[k] = v
+	}
+
+	return mutate.ConfigFile(img, cfg)
+}
+
+// sourceDateEpoch returns the time set by SOURCE_DATE_EPOCH, which
+// reproducible build tools use to timestamp their output, or the Unix epoch.
+func sourceDateEpoch() (time.Time, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return time.Unix(0, 0).UTC(), nil
+	}
+
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: %w", epoch, err)
+	}
+
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+// flyRegistryKeychain authenticates to the Fly registry with a Fly token.
+type flyRegistryKeychain struct {
+	token string
+}
+
+func (k flyRegistryKeychain) Resolve(r authn.Resource) (authn.Authenticator, error) {
+	if k.token == "" || r.RegistryStr() != viper.GetString(flyctl.ConfigRegistryHost) {
+		return authn.Anonymous, nil
+	}
+
+	return &authn.Basic{Username: "x", Password: k.token}, nil
+}
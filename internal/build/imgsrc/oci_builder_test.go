@@ -0,0 +1,97 @@
+package imgsrc
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func TestOCIBuilder(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	host := u.Host
+
+	base, err := random.Image(64, 2)
+	require.NoError(t, err)
+	baseRef, err := name.ParseReference(host + "/base:latest")
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(baseRef, base))
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "server"), []byte("binary"), 0o755))
+
+	streams, _, _, _ := iostreams.Test()
+	builder := &ociBuilder{keychain: authn.NewMultiKeychain()}
+	opts := ImageOptions{
+		WorkingDir: dir,
+		Publish:    true,
+		Tag:        host + "/app:deployment-1",
+		BaseImage:  host + "/base:latest",
+		Files:      []appconfig.BuildFile{{LocalPath: "server", ImagePath: "/app/server"}},
+		Label:      map[string]string{"GH_SHA": "abc"},
+	}
+
+	img, note, err := builder.Run(context.Background(), nil, streams, opts, newBuild(1, false))
+	require.NoError(t, err)
+	require.NotNil(t, img, note)
+	assert.Equal(t, opts.Tag, img.Tag)
+
+	ref, err := name.ParseReference(opts.Tag)
+	require.NoError(t, err)
+	pushed, err := remote.Image(ref)
+	require.NoError(t, err)
+	digest, err := pushed.Digest()
+	require.NoError(t, err)
+	assert.Equal(t, digest.String(), img.Digest)
+
+	layers, err := pushed.Layers()
+	require.NoError(t, err)
+	assert.Len(t, layers, 3)
+	cfg, err := pushed.ConfigFile()
+	require.NoError(t, err)
+	assert.Equal(t, "abc", cfg.Config.Labels["GH_SHA"])
+
+	// The same files give the same image.
+	opts.Tag = host + "/app:deployment-2"
+	again, _, err := builder.Run(context.Background(), nil, streams, opts, newBuild(2, false))
+	require.NoError(t, err)
+	assert.Equal(t, img.Digest, again.Digest)
+
+	_, _, err = builder.Run(context.Background(), nil, streams, ImageOptions{
+		BaseImage: "scratch",
+		Files:     opts.Files,
+		Platforms: []string{"linux/amd64", "linux/arm64"},
+	}, newBuild(3, false))
+	assert.ErrorContains(t, err, "the daemonless builder can't build for more than one platform")
+}
+
+func TestSourceDateEpoch(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+	created, err := sourceDateEpoch()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), created.Unix())
+
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	created, err = sourceDateEpoch()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1700000000), created.Unix())
+
+	t.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	_, err = sourceDateEpoch()
+	assert.Error(t, err)
+}
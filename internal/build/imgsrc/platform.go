@@ -0,0 +1,133 @@
+package imgsrc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/containerd/containerd/api/services/content/v1"
+	"github.com/superfly/flyctl/terminal"
+)
+
+// defaultPlatform is the platform images are built for unless [build]
+// platforms says otherwise.
+const defaultPlatform = "linux/amd64"
+
+// Media types of multi-platform images.
+const (
+	ociImageIndexMediaType      = "application/vnd.oci.image.index.v1+json"
+	dockerManifestListMediaType = "application/vnd.docker.distribution.manifest.list.v2+json"
+)
+
+// PlatformImage is the image of one platform of a multi-platform image.
+type PlatformImage struct {
+	Platform string
+	Digest   string
+	Size     int64
+}
+
+// buildPlatforms returns the platforms to build opts for.
+func buildPlatforms(opts ImageOptions) []string {
+	if len(opts.Platforms) > 0 {
+		return opts.Platforms
+	}
+
+	return []string{defaultPlatform}
+}
+
+// singlePlatform returns the one platform to build opts for with a builder
+// that can't produce multi-platform images.
+func singlePlatform(opts ImageOptions, builder string) (string, error) {
+	platforms := buildPlatforms(opts)
+	if len(platforms) > 1 {
+		return "", fmt.Errorf("the %s builder can't build for more than one platform (%s); use the Depot builder or a BuildKit builder for multi-platform images", builder, strings.Join(platforms, ", "))
+	}
+
+	return platforms[0], nil
+}
+
+// localDockerPlatform is singlePlatform for builds on a local Docker Engine,
+// where FLY_DEV_PLATFORM overrides the platform for local testing.
+func localDockerPlatform(opts ImageOptions, builder string) (string, error) {
+	if p := os.Getenv("FLY_DEV_PLATFORM"); p != "" {
+		return p, nil
+	}
+
+	return singlePlatform(opts, builder)
+}
+
+// platformArch returns the architecture of a platform like linux/arm64.
+func platformArch(platform string) string {
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 {
+		return platform
+	}
+
+	return parts[1]
+}
+
+type imageIndex struct {
+	MediaType string `json:"mediaType,omitempty"`
+	Manifests []struct {
+		OCIDescriptor
+		Platform    *ociPlatform      `json:"platform,omitempty"`
+		Annotations map[string]string `json:"annotations,omitempty"`
+	} `json:"manifests"`
+}
+
+type ociPlatform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+func (p *ociPlatform) String() string {
+	platform := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		platform += "/" + p.Variant
+	}
+
+	return platform
+}
+
+// isImageIndex reports whether a descriptor is of an image index, as
+// multi-platform images and images with attestations are.
+func isImageIndex(mediaType string) bool {
+	return mediaType == ociImageIndexMediaType || mediaType == dockerManifestListMediaType
+}
+
+// platformImages returns the image of each platform in the image index
+// rawIndex, reading their manifests from contentClient to size them.
+// Attestation manifests, which have an unknown platform, are left out.
+func platformImages(ctx context.Context, contentClient content.ContentClient, rawIndex string) ([]PlatformImage, error) {
+	var index imageIndex
+	if err := json.Unmarshal([]byte(rawIndex), &index); err != nil {
+		return nil, fmt.Errorf("failed to parse image index: %w", err)
+	}
+
+	var images []PlatformImage
+	for _, m := range index.Manifests {
+		if m.Platform == nil || m.Platform.OS == "unknown" {
+			continue
+		}
+
+		platform := m.Platform.String()
+		image := PlatformImage{Platform: platform, Digest: m.Digest}
+
+		raw, err := readContent(ctx, contentClient, &Descriptor{Digest: m.Digest})
+		if err == nil {
+			var manifest Manifest
+			err = json.Unmarshal([]byte(raw), &manifest)
+			image.Size = manifest.Bytes()
+		}
+		if err != nil {
+			terminal.Debugf("failed to read the %s manifest %s: %v\n", platform, m.Digest, err)
+		}
+
+		images = append(images, image)
+	}
+
+	return images, nil
+}
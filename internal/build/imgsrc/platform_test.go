@@ -0,0 +1,92 @@
+package imgsrc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/containerd/containerd/api/services/content/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// fakeContentClient serves blobs from memory.
+type fakeContentClient struct {
+	content.ContentClient
+	blobs map[string]string
+}
+
+func (f *fakeContentClient) Read(_ context.Context, in *content.ReadContentRequest, _ ...grpc.CallOption) (content.Content_ReadClient, error) {
+	blob, ok := f.blobs[string(in.Digest)]
+	if !ok {
+		return nil, fmt.Errorf("blob %s not found", in.Digest)
+	}
+
+	return &fakeReadClient{data: []byte(blob)}, nil
+}
+
+type fakeReadClient struct {
+	grpc.ClientStream
+	data []byte
+}
+
+func (f *fakeReadClient) Recv() (*content.ReadContentResponse, error) {
+	if f.data == nil {
+		return nil, io.EOF
+	}
+	resp := &content.ReadContentResponse{Data: f.data}
+	f.data = nil
+
+	return resp, nil
+}
+
+func TestBuildPlatforms(t *testing.T) {
+	assert.Equal(t, []string{"linux/amd64"}, buildPlatforms(ImageOptions{}))
+
+	// FLY_DEV_PLATFORM is only for builds on a local Docker Engine
+	t.Setenv("FLY_DEV_PLATFORM", "linux/arm64")
+	assert.Equal(t, []string{"linux/amd64"}, buildPlatforms(ImageOptions{}))
+	platform, err := localDockerPlatform(ImageOptions{}, "classic Docker")
+	require.NoError(t, err)
+	assert.Equal(t, "linux/arm64", platform)
+	t.Setenv("FLY_DEV_PLATFORM", "")
+
+	opts := ImageOptions{Platforms: []string{"linux/amd64", "linux/arm64"}}
+	assert.Equal(t, opts.Platforms, buildPlatforms(opts))
+
+	_, err = singlePlatform(opts, "classic Docker")
+	assert.ErrorContains(t, err, "the classic Docker builder can't build for more than one platform (linux/amd64, linux/arm64)")
+
+	platform, err = singlePlatform(ImageOptions{Platforms: []string{"linux/arm64"}}, "classic Docker")
+	require.NoError(t, err)
+	assert.Equal(t, "linux/arm64", platform)
+	assert.Equal(t, "arm64", platformArch(platform))
+}
+
+func TestPlatformImages(t *testing.T) {
+	index := `{
+  "mediaType": "application/vnd.oci.image.index.v1+json",
+  "manifests": [
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:amd", "size": 500, "platform": {"os": "linux", "architecture": "amd64"}},
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:arm", "size": 500, "platform": {"os": "linux", "architecture": "arm64", "variant": "v8"}},
+    {"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": "sha256:att", "size": 300, "platform": {"os": "unknown", "architecture": "unknown"},
+     "annotations": {"vnd.docker.reference.type": "attestation-manifest"}}
+  ]
+}`
+	client := &fakeContentClient{blobs: map[string]string{
+		"sha256:amd": `{"config": {"size": 100}, "layers": [{"size": 1000}, {"size": 2000}]}`,
+		"sha256:arm": `{"config": {"size": 100}, "layers": [{"size": 1500}]}`,
+	}}
+
+	images, err := platformImages(context.Background(), client, index)
+	require.NoError(t, err)
+	assert.Equal(t, []PlatformImage{
+		{Platform: "linux/amd64", Digest: "sha256:amd", Size: 3100},
+		{Platform: "linux/arm64/v8", Digest: "sha256:arm", Size: 1600},
+	}, images)
+
+	assert.True(t, isImageIndex(ociImageIndexMediaType))
+	assert.False(t, isImageIndex("application/vnd.oci.image.manifest.v1+json"))
+}
@@ -0,0 +1,206 @@
+package imgsrc
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/moby/buildkit/frontend/dockerfile/parser"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// ImagePolicy is a policy an image must pass before it's deployed. It's read
+// from a TOML file like:
+//
+//	[licenses]
+//	deny = ["AGPL-3.0-*", "SSPL-1.0"]
+//
+//	[base_images]
+//	deny = ["node:16*", "ubuntu:18.04"]
+//
+// Patterns are matched with path.Match; license patterns ignore case.
+type ImagePolicy struct {
+	Licenses   ImagePolicyRule `toml:"licenses"`
+	BaseImages ImagePolicyRule `toml:"base_images"`
+}
+
+// ImagePolicyRule lists patterns of what an image must not contain.
+type ImagePolicyRule struct {
+	Deny []string `toml:"deny"`
+}
+
+// LoadImagePolicy reads the image policy at policyPath.
+func LoadImagePolicy(policyPath string) (*ImagePolicy, error) {
+	data, err := os.ReadFile(policyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image policy: %w", err)
+	}
+
+	var policy ImagePolicy
+	decoder := toml.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("failed to parse image policy %s: %w", policyPath, err)
+	}
+
+	for _, pattern := range slices.Concat(policy.Licenses.Deny, policy.BaseImages.Deny) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q in image policy %s: %w", pattern, policyPath, err)
+		}
+	}
+
+	return &policy, nil
+}
+
+// Check returns how img breaks the policy. Base images come from the
+// provenance of img, or else from the FROM instructions of dockerfile, if
+// it's not "". A rule that can't be checked, because the image has no SBOM
+// or its base images aren't known, is broken.
+func (p *ImagePolicy) Check(img *DeploymentImage, dockerfile string) ([]string, error) {
+	var violations []string
+
+	if len(p.Licenses.Deny) > 0 {
+		sboms := 0
+		for _, a := range img.Attestations {
+			if a.SBOM == nil {
+				continue
+			}
+			sboms++
+			for _, pkg := range a.SBOM.Packages {
+				if pkg.License != "" && licenseDenied(pkg.License, p.Licenses.Deny) {
+					violations = append(violations, fmt.Sprintf("package %s %s has disallowed license %s", pkg.Name, pkg.Version, pkg.License))
+				}
+			}
+		}
+		if sboms == 0 {
+			violations = append(violations, "the image has no SBOM to check licenses against; set sbom = true in [build.attestations]")
+		}
+	}
+
+	if len(p.BaseImages.Deny) > 0 {
+		var baseImages []string
+		for _, a := range img.Attestations {
+			if a.Provenance != nil {
+				baseImages = append(baseImages, a.Provenance.BaseImages...)
+			}
+		}
+		if len(baseImages) == 0 && dockerfile != "" {
+			var err error
+			if baseImages, err = DockerfileBaseImages(dockerfile); err != nil {
+				return nil, err
+			}
+		}
+
+		if len(baseImages) == 0 {
+			violations = append(violations, "the base images of the image aren't known; set provenance in [build.attestations]")
+		}
+		for _, image := range slices.Compact(slices.Sorted(slices.Values(baseImages))) {
+			if pattern, ok := baseImageDenied(image, p.BaseImages.Deny); ok {
+				violations = append(violations, fmt.Sprintf("base image %s is disallowed by %q", image, pattern))
+			}
+		}
+	}
+
+	return slices.Compact(violations), nil
+}
+
+// licenseDenied reports whether the SPDX license expression can only be met
+// with denied licenses: every alternative of an OR has a denied license.
+func licenseDenied(expression string, deny []string) bool {
+	expression = strings.NewReplacer("(", " ", ")", " ").Replace(expression)
+
+	for _, alternative := range splitLicenseExpression(expression, "OR") {
+		denied := false
+		for _, license := range splitLicenseExpression(alternative, "AND") {
+			license, _, _ = strings.Cut(license, " WITH ")
+			if licenseMatches(strings.TrimSpace(license), deny) {
+				denied = true
+				break
+			}
+		}
+		if !denied {
+			return false
+		}
+	}
+
+	return true
+}
+
+func splitLicenseExpression(expression, operator string) []string {
+	var parts []string
+	for _, part := range strings.Split(expression, " "+operator+" ") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	return parts
+}
+
+// baseImageDenied returns the pattern that denies image, matching both the
+// image as written and its fully qualified name.
+func baseImageDenied(image string, deny []string) (string, bool) {
+	names := []string{image}
+	if named, err := reference.ParseNormalizedNamed(image); err == nil {
+		named = reference.TagNameOnly(named)
+		names = append(names, reference.FamiliarString(named), named.String())
+	}
+
+	for _, pattern := range deny {
+		for _, name := range names {
+			if ok, _ := path.Match(pattern, name); ok {
+				return pattern, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+func licenseMatches(license string, deny []string) bool {
+	for _, pattern := range deny {
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(license)); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DockerfileBaseImages returns the images the stages of dockerfile are built
+// from, leaving out earlier stages and scratch.
+func DockerfileBaseImages(dockerfile string) ([]string, error) {
+	data, err := os.ReadFile(dockerfile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Dockerfile: %w", err)
+	}
+
+	result, err := parser.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Dockerfile: %w", err)
+	}
+
+	var (
+		images []string
+		stages []string
+	)
+	for _, node := range result.AST.Children {
+		if !strings.EqualFold(node.Value, "from") || node.Next == nil {
+			continue
+		}
+
+		image := node.Next.Value
+		if image != "scratch" && !slices.Contains(stages, strings.ToLower(image)) {
+			images = append(images, image)
+		}
+		if as := node.Next.Next; as != nil && strings.EqualFold(as.Value, "as") && as.Next != nil {
+			stages = append(stages, strings.ToLower(as.Next.Value))
+		}
+	}
+
+	return images, nil
+}
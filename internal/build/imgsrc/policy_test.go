@@ -0,0 +1,111 @@
+package imgsrc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLicenseDenied(t *testing.T) {
+	deny := []string{"AGPL-*", "gpl-3.0-only"}
+
+	for _, tc := range []struct {
+		expression string
+		want       bool
+	}{
+		{"MIT", false},
+		{"AGPL-3.0-or-later", true},
+		{"GPL-3.0-only", true},
+		{"MIT OR GPL-3.0-only", false},
+		{"MIT AND GPL-3.0-only", true},
+		{"(AGPL-3.0-only OR GPL-3.0-only) AND MIT", true},
+		{"GPL-3.0-only WITH Classpath-exception-2.0", true},
+	} {
+		assert.Equal(t, tc.want, licenseDenied(tc.expression, deny), tc.expression)
+	}
+}
+
+func TestBaseImageDenied(t *testing.T) {
+	deny := []string{"node:16*", "docker.io/library/ubuntu:18.04"}
+
+	pattern, ok := baseImageDenied("node:16-alpine", deny)
+	assert.True(t, ok)
+	assert.Equal(t, "node:16*", pattern)
+
+	_, ok = baseImageDenied("ubuntu:18.04", deny)
+	assert.True(t, ok, "matches the fully qualified name")
+
+	_, ok = baseImageDenied("node:20", deny)
+	assert.False(t, ok)
+}
+
+func TestDockerfileBaseImages(t *testing.T) {
+	dockerfile := filepath.Join(t.TempDir(), "Dockerfile")
+	require.NoError(t, os.WriteFile(dockerfile, []byte(`FROM node:20-slim AS base
+FROM base AS build
+RUN npm ci
+FROM --platform=linux/amd64 gcr.io/distroless/nodejs20
+COPY --from=build /app /app
+FROM scratch
+`), 0o644))
+
+	images, err := DockerfileBaseImages(dockerfile)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"node:20-slim", "gcr.io/distroless/nodejs20"}, images)
+}
+
+func TestImagePolicyCheck(t *testing.T) {
+	dir := t.TempDir()
+	policyPath := filepath.Join(dir, "policy.toml")
+	require.NoError(t, os.WriteFile(policyPath, []byte(`
+[licenses]
+deny = ["GPL-2.0-*"]
+
+[base_images]
+deny = ["node:1[0-6]*"]
+`), 0o644))
+
+	policy, err := LoadImagePolicy(policyPath)
+	require.NoError(t, err)
+
+	img := &DeploymentImage{Tag: "registry.fly.io/app:deployment-1", Attestations: []ImageAttestation{{
+		Platform: "linux/amd64",
+		SBOM: &SBOM{Packages: []SBOMPackage{
+			{Name: "express", Version: "4.19.2", License: "MIT"},
+			{Name: "busybox", Version: "1.36.1", License: "GPL-2.0-only"},
+		}},
+		Provenance: &Provenance{BaseImages: []string{"node:16-alpine"}},
+	}}}
+
+	violations, err := policy.Check(img, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"package busybox 1.36.1 has disallowed license GPL-2.0-only",
+		`base image node:16-alpine is disallowed by "node:1[0-6]*"`,
+	}, violations)
+
+	// Without attestations, licenses can't be checked and base images come
+	// from the Dockerfile.
+	dockerfile := filepath.Join(dir, "Dockerfile")
+	require.NoError(t, os.WriteFile(dockerfile, []byte("FROM node:20\n"), 0o644))
+	violations, err = policy.Check(&DeploymentImage{}, dockerfile)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"the image has no SBOM to check licenses against; set sbom = true in [build.attestations]"}, violations)
+}
+
+func TestLoadImagePolicyErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	unknown := filepath.Join(dir, "unknown.toml")
+	require.NoError(t, os.WriteFile(unknown, []byte("[licences]\ndeny = [\"GPL*\"]\n"), 0o644))
+	_, err := LoadImagePolicy(unknown)
+	assert.ErrorContains(t, err, "failed to parse image policy")
+
+	bad := filepath.Join(dir, "bad.toml")
+	require.NoError(t, os.WriteFile(bad, []byte("[licenses]\ndeny = [\"GPL[\"]\n"), 0o644))
+	_, err = LoadImagePolicy(bad)
+	assert.ErrorContains(t, err, `invalid pattern "GPL["`)
+}
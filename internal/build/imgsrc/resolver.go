@@ -20,6 +20,7 @@ import (
 	dockerclient "github.com/docker/docker/client"
 	fly "github.com/superfly/fly-go"
 	"github.com/superfly/flyctl/agent"
+	"github.com/superfly/flyctl/internal/appconfig"
 	"github.com/superfly/flyctl/internal/buildinfo"
 	"github.com/superfly/flyctl/internal/config"
 	"github.com/superfly/flyctl/internal/dockerfileurl"
@@ -58,6 +59,12 @@ type ImageOptions struct {
 	BuildpacksVolumes    []string
 	Compression          string
 	CompressionLevel     int
+	Cache                *appconfig.BuildCache
+	Platforms            []string
+	Attestations         *appconfig.BuildAttestations
+	// BaseImage and Files build the image without Docker; see ociBuilder.
+	BaseImage string
+	Files     []appconfig.BuildFile
 }
 
 func (io ImageOptions) ToSpanAttributes() []attribute.KeyValue {
@@ -78,6 +85,11 @@ func (io ImageOptions) ToSpanAttributes() []attribute.KeyValue {
 		attribute.StringSlice("imageoptions.buildpacks_volumes", io.BuildpacksVolumes),
 		attribute.String("imageoptions.compression", io.Compression),
 		attribute.Int("imageoptions.compressionLevel", io.CompressionLevel),
+		attribute.Bool("imageoptions.cache", io.Cache != nil),
+		attribute.StringSlice("imageoptions.platforms", io.Platforms),
+		attribute.Bool("imageoptions.attestations", io.Attestations != nil),
+		attribute.String("imageoptions.base_image", io.BaseImage),
+		attribute.Int("imageoptions.files", len(io.Files)),
 	}
 
 	if io.BuildArgs != nil {
@@ -129,6 +141,12 @@ type DeploymentImage struct {
 	BuildID   int64
 	BuilderID string
 	Labels    map[string]string
+	// Platforms holds the image of each platform when this is a
+	// multi-platform image.
+	Platforms []PlatformImage
+	// Attestations holds the SBOM and provenance of each platform, when the
+	// build generated them.
+	Attestations []ImageAttestation
 }
 
 func (di *DeploymentImage) String() string {
@@ -150,6 +168,10 @@ func (di *DeploymentImage) ToSpanAttributes() []attribute.KeyValue {
 		attribute.Int64("image.size", di.Size),
 	}
 
+	for _, p := range di.Platforms {
+		attrs = append(attrs, attribute.String("image.platform."+p.Platform, p.Digest))
+	}
+
 	b, err := json.Marshal(di.Labels)
 	if err == nil {
 		attrs = append(attrs, attribute.String("image.labels", string(b)))
@@ -252,7 +274,10 @@ func (r *Resolver) BuildImage(ctx context.Context, streams *iostreams.IOStreams,
 		}()
 	}
 
-	if !r.dockerFactory.mode.IsAvailable() {
+	// Copying files onto a base image doesn't need Docker.
+	daemonless := len(opts.Files) > 0
+
+	if !daemonless && !r.dockerFactory.mode.IsAvailable() {
 		err := errors.New("docker is unavailable to build the deployment image")
 		tracing.RecordError(span, err, "docker is unavailable to build the deployment image")
 
@@ -284,7 +309,9 @@ func (r *Resolver) BuildImage(ctx context.Context, streams *iostreams.IOStreams,
 		return nil, fmt.Errorf("invalid depot-scope value. must be 'org' or 'app'")
 	}
 
-	if r.provisioner.UseBuildkit() {
+	if daemonless {
+		strategies = append(strategies, &ociBuilder{})
+	} else if r.provisioner.UseBuildkit() {
 		strategies = append(strategies, NewBuildkitBuilder(flag.GetBuildkitAddr(ctx), r.provisioner))
 	} else if r.dockerFactory.mode.UseNixpacks() {
 		flapsClient := flapsutil.ClientFromContext(ctx)
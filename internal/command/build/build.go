@@ -0,0 +1,28 @@
+// Package build implements the build command chain.
+package build
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/superfly/flyctl/internal/command"
+)
+
+// New initializes and returns a new build Command.
+func New() *cobra.Command {
+	const (
+		short = "Inspect how flyctl builds app images"
+		long  = short + "\n"
+
+		usage = "build"
+	)
+
+	cmd := command.New(usage, short, long, nil)
+
+	cmd.Args = cobra.NoArgs
+
+	cmd.AddCommand(
+		newContext(),
+	)
+
+	return cmd
+}
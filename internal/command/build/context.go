@@ -0,0 +1,243 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	humanize "github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/build/imgsrc"
+	"github.com/superfly/flyctl/internal/command"
+	"github.com/superfly/flyctl/internal/config"
+	"github.com/superfly/flyctl/internal/dockerfileurl"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/internal/render"
+	"github.com/superfly/flyctl/internal/state"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func newContext() *cobra.Command {
+	const (
+		short = "Show what is sent to the builder as the build context"
+		long  = short + `. Walks the working directory applying the
+same .dockerignore rules as 'fly deploy', and shows the files that would be
+uploaded, what each ignore rule left out, the largest files, and patterns worth
+adding to .dockerignore.
+
+The Dockerfile and ignore file are taken from fly.toml when present; the
+--dockerfile and --ignorefile flags override them.`
+
+		usage = "context [WORKING_DIRECTORY]"
+	)
+
+	cmd := command.New(usage, short, long, runContext,
+		command.ChangeWorkingDirectoryToFirstArgIfPresent,
+		command.LoadAppConfigIfPresent,
+	)
+
+	cmd.Args = cobra.MaximumNArgs(1)
+
+	flag.Add(cmd,
+		flag.AppConfig(),
+		flag.Dockerfile(),
+		flag.Ignorefile(),
+		flag.Int{
+			Name:        "depth",
+			Description: "How many directory levels of the context to show; 0 shows them all",
+			Default:     2,
+		},
+		flag.JSONOutput(),
+	)
+
+	return cmd
+}
+
+func runContext(ctx context.Context) error {
+	var (
+		io         = iostreams.FromContext(ctx)
+		workingDir = state.WorkingDirectory(ctx)
+	)
+
+	dockerfile, ignoreFile, err := contextFiles(ctx)
+	if err != nil {
+		return err
+	}
+
+	report, err := imgsrc.AnalyzeBuildContext(workingDir, dockerfile, ignoreFile, flag.GetInt(ctx, "depth"))
+	if err != nil {
+		return fmt.Errorf("failed to analyze build context: %w", err)
+	}
+
+	if config.FromContext(ctx).JSONOutput {
+		return render.JSON(io.Out, report)
+	}
+
+	return renderContextReport(io, report)
+}
+
+// contextFiles returns the absolute paths of the Dockerfile and ignore file
+// of the build, or "" for either if the defaults in the working directory
+// apply.
+func contextFiles(ctx context.Context) (dockerfile, ignoreFile string, err error) {
+	workingDir := state.WorkingDirectory(ctx)
+
+	if cfg := appconfig.ConfigFromContext(ctx); cfg != nil {
+		configDir := filepath.Dir(cfg.ConfigFilePath())
+		if path := cfg.Dockerfile(); path != "" && !dockerfileurl.IsURL(path) {
+			dockerfile = filepath.Join(configDir, path)
+		}
+		if path := cfg.Ignorefile(); path != "" {
+			ignoreFile = filepath.Join(configDir, path)
+		}
+	}
+
+	if path := flag.GetString(ctx, "dockerfile"); path != "" {
+		if dockerfile, err = filepath.Abs(path); err != nil {
+			return "", "", err
+		}
+	}
+	if path := flag.GetString(ctx, "ignorefile"); path != "" {
+		if ignoreFile, err = filepath.Abs(path); err != nil {
+			return "", "", err
+		}
+	}
+
+	if dockerfile == "" {
+		dockerfile = imgsrc.ResolveDockerfile(workingDir)
+	}
+
+	return dockerfile, ignoreFile, nil
+}
+
+func renderContextReport(io *iostreams.IOStreams, report *imgsrc.BuildContextReport) error {
+	var (
+		out      = io.Out
+		colorize = io.ColorScheme()
+	)
+
+	fmt.Fprintf(out, "Build context: %s\n", report.WorkingDir)
+	if report.Dockerfile != "" {
+		fmt.Fprintf(out, "Dockerfile:    %s\n", report.Dockerfile)
+	}
+	if report.IgnoreFile != "" {
+		fmt.Fprintf(out, "Ignore file:   %s\n", report.IgnoreFile)
+	} else {
+		fmt.Fprintf(out, "Ignore file:   %s\n", colorize.Gray("none; only fly.toml is left out"))
+	}
+	fmt.Fprintf(out, "Total:         %s in %s %s\n\n",
+		humanize.Bytes(uint64(report.TotalBytes)),
+		humanize.Comma(int64(report.FileCount)),
+		plural(report.FileCount, "file", "files"),
+	)
+
+	fmt.Fprintln(out, colorize.Bold("Context"))
+	for _, child := range report.Tree.Children {
+		renderContextNode(out, child, 1)
+	}
+	fmt.Fprintln(out)
+
+	if len(report.Rules) > 0 {
+		rows := make([][]string, 0, len(report.Rules))
+		for _, rule := range report.Rules {
+			paths := make([]string, 0, len(rule.Paths))
+			for _, p := range rule.Paths {
+				paths = append(paths, displayPath(p.Path, p.Dir))
+			}
+			if countFiles(rule.Paths) < rule.Files {
+				paths = append(paths, "...")
+			}
+			rows = append(rows, []string{
+				rule.Pattern,
+				humanize.Bytes(uint64(rule.Bytes)),
+				humanize.Comma(int64(rule.Files)),
+				strings.Join(paths, ", "),
+			})
+		}
+		if err := render.Table(out, "Excluded by ignore rules", rows, "Rule", "Size", "Files", "Paths"); err != nil {
+			return err
+		}
+	}
+
+	if len(report.Largest) > 0 {
+		rows := make([][]string, 0, len(report.Largest))
+		for _, f := range report.Largest {
+			rows = append(rows, []string{f.Path, humanize.Bytes(uint64(f.Bytes))})
+		}
+		if err := render.Table(out, "Largest files", rows, "Path", "Size"); err != nil {
+			return err
+		}
+	}
+
+	if len(report.Suggestions) == 0 {
+		fmt.Fprintln(out, "No .dockerignore additions to suggest.")
+		return nil
+	}
+
+	rows := make([][]string, 0, len(report.Suggestions))
+	for _, s := range report.Suggestions {
+		rows = append(rows, []string{
+			s.Pattern,
+			humanize.Bytes(uint64(s.Bytes)),
+			humanize.Comma(int64(s.Files)),
+			s.Reason,
+		})
+	}
+	if err := render.Table(out, "Suggested .dockerignore additions", rows, "Pattern", "Size", "Files", "Reason"); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "Add the patterns you don't need in the image to .dockerignore:")
+	for _, s := range report.Suggestions {
+		fmt.Fprintf(out, "  %s\n", s.Pattern)
+	}
+
+	return nil
+}
+
+func renderContextNode(out io.Writer, node *imgsrc.BuildContextNode, level int) {
+	name := displayPath(node.Name, node.Dir)
+	indent := strings.Repeat("  ", level)
+
+	if node.Dir {
+		fmt.Fprintf(out, "%s%-*s %9s  %s %s\n", indent, 40-len(indent), name,
+			humanize.Bytes(uint64(node.Bytes)),
+			humanize.Comma(int64(node.Files)),
+			plural(node.Files, "file", "files"),
+		)
+	} else {
+		fmt.Fprintf(out, "%s%-*s %9s\n", indent, 40-len(indent), name, humanize.Bytes(uint64(node.Bytes)))
+	}
+
+	for _, child := range node.Children {
+		renderContextNode(out, child, level+1)
+	}
+}
+
+func displayPath(path string, dir bool) string {
+	if dir {
+		return path + "/"
+	}
+
+	return path
+}
+
+func countFiles(paths []imgsrc.BuildContextPath) (n int) {
+	for _, p := range paths {
+		n += p.Files
+	}
+
+	return
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+
+	return many
+}
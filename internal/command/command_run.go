@@ -83,6 +83,12 @@ func DetermineImage(ctx context.Context, appName string, imageOrPath string) (im
 			BuildpacksVolumes:    flag.GetStringSlice(ctx, flag.BuildpacksVolume),
 		}
 
+		if cfg.Build != nil {
+			opts.Cache = cfg.Build.Cache
+			opts.Platforms = cfg.Build.Platforms
+			opts.Attestations = cfg.Build.Attestations
+		}
+
 		dockerfilePath := cfg.Dockerfile()
 
 		// dockerfile passed through flags takes precedence over the one set in config
@@ -85,6 +85,10 @@ var CommonFlags = flag.Set{
 			"flyctl releases leases in most cases.",
 		Default: DefaultLeaseTtl.String(),
 	},
+	flag.String{
+		Name:        "lock-wait",
+		Description: "Time duration to wait for another deploy of the app to finish and release its deploy lock, instead of failing right away.",
+	},
 	flag.Bool{
 		Name:        "force-machines",
 		Description: "Use the Apps v2 platform built with Machines",
@@ -194,6 +198,22 @@ var CommonFlags = flag.Set{
 	},
 	flag.Compression(),
 	flag.CompressionLevel(),
+	flag.String{
+		Name:        "image-policy",
+		Description: "Path to an image policy file the image must pass before it's deployed. Overrides image_policy in the [deploy] section of fly.toml",
+	},
+	flag.String{
+		Name:        "policy",
+		Description: "Path to a deploy policy file of rules the deploy must pass before it starts, on top of policy in the [deploy] section of fly.toml",
+	},
+	flag.String{
+		Name:        "override-policy",
+		Description: "Deploy even if the deploy breaks the deploy policy, for the given reason. The override is recorded with the release",
+	},
+	flag.String{
+		Name:        "attestations-dir",
+		Description: "Write the SBOM and provenance attestations of the built image to this directory",
+	},
 }
 
 type Command struct {
@@ -244,6 +264,8 @@ func New() *Command {
 		},
 	)
 
+	cmd.AddCommand(newLock())
+
 	return cmd
 }
 
@@ -342,6 +364,16 @@ func DeployWithConfig(ctx context.Context, appConfig *appconfig.Config, userID i
 		return err
 	}
 
+	// Builds and manifest exports don't touch the app's machines
+	if !flag.GetBuildOnly(ctx) && flag.GetString(ctx, "export-manifest") == "" {
+		var unlock func()
+		ctx, unlock, err = lockDeployFromFlags(ctx, appName)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
 	// Start the feature flag client, if we haven't already
 	if launchdarkly.ClientFromContext(ctx) == nil {
 		ffClient, err := launchdarkly.NewClient(ctx, launchdarkly.UserInfo{
@@ -385,6 +417,13 @@ func DeployWithConfig(ctx context.Context, appConfig *appconfig.Config, userID i
 		return fmt.Errorf("failed to fetch an image or build from source: %w", err)
 	}
 
+	if err := writeAttestations(ctx, img); err != nil {
+		return err
+	}
+	if err := checkImagePolicy(ctx, appConfig, img); err != nil {
+		return err
+	}
+
 	if flag.GetBuildOnly(ctx) {
 		return nil
 	}
@@ -707,6 +746,11 @@ func deployToMachines(
 		return nil
 	}
 
+	ctx, err = checkDeployPolicy(ctx, NewManifest(app.Name, cfg, args))
+	if err != nil {
+		return err
+	}
+
 	md, err := NewMachineDeployment(ctx, args)
 	if err != nil {
 		sentry.CaptureExceptionWithFlapsAppInfo(ctx, err, "deploy", app)
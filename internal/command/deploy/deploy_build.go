@@ -189,6 +189,14 @@ func determineImage(ctx context.Context, app *flaps.App, appConfig *appconfig.Co
 		Buildpacks:           build.Buildpacks,
 		BuildpacksDockerHost: flag.GetString(ctx, flag.BuildpacksDockerHost),
 		BuildpacksVolumes:    flag.GetStringSlice(ctx, flag.BuildpacksVolume),
+		Cache:                build.Cache,
+		Platforms:            build.Platforms,
+		Attestations:         build.Attestations,
+	}
+
+	if len(build.Files) > 0 {
+		opts.BaseImage = build.Image
+		opts.Files = buildFiles(appConfig, build.Files)
 	}
 
 	if appConfig.Experimental != nil && appConfig.Experimental.LazyLoadImages {
@@ -258,12 +266,15 @@ func determineImage(ctx context.Context, app *flaps.App, appConfig *appconfig.Co
 	span.SetAttributes(opts.ToSpanAttributes()...)
 
 	// finally, build the image
-	heartbeat, err := resolver.StartHeartbeat(ctx)
-	if err != nil {
-		metrics.SendNoData(ctx, "remote_builder_failure")
-		tracing.RecordError(span, err, "failed to start heartbeat")
+	var heartbeat *imgsrc.StopSignal
+	if len(opts.Files) == 0 {
+		heartbeat, err = resolver.StartHeartbeat(ctx)
+		if err != nil {
+			metrics.SendNoData(ctx, "remote_builder_failure")
+			tracing.RecordError(span, err, "failed to start heartbeat")
 
-		return nil, err
+			return nil, err
+		}
 	}
 	defer heartbeat.Stop()
 
@@ -282,6 +293,9 @@ func determineImage(ctx context.Context, app *flaps.App, appConfig *appconfig.Co
 	if err == nil {
 		tb.Printf("image: %s\n", img.Tag)
 		tb.Printf("image size: %s\n", humanize.Bytes(uint64(img.Size)))
+		for _, p := range img.Platforms {
+			tb.Printf("  %s: %s (%s)\n", p.Platform, p.Digest, humanize.Bytes(uint64(p.Size)))
+		}
 	}
 
 	return
@@ -350,7 +364,8 @@ func fetchImageRef(ctx context.Context, cfg *appconfig.Config) (ref string, err
 		return
 	}
 
-	if cfg != nil && cfg.Build != nil {
+	// With [[build.files]], the image is a base to build on.
+	if cfg != nil && cfg.Build != nil && len(cfg.Build.Files) == 0 {
 		if ref = cfg.Build.Image; ref != "" {
 			return
 		}
@@ -358,3 +373,17 @@ func fetchImageRef(ctx context.Context, cfg *appconfig.Config) (ref string, err
 
 	return ref, nil
 }
+
+// buildFiles returns files with local paths made absolute, relative to the
+// directory of fly.toml.
+func buildFiles(appConfig *appconfig.Config, files []appconfig.BuildFile) []appconfig.BuildFile {
+	resolved := make([]appconfig.BuildFile, 0, len(files))
+	for _, f := range files {
+		if !filepath.IsAbs(f.LocalPath) {
+			f.LocalPath = filepath.Join(filepath.Dir(appConfig.ConfigFilePath()), f.LocalPath)
+		}
+		resolved = append(resolved, f)
+	}
+
+	return resolved
+}
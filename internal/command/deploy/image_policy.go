@@ -0,0 +1,122 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/build/imgsrc"
+	"github.com/superfly/flyctl/internal/dockerfileurl"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/internal/state"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+// imagePolicyPath returns the path of the image policy to check the image
+// against, or "" if there is none. --image-policy takes precedence over
+// [deploy] image_policy, which is relative to fly.toml.
+func imagePolicyPath(ctx context.Context, appConfig *appconfig.Config) string {
+	if path := flag.GetString(ctx, "image-policy"); path != "" {
+		return path
+	}
+	if appConfig.Deploy == nil || appConfig.Deploy.ImagePolicy == "" {
+		return ""
+	}
+	if filepath.IsAbs(appConfig.Deploy.ImagePolicy) {
+		return appConfig.Deploy.ImagePolicy
+	}
+
+	return filepath.Join(filepath.Dir(appConfig.ConfigFilePath()), appConfig.Deploy.ImagePolicy)
+}
+
+// checkImagePolicy fails if img breaks the image policy of the deploy.
+func checkImagePolicy(ctx context.Context, appConfig *appconfig.Config, img *imgsrc.DeploymentImage) error {
+	path := imagePolicyPath(ctx, appConfig)
+	if path == "" {
+		return nil
+	}
+
+	policy, err := imgsrc.LoadImagePolicy(path)
+	if err != nil {
+		return err
+	}
+
+	// The Dockerfile only tells what an image was built from when it was
+	// built from source here.
+	var dockerfile string
+	if ref, err := fetchImageRef(ctx, appConfig); err == nil && ref == "" {
+		dockerfile, err = resolveDockerfilePath(ctx, appConfig)
+		if err != nil || dockerfileurl.IsURL(dockerfile) {
+			dockerfile = ""
+		}
+		if dockerfile == "" {
+			dockerfile = imgsrc.ResolveDockerfile(state.WorkingDirectory(ctx))
+		}
+	}
+
+	violations, err := policy.Check(img, dockerfile)
+	if err != nil {
+		return fmt.Errorf("failed to check image policy: %w", err)
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("image %s breaks the image policy %s:\n  %s", img.Tag, path, strings.Join(violations, "\n  "))
+	}
+
+	io := iostreams.FromContext(ctx)
+	fmt.Fprintf(io.ErrOut, "Image passed the image policy %s\n", path)
+
+	return nil
+}
+
+// writeAttestations writes the SBOMs and provenance of img to the directory
+// set with --attestations-dir, if any.
+func writeAttestations(ctx context.Context, img *imgsrc.DeploymentImage) error {
+	dir := flag.GetString(ctx, "attestations-dir")
+	if dir == "" {
+		return nil
+	}
+	if len(img.Attestations) == 0 {
+		return fmt.Errorf("--attestations-dir was set, but the image has no attestations; enable them in [build.attestations]")
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	io := iostreams.FromContext(ctx)
+	write := func(name string, data []byte) error {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(io.ErrOut, "Wrote %s\n", path)
+
+		return nil
+	}
+
+	for _, a := range img.Attestations {
+		prefix := ""
+		if len(img.Attestations) > 1 {
+			prefix = strings.ReplaceAll(a.Platform, "/", "-") + "."
+		}
+		if a.SBOM != nil {
+			ext := ".spdx.json"
+			if a.SBOM.Format == imgsrc.SBOMFormatCycloneDX {
+				ext = ".cdx.json"
+			}
+			if err := write(prefix+"sbom"+ext, a.SBOM.Document); err != nil {
+				return err
+			}
+		}
+		if a.Provenance != nil {
+			if err := write(prefix+"provenance.json", a.Provenance.Predicate); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
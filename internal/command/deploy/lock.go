@@ -0,0 +1,447 @@
+package deploy
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	fly "github.com/superfly/fly-go"
+	"github.com/superfly/fly-go/flaps"
+	"github.com/superfly/flyctl/helpers"
+	"github.com/superfly/flyctl/internal/env"
+	"github.com/superfly/flyctl/internal/flapsutil"
+	"github.com/superfly/flyctl/internal/flyutil"
+	"github.com/superfly/flyctl/iostreams"
+	"github.com/superfly/flyctl/terminal"
+)
+
+// deployLockKey is the machine metadata key the deploy lock of an app is
+// stored under. Apps have no metadata of their own, so the lock is taken on
+// their oldest machine.
+const deployLockKey = "fly_deploy_lock"
+
+// deployLockGuardTTL is how long, in seconds, the machine the deploy lock is
+// taken on is leased for while the lock is taken, so that two deploys can't
+// take it at once.
+const deployLockGuardTTL = 10
+
+var (
+	// deployLockTTL is how long a deploy lock lasts unless it's refreshed, so
+	// that the lock of a deploy that crashed goes away by itself.
+	deployLockTTL             = 5 * time.Minute
+	deployLockRefreshInterval = time.Minute
+	deployLockPollInterval    = 5 * time.Second
+)
+
+// deployLock is the lock a deploy holds on its app so that other deploys
+// wait for it or fail, instead of failing to lease machines mid-rollout.
+type deployLock struct {
+	ID         string    `json:"id"`
+	User       string    `json:"user,omitempty"`
+	Host       string    `json:"host,omitempty"`
+	Job        string    `json:"job,omitempty"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+
+	// MachineID is the machine the lock was found on
+	MachineID string `json:"-"`
+}
+
+// newDeployLock returns a deploy lock held by the current user on this host
+// or CI job.
+func newDeployLock(ctx context.Context) (*deployLock, error) {
+	id, err := helpers.RandHex(8)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &deployLock{
+		ID:  id,
+		Job: ciJob(),
+	}
+	lock.Host, _ = os.Hostname()
+
+	// Deploy tokens can't tell who they belong to
+	if user, err := flyutil.ClientFromContext(ctx).GetCurrentUser(ctx); err == nil {
+		lock.User = user.Email
+	}
+
+	return lock, nil
+}
+
+// ciJob returns a link to or the name of the CI job flyctl runs in, or "".
+func ciJob() string {
+	if env.IS_GH_ACTION() && os.Getenv("GITHUB_RUN_ID") != "" {
+		return fmt.Sprintf("%s/%s/actions/runs/%s",
+			cmp.Or(os.Getenv("GITHUB_SERVER_URL"), "https://github.com"), env.GitRepoAndOwner(), os.Getenv("GITHUB_RUN_ID"))
+	}
+
+	return env.First("CI_JOB_URL", "CIRCLE_BUILD_URL", "BUILD_URL", "BUILDKITE_BUILD_URL")
+}
+
+func (l *deployLock) holder() string {
+	holder := cmp.Or(l.User, "an unknown user")
+	switch {
+	case l.Job != "":
+		holder += " in " + l.Job
+	case l.Host != "":
+		holder += " on " + l.Host
+	}
+
+	return holder
+}
+
+func (l *deployLock) expired(now time.Time) bool {
+	return !now.Before(l.ExpiresAt)
+}
+
+// heldDeployLock is the deploy lock held by the current deploy, whose expiry
+// is pushed back while the deploy runs.
+type heldDeployLock struct {
+	mu   sync.Mutex
+	lock deployLock
+}
+
+type heldDeployLockKey struct{}
+
+// heldDeployLockFromContext returns the deploy lock held by the deploy of
+// ctx, or nil if it holds none.
+func heldDeployLockFromContext(ctx context.Context) *heldDeployLock {
+	held, _ := ctx.Value(heldDeployLockKey{}).(*heldDeployLock)
+
+	return held
+}
+
+// value returns the lock as stored in machine metadata.
+func (h *heldDeployLock) value() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	data, _ := json.Marshal(h.lock)
+
+	return string(data)
+}
+
+func (h *heldDeployLock) extend(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lock.ExpiresAt = now.UTC().Add(deployLockTTL)
+}
+
+func (h *heldDeployLock) expiresAt() time.Time {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.lock.ExpiresAt
+}
+
+// deployLockedError is returned when a deploy can't take the deploy lock of
+// an app because another deploy holds it.
+type deployLockedError struct {
+	app string
+	// lock is nil when the machines of the app are leased by a deploy that
+	// doesn't take the deploy lock, like one of an older flyctl.
+	lock *deployLock
+}
+
+func (e *deployLockedError) Error() string {
+	if e.lock == nil {
+		return fmt.Sprintf("%s is being deployed or updated by someone else", e.app)
+	}
+
+	return fmt.Sprintf("%s is being deployed by %s since %s (the lock expires at %s unless it's refreshed)",
+		e.app, e.lock.holder(), e.lock.AcquiredAt.Local().Format(time.DateTime), e.lock.ExpiresAt.Local().Format(time.DateTime))
+}
+
+func (e *deployLockedError) Suggestion() string {
+	return fmt.Sprintf("Wait for the other deploy with --lock-wait, or if it's gone, break its lock with 'fly deploy lock break -a %s'", e.app)
+}
+
+// findDeployLock returns the deploy lock held on machines, or nil if none
+// is. Machines that are created from a locked one inherit the lock, so the
+// lock that lasts the longest is returned.
+func findDeployLock(machines []*fly.Machine, now time.Time) *deployLock {
+	var found *deployLock
+	for _, m := range machines {
+		if m.Config == nil {
+			continue
+		}
+		lock := parseDeployLock(m.Config.Metadata[deployLockKey])
+		if lock == nil || lock.expired(now) {
+			continue
+		}
+		if found == nil || lock.ExpiresAt.After(found.ExpiresAt) {
+			lock.MachineID = m.ID
+			found = lock
+		}
+	}
+
+	return found
+}
+
+func parseDeployLock(value string) *deployLock {
+	if value == "" {
+		return nil
+	}
+
+	var lock deployLock
+	if err := json.Unmarshal([]byte(value), &lock); err != nil {
+		return nil
+	}
+
+	return &lock
+}
+
+// lockMachine returns the machine the deploy lock of an app is taken on,
+// which is its oldest machine, or nil if the app has none.
+func lockMachine(machines []*fly.Machine) *fly.Machine {
+	if len(machines) == 0 {
+		return nil
+	}
+
+	return slices.MinFunc(machines, func(a, b *fly.Machine) int {
+		return cmp.Or(strings.Compare(a.CreatedAt, b.CreatedAt), strings.Compare(a.ID, b.ID))
+	})
+}
+
+// lockDeploy takes the deploy lock of an app for the current deploy, waiting
+// up to wait for another deploy to release it, and returns a context
+// carrying the lock. The returned func releases the lock; until it's called
+// the lock is refreshed in the background.
+//
+// Apps without machines can't be locked, nor can they be mid-rollout.
+func lockDeploy(ctx context.Context, appName string, wait time.Duration) (context.Context, func(), error) {
+	var (
+		io          = iostreams.FromContext(ctx)
+		flapsClient = flapsutil.ClientFromContext(ctx)
+	)
+
+	lock, err := newDeployLock(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deadline := time.Now().Add(wait)
+	var waitingFor string
+	for {
+		err := tryDeployLock(ctx, flapsClient, appName, lock)
+
+		var lockedErr *deployLockedError
+		if !errors.As(err, &lockedErr) || time.Now().Add(deployLockPollInterval).After(deadline) {
+			if errors.Is(err, errNoLockMachine) {
+				terminal.Debugf("not locking %s as it has no machines\n", appName)
+
+				return ctx, func() {}, nil
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+
+			break
+		}
+
+		if msg := lockedErr.Error(); msg != waitingFor {
+			fmt.Fprintf(io.ErrOut, "Waiting for the deploy lock: %s\n", msg)
+			waitingFor = msg
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(deployLockPollInterval):
+		}
+	}
+
+	fmt.Fprintf(io.Out, "Acquired the deploy lock of %s\n", appName)
+
+	held := &heldDeployLock{lock: *lock}
+
+	refreshCtx, stopRefresh := context.WithCancel(ctx)
+	refreshed := make(chan struct{})
+	go func() {
+		defer close(refreshed)
+		refreshDeployLock(refreshCtx, flapsClient, appName, held)
+	}()
+
+	return context.WithValue(ctx, heldDeployLockKey{}, held), func() {
+		stopRefresh()
+		<-refreshed
+
+		if err := releaseDeployLock(context.WithoutCancel(ctx), flapsClient, appName, lock.ID); err != nil {
+			terminal.Warnf("failed to release the deploy lock of %s, it expires at %s: %v\n",
+				appName, held.expiresAt().Local().Format(time.DateTime), err)
+		}
+	}, nil
+}
+
+// lockDeployFromFlags takes the deploy lock of an app, waiting for as long
+// as --lock-wait says.
+func lockDeployFromFlags(ctx context.Context, appName string) (context.Context, func(), error) {
+	wait, err := parseDurationFlag(ctx, "lock-wait")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return lockDeploy(ctx, appName, lo.FromPtr(wait))
+}
+
+var errNoLockMachine = errors.New("app has no machines to lock")
+
+// tryDeployLock takes the deploy lock of an app for lock, or returns a
+// *deployLockedError if another deploy holds it.
+func tryDeployLock(ctx context.Context, flapsClient flapsutil.FlapsClient, appName string, lock *deployLock) error {
+	machines, _, err := flapsClient.ListFlyAppsMachines(ctx, appName)
+	if err != nil {
+		return fmt.Errorf("failed to list machines of %s to lock it: %w", appName, err)
+	}
+
+	if held := findDeployLock(machines, time.Now()); held != nil && held.ID != lock.ID {
+		return &deployLockedError{app: appName, lock: held}
+	}
+
+	m := lockMachine(machines)
+	if m == nil {
+		return errNoLockMachine
+	}
+
+	// The lease makes checking for and taking the lock atomic. A machine
+	// that's already leased is mid-deploy, or about to be locked.
+	lease, err := flapsClient.AcquireLease(ctx, appName, m.ID, new(deployLockGuardTTL))
+	if err != nil {
+		var flapsErr *flaps.FlapsError
+		if errors.As(err, &flapsErr) && flapsErr.ResponseStatusCode == http.StatusConflict {
+			return &deployLockedError{app: appName}
+		}
+
+		return fmt.Errorf("failed to lock %s: %w", appName, err)
+	}
+	if lease.Data == nil {
+		return &deployLockedError{app: appName}
+	}
+	defer func() {
+		if err := flapsClient.ReleaseLease(context.WithoutCancel(ctx), appName, m.ID, lease.Data.Nonce); err != nil {
+			terminal.Debugf("failed to release lease on %s: %v\n", m.ID, err)
+		}
+	}()
+
+	metadata, err := flapsClient.GetMetadata(ctx, appName, m.ID)
+	if err != nil {
+		return fmt.Errorf("failed to lock %s: %w", appName, err)
+	}
+	if held := parseDeployLock(metadata[deployLockKey]); held != nil && held.ID != lock.ID && !held.expired(time.Now()) {
+		held.MachineID = m.ID
+
+		return &deployLockedError{app: appName, lock: held}
+	}
+
+	lock.AcquiredAt = time.Now().UTC()
+	lock.ExpiresAt = lock.AcquiredAt.Add(deployLockTTL)
+	value, err := json.Marshal(lock)
+	if err != nil {
+		return err
+	}
+	if err := flapsClient.SetMetadata(ctx, appName, m.ID, deployLockKey, string(value)); err != nil {
+		return fmt.Errorf("failed to lock %s: %w", appName, err)
+	}
+	lock.MachineID = m.ID
+
+	return nil
+}
+
+// refreshDeployLock pushes back the expiry of lock until ctx is done, or
+// until the lock is broken.
+func refreshDeployLock(ctx context.Context, flapsClient flapsutil.FlapsClient, appName string, held *heldDeployLock) {
+	ticker := time.NewTicker(deployLockRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		machines, _, err := flapsClient.ListFlyAppsMachines(ctx, appName)
+		if err != nil {
+			terminal.Debugf("failed to refresh the deploy lock of %s: %v\n", appName, err)
+
+			continue
+		}
+
+		// The machine the lock was taken on may be gone, as with bluegreen
+		// deploys, but the machines created from it carry the lock.
+		var holding []string
+		for _, m := range machines {
+			if m.Config != nil {
+				if lock := parseDeployLock(m.Config.Metadata[deployLockKey]); lock != nil && lock.ID == held.lock.ID {
+					holding = append(holding, m.ID)
+				}
+			}
+		}
+		if len(holding) == 0 {
+			terminal.Warnf("the deploy lock of %s was broken, another deploy could start\n", appName)
+
+			return
+		}
+
+		held.extend(time.Now())
+		value := held.value()
+		for _, id := range holding {
+			if err := flapsClient.SetMetadata(ctx, appName, id, deployLockKey, value); err != nil {
+				terminal.Debugf("failed to refresh the deploy lock of %s on %s: %v\n", appName, id, err)
+			}
+		}
+	}
+}
+
+// releaseDeployLock removes the deploy lock with the given ID from all the
+// machines of an app.
+func releaseDeployLock(ctx context.Context, flapsClient flapsutil.FlapsClient, appName, id string) error {
+	return removeDeployLocks(ctx, flapsClient, appName, func(lock *deployLock) bool {
+		return lock.ID == id
+	})
+}
+
+// breakDeployLock removes any deploy lock, held or expired, from the
+// machines of an app.
+func breakDeployLock(ctx context.Context, flapsClient flapsutil.FlapsClient, appName string) error {
+	return removeDeployLocks(ctx, flapsClient, appName, func(*deployLock) bool {
+		return true
+	})
+}
+
+func removeDeployLocks(ctx context.Context, flapsClient flapsutil.FlapsClient, appName string, match func(*deployLock) bool) error {
+	machines, _, err := flapsClient.ListFlyAppsMachines(ctx, appName)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, m := range machines {
+		if m.Config == nil {
+			continue
+		}
+		if _, ok := m.Config.Metadata[deployLockKey]; !ok {
+			continue
+		}
+		// Unreadable locks are removed along with the matching ones
+		if lock := parseDeployLock(m.Config.Metadata[deployLockKey]); lock != nil && !match(lock) {
+			continue
+		}
+		if err := flapsClient.DeleteMetadata(ctx, appName, m.ID, deployLockKey); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
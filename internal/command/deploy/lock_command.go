@@ -0,0 +1,169 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/command"
+	"github.com/superfly/flyctl/internal/config"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/internal/flapsutil"
+	"github.com/superfly/flyctl/internal/format"
+	"github.com/superfly/flyctl/internal/prompt"
+	"github.com/superfly/flyctl/internal/render"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func newLock() *cobra.Command {
+	const (
+		short = "Manage the deploy lock of an app"
+		long  = `Manage the deploy lock of an app. Deploys take the lock before they start and
+release it when they're done, so that two deploys of the same app don't roll
+out at once. A deploy that finds the app locked fails, or waits for the lock
+with --lock-wait.
+
+The lock expires a few minutes after the deploy holding it stops refreshing
+it, like when it crashed.
+
+To deploy a working directory named lock, run 'fly deploy ./lock'.
+`
+	)
+
+	cmd := command.New("lock", short, long, nil)
+
+	cmd.AddCommand(
+		newLockStatus(),
+		newLockBreak(),
+	)
+
+	return cmd
+}
+
+func newLockStatus() *cobra.Command {
+	const (
+		short = "Show who holds the deploy lock of an app"
+		long  = short + "\n"
+	)
+
+	cmd := command.New("status", short, long, runLockStatus,
+		command.RequireSession,
+		command.RequireAppName,
+	)
+
+	cmd.Args = cobra.NoArgs
+
+	flag.Add(cmd,
+		flag.App(),
+		flag.AppConfig(),
+		flag.JSONOutput(),
+	)
+
+	return cmd
+}
+
+// deployLockStatus is the JSON output of fly deploy lock status.
+type deployLockStatus struct {
+	Locked    bool   `json:"locked"`
+	MachineID string `json:"machine_id,omitempty"`
+	*deployLock
+}
+
+func runLockStatus(ctx context.Context) error {
+	var (
+		io      = iostreams.FromContext(ctx)
+		appName = appconfig.NameFromContext(ctx)
+	)
+
+	machines, _, err := flapsutil.ClientFromContext(ctx).ListFlyAppsMachines(ctx, appName)
+	if err != nil {
+		return fmt.Errorf("failed to list machines of %s: %w", appName, err)
+	}
+	lock := findDeployLock(machines, time.Now())
+
+	if config.FromContext(ctx).JSONOutput {
+		status := deployLockStatus{Locked: lock != nil, deployLock: lock}
+		if lock != nil {
+			status.MachineID = lock.MachineID
+		}
+
+		return render.JSON(io.Out, status)
+	}
+
+	if lock == nil {
+		fmt.Fprintf(io.Out, "%s isn't locked\n", appName)
+
+		return nil
+	}
+
+	rows := [][]string{{
+		lock.holder(),
+		format.RelativeTime(lock.AcquiredAt),
+		lock.ExpiresAt.Local().Format(time.DateTime),
+		lock.MachineID,
+	}}
+
+	return render.VerticalTable(io.Out, fmt.Sprintf("%s is locked", appName), rows, "Holder", "Acquired", "Expires", "Machine")
+}
+
+func newLockBreak() *cobra.Command {
+	const (
+		short = "Break the deploy lock of an app"
+		long  = `Break the deploy lock of an app, so that other deploys can start. Only break
+the lock of a deploy that's gone: a deploy still running keeps rolling out.
+`
+	)
+
+	cmd := command.New("break", short, long, runLockBreak,
+		command.RequireSession,
+		command.RequireAppName,
+	)
+
+	cmd.Args = cobra.NoArgs
+
+	flag.Add(cmd,
+		flag.App(),
+		flag.AppConfig(),
+		flag.Yes(),
+	)
+
+	return cmd
+}
+
+func runLockBreak(ctx context.Context) error {
+	var (
+		io          = iostreams.FromContext(ctx)
+		appName     = appconfig.NameFromContext(ctx)
+		flapsClient = flapsutil.ClientFromContext(ctx)
+	)
+
+	machines, _, err := flapsClient.ListFlyAppsMachines(ctx, appName)
+	if err != nil {
+		return fmt.Errorf("failed to list machines of %s: %w", appName, err)
+	}
+
+	lock := findDeployLock(machines, time.Now())
+	if lock != nil && !flag.GetYes(ctx) {
+		switch confirmed, err := prompt.Confirmf(ctx, "Break the deploy lock of %s held by %s since %s?",
+			appName, lock.holder(), format.RelativeTime(lock.AcquiredAt)); {
+		case err != nil:
+			return err
+		case !confirmed:
+			return nil
+		}
+	}
+
+	if err := breakDeployLock(ctx, flapsClient, appName); err != nil {
+		return fmt.Errorf("failed to break the deploy lock of %s: %w", appName, err)
+	}
+
+	if lock == nil {
+		fmt.Fprintf(io.Out, "%s isn't locked\n", appName)
+	} else {
+		fmt.Fprintf(io.Out, "Broke the deploy lock of %s held by %s\n", appName, lock.holder())
+	}
+
+	return nil
+}
@@ -0,0 +1,251 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	fly "github.com/superfly/fly-go"
+	"github.com/superfly/fly-go/flaps"
+	"github.com/superfly/flyctl/internal/flapsutil"
+	"github.com/superfly/flyctl/internal/flyutil"
+	"github.com/superfly/flyctl/internal/mock"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+// lockMachines is a set of machines whose metadata and leases are kept in
+// memory, for a mock flaps client.
+type lockMachines struct {
+	mu       sync.Mutex
+	machines map[string]*fly.Machine
+	leased   map[string]bool
+}
+
+func newLockMachines(machines ...*fly.Machine) (*lockMachines, *mock.FlapsClient) {
+	lm := &lockMachines{machines: map[string]*fly.Machine{}, leased: map[string]bool{}}
+	for _, m := range machines {
+		m.Config = &fly.MachineConfig{Metadata: map[string]string{}}
+		lm.machines[m.ID] = m
+	}
+
+	client := &mock.FlapsClient{
+		ListFlyAppsMachinesFunc: func(ctx context.Context, appName string) ([]*fly.Machine, *fly.Machine, error) {
+			lm.mu.Lock()
+			defer lm.mu.Unlock()
+
+			var machines []*fly.Machine
+			for _, m := range lm.machines {
+				copied := *m
+				copied.Config = &fly.MachineConfig{Metadata: maps.Clone(m.Config.Metadata)}
+				machines = append(machines, &copied)
+			}
+
+			return machines, nil, nil
+		},
+		AcquireLeaseFunc: func(ctx context.Context, appName, machineID string, ttl *int) (*fly.MachineLease, error) {
+			lm.mu.Lock()
+			defer lm.mu.Unlock()
+
+			if lm.leased[machineID] {
+				return nil, &flaps.FlapsError{ResponseStatusCode: http.StatusConflict}
+			}
+			lm.leased[machineID] = true
+
+			return &fly.MachineLease{Data: &fly.MachineLeaseData{Nonce: "nonce"}}, nil
+		},
+		ReleaseLeaseFunc: func(ctx context.Context, appName, machineID, nonce string) error {
+			lm.mu.Lock()
+			defer lm.mu.Unlock()
+
+			delete(lm.leased, machineID)
+
+			return nil
+		},
+		GetMetadataFunc: func(ctx context.Context, appName, machineID string) (map[string]string, error) {
+			lm.mu.Lock()
+			defer lm.mu.Unlock()
+
+			return maps.Clone(lm.machines[machineID].Config.Metadata), nil
+		},
+		SetMetadataFunc: func(ctx context.Context, appName, machineID, key, value string) error {
+			lm.mu.Lock()
+			defer lm.mu.Unlock()
+
+			lm.machines[machineID].Config.Metadata[key] = value
+
+			return nil
+		},
+		DeleteMetadataFunc: func(ctx context.Context, appName, machineID, key string) error {
+			lm.mu.Lock()
+			defer lm.mu.Unlock()
+
+			delete(lm.machines[machineID].Config.Metadata, key)
+
+			return nil
+		},
+	}
+
+	return lm, client
+}
+
+func (lm *lockMachines) metadata(machineID string) string {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	return lm.machines[machineID].Config.Metadata[deployLockKey]
+}
+
+func lockTestContext(flapsClient flapsutil.FlapsClient, email string) context.Context {
+	ios, _, _, _ := iostreams.Test()
+	ctx := iostreams.NewContext(context.Background(), ios)
+	ctx = flapsutil.NewContextWithClient(ctx, flapsClient)
+
+	return flyutil.NewContextWithClient(ctx, &mock.Client{
+		GetCurrentUserFunc: func(ctx context.Context) (*fly.User, error) {
+			if email == "" {
+				return nil, errors.New("unauthorized")
+			}
+
+			return &fly.User{Email: email}, nil
+		},
+	})
+}
+
+func TestLockDeploy(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_REPOSITORY", "acme/my-app")
+	t.Setenv("GITHUB_RUN_ID", "42")
+
+	lm, flapsClient := newLockMachines(
+		&fly.Machine{ID: "m2", CreatedAt: "2026-10-02T00:00:00Z"},
+		&fly.Machine{ID: "m1", CreatedAt: "2026-10-01T00:00:00Z"},
+	)
+
+	ctx, unlock, err := lockDeploy(lockTestContext(flapsClient, "ci@example.com"), "my-app", 0)
+	require.NoError(t, err)
+	require.NotNil(t, heldDeployLockFromContext(ctx))
+
+	// The lock is taken on the oldest machine
+	assert.NotEmpty(t, lm.metadata("m1"))
+	assert.Empty(t, lm.metadata("m2"))
+	assert.Empty(t, lm.leased)
+
+	_, _, err = lockDeploy(lockTestContext(flapsClient, "dev@example.com"), "my-app", 0)
+	var lockedErr *deployLockedError
+	require.ErrorAs(t, err, &lockedErr)
+	assert.Equal(t, "ci@example.com in https://github.com/acme/my-app/actions/runs/42", lockedErr.lock.holder())
+	assert.Equal(t, "m1", lockedErr.lock.MachineID)
+	assert.ErrorContains(t, err, "my-app is being deployed by ci@example.com")
+
+	unlock()
+	assert.Empty(t, lm.metadata("m1"))
+
+	_, unlock, err = lockDeploy(lockTestContext(flapsClient, "dev@example.com"), "my-app", 0)
+	require.NoError(t, err)
+	unlock()
+}
+
+func TestLockDeployWaits(t *testing.T) {
+	defer func(interval time.Duration) { deployLockPollInterval = interval }(deployLockPollInterval)
+	deployLockPollInterval = 10 * time.Millisecond
+
+	lm, flapsClient := newLockMachines(&fly.Machine{ID: "m1"})
+
+	_, unlock, err := lockDeploy(lockTestContext(flapsClient, "ci@example.com"), "my-app", 0)
+	require.NoError(t, err)
+	first := lm.metadata("m1")
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		unlock()
+	}()
+
+	_, unlock, err = lockDeploy(lockTestContext(flapsClient, "dev@example.com"), "my-app", time.Minute)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, lm.metadata("m1"))
+	assert.Equal(t, "dev@example.com", parseDeployLock(lm.metadata("m1")).User)
+	unlock()
+
+	// Giving up once the wait is over
+	_, unlock, err = lockDeploy(lockTestContext(flapsClient, "ci@example.com"), "my-app", 0)
+	require.NoError(t, err)
+	defer unlock()
+
+	_, _, err = lockDeploy(lockTestContext(flapsClient, "dev@example.com"), "my-app", 50*time.Millisecond)
+	assert.ErrorAs(t, err, new(*deployLockedError))
+}
+
+func TestLockDeployExpired(t *testing.T) {
+	lm, flapsClient := newLockMachines(&fly.Machine{ID: "m1"})
+	expired := `{"id":"crashed","user":"ci@example.com","expires_at":"2026-10-01T00:00:00Z"}`
+	lm.machines["m1"].Config.Metadata[deployLockKey] = expired
+
+	assert.Nil(t, findDeployLock([]*fly.Machine{lm.machines["m1"]}, time.Now()))
+
+	_, unlock, err := lockDeploy(lockTestContext(flapsClient, ""), "my-app", 0)
+	require.NoError(t, err)
+	defer unlock()
+
+	lock := parseDeployLock(lm.metadata("m1"))
+	assert.NotEqual(t, "crashed", lock.ID)
+	assert.Empty(t, lock.User)
+	assert.True(t, strings.HasPrefix(lock.holder(), "an unknown user"))
+}
+
+func TestLockDeployLeased(t *testing.T) {
+	lm, flapsClient := newLockMachines(&fly.Machine{ID: "m1"})
+	// As by a deploy of a flyctl without deploy locks
+	lm.leased["m1"] = true
+
+	_, _, err := lockDeploy(lockTestContext(flapsClient, "ci@example.com"), "my-app", 0)
+	var lockedErr *deployLockedError
+	require.ErrorAs(t, err, &lockedErr)
+	assert.Nil(t, lockedErr.lock)
+	assert.Empty(t, lm.metadata("m1"))
+}
+
+func TestLockDeployWithoutMachines(t *testing.T) {
+	_, flapsClient := newLockMachines()
+
+	ctx, unlock, err := lockDeploy(lockTestContext(flapsClient, "ci@example.com"), "my-app", 0)
+	require.NoError(t, err)
+	assert.Nil(t, heldDeployLockFromContext(ctx))
+	unlock()
+}
+
+func TestBreakDeployLock(t *testing.T) {
+	lm, flapsClient := newLockMachines(&fly.Machine{ID: "m1"}, &fly.Machine{ID: "m2"})
+	ctx := lockTestContext(flapsClient, "ci@example.com")
+
+	_, unlock, err := lockDeploy(ctx, "my-app", 0)
+	require.NoError(t, err)
+	defer unlock()
+	// As on a machine created from the locked one by a bluegreen deploy
+	lm.machines["m2"].Config.Metadata[deployLockKey] = lm.metadata("m1")
+
+	require.NoError(t, breakDeployLock(ctx, flapsClient, "my-app"))
+	assert.Empty(t, lm.metadata("m1"))
+	assert.Empty(t, lm.metadata("m2"))
+}
+
+func TestSetMachineReleaseDataRefreshesDeployLock(t *testing.T) {
+	held := &heldDeployLock{lock: deployLock{ID: "abc", ExpiresAt: time.Now()}}
+	md := &machineDeployment{app: &flaps.App{Name: "my-app"}, deployLock: held}
+
+	mConfig := &fly.MachineConfig{Metadata: map[string]string{deployLockKey: held.value()}}
+	held.extend(time.Now())
+	md.setMachineReleaseData(mConfig)
+	assert.Equal(t, held.value(), mConfig.Metadata[deployLockKey])
+
+	// Machines without the lock don't get it
+	mConfig = &fly.MachineConfig{}
+	md.setMachineReleaseData(mConfig)
+	assert.NotContains(t, mConfig.Metadata, deployLockKey)
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/superfly/flyctl/internal/buildinfo"
 	"github.com/superfly/flyctl/internal/cmdutil"
 	"github.com/superfly/flyctl/internal/command/deploy/statics"
+	"github.com/superfly/flyctl/internal/command/deploycontext"
 	"github.com/superfly/flyctl/internal/flapsutil"
 	"github.com/superfly/flyctl/internal/flyutil"
 	"github.com/superfly/flyctl/internal/machine"
@@ -162,6 +163,13 @@ type machineDeployment struct {
 	deployRetries         int
 	buildID               int64
 	builderID             string
+	// deployLock is the deploy lock held by the deploy, if any.
+	deployLock *heldDeployLock
+	// startedAt is when the machines started being deployed.
+	startedAt time.Time
+	// failedMachines are the IDs of machines that failed to be updated.
+	failedMachines   []string
+	failedMachinesMu sync.Mutex
 }
 
 func NewMachineDeployment(ctx context.Context, args MachineDeploymentArgs) (_ MachineDeployment, err error) {
@@ -258,6 +266,7 @@ func NewMachineDeployment(ctx context.Context, args MachineDeploymentArgs) (_ Ma
 		app:                   args.App,
 		appConfig:             appConfig,
 		img:                   args.DeploymentImage,
+		deployLock:            heldDeployLockFromContext(ctx),
 		skipSmokeChecks:       args.SkipSmokeChecks,
 		skipHealthChecks:      args.SkipHealthChecks,
 		skipDNSChecks:         args.SkipDNSChecks,
@@ -714,6 +723,14 @@ func (md *machineDeployment) createReleaseInBackend(ctx context.Context) error {
 	return nil
 }
 
+// annotatedReleaseMetadata is the metadata of a release made by `fly releases
+// rollback`, or despite breaking the deploy policy.
+type annotatedReleaseMetadata struct {
+	*fly.ReleaseMetadata
+	Rollback       *deploycontext.Rollback       `json:"rollback,omitempty"`
+	PolicyOverride *deploycontext.PolicyOverride `json:"policy_override,omitempty"`
+}
+
 func (md *machineDeployment) updateReleaseInBackend(ctx context.Context, status string, metadata *fly.ReleaseMetadata) error {
 	ctx, span := tracing.GetTracer().Start(ctx, "update_release_in_backend", trace.WithAttributes(
 		attribute.String("release_id", md.releaseId),
@@ -721,7 +738,14 @@ func (md *machineDeployment) updateReleaseInBackend(ctx context.Context, status
 	))
 	defer span.End()
 
-	_, err := md.uiexClient.UpdateRelease(ctx, md.releaseId, status, metadata)
+	var releaseMetadata any = metadata
+	rollback, _ := ctx.Value(deploycontext.RollbackKey).(*deploycontext.Rollback)
+	override, _ := ctx.Value(deploycontext.PolicyOverrideKey).(*deploycontext.PolicyOverride)
+	if (rollback != nil || override != nil) && metadata != nil {
+		releaseMetadata = annotatedReleaseMetadata{ReleaseMetadata: metadata, Rollback: rollback, PolicyOverride: override}
+	}
+
+	_, err := md.uiexClient.UpdateRelease(ctx, md.releaseId, status, releaseMetadata)
 
 	if err != nil {
 		tracing.RecordError(span, err, "failed to update machine release")
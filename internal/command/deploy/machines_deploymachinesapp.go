@@ -80,6 +80,11 @@ func (md *machineDeployment) DeployMachinesApp(ctx context.Context) error {
 		}
 	}
 
+	md.startedAt = time.Now()
+	if !md.restartOnly {
+		notifyDeploy(ctx, md.appConfig, md.deployEvent(ctx, "started", nil))
+	}
+
 	var err error
 	if md.restartOnly {
 		err = md.restartMachinesApp(ctx)
@@ -126,6 +131,14 @@ func (md *machineDeployment) DeployMachinesApp(ctx context.Context) error {
 		}
 	}
 
+	if !md.restartOnly {
+		event := md.deployEvent(onInterruptContext, "succeeded", err)
+		if err != nil {
+			event = md.deployEvent(onInterruptContext, "failed", err)
+		}
+		notifyDeploy(onInterruptContext, md.appConfig, event)
+	}
+
 	// no need to run dns checks if the deployment failed
 	if !md.skipDNSChecks && err == nil {
 		if err := md.checkDNS(ctx); err != nil {
@@ -827,6 +840,7 @@ func (md *machineDeployment) updateUsingImmediateStrategy(parentCtx context.Cont
 			if err := md.updateMachine(eCtx, e, sl.Line(i)); err != nil {
 				tracing.RecordError(span, err, "failed to update machine")
 				statusFailure(err)
+				md.machineFailed(e.leasableMachine.Machine().ID, err)
 
 				return err
 			}
@@ -1004,12 +1018,14 @@ func (md *machineDeployment) updateEntriesGroup(parentCtx context.Context, group
 			if err := md.updateMachine(ctx, e, sl.Line(startIdx+idx)); err != nil {
 				statusFailure(err)
 				tracing.RecordError(span, err, "failed to update machine")
+				md.machineFailed(e.leasableMachine.Machine().ID, err)
 
 				return err
 			}
 			if err := md.waitForMachine(ctx, e, sl.Line(startIdx+idx)); err != nil {
 				tracing.RecordError(span, err, "failed to wait for machine")
 				statusFailure(err)
+				md.machineFailed(e.leasableMachine.Machine().ID, err)
 
 				return err
 			}
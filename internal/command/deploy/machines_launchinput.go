@@ -287,6 +287,12 @@ func (md *machineDeployment) setMachineReleaseData(mConfig *fly.MachineConfig) {
 	if md.builderID != "" {
 		mConfig.Metadata["fly_builder_id"] = md.builderID
 	}
+
+	// Machines carry the deploy lock as it was when they were listed, which
+	// mustn't undo the refreshes of its expiry since
+	if _, ok := mConfig.Metadata[deployLockKey]; ok && md.deployLock != nil {
+		mConfig.Metadata[deployLockKey] = md.deployLock.value()
+	}
 }
 
 // Skip launching currently-stopped or suspended machines if:
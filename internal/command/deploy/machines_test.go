@@ -2,6 +2,7 @@ package deploy
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -10,8 +11,10 @@ import (
 	"github.com/superfly/fly-go/flaps"
 	"github.com/superfly/flyctl/internal/appconfig"
 	"github.com/superfly/flyctl/internal/buildinfo"
+	"github.com/superfly/flyctl/internal/command/deploycontext"
 	"github.com/superfly/flyctl/internal/machine"
 	"github.com/superfly/flyctl/internal/mock"
+	"github.com/superfly/flyctl/internal/uiex"
 	"github.com/superfly/flyctl/iostreams"
 )
 
@@ -535,3 +538,47 @@ func Test_resolveUpdatedMachineConfig_restartOnlyProcessGroup(t *testing.T) {
 		MinSecretsVersion: nil,
 	}, got)
 }
+
+func Test_updateReleaseInBackend_Rollback(t *testing.T) {
+	var sent any
+	md, err := stabMachineDeployment(&appconfig.Config{AppName: "my-cool-app"})
+	require.NoError(t, err)
+	md.releaseId = "release-id"
+	md.uiexClient = &mock.UiexClient{
+		UpdateReleaseFunc: func(ctx context.Context, releaseID, status string, metadata any) (*uiex.Release, error) {
+			sent = metadata
+
+			return &uiex.Release{}, nil
+		},
+	}
+
+	metadata := &fly.ReleaseMetadata{PostDeploymentInfo: fly.PostDeploymentInfo{FlyctlVersion: "1.2.3"}}
+	ctx := context.WithValue(context.Background(), deploycontext.RollbackKey, &deploycontext.Rollback{
+		FromVersion: 7, ToVersion: 5, Reason: "bad deploy",
+	})
+	require.NoError(t, md.updateReleaseInBackend(ctx, "complete", metadata))
+
+	data, err := json.Marshal(sent)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"post_deployment_info": {"flyctl_version": "1.2.3", "error": ""},
+		"rollback": {"from_version": 7, "to_version": 5, "reason": "bad deploy"}
+	}`, string(data))
+
+	// Overrides of the deploy policy are recorded too
+	ctx = context.WithValue(context.Background(), deploycontext.PolicyOverrideKey, &deploycontext.PolicyOverride{
+		Reason: "hotfix", Violations: []string{"friday-freeze: No deploys on Friday evenings"},
+	})
+	require.NoError(t, md.updateReleaseInBackend(ctx, "complete", metadata))
+
+	data, err = json.Marshal(sent)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"post_deployment_info": {"flyctl_version": "1.2.3", "error": ""},
+		"policy_override": {"reason": "hotfix", "violations": ["friday-freeze: No deploys on Friday evenings"]}
+	}`, string(data))
+
+	// Other deployments send the metadata as is
+	require.NoError(t, md.updateReleaseInBackend(context.Background(), "complete", metadata))
+	assert.Equal(t, metadata, sent)
+}
@@ -151,8 +151,19 @@ func deployFromManifest(ctx context.Context, manifest *DeployManifest) error {
 		return err
 	}
 
+	ctx, unlock, err := lockDeployFromFlags(ctx, manifest.AppName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	ctx = appconfig.WithConfig(ctx, manifest.Config)
 
+	ctx, err = checkDeployPolicy(ctx, manifest)
+	if err != nil {
+		return err
+	}
+
 	args := argsFromManifest(manifest, app)
 
 	md, err := NewMachineDeployment(ctx, args)
@@ -0,0 +1,270 @@
+package deploy
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sourcegraph/conc/pool"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/command/deploycontext"
+	"github.com/superfly/flyctl/internal/env"
+	"github.com/superfly/flyctl/terminal"
+)
+
+// deployEvent is posted to the notification webhooks of an app when a
+// deploy starts, succeeds or fails.
+type deployEvent struct {
+	Event          string    `json:"event"`
+	App            string    `json:"app"`
+	ReleaseID      string    `json:"release_id,omitempty"`
+	ReleaseVersion int       `json:"release_version,omitempty"`
+	Image          string    `json:"image"`
+	Strategy       string    `json:"strategy"`
+	GitCommit      string    `json:"git_commit,omitempty"`
+	Time           time.Time `json:"time"`
+	// Duration is how long the deploy took, in seconds, once it's over.
+	Duration       float64  `json:"duration,omitempty"`
+	Error          string   `json:"error,omitempty"`
+	FailedMachines []string `json:"failed_machines,omitempty"`
+	// PolicyOverride is set when the deploy breaks the deploy policy.
+	PolicyOverride *deploycontext.PolicyOverride `json:"policy_override,omitempty"`
+}
+
+const webhookTimeout = 10 * time.Second
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// notifyDeploy posts event to the [deploy.notifications] webhooks that want
+// it. Notifications never fail a deploy, undelivered ones are warned about.
+func notifyDeploy(ctx context.Context, appConfig *appconfig.Config, event *deployEvent) {
+	if appConfig.Deploy == nil || appConfig.Deploy.Notifications == nil {
+		return
+	}
+
+	p := pool.New()
+	for _, webhook := range appConfig.Deploy.Notifications.Webhooks {
+		if len(webhook.Events) > 0 && !slices.Contains(webhook.Events, event.Event) {
+			continue
+		}
+
+		p.Go(func() {
+			if err := postWebhook(ctx, webhook, event); err != nil {
+				terminal.Warnf("failed to send deploy notification: %v\n", err)
+			}
+		})
+	}
+	p.Wait()
+}
+
+func postWebhook(ctx context.Context, webhook appconfig.DeployWebhook, event *deployEvent) error {
+	url := os.ExpandEnv(webhook.URL)
+	if url == "" {
+		return fmt.Errorf("webhook URL %q is empty once expanded", webhook.URL)
+	}
+
+	body, err := json.Marshal(webhookPayload(webhook.Format, event))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := webhookClient.Do(req)
+	if err != nil {
+		// The URL could hold a secret, so it's left out of errors
+		return fmt.Errorf("%s webhook request failed", cmp.Or(webhook.Format, "json"))
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("%s webhook returned status %d", cmp.Or(webhook.Format, "json"), res.StatusCode)
+	}
+
+	return nil
+}
+
+// webhookPayload returns the body to post event to a webhook with the given
+// format.
+func webhookPayload(format string, event *deployEvent) any {
+	switch format {
+	case "slack":
+		return map[string]any{
+			"text": event.summary(),
+			"attachments": []map[string]any{{
+				"color":  event.color(),
+				"fields": slackFields(event),
+			}},
+		}
+	case "discord":
+		return map[string]any{
+			"embeds": []map[string]any{{
+				"title":     event.summary(),
+				"color":     event.discordColor(),
+				"fields":    discordFields(event),
+				"timestamp": event.Time.Format(time.RFC3339),
+			}},
+		}
+	default:
+		return event
+	}
+}
+
+func (e *deployEvent) summary() string {
+	release := e.App
+	if e.ReleaseVersion > 0 {
+		release = fmt.Sprintf("%s v%d", e.App, e.ReleaseVersion)
+	}
+
+	switch e.Event {
+	case "started":
+		return fmt.Sprintf("Deploy of %s started", release)
+	case "succeeded":
+		return fmt.Sprintf("Deploy of %s succeeded in %s", release, e.duration())
+	default:
+		return fmt.Sprintf("Deploy of %s failed after %s", release, e.duration())
+	}
+}
+
+func (e *deployEvent) duration() time.Duration {
+	return time.Duration(e.Duration * float64(time.Second)).Round(time.Second)
+}
+
+func (e *deployEvent) color() string {
+	switch e.Event {
+	case "succeeded":
+		return "good"
+	case "failed":
+		return "danger"
+	default:
+		return "#7c3aed"
+	}
+}
+
+func (e *deployEvent) discordColor() int {
+	switch e.Event {
+	case "succeeded":
+		return 0x2eb67d
+	case "failed":
+		return 0xe01e5a
+	default:
+		return 0x7c3aed
+	}
+}
+
+// eventFields returns the details of e shown in chat messages.
+func eventFields(e *deployEvent) [][2]string {
+	fields := [][2]string{
+		{"Image", e.Image},
+		{"Strategy", e.Strategy},
+	}
+	if e.GitCommit != "" {
+		fields = append(fields, [2]string{"Commit", e.GitCommit})
+	}
+	if e.PolicyOverride != nil {
+		fields = append(fields, [2]string{"Policy override", e.PolicyOverride.Reason})
+	}
+	if len(e.FailedMachines) > 0 {
+		fields = append(fields, [2]string{"Failed machines", strings.Join(e.FailedMachines, ", ")})
+	}
+	if e.Error != "" {
+		fields = append(fields, [2]string{"Error", e.Error})
+	}
+
+	return fields
+}
+
+func slackFields(e *deployEvent) []map[string]any {
+	var fields []map[string]any
+	for _, f := range eventFields(e) {
+		fields = append(fields, map[string]any{"title": f[0], "value": f[1], "short": f[0] != "Error"})
+	}
+
+	return fields
+}
+
+func discordFields(e *deployEvent) []map[string]any {
+	var fields []map[string]any
+	for _, f := range eventFields(e) {
+		fields = append(fields, map[string]any{"name": f[0], "value": f[1], "inline": f[0] != "Error"})
+	}
+
+	return fields
+}
+
+// deployEvent returns the event of md's deploy, which is over if event
+// isn't "started".
+func (md *machineDeployment) deployEvent(ctx context.Context, event string, err error) *deployEvent {
+	e := &deployEvent{
+		Event:          event,
+		App:            md.app.Name,
+		ReleaseID:      md.releaseId,
+		ReleaseVersion: md.releaseVersion,
+		Image:          md.img,
+		Strategy:       md.strategy,
+		GitCommit:      gitCommit(ctx, md.appConfig),
+		Time:           time.Now().UTC(),
+	}
+	e.PolicyOverride, _ = ctx.Value(deploycontext.PolicyOverrideKey).(*deploycontext.PolicyOverride)
+	if event == "started" {
+		return e
+	}
+
+	e.Duration = time.Since(md.startedAt).Seconds()
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	md.failedMachinesMu.Lock()
+	e.FailedMachines = slices.Sorted(slices.Values(md.failedMachines))
+	md.failedMachinesMu.Unlock()
+
+	return e
+}
+
+// machineFailed records that the machine with the given ID failed to be
+// deployed, unless the deploy was interrupted.
+func (md *machineDeployment) machineFailed(id string, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	md.failedMachinesMu.Lock()
+	defer md.failedMachinesMu.Unlock()
+	md.failedMachines = append(md.failedMachines, id)
+}
+
+// gitCommit returns the commit being deployed, from CI or the git
+// repository of fly.toml, or "" if it isn't known.
+func gitCommit(ctx context.Context, appConfig *appconfig.Config) string {
+	if sha := env.GitCommitSHA(); sha != "" {
+		return sha
+	}
+	if appConfig.ConfigFilePath() == "" {
+		return ""
+	}
+
+	out, err := exec.CommandContext(ctx, "git", "-C", filepath.Dir(appConfig.ConfigFilePath()), "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(out))
+}
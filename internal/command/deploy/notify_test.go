@@ -0,0 +1,132 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/fly-go/flaps"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/command/deploycontext"
+)
+
+// webhookListener is a local HTTP listener that records the payloads posted
+// to it by path.
+type webhookListener struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	payloads map[string][]map[string]any
+}
+
+func newWebhookListener(t *testing.T) *webhookListener {
+	l := &webhookListener{payloads: map[string][]map[string]any{}}
+	l.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var payload map[string]any
+		require.NoError(t, json.Unmarshal(body, &payload))
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.payloads[r.URL.Path] = append(l.payloads[r.URL.Path], payload)
+
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(l.Close)
+
+	return l
+}
+
+func (l *webhookListener) received(path string) []map[string]any {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.payloads[path]
+}
+
+func TestNotifyDeploy(t *testing.T) {
+	t.Setenv("GITHUB_SHA", "abc123")
+	l := newWebhookListener(t)
+	t.Setenv("TEST_SLACK_WEBHOOK", l.URL+"/slack")
+
+	md := &machineDeployment{
+		app: &flaps.App{Name: "my-app"},
+		appConfig: &appconfig.Config{Deploy: &appconfig.Deploy{Notifications: &appconfig.DeployNotifications{
+			Webhooks: []appconfig.DeployWebhook{
+				{URL: l.URL + "/json"},
+				{URL: "${TEST_SLACK_WEBHOOK}", Format: "slack", Events: []string{"failed"}},
+				{URL: l.URL + "/discord", Format: "discord"},
+				{URL: l.URL + "/broken"},
+			},
+		}}},
+		img:            "registry.fly.io/my-app:deployment-1",
+		strategy:       "rolling",
+		releaseId:      "release-1",
+		releaseVersion: 7,
+		startedAt:      time.Now().Add(-90 * time.Second),
+	}
+	ctx := context.WithValue(context.Background(), deploycontext.PolicyOverrideKey, &deploycontext.PolicyOverride{
+		Reason: "hotfix", Violations: []string{"friday-freeze: No deploys on Friday evenings"},
+	})
+
+	notifyDeploy(ctx, md.appConfig, md.deployEvent(ctx, "started", nil))
+	md.machineFailed("m2", errors.New("health checks failed"))
+	md.machineFailed("m1", errors.New("health checks failed"))
+	md.machineFailed("m3", context.Canceled)
+	notifyDeploy(ctx, md.appConfig, md.deployEvent(ctx, "failed", errors.New("2 machines failed")))
+
+	events := l.received("/json")
+	require.Len(t, events, 2)
+	assert.Equal(t, "started", events[0]["event"])
+	assert.Equal(t, "my-app", events[0]["app"])
+	assert.Equal(t, float64(7), events[0]["release_version"])
+	assert.Equal(t, "registry.fly.io/my-app:deployment-1", events[0]["image"])
+	assert.Equal(t, "rolling", events[0]["strategy"])
+	assert.Equal(t, "abc123", events[0]["git_commit"])
+	assert.NotContains(t, events[0], "duration")
+	assert.Equal(t, map[string]any{"reason": "hotfix", "violations": []any{"friday-freeze: No deploys on Friday evenings"}}, events[0]["policy_override"])
+
+	assert.Equal(t, "failed", events[1]["event"])
+	assert.Equal(t, "2 machines failed", events[1]["error"])
+	assert.Equal(t, []any{"m1", "m2"}, events[1]["failed_machines"])
+	assert.InDelta(t, 90, events[1]["duration"], 5)
+
+	// Slack only wants failures
+	slack := l.received("/slack")
+	require.Len(t, slack, 1)
+	assert.Equal(t, "Deploy of my-app v7 failed after 1m30s", slack[0]["text"])
+	attachment := slack[0]["attachments"].([]any)[0].(map[string]any)
+	assert.Equal(t, "danger", attachment["color"])
+	assert.Contains(t, attachment["fields"], map[string]any{"title": "Failed machines", "value": "m1, m2", "short": true})
+
+	discord := l.received("/discord")
+	require.Len(t, discord, 2)
+	embed := discord[0]["embeds"].([]any)[0].(map[string]any)
+	assert.Equal(t, "Deploy of my-app v7 started", embed["title"])
+	assert.Contains(t, embed["fields"], map[string]any{"name": "Commit", "value": "abc123", "inline": true})
+	assert.Contains(t, embed["fields"], map[string]any{"name": "Policy override", "value": "hotfix", "inline": true})
+
+	// A broken webhook doesn't stop the others
+	assert.Len(t, l.received("/broken"), 2)
+}
+
+func TestNotifyDeployWithoutNotifications(t *testing.T) {
+	md := &machineDeployment{app: &flaps.App{Name: "my-app"}, appConfig: &appconfig.Config{}}
+	ctx := context.Background()
+
+	// Nothing to post to, and nothing to fail
+	notifyDeploy(ctx, md.appConfig, md.deployEvent(ctx, "started", nil))
+}
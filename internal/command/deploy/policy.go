@@ -0,0 +1,259 @@
+package deploy
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/command/deploycontext"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/internal/flyerr"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+// deployPolicy is a policy a deploy must pass before it starts. It's read
+// from a TOML file of rules, each a CEL expression that must be true for the
+// deploy to go ahead:
+//
+//	[[rules]]
+//	name = "friday-freeze"
+//	expr = 'now.getDayOfWeek("Europe/Paris") != 5 || now.getHours("Europe/Paris") < 16'
+//	message = "No deploys on Friday evenings"
+//
+//	[[rules]]
+//	name = "production-max-unavailable"
+//	expr = 'app != "my-app-production" || manifest.max_unavailable <= 0.25'
+//
+// Expressions see the deploy manifest as manifest, the app name as app and
+// the current time as now. The strategy and max_unavailable of manifest are
+// the ones the deploy uses, even when they aren't set.
+type deployPolicy struct {
+	Rules []*deployPolicyRule `toml:"rules"`
+}
+
+type deployPolicyRule struct {
+	Name    string `toml:"name"`
+	Expr    string `toml:"expr"`
+	Message string `toml:"message"`
+
+	program cel.Program
+}
+
+// deployPolicyPaths returns the paths of the deploy policies to check the
+// deploy against: [deploy] policy, which is relative to fly.toml, and
+// --policy. --policy adds to [deploy] policy rather than replacing it, so
+// that getting around the policy of an app takes --override-policy.
+func deployPolicyPaths(ctx context.Context, appConfig *appconfig.Config) []string {
+	var paths []string
+	if appConfig.Deploy != nil && appConfig.Deploy.Policy != "" {
+		path := appConfig.Deploy.Policy
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(appConfig.ConfigFilePath()), path)
+		}
+		paths = append(paths, path)
+	}
+	if path := flag.GetString(ctx, "policy"); path != "" && !slices.ContainsFunc(paths, func(p string) bool { return sameFile(p, path) }) {
+		paths = append(paths, path)
+	}
+
+	return paths
+}
+
+// sameFile reports whether paths a and b name the same file.
+func sameFile(a, b string) bool {
+	aInfo, aErr := os.Stat(a)
+	bInfo, bErr := os.Stat(b)
+	if aErr != nil || bErr != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+
+	return os.SameFile(aInfo, bInfo)
+}
+
+// loadDeployPolicy reads and compiles the deploy policy at policyPath.
+func loadDeployPolicy(policyPath string) (*deployPolicy, error) {
+	data, err := os.ReadFile(policyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deploy policy: %w", err)
+	}
+
+	var policy deployPolicy
+	decoder := toml.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("failed to parse deploy policy %s: %w", policyPath, err)
+	}
+
+	env, err := cel.NewEnv(
+		cel.Variable("manifest", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("app", cel.StringType),
+		cel.Variable("now", cel.TimestampType),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, rule := range policy.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if rule.Expr == "" {
+			return nil, fmt.Errorf("%s of deploy policy %s has no expr", rule.Name, policyPath)
+		}
+
+		ast, issues := env.Compile(rule.Expr)
+		if issues.Err() != nil {
+			return nil, fmt.Errorf("invalid expr of %s in deploy policy %s: %w", rule.Name, policyPath, issues.Err())
+		}
+		if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+			return nil, fmt.Errorf("expr of %s in deploy policy %s must be a bool, not %s", rule.Name, policyPath, ast.OutputType())
+		}
+
+		rule.program, err = env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expr of %s in deploy policy %s: %w", rule.Name, policyPath, err)
+		}
+	}
+
+	return &policy, nil
+}
+
+// check returns how the deploy of manifest at now breaks the policy. Rules
+// that can't be evaluated, like when they refer to a field manifest doesn't
+// have, are broken.
+func (p *deployPolicy) check(manifest *DeployManifest, now time.Time) ([]string, error) {
+	input, err := policyManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+	vars := map[string]any{
+		"manifest": input,
+		"app":      manifest.AppName,
+		"now":      now,
+	}
+
+	var violations []string
+	for _, rule := range p.Rules {
+		out, _, err := rule.program.Eval(vars)
+		if err != nil {
+			violations = append(violations, fmt.Sprintf("%s: can't be evaluated: %v", rule.Name, err))
+
+			continue
+		}
+
+		switch passed, ok := out.Value().(bool); {
+		case !ok:
+			violations = append(violations, fmt.Sprintf("%s: evaluates to %v rather than a bool", rule.Name, out.Value()))
+		case !passed:
+			violations = append(violations, fmt.Sprintf("%s: %s", rule.Name, cmp.Or(rule.Message, rule.Expr)))
+		}
+	}
+
+	return violations, nil
+}
+
+// policyManifest returns manifest as deploy policies see it, with the
+// defaults of the deploy filled in.
+func policyManifest(manifest *DeployManifest) (map[string]any, error) {
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	var input map[string]any
+	if err := json.Unmarshal(data, &input); err != nil {
+		return nil, err
+	}
+
+	maxUnavailable := DefaultMaxUnavailable
+	switch {
+	case manifest.MaxUnavailable != nil:
+		maxUnavailable = *manifest.MaxUnavailable
+	case manifest.Config != nil && manifest.Config.Deploy != nil && manifest.Config.Deploy.MaxUnavailable != nil:
+		maxUnavailable = *manifest.Config.Deploy.MaxUnavailable
+	}
+	input["max_unavailable"] = maxUnavailable
+
+	strategy := manifest.Strategy
+	if strategy == "" && manifest.Config != nil {
+		strategy = manifest.Config.DeployStrategy()
+	}
+	input["strategy"] = strategy
+
+	return input, nil
+}
+
+// checkDeployPolicy fails if the deploy of manifest breaks its deploy
+// policies, unless they're overridden with --override-policy. The returned
+// context records the override, so that it's logged with the release.
+func checkDeployPolicy(ctx context.Context, manifest *DeployManifest) (context.Context, error) {
+	reason := strings.TrimSpace(flag.GetString(ctx, "override-policy"))
+	if flag.IsSpecified(ctx, "override-policy") && reason == "" {
+		return nil, errors.New("--override-policy needs a reason, like --override-policy \"hotfix for incident 42\"")
+	}
+
+	paths := deployPolicyPaths(ctx, manifest.Config)
+	if len(paths) == 0 {
+		return ctx, nil
+	}
+
+	var (
+		violations []string
+		now        = time.Now()
+	)
+	for _, path := range paths {
+		policy, err := loadDeployPolicy(path)
+		if err != nil {
+			return nil, err
+		}
+
+		broken, err := policy.check(manifest, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check deploy policy: %w", err)
+		}
+		if len(paths) > 1 {
+			for i := range broken {
+				broken[i] = path + ": " + broken[i]
+			}
+		}
+		violations = append(violations, broken...)
+	}
+
+	policies := "the deploy policy " + paths[0]
+	if len(paths) > 1 {
+		policies = "the deploy policies " + strings.Join(paths, " and ")
+	}
+
+	io := iostreams.FromContext(ctx)
+	switch {
+	case len(violations) == 0:
+		fmt.Fprintf(io.ErrOut, "Deploy passed %s\n", policies)
+
+		return ctx, nil
+	case reason == "":
+		return nil, flyerr.GenericErr{
+			Err:     fmt.Sprintf("deploy of %s breaks %s:\n  %s", manifest.AppName, policies, strings.Join(violations, "\n  ")),
+			Suggest: "If this deploy must go ahead anyway, run it again with --override-policy \"<reason>\". The override and its reason are recorded with the release.",
+		}
+	}
+
+	colorize := io.ColorScheme()
+	fmt.Fprintf(io.ErrOut, "%s Overriding %s (%s):\n  %s\n",
+		colorize.Yellow("WARN"), policies, reason, strings.Join(violations, "\n  "))
+
+	return context.WithValue(ctx, deploycontext.PolicyOverrideKey, &deploycontext.PolicyOverride{
+		Reason:     reason,
+		Violations: violations,
+	}), nil
+}
@@ -0,0 +1,155 @@
+package deploy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/command/deploycontext"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/internal/flyerr"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+const testDeployPolicy = `
+[[rules]]
+name = "friday-freeze"
+expr = 'now.getDayOfWeek("Europe/Paris") != 5 || now.getHours("Europe/Paris") < 16'
+message = "No deploys on Friday evenings"
+
+[[rules]]
+name = "production-max-unavailable"
+expr = 'app != "my-app-production" || manifest.max_unavailable <= 0.25'
+
+[[rules]]
+expr = 'manifest.strategy != "immediate"'
+`
+
+func writeDeployPolicy(t *testing.T, policy string) string {
+	path := filepath.Join(t.TempDir(), "deploy-policy.toml")
+	require.NoError(t, os.WriteFile(path, []byte(policy), 0o600))
+
+	return path
+}
+
+func TestDeployPolicy(t *testing.T) {
+	policy, err := loadDeployPolicy(writeDeployPolicy(t, testDeployPolicy))
+	require.NoError(t, err)
+
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+	fridayEvening := time.Date(2026, 10, 16, 17, 30, 0, 0, paris)
+	mondayMorning := time.Date(2026, 10, 19, 9, 0, 0, 0, paris)
+
+	manifest := &DeployManifest{AppName: "my-app", Config: &appconfig.Config{}}
+	violations, err := policy.check(manifest, mondayMorning)
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+
+	violations, err = policy.check(manifest, fridayEvening.UTC())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"friday-freeze: No deploys on Friday evenings"}, violations)
+
+	// max_unavailable defaults to the one of the deploy
+	manifest.AppName = "my-app-production"
+	violations, err = policy.check(manifest, mondayMorning)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"production-max-unavailable: app != \"my-app-production\" || manifest.max_unavailable <= 0.25"}, violations)
+
+	manifest.Config.Deploy = &appconfig.Deploy{MaxUnavailable: new(0.25), Strategy: "immediate"}
+	violations, err = policy.check(manifest, mondayMorning)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"rule 3: manifest.strategy != \"immediate\""}, violations)
+
+	// The flags of the deploy win over fly.toml
+	manifest.MaxUnavailable = new(0.5)
+	manifest.Strategy = "rolling"
+	violations, err = policy.check(manifest, mondayMorning)
+	require.NoError(t, err)
+	assert.Len(t, violations, 1)
+	assert.Contains(t, violations[0], "production-max-unavailable")
+}
+
+func TestDeployPolicyBrokenRules(t *testing.T) {
+	_, err := loadDeployPolicy(writeDeployPolicy(t, "[[rules]]\nname = \"empty\"\n"))
+	assert.ErrorContains(t, err, "empty of deploy policy")
+
+	_, err = loadDeployPolicy(writeDeployPolicy(t, "[[rules]]\nexpr = 'now >'\n"))
+	assert.ErrorContains(t, err, "invalid expr of rule 1")
+
+	_, err = loadDeployPolicy(writeDeployPolicy(t, "[[rules]]\nexpr = 'app'\n"))
+	assert.ErrorContains(t, err, "must be a bool")
+
+	_, err = loadDeployPolicy(writeDeployPolicy(t, "[[rule]]\nexpr = 'true'\n"))
+	assert.ErrorContains(t, err, "failed to parse deploy policy")
+
+	// Rules that can't be evaluated are broken
+	policy, err := loadDeployPolicy(writeDeployPolicy(t, "[[rules]]\nname = \"typo\"\nexpr = 'manifest.max_unavailabel < 1.0'\n"))
+	require.NoError(t, err)
+	violations, err := policy.check(&DeployManifest{AppName: "my-app"}, time.Now())
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	assert.Contains(t, violations[0], "typo: can't be evaluated")
+}
+
+func policyTestContext(t *testing.T, args ...string) context.Context {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.String("policy", "", "")
+	flags.String("override-policy", "", "")
+	require.NoError(t, flags.Parse(args))
+
+	ios, _, _, _ := iostreams.Test()
+
+	return iostreams.NewContext(flag.NewContext(context.Background(), flags), ios)
+}
+
+func TestCheckDeployPolicy(t *testing.T) {
+	path := writeDeployPolicy(t, "[[rules]]\nname = \"frozen\"\nexpr = 'false'\nmessage = \"Deploys are frozen\"\n")
+	manifest := &DeployManifest{AppName: "my-app", Config: &appconfig.Config{Deploy: &appconfig.Deploy{Policy: path}}}
+
+	_, err := checkDeployPolicy(policyTestContext(t), manifest)
+	var genericErr flyerr.GenericErr
+	require.ErrorAs(t, err, &genericErr)
+	assert.Contains(t, err.Error(), "deploy of my-app breaks the deploy policy")
+	assert.Contains(t, err.Error(), "frozen: Deploys are frozen")
+	assert.Contains(t, genericErr.Suggestion(), "--override-policy")
+
+	ctx, err := checkDeployPolicy(policyTestContext(t, "--override-policy", "hotfix for incident 42"), manifest)
+	require.NoError(t, err)
+	assert.Equal(t, &deploycontext.PolicyOverride{
+		Reason:     "hotfix for incident 42",
+		Violations: []string{"frozen: Deploys are frozen"},
+	}, ctx.Value(deploycontext.PolicyOverrideKey))
+
+	_, err = checkDeployPolicy(policyTestContext(t, "--override-policy", " "), manifest)
+	assert.ErrorContains(t, err, "needs a reason")
+
+	// --policy doesn't get around the policy in fly.toml
+	passing := writeDeployPolicy(t, "[[rules]]\nexpr = 'true'\n")
+	_, err = checkDeployPolicy(policyTestContext(t, "--policy", passing), manifest)
+	assert.ErrorContains(t, err, "breaks the deploy policies "+path+" and "+passing)
+	assert.ErrorContains(t, err, path+": frozen: Deploys are frozen")
+
+	// but adds to it
+	_, err = checkDeployPolicy(policyTestContext(t, "--policy", path), &DeployManifest{AppName: "my-app", Config: &appconfig.Config{}})
+	assert.ErrorContains(t, err, "breaks the deploy policy "+path)
+
+	ctx, err = checkDeployPolicy(policyTestContext(t, "--policy", passing), &DeployManifest{AppName: "my-app", Config: &appconfig.Config{}})
+	require.NoError(t, err)
+	assert.Nil(t, ctx.Value(deploycontext.PolicyOverrideKey))
+
+	// The policy in fly.toml is checked once, even when --policy names it too
+	ctx, err = checkDeployPolicy(policyTestContext(t, "--policy", path, "--override-policy", "hotfix"), manifest)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"frozen: Deploys are frozen"}, ctx.Value(deploycontext.PolicyOverrideKey).(*deploycontext.PolicyOverride).Violations)
+
+	// Apps without a policy deploy as usual
+	_, err = checkDeployPolicy(policyTestContext(t), &DeployManifest{AppName: "my-app", Config: &appconfig.Config{}})
+	require.NoError(t, err)
+}
@@ -5,3 +5,23 @@ type ContextKey string
 
 // IsFirstLaunchKey is the context key for marking a deployment as the first launch
 const IsFirstLaunchKey ContextKey = "isFirstLaunch"
+
+// RollbackKey is the context key for the *Rollback a deployment is made for
+const RollbackKey ContextKey = "rollback"
+
+// Rollback describes a deployment that restores an earlier release
+type Rollback struct {
+	FromVersion int    `json:"from_version"`
+	ToVersion   int    `json:"to_version"`
+	Reason      string `json:"reason"`
+}
+
+// PolicyOverrideKey is the context key for the *PolicyOverride of a deployment
+// that breaks the deploy policy
+const PolicyOverrideKey ContextKey = "policyOverride"
+
+// PolicyOverride describes a deployment made despite breaking the deploy policy
+type PolicyOverride struct {
+	Reason     string   `json:"reason"`
+	Violations []string `json:"violations"`
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/superfly/flyctl/gql"
 	"github.com/superfly/flyctl/internal/appconfig"
 	"github.com/superfly/flyctl/internal/appsecrets"
+	"github.com/superfly/flyctl/internal/cmdutil"
 	"github.com/superfly/flyctl/internal/command"
 	"github.com/superfly/flyctl/internal/command/deploy"
 	"github.com/superfly/flyctl/internal/command/launch/plan"
@@ -32,6 +33,7 @@ import (
 	"github.com/superfly/flyctl/internal/state"
 	"github.com/superfly/flyctl/internal/tracing"
 	"github.com/superfly/flyctl/iostreams"
+	"github.com/superfly/flyctl/scanner"
 	"go.opentelemetry.io/otel/attribute"
 )
 
@@ -97,14 +99,13 @@ func New() (cmd *cobra.Command) {
 		},
 		flag.Bool{
 			Name:        "manifest",
-			Description: "Output the generated manifest to stdout",
-			Hidden:      true,
+			Description: "Output the generated manifest to stdout. See 'fly launch schema' for its format",
 		},
 		flag.String{
 			Name:        "from-manifest",
 			Description: "Path to a manifest file for Launch ('-' reads from stdin)",
-			Hidden:      true,
 		},
+		manifestVarFlag(),
 		// legacy launch flags (deprecated)
 		flag.Bool{
 			Name:        "legacy",
@@ -145,6 +146,18 @@ func New() (cmd *cobra.Command) {
 			Name:        "yaml",
 			Description: "Generate configuration in YAML format",
 		},
+		flag.Bool{
+			Name:        "explain-scan",
+			Description: "Show which source scanners were tried, which one matched and why",
+		},
+		flag.Bool{
+			Name:        "run-scanner-plugins",
+			Description: "Run executable scanners found in " + scanner.PluginDir + " of the source directory",
+		},
+		flag.Bool{
+			Name:        "monorepo",
+			Description: "Launch each service of a pnpm, npm, Go, Cargo or Nx workspace as its own app",
+		},
 		// don't try to generate a name
 		flag.Bool{
 			Name:        "force-name",
@@ -188,6 +201,7 @@ func New() (cmd *cobra.Command) {
 	flag.Add(cmd, flags...)
 
 	cmd.AddCommand(NewPlan())
+	cmd.AddCommand(newSchema())
 
 	return
 }
@@ -198,27 +212,21 @@ func getManifestArgument(ctx context.Context) (*LaunchManifest, error) {
 		return nil, nil
 	}
 
-	var jsonDecoder *json.Decoder
-	if path != "-" {
-		manifestJson, err := os.ReadFile(path)
-		if err != nil {
-			return nil, err
-		}
-		reader := bytes.NewReader(manifestJson)
-		jsonDecoder = json.NewDecoder(reader)
-	} else {
-		// Read from stdin
-		stdin := iostreams.FromContext(ctx).In
-		jsonDecoder = json.NewDecoder(stdin)
+	vars, err := cmdutil.ParseKVStringsToMap(flag.GetStringArray(ctx, "var"))
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing --var flags: %w", err)
 	}
 
-	var manifest LaunchManifest
-	err := jsonDecoder.Decode(&manifest)
+	if path == "-" {
+		return loadManifest(iostreams.FromContext(ctx).In, vars)
+	}
+
+	manifestJson, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	return &manifest, nil
+	return loadManifest(bytes.NewReader(manifestJson), vars)
 }
 
 func setupFromTemplate(ctx context.Context) (context.Context, *appconfig.Config, error) {
@@ -343,6 +351,10 @@ func run(ctx context.Context) (err error) {
 		return err
 	}
 
+	if flag.GetBool(ctx, "monorepo") {
+		return runMonorepo(ctx)
+	}
+
 	var (
 		launchManifest *LaunchManifest
 		cache          *planBuildCache
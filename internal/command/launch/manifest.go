@@ -0,0 +1,388 @@
+package launch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	validator "github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/spf13/cobra"
+	"github.com/superfly/flyctl/internal/command"
+	"github.com/superfly/flyctl/internal/command/launch/plan"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/iostreams"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// manifestSchemaURL names the manifest schema while validating; it is never
+// fetched.
+const manifestSchemaURL = "urn:flyctl:launch-manifest"
+
+// manifestParamName is what the names of manifest parameters look like.
+const manifestParamName = `[A-Za-z_][A-Za-z0-9_]*`
+
+// manifestParamRegex matches the ${name} and ${name:-default} parameters of
+// a manifest, along with the $ that escapes them as in $${name}.
+var manifestParamRegex = regexp.MustCompile(`(\$?)\$\{(` + manifestParamName + `)(?::-([^}]*))?\}`)
+
+// manifestVarFlag is the flag that sets the parameters of a manifest read
+// with --from-manifest.
+func manifestVarFlag() flag.StringArray {
+	return flag.StringArray{
+		Name:        "var",
+		Description: "Set a ${name} parameter declared by the manifest read with --from-manifest, as name=value. Can be specified multiple times",
+	}
+}
+
+func newSchema() *cobra.Command {
+	const (
+		short = "Print the JSON Schema of launch manifests"
+		long  = short + `. Manifests are written with 'fly launch --manifest' and
+read with 'fly launch --from-manifest'. String values in a manifest may use
+${name} or ${name:-default} parameters, which are filled in from --var flags.
+Only the parameters listed in the "parameters" of the manifest are filled in;
+$${name} keeps a literal ${name}.`
+	)
+
+	cmd := command.New("schema", short, long, runSchema)
+	cmd.Args = cobra.NoArgs
+
+	return cmd
+}
+
+func runSchema(ctx context.Context) error {
+	schema, err := manifestSchema()
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(iostreams.FromContext(ctx).Out)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(schema)
+}
+
+// manifestSchema returns the JSON Schema of a LaunchManifest.
+func manifestSchema() (*jsonschema.Schema, error) {
+	planSchema, err := plan.Schema()
+	if err != nil {
+		return nil, err
+	}
+
+	return &jsonschema.Schema{
+		Schema: plan.SchemaDialect,
+		Title:  "Fly Launch manifest",
+		Type:   "object",
+		Properties: map[string]*jsonschema.Schema{
+			"$schema": {Type: "string"},
+			"parameters": {
+				Type:        "array",
+				Description: "Names of the ${name} parameters used in the manifest",
+				Items:       &jsonschema.Schema{Type: "string", Pattern: "^" + manifestParamName + "$"},
+				UniqueItems: true,
+			},
+			"plan": planSchema,
+			// The plan source only records where flyctl got each value from.
+			"plan_source": {Type: "object"},
+			"config": {
+				Types:       []string{"object", "null"},
+				Description: "Configuration of the app, in the JSON form of fly.toml",
+			},
+		},
+		Required:             []string{"plan"},
+		AdditionalProperties: &jsonschema.Schema{Not: &jsonschema.Schema{}},
+	}, nil
+}
+
+// loadManifest reads a launch manifest from r, fills in its parameters from
+// vars and validates it against the manifest schema.
+func loadManifest(r io.Reader, vars map[string]string) (*LaunchManifest, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse launch manifest: %w", err)
+	}
+
+	schema, err := manifestSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	params := &manifestParams{vars: vars, declared: declaredManifestParams(doc), used: map[string]bool{}}
+	doc = params.fill(doc, schema, "")
+	if err := params.err(); err != nil {
+		return nil, err
+	}
+
+	if err := validateManifest(doc, schema); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest LaunchManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse launch manifest: %w", err)
+	}
+
+	return &manifest, nil
+}
+
+// declaredManifestParams returns the names listed in the parameters of doc.
+// Anything else that looks like a parameter, like ${HOME} in the env of the
+// app, is left as is.
+func declaredManifestParams(doc any) map[string]bool {
+	declared := map[string]bool{}
+	if m, ok := doc.(map[string]any); ok {
+		names, _ := m["parameters"].([]any)
+		for _, name := range names {
+			if name, ok := name.(string); ok {
+				declared[name] = true
+			}
+		}
+	}
+
+	return declared
+}
+
+// manifestParams fills the parameters of a manifest in.
+type manifestParams struct {
+	vars     map[string]string
+	declared map[string]bool
+	used     map[string]bool
+	missing  []string
+	errs     []string
+}
+
+// fill returns v with the parameters in its strings filled in. A string that
+// is nothing but a parameter takes the type schema asks for, so that
+// "${port}" can set an integer.
+func (p *manifestParams) fill(v any, schema *jsonschema.Schema, path string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, child := range v {
+			var childSchema *jsonschema.Schema
+			if schema != nil {
+				childSchema = schema.Properties[key]
+				if childSchema == nil {
+					childSchema = schema.AdditionalProperties
+				}
+			}
+			v[key] = p.fill(child, childSchema, joinManifestPath(path, key))
+		}
+		return v
+	case []any:
+		var itemSchema *jsonschema.Schema
+		if schema != nil {
+			itemSchema = schema.Items
+		}
+		for i, child := range v {
+			v[i] = p.fill(child, itemSchema, fmt.Sprintf("%s[%d]", path, i))
+		}
+		return v
+	case string:
+		return p.fillString(v, schema, path)
+	default:
+		return v
+	}
+}
+
+func (p *manifestParams) fillString(s string, schema *jsonschema.Schema, path string) any {
+	if !strings.Contains(s, "$") {
+		return s
+	}
+
+	if m := manifestParamRegex.FindStringSubmatchIndex(s); m != nil && m[0] == 0 && m[1] == len(s) && m[3] == m[2] {
+		if want := nonStringType(schema); want != "" && p.declared[s[m[4]:m[5]]] {
+			value, ok := p.lookup(s, m)
+			if !ok {
+				return s
+			}
+			return p.convert(value, want, path)
+		}
+	}
+
+	return manifestParamRegex.ReplaceAllStringFunc(s, func(match string) string {
+		m := manifestParamRegex.FindStringSubmatchIndex(match)
+		switch {
+		case !p.declared[match[m[4]:m[5]]]:
+			return match
+		case m[3] > m[2]:
+			return match[1:]
+		}
+		value, _ := p.lookup(match, m)
+		return value
+	})
+}
+
+// lookup returns the value of the parameter matched by m in s.
+func (p *manifestParams) lookup(s string, m []int) (string, bool) {
+	name := s[m[4]:m[5]]
+	if value, ok := p.vars[name]; ok {
+		p.used[name] = true
+		return value, true
+	}
+	if m[6] >= 0 {
+		return s[m[6]:m[7]], true
+	}
+	if !slices.Contains(p.missing, name) {
+		p.missing = append(p.missing, name)
+	}
+
+	return "", false
+}
+
+func (p *manifestParams) convert(value, want, path string) any {
+	switch want {
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return json.Number(value)
+		}
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return json.Number(value)
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+
+	p.errs = append(p.errs, fmt.Sprintf("%s: %q is not a valid %s", path, value, want))
+
+	return value
+}
+
+func (p *manifestParams) err() error {
+	var problems []string
+	if len(p.missing) > 0 {
+		slices.Sort(p.missing)
+		problems = append(problems, fmt.Sprintf("no value for parameter(s) %s; set them with --var name=value", strings.Join(p.missing, ", ")))
+	}
+
+	var unused []string
+	for name := range p.vars {
+		if !p.used[name] {
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) > 0 {
+		slices.Sort(unused)
+		problems = append(problems, fmt.Sprintf("--var %s not used by the manifest", strings.Join(unused, ", ")))
+	}
+
+	slices.Sort(p.errs)
+	problems = append(problems, p.errs...)
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("invalid launch manifest:\n  %s", strings.Join(problems, "\n  "))
+}
+
+// nonStringType returns the type schema asks for, if it doesn't allow
+// strings.
+func nonStringType(schema *jsonschema.Schema) string {
+	if schema == nil {
+		return ""
+	}
+
+	types := schema.Types
+	if schema.Type != "" {
+		types = []string{schema.Type}
+	}
+	if slices.Contains(types, "string") {
+		return ""
+	}
+	for _, t := range []string{"integer", "number", "boolean"} {
+		if slices.Contains(types, t) {
+			return t
+		}
+	}
+
+	return ""
+}
+
+// validateManifest validates doc against schema, reporting every problem
+// with where it is in the manifest.
+func validateManifest(doc any, schema *jsonschema.Schema) error {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return err
+	}
+	schemaDoc, err := validator.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	compiler := validator.NewCompiler()
+	if err := compiler.AddResource(manifestSchemaURL, schemaDoc); err != nil {
+		return err
+	}
+	compiled, err := compiler.Compile(manifestSchemaURL)
+	if err != nil {
+		return err
+	}
+
+	err = compiled.Validate(doc)
+	var validationErr *validator.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err
+	}
+
+	printer := message.NewPrinter(language.English)
+	var problems []string
+	var collect func(e *validator.ValidationError)
+	collect = func(e *validator.ValidationError) {
+		if len(e.Causes) == 0 {
+			path := manifestPath(e.InstanceLocation)
+			problems = append(problems, fmt.Sprintf("%s: %s", path, e.ErrorKind.LocalizedString(printer)))
+			return
+		}
+		for _, cause := range e.Causes {
+			collect(cause)
+		}
+	}
+	collect(validationErr)
+
+	return fmt.Errorf("invalid launch manifest:\n  %s", strings.Join(problems, "\n  "))
+}
+
+// manifestPath turns a JSON pointer into a path like plan.compute[0].cpus.
+func manifestPath(tokens []string) string {
+	path := ""
+	for _, token := range tokens {
+		if _, err := strconv.Atoi(token); err == nil {
+			path += "[" + token + "]"
+		} else {
+			path = joinManifestPath(path, token)
+		}
+	}
+	if path == "" {
+		return "manifest"
+	}
+
+	return path
+}
+
+func joinManifestPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
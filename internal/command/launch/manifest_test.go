@@ -0,0 +1,125 @@
+package launch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const previewManifest = `{
+  "$schema": "./launch-manifest.schema.json",
+  "parameters": ["env", "org", "ha", "cpus", "port"],
+  "plan": {
+    "name": "shop-${env}",
+    "org": "${org:-acme}",
+    "region": "ord",
+    "ha": "${ha:-false}",
+    "compute": [{"memory": "1gb", "cpus": "${cpus}"}],
+    "http_service_port": "${port}",
+    "scanner_family": "Price in $${env} $$USD"
+  },
+  "plan_source": {},
+  "config": {"app": "shop-${env}", "env": {"GREETING": "${env:-dev} ${HOME}"}}
+}`
+
+func TestLoadManifestParameters(t *testing.T) {
+	m, err := loadManifest(strings.NewReader(previewManifest), map[string]string{
+		"env":  "pr-42",
+		"cpus": "2",
+		"port": "3000",
+		"ha":   "true",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "shop-pr-42", m.Plan.AppName)
+	assert.Equal(t, "acme", m.Plan.OrgSlug, "default applies when --var is missing")
+	assert.True(t, m.Plan.HighAvailability)
+	assert.Equal(t, 3000, m.Plan.HttpServicePort)
+	require.Len(t, m.Plan.Compute, 1)
+	assert.Equal(t, 2, m.Plan.Compute[0].CPUs)
+	assert.Equal(t, "Price in ${env} $$USD", m.Plan.ScannerFamily)
+	assert.Equal(t, "shop-pr-42", m.Config.AppName)
+	assert.Equal(t, "pr-42 ${HOME}", m.Config.Env["GREETING"], "undeclared parameters are left as is")
+}
+
+func TestLoadManifestParameterErrors(t *testing.T) {
+	_, err := loadManifest(strings.NewReader(previewManifest), map[string]string{
+		"cpus": "two",
+		"port": "3000",
+		"typo": "x",
+	})
+	require.Error(t, err)
+
+	assert.Contains(t, err.Error(), "no value for parameter(s) env")
+	assert.Contains(t, err.Error(), "--var typo not used by the manifest")
+	assert.Contains(t, err.Error(), `plan.compute[0].cpus: "two" is not a valid integer`)
+}
+
+func TestLoadManifestValidation(t *testing.T) {
+	_, err := loadManifest(strings.NewReader(`{
+  "plan": {
+    "name": "shop",
+    "region": "ord",
+    "compute": [{"cpus": "two"}],
+    "redis": {"upstash": {}}
+  },
+  "extra": true
+}`), nil)
+	require.Error(t, err)
+
+	msg := err.Error()
+	assert.Contains(t, msg, "plan.compute[0].cpus: got string, want integer")
+	assert.Contains(t, msg, "plan: missing property 'org'")
+	assert.Contains(t, msg, "plan.redis: additional properties 'upstash' not allowed")
+	assert.Contains(t, msg, "manifest: additional properties 'extra' not allowed")
+}
+
+func TestLoadManifestRoundTrip(t *testing.T) {
+	// What fly launch --manifest writes must load back.
+	m, err := loadManifest(strings.NewReader(`{
+  "plan": {
+    "name": "shop",
+    "org": "personal",
+    "region": "ord",
+    "ha": true,
+    "compute": [{"memory": "1gb", "cpu_kind": "shared", "cpus": 1, "memory_mb": 1024}],
+    "http_service_port": 8080,
+    "postgres": {"fly_postgres": null, "managed_postgres": null},
+    "redis": {"upstash_redis": null},
+    "github_actions": {"deploy": false, "review": false},
+    "sentry": false,
+    "object_storage": {"tigris_object_storage": null},
+    "scanner_family": "Rails",
+    "flyctl_version": "0.3.0",
+    "runtime": {"language": "ruby", "version": "3.3", "no_install_required": false}
+  },
+  "plan_source": {},
+  "config": null
+}`), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Rails", m.Plan.ScannerFamily)
+}
+
+func TestLoadManifestWithoutParameters(t *testing.T) {
+	// Manifests that don't declare parameters load as they are, whatever
+	// their strings look like.
+	const manifest = `{
+  "plan": {"name": "shop", "org": "personal", "region": "ord"},
+  "config": {
+    "app": "shop",
+    "env": {"CACHE_DIR": "${HOME}/.cache", "PRICE": "$$5", "PORT": "${PORT:-8080}"}
+  }
+}`
+	m, err := loadManifest(strings.NewReader(manifest), nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"CACHE_DIR": "${HOME}/.cache",
+		"PRICE":     "$$5",
+		"PORT":      "${PORT:-8080}",
+	}, m.Config.Env)
+
+	_, err = loadManifest(strings.NewReader(manifest), map[string]string{"HOME": "/root"})
+	assert.ErrorContains(t, err, "--var HOME not used by the manifest")
+}
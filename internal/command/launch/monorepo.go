@@ -0,0 +1,268 @@
+package launch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/command"
+	"github.com/superfly/flyctl/internal/command/launch/monorepo"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/internal/flag/flagnames"
+	"github.com/superfly/flyctl/internal/flyerr"
+	"github.com/superfly/flyctl/internal/prompt"
+	"github.com/superfly/flyctl/internal/render"
+	"github.com/superfly/flyctl/internal/state"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+// monorepoLaunch is the plan for one service of a workspace.
+type monorepoLaunch struct {
+	pkg      monorepo.Package
+	dir      string
+	manifest *LaunchManifest
+	cache    *planBuildCache
+	env      map[string]string
+}
+
+type monorepoManifest struct {
+	Workspace *monorepo.Workspace       `json:"workspace"`
+	Services  []monorepoServiceManifest `json:"services"`
+}
+
+type monorepoServiceManifest struct {
+	monorepo.Package
+	Env map[string]string `json:"env,omitempty"`
+	LaunchManifest
+}
+
+// runMonorepo launches every service of the workspace at --path as its own
+// app, in one organization and region, with environment variables pointing
+// each service at the others over the private network.
+func runMonorepo(ctx context.Context) error {
+	io := iostreams.FromContext(ctx)
+
+	for _, name := range []string{"from", "from-manifest", "into", "attach"} {
+		if flag.IsSpecified(ctx, name) {
+			return fmt.Errorf("--monorepo can't be used with --%s", name)
+		}
+	}
+
+	root, err := filepath.Abs(flag.GetString(ctx, "path"))
+	if err != nil {
+		return err
+	}
+
+	ws, err := monorepo.Detect(root)
+	if err != nil {
+		return err
+	}
+	if ws == nil {
+		return flyerr.GenericErr{
+			Err:     fmt.Sprintf("no workspace found in %s", root),
+			Suggest: "--monorepo reads pnpm-workspace.yaml, package.json workspaces, go.work, Cargo.toml [workspace] and nx.json",
+		}
+	}
+
+	fmt.Fprintf(io.Out, "Found a %s workspace with %d package(s), %d of them services\n", strings.Join(ws.Tools, ", "), len(ws.Packages), len(ws.Services()))
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	defer os.Chdir(wd)
+
+	// Apps are named after the workspace, or --name, and the package, the
+	// way --into names apps after their parent.
+	prefix := flag.GetString(ctx, "name")
+	if prefix == "" {
+		prefix = sanitizeAppName(filepath.Base(root))
+	}
+	if err := flag.SetString(ctx, "name", ""); err != nil {
+		return err
+	}
+	parentConfig := &appconfig.Config{AppName: prefix}
+
+	// The launch UI handles one app at a time, so every problem is fatal.
+	recoverableErrors := recoverableErrorBuilder{canEnterUi: false}
+
+	var launches []*monorepoLaunch
+	for _, pkg := range ws.Services() {
+		dir := filepath.Join(root, filepath.FromSlash(pkg.Dir))
+
+		fmt.Fprintf(io.Out, "\nPlanning %s (%s)\n", pkg.Name, pkg.Dir)
+
+		pkgCtx, err := monorepoPackageContext(ctx, dir)
+		if err != nil {
+			return err
+		}
+
+		manifest, cache, err := buildManifest(pkgCtx, parentConfig, &recoverableErrors)
+		if err != nil {
+			return fmt.Errorf("failed to plan %s: %w", pkg.Dir, err)
+		}
+		if cache.sourceInfo == nil {
+			fmt.Fprintf(io.ErrOut, "Skipping %s: could not find anything to launch\n", pkg.Dir)
+			continue
+		}
+
+		// Every service goes where the first one went.
+		if len(launches) == 0 {
+			if err := flag.SetString(ctx, flagnames.Org, manifest.Plan.OrgSlug); err != nil {
+				return err
+			}
+			if err := flag.SetString(ctx, flagnames.Region, manifest.Plan.RegionCode); err != nil {
+				return err
+			}
+		}
+
+		launches = append(launches, &monorepoLaunch{
+			pkg:      pkg,
+			dir:      dir,
+			manifest: manifest,
+			cache:    cache,
+		})
+	}
+
+	if len(launches) == 0 {
+		return errors.New("no services to launch in this workspace")
+	}
+
+	services := lo.Map(launches, func(l *monorepoLaunch, _ int) monorepo.Service {
+		return monorepo.Service{
+			Name:    l.pkg.Name,
+			AppName: l.manifest.Plan.AppName,
+			Port:    servicePort(l),
+		}
+	})
+	for _, l := range launches {
+		l.env = monorepo.PrivateNetworkEnv(l.pkg.Name, services)
+	}
+
+	if flag.GetBool(ctx, "manifest") {
+		return writeMonorepoManifest(ctx, ws, launches)
+	}
+
+	rows := lo.Map(launches, func(l *monorepoLaunch, _ int) []string {
+		port := ""
+		if p := servicePort(l); p != 0 {
+			port = strconv.Itoa(p)
+		}
+		return []string{l.pkg.Name, l.pkg.Dir, l.cache.sourceInfo.Family, l.manifest.Plan.AppName, port}
+	})
+	fmt.Fprintln(io.Out)
+	if err := render.Table(io.Out, "", rows, "Service", "Directory", "Family", "App", "Port"); err != nil {
+		return err
+	}
+	fmt.Fprintf(io.Out, "Organization: %s\nRegion: %s\n\n", launches[0].manifest.Plan.OrgSlug, launches[0].manifest.Plan.RegionCode)
+
+	for _, l := range launches {
+		for _, name := range slices.Sorted(maps.Keys(l.env)) {
+			fmt.Fprintf(io.Out, "%s will get %s=%s\n", l.manifest.Plan.AppName, name, l.env[name])
+		}
+	}
+
+	if !flag.GetYes(ctx) {
+		switch confirmed, err := prompt.Confirm(ctx, fmt.Sprintf("Launch these %d apps?", len(launches))); {
+		case err == nil:
+			if !confirmed {
+				return nil
+			}
+		case prompt.IsNonInteractive(err):
+			return prompt.NonInteractiveError("yes flag must be specified when not running interactively")
+		default:
+			return err
+		}
+	}
+
+	for _, l := range launches {
+		fmt.Fprintf(io.Out, "\n==> Launching %s from %s\n", l.manifest.Plan.AppName, l.pkg.Dir)
+
+		pkgCtx, err := monorepoPackageContext(ctx, l.dir)
+		if err != nil {
+			return err
+		}
+
+		launchState, err := stateFromManifest(pkgCtx, *l.manifest, l.cache, &recoverableErrors)
+		if err != nil {
+			return fmt.Errorf("failed to launch %s: %w", l.pkg.Dir, err)
+		}
+
+		// --env wins over the generated variables.
+		env := maps.Clone(l.env)
+		maps.Copy(env, launchState.env)
+		launchState.env = env
+
+		if err := launchState.Launch(pkgCtx); err != nil {
+			return fmt.Errorf("failed to launch %s: %w", l.pkg.Dir, err)
+		}
+	}
+
+	return nil
+}
+
+// monorepoPackageContext points ctx, and the process, at a package of the
+// workspace, as if launch had been run in it.
+func monorepoPackageContext(ctx context.Context, dir string) (context.Context, error) {
+	// Some scanners look at the current directory rather than the one they're
+	// handed.
+	if err := os.Chdir(dir); err != nil {
+		return nil, fmt.Errorf("failed to change directory: %w", err)
+	}
+	if err := flag.SetString(ctx, "path", dir); err != nil {
+		return nil, err
+	}
+
+	ctx = state.WithWorkingDirectory(ctx, dir)
+	ctx = appconfig.WithConfig(ctx, nil)
+
+	return command.LoadAppConfigIfPresent(ctx)
+}
+
+// servicePort is the port other services reach l on, or zero for services
+// that don't listen on one, such as workers.
+func servicePort(l *monorepoLaunch) int {
+	if l.cache.sourceInfo.Port != 0 {
+		return l.cache.sourceInfo.Port
+	}
+	if service := l.manifest.Config.HTTPService; service != nil {
+		return service.InternalPort
+	}
+
+	return 0
+}
+
+func writeMonorepoManifest(ctx context.Context, ws *monorepo.Workspace, launches []*monorepoLaunch) error {
+	out := monorepoManifest{Workspace: ws}
+	for _, l := range launches {
+		out.Services = append(out.Services, monorepoServiceManifest{
+			Package:        l.pkg,
+			Env:            l.env,
+			LaunchManifest: *l.manifest,
+		})
+	}
+
+	w := iostreams.FromContext(ctx).Out
+	if path := flag.GetString(ctx, "manifest-path"); path != "" {
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(out)
+}
@@ -0,0 +1,54 @@
+package monorepo
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Service is a workspace package that's being launched as an app.
+type Service struct {
+	Name    string
+	AppName string
+	// Port is the port the service listens on, or zero if it doesn't serve
+	// anything other services could call.
+	Port int
+}
+
+// EnvName returns the name of the variable that holds the private URL of the
+// named service, for instance API_URL for "api" or WEB_APP_URL for "web-app".
+func EnvName(service string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range service {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(unicode.ToUpper(r))
+			underscore = false
+		} else if !underscore && b.Len() > 0 {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+
+	name := strings.TrimSuffix(b.String(), "_")
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "SERVICE_" + name
+	}
+
+	return name + "_URL"
+}
+
+// PrivateNetworkEnv returns the environment of the service named self: one
+// variable per other service that listens on a port, holding the URL that
+// reaches it over the organization's private network.
+func PrivateNetworkEnv(self string, services []Service) map[string]string {
+	env := map[string]string{}
+	for _, s := range services {
+		if s.Name == self || s.Port == 0 {
+			continue
+		}
+		env[EnvName(s.Name)] = fmt.Sprintf("http://%s.internal:%d", s.AppName, s.Port)
+	}
+
+	return env
+}
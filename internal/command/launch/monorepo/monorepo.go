@@ -0,0 +1,489 @@
+// Package monorepo finds the packages of a workspace so that fly launch can
+// launch each service in it as its own app.
+package monorepo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"golang.org/x/mod/modfile"
+	"gopkg.in/yaml.v3"
+)
+
+// Tools that define a workspace.
+const (
+	ToolPnpm  = "pnpm"
+	ToolNpm   = "npm"
+	ToolGo    = "go"
+	ToolCargo = "cargo"
+	ToolNx    = "nx"
+	ToolTurbo = "turbo"
+)
+
+// maxDepth limits how deep workspace globs are matched below the root.
+const maxDepth = 6
+
+// skipDirs are never searched for packages.
+var skipDirs = []string{".git", "node_modules", "target", "vendor", "dist", "build", ".fly", ".next", ".turbo", ".nx"}
+
+// Package is a member of a workspace.
+type Package struct {
+	// Name is the package's name from its manifest, or its directory name.
+	Name string `json:"name"`
+	// Dir is the package's directory, relative to the workspace root and
+	// slash separated.
+	Dir string `json:"dir"`
+	// Tool is the workspace tool that lists the package.
+	Tool string `json:"tool"`
+	// Service is set when the package looks like something that runs, rather
+	// than a library.
+	Service bool `json:"service"`
+}
+
+// Workspace is a repository holding several packages.
+type Workspace struct {
+	Root     string    `json:"root"`
+	Tools    []string  `json:"tools"`
+	Packages []Package `json:"packages"`
+}
+
+// Services returns the packages that look like services.
+func (w *Workspace) Services() []Package {
+	var services []Package
+	for _, p := range w.Packages {
+		if p.Service {
+			services = append(services, p)
+		}
+	}
+
+	return services
+}
+
+// Detect reads the workspace manifests in root and returns the packages they
+// list. It returns nil if root isn't a workspace.
+func Detect(root string) (*Workspace, error) {
+	w := &Workspace{Root: root}
+	seen := map[string]int{}
+
+	add := func(tool string, pkgs []Package) {
+		if len(pkgs) == 0 {
+			return
+		}
+		if !slices.Contains(w.Tools, tool) {
+			w.Tools = append(w.Tools, tool)
+		}
+		for _, p := range pkgs {
+			if i, ok := seen[p.Dir]; ok {
+				// Several tools may list the same package, for instance
+				// pnpm and Turbo. The first one wins, but any of them may
+				// know it's a service.
+				w.Packages[i].Service = w.Packages[i].Service || p.Service
+				continue
+			}
+			seen[p.Dir] = len(w.Packages)
+			w.Packages = append(w.Packages, p)
+		}
+	}
+
+	detectors := []struct {
+		tool   string
+		detect func(root string) ([]Package, error)
+	}{
+		{ToolPnpm, detectPnpm},
+		{ToolNpm, detectNpm},
+		{ToolGo, detectGo},
+		{ToolCargo, detectCargo},
+		{ToolNx, detectNx},
+	}
+
+	for _, d := range detectors {
+		pkgs, err := d.detect(root)
+		if err != nil {
+			return nil, err
+		}
+		add(d.tool, pkgs)
+	}
+
+	if len(w.Packages) == 0 {
+		return nil, nil
+	}
+
+	// Turbo runs on top of the package manager's workspaces, so it only
+	// needs noting.
+	if fileExists(filepath.Join(root, "turbo.json")) {
+		w.Tools = append(w.Tools, ToolTurbo)
+	}
+
+	slices.SortFunc(w.Packages, func(a, b Package) int {
+		return strings.Compare(a.Dir, b.Dir)
+	})
+
+	return w, nil
+}
+
+func detectPnpm(root string) ([]Package, error) {
+	data, err := os.ReadFile(filepath.Join(root, "pnpm-workspace.yaml"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var manifest struct {
+		Packages []string `yaml:"packages"`
+	}
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse pnpm-workspace.yaml: %w", err)
+	}
+
+	return nodePackages(root, ToolPnpm, manifest.Packages)
+}
+
+func detectNpm(root string) ([]Package, error) {
+	pkg, err := readPackageJSON(filepath.Join(root, "package.json"))
+	if err != nil || pkg == nil {
+		return nil, err
+	}
+
+	// Workspaces are either a list of globs, or, for yarn, an object with
+	// the list under "packages".
+	var patterns []string
+	if len(pkg.Workspaces) > 0 && json.Unmarshal(pkg.Workspaces, &patterns) != nil {
+		var workspaces struct {
+			Packages []string `json:"packages"`
+		}
+		if err := json.Unmarshal(pkg.Workspaces, &workspaces); err != nil {
+			return nil, fmt.Errorf("failed to parse workspaces in package.json: %w", err)
+		}
+		patterns = workspaces.Packages
+	}
+
+	return nodePackages(root, ToolNpm, patterns)
+}
+
+type packageJSON struct {
+	Name       string            `json:"name"`
+	Scripts    map[string]string `json:"scripts"`
+	Workspaces json.RawMessage   `json:"workspaces"`
+}
+
+func readPackageJSON(path string) (*packageJSON, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var pkg packageJSON
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return &pkg, nil
+}
+
+func nodePackages(root, tool string, patterns []string) ([]Package, error) {
+	dirs, err := expand(root, patterns, "package.json")
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []Package
+	for _, dir := range dirs {
+		pkg, err := readPackageJSON(filepath.Join(root, filepath.FromSlash(dir), "package.json"))
+		if err != nil {
+			return nil, err
+		}
+
+		name := pkg.Name
+		// Drop the scope of scoped packages like @acme/api.
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			name = name[i+1:]
+		}
+
+		pkgs = append(pkgs, Package{
+			Name:    nameOr(name, dir),
+			Dir:     dir,
+			Tool:    tool,
+			Service: pkg.Scripts["start"] != "" || hasDockerfile(root, dir),
+		})
+	}
+
+	return pkgs, nil
+}
+
+func detectGo(root string) ([]Package, error) {
+	data, err := os.ReadFile(filepath.Join(root, "go.work"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	work, err := modfile.ParseWork("go.work", data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse go.work: %w", err)
+	}
+
+	var pkgs []Package
+	for _, use := range work.Use {
+		dir := path.Clean(filepath.ToSlash(use.Path))
+		if dir == "." || strings.HasPrefix(dir, "../") {
+			continue
+		}
+
+		name := path.Base(dir)
+		if data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(dir), "go.mod")); err == nil {
+			if modPath := modfile.ModulePath(data); modPath != "" {
+				name = path.Base(modPath)
+			}
+		}
+
+		pkgs = append(pkgs, Package{
+			Name:    name,
+			Dir:     dir,
+			Tool:    ToolGo,
+			Service: hasDockerfile(root, dir) || isGoMain(filepath.Join(root, filepath.FromSlash(dir))),
+		})
+	}
+
+	return pkgs, nil
+}
+
+var goMainRegex = regexp.MustCompile(`(?m)^package main\b`)
+
+// isGoMain reports whether the Go module in dir has a main package at its
+// root.
+func isGoMain(dir string) bool {
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	for _, m := range matches {
+		if strings.HasSuffix(m, "_test.go") {
+			continue
+		}
+		if data, err := os.ReadFile(m); err == nil && goMainRegex.Match(data) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type cargoManifest struct {
+	Package struct {
+		Name string `toml:"name"`
+	} `toml:"package"`
+	Workspace *struct {
+		Members []string `toml:"members"`
+		Exclude []string `toml:"exclude"`
+	} `toml:"workspace"`
+	Bin []struct {
+		Name string `toml:"name"`
+	} `toml:"bin"`
+}
+
+func readCargoManifest(path string) (*cargoManifest, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var manifest cargoManifest
+	if err := toml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return &manifest, nil
+}
+
+func detectCargo(root string) ([]Package, error) {
+	manifest, err := readCargoManifest(filepath.Join(root, "Cargo.toml"))
+	if err != nil || manifest == nil || manifest.Workspace == nil {
+		return nil, err
+	}
+
+	patterns := manifest.Workspace.Members
+	for _, exclude := range manifest.Workspace.Exclude {
+		patterns = append(patterns, "!"+exclude)
+	}
+
+	dirs, err := expand(root, patterns, "Cargo.toml")
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []Package
+	for _, dir := range dirs {
+		member, err := readCargoManifest(filepath.Join(root, filepath.FromSlash(dir), "Cargo.toml"))
+		if err != nil {
+			return nil, err
+		}
+
+		pkgs = append(pkgs, Package{
+			Name: nameOr(member.Package.Name, dir),
+			Dir:  dir,
+			Tool: ToolCargo,
+			Service: hasDockerfile(root, dir) || len(member.Bin) > 0 ||
+				fileExists(filepath.Join(root, filepath.FromSlash(dir), "src", "main.rs")),
+		})
+	}
+
+	return pkgs, nil
+}
+
+// detectNx finds Nx projects, which are directories with a project.json.
+func detectNx(root string) ([]Package, error) {
+	if !fileExists(filepath.Join(root, "nx.json")) {
+		return nil, nil
+	}
+
+	dirs, err := expand(root, []string{"**"}, "project.json")
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []Package
+	for _, dir := range dirs {
+		if dir == "." {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(dir), "project.json"))
+		if err != nil {
+			return nil, err
+		}
+
+		var project struct {
+			Name        string `json:"name"`
+			ProjectType string `json:"projectType"`
+		}
+		if err := json.Unmarshal(data, &project); err != nil {
+			return nil, fmt.Errorf("failed to parse %s/project.json: %w", dir, err)
+		}
+
+		pkgs = append(pkgs, Package{
+			Name:    nameOr(project.Name, dir),
+			Dir:     dir,
+			Tool:    ToolNx,
+			Service: project.ProjectType == "application" || hasDockerfile(root, dir),
+		})
+	}
+
+	return pkgs, nil
+}
+
+// expand returns the directories below root, relative and slash separated,
+// that match patterns and contain manifest. Patterns starting with ! exclude
+// the directories they match.
+func expand(root string, patterns []string, manifest string) ([]string, error) {
+	var include, exclude []string
+	for _, p := range patterns {
+		if rest, ok := strings.CutPrefix(p, "!"); ok {
+			exclude = append(exclude, cleanPattern(rest))
+		} else {
+			include = append(include, cleanPattern(p))
+		}
+	}
+	if len(include) == 0 {
+		return nil, nil
+	}
+
+	var dirs []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if rel != "." {
+			if slices.Contains(skipDirs, d.Name()) || strings.Count(rel, "/") >= maxDepth {
+				return filepath.SkipDir
+			}
+		}
+
+		matches := func(patterns []string) bool {
+			return slices.ContainsFunc(patterns, func(pattern string) bool {
+				return match(pattern, rel)
+			})
+		}
+		if matches(include) && !matches(exclude) && fileExists(filepath.Join(p, manifest)) {
+			dirs = append(dirs, rel)
+		}
+
+		return nil
+	})
+
+	return dirs, err
+}
+
+func cleanPattern(p string) string {
+	p = strings.TrimSuffix(filepath.ToSlash(strings.TrimSpace(p)), "/")
+	return path.Clean(p)
+}
+
+// match reports whether the slash separated dir matches pattern, where * and
+// the other path.Match syntax match within a path element and ** matches any
+// number of them.
+func match(pattern, dir string) bool {
+	return matchElems(strings.Split(pattern, "/"), strings.Split(dir, "/"))
+}
+
+func matchElems(pattern, dir []string) bool {
+	if len(pattern) == 0 {
+		return len(dir) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(dir); i++ {
+			if matchElems(pattern[1:], dir[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(dir) == 0 {
+		return false
+	}
+	if ok, err := path.Match(pattern[0], dir[0]); err != nil || !ok {
+		return false
+	}
+
+	return matchElems(pattern[1:], dir[1:])
+}
+
+func hasDockerfile(root, dir string) bool {
+	return fileExists(filepath.Join(root, filepath.FromSlash(dir), "Dockerfile"))
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func nameOr(name, dir string) string {
+	if name != "" {
+		return name
+	}
+
+	return path.Base(dir)
+}
@@ -0,0 +1,168 @@
+package monorepo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+
+	for name, contents := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+	}
+}
+
+func TestDetectPnpmWithTurbo(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"pnpm-workspace.yaml":                     "packages:\n  - 'apps/*'\n  - 'packages/**'\n  - '!packages/ignored'\n",
+		"package.json":                            `{"name": "root", "private": true}`,
+		"turbo.json":                              `{}`,
+		"apps/api/package.json":                   `{"name": "@acme/api", "scripts": {"start": "node index.js"}}`,
+		"apps/web/package.json":                   `{"name": "@acme/web", "scripts": {"build": "next build"}}`,
+		"apps/web/Dockerfile":                     "FROM node\n",
+		"apps/docs/README.md":                     "no package.json",
+		"packages/ui/package.json":                `{"name": "@acme/ui"}`,
+		"packages/ignored/package.json":           `{"name": "ignored"}`,
+		"packages/ui/node_modules/x/package.json": `{"name": "x"}`,
+	})
+
+	ws, err := Detect(root)
+	require.NoError(t, err)
+	require.NotNil(t, ws)
+
+	assert.Equal(t, []string{ToolPnpm, ToolTurbo}, ws.Tools)
+	assert.Equal(t, []Package{
+		{Name: "api", Dir: "apps/api", Tool: ToolPnpm, Service: true},
+		{Name: "web", Dir: "apps/web", Tool: ToolPnpm, Service: true},
+		{Name: "ui", Dir: "packages/ui", Tool: ToolPnpm},
+	}, ws.Packages)
+	assert.Len(t, ws.Services(), 2)
+}
+
+func TestDetectYarnWorkspacesObject(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"package.json":                 `{"workspaces": {"packages": ["services/*"]}}`,
+		"services/worker/package.json": `{"name": "worker", "scripts": {"start": "node worker.js"}}`,
+	})
+
+	ws, err := Detect(root)
+	require.NoError(t, err)
+	require.NotNil(t, ws)
+	assert.Equal(t, []Package{{Name: "worker", Dir: "services/worker", Tool: ToolNpm, Service: true}}, ws.Packages)
+}
+
+func TestDetectGoWork(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"go.work":            "go 1.22\n\nuse (\n\t./cmd/server\n\t./lib\n)\n",
+		"cmd/server/go.mod":  "module example.com/acme/server\n",
+		"cmd/server/main.go": "package main\n\nfunc main() {}\n",
+		"lib/go.mod":         "module example.com/acme/lib\n",
+		"lib/lib.go":         "package lib\n",
+	})
+
+	ws, err := Detect(root)
+	require.NoError(t, err)
+	require.NotNil(t, ws)
+	assert.Equal(t, []Package{
+		{Name: "server", Dir: "cmd/server", Tool: ToolGo, Service: true},
+		{Name: "lib", Dir: "lib", Tool: ToolGo},
+	}, ws.Packages)
+}
+
+func TestDetectCargoWorkspace(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"Cargo.toml":                "[workspace]\nmembers = [\"crates/*\"]\nexclude = [\"crates/scratch\"]\n",
+		"crates/api/Cargo.toml":     "[package]\nname = \"acme-api\"\n",
+		"crates/api/src/main.rs":    "fn main() {}\n",
+		"crates/core/Cargo.toml":    "[package]\nname = \"acme-core\"\n",
+		"crates/cli/Cargo.toml":     "[package]\nname = \"acme-cli\"\n\n[[bin]]\nname = \"acme\"\n",
+		"crates/scratch/Cargo.toml": "[package]\nname = \"scratch\"\n",
+	})
+
+	ws, err := Detect(root)
+	require.NoError(t, err)
+	require.NotNil(t, ws)
+	assert.Equal(t, []Package{
+		{Name: "acme-api", Dir: "crates/api", Tool: ToolCargo, Service: true},
+		{Name: "acme-cli", Dir: "crates/cli", Tool: ToolCargo, Service: true},
+		{Name: "acme-core", Dir: "crates/core", Tool: ToolCargo},
+	}, ws.Packages)
+}
+
+func TestDetectNx(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"nx.json":                  `{}`,
+		"apps/api/project.json":    `{"name": "api", "projectType": "application"}`,
+		"libs/shared/project.json": `{"name": "shared", "projectType": "library"}`,
+	})
+
+	ws, err := Detect(root)
+	require.NoError(t, err)
+	require.NotNil(t, ws)
+	assert.Len(t, ws.Packages, 2)
+	require.Len(t, ws.Services(), 1)
+	assert.Equal(t, "api", ws.Services()[0].Name)
+}
+
+func TestDetectNotAWorkspace(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"package.json": `{"name": "app"}`,
+		"Cargo.toml":   "[package]\nname = \"app\"\n",
+	})
+
+	ws, err := Detect(root)
+	require.NoError(t, err)
+	assert.Nil(t, ws)
+}
+
+func TestMatch(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, dir string
+		want         bool
+	}{
+		{"apps/*", "apps/api", true},
+		{"apps/*", "apps/api/src", false},
+		{"apps/**", "apps/api/src", true},
+		{"**/svc-*", "a/b/svc-x", true},
+		{"apps/api", "apps/api", true},
+		{"apps/api", "apps/web", false},
+	} {
+		assert.Equal(t, tc.want, match(tc.pattern, tc.dir), "%s ~ %s", tc.pattern, tc.dir)
+	}
+}
+
+func TestPrivateNetworkEnv(t *testing.T) {
+	services := []Service{
+		{Name: "api", AppName: "acme-api", Port: 3000},
+		{Name: "web-app", AppName: "acme-web-app", Port: 8080},
+		{Name: "worker", AppName: "acme-worker"},
+	}
+
+	assert.Equal(t, map[string]string{
+		"WEB_APP_URL": "http://acme-web-app.internal:8080",
+	}, PrivateNetworkEnv("api", services))
+
+	assert.Equal(t, map[string]string{
+		"API_URL":     "http://acme-api.internal:3000",
+		"WEB_APP_URL": "http://acme-web-app.internal:8080",
+	}, PrivateNetworkEnv("worker", services))
+}
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "API_URL", EnvName("api"))
+	assert.Equal(t, "WEB_APP_URL", EnvName("web--app"))
+	assert.Equal(t, "SERVICE_2FA_URL", EnvName("2fa"))
+}
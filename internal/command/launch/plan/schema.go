@@ -0,0 +1,70 @@
+package plan
+
+import (
+	"reflect"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/superfly/flyctl/internal/version"
+)
+
+// SchemaDialect is the JSON Schema draft the launch schemas are written in.
+const SchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+var fieldDescriptions = map[string]string{
+	"name":              "Name of the app to create",
+	"org":               "Slug of the organization to create the app in",
+	"region":            "Primary region of the app",
+	"ha":                "Run more than one machine per process group for high availability",
+	"compute":           "Machine sizes, as in the [[vm]] sections of fly.toml",
+	"http_service_port": "Port the app listens on for HTTP",
+	"postgres":          "Postgres database to create and attach, if any",
+	"redis":             "Redis database to create and attach, if any",
+	"github_actions":    "GitHub Actions workflows to generate",
+	"sentry":            "Create a Sentry project for the app",
+	"object_storage":    "Tigris bucket to create and attach, if any",
+	"scanner_family":    "Kind of app the source scanner detected, e.g. Rails",
+	"flyctl_version":    "Version of flyctl that wrote the plan",
+	"runtime":           "Language runtime the app is built with",
+}
+
+// Schema returns the JSON Schema of a LaunchPlan as it's written to a launch
+// manifest. Only name, org and region are required; any other field that's
+// left out takes its zero value.
+func Schema() (*jsonschema.Schema, error) {
+	s, err := jsonschema.For[LaunchPlan](&jsonschema.ForOptions{
+		TypeSchemas: map[reflect.Type]*jsonschema.Schema{
+			reflect.TypeFor[version.Version](): {Type: "string"},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dropRequired(s)
+	s.Required = []string{"name", "org", "region"}
+	s.Title = "Fly Launch plan"
+
+	for name, description := range fieldDescriptions {
+		if prop, ok := s.Properties[name]; ok {
+			prop.Description = description
+		}
+	}
+
+	return s, nil
+}
+
+// dropRequired makes every property of s, and of the schemas below it,
+// optional. Schemas inferred from Go types require every field without
+// omitempty.
+func dropRequired(s *jsonschema.Schema) {
+	if s == nil {
+		return
+	}
+
+	s.Required = nil
+	for _, prop := range s.Properties {
+		dropRequired(prop)
+	}
+	dropRequired(s.Items)
+	dropRequired(s.AdditionalProperties)
+}
@@ -105,6 +105,7 @@ func newCreate() *cobra.Command {
 			Default:     "",
 			Hidden:      true,
 		},
+		manifestVarFlag(),
 		flag.Int{
 			Name:        "internal-port",
 			Description: "Set internal_port for all services in the generated fly.toml",
@@ -131,6 +132,7 @@ func newPostgres() *cobra.Command {
 			Default:     "",
 			Hidden:      true,
 		},
+		manifestVarFlag(),
 	)
 
 	return cmd
@@ -150,6 +152,7 @@ func newRedis() *cobra.Command {
 			Default:     "",
 			Hidden:      true,
 		},
+		manifestVarFlag(),
 	)
 
 	return cmd
@@ -169,6 +172,7 @@ func newTigris() *cobra.Command {
 			Default:     "",
 			Hidden:      true,
 		},
+		manifestVarFlag(),
 	)
 
 	return cmd
@@ -204,6 +208,7 @@ func newGenerate() *cobra.Command {
 			Default:     "",
 			Hidden:      true,
 		},
+		manifestVarFlag(),
 		flag.Compression(),
 		flag.CompressionLevel(),
 		flag.Int{
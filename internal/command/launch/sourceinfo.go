@@ -26,6 +26,7 @@ func determineSourceInfo(ctx context.Context, appConfig *appconfig.Config, copyC
 		ExistingPort: appConfig.InternalPort(),
 		Mode:         "launch",
 		Colorize:     io.ColorScheme(),
+		RunPlugins:   flag.GetBool(ctx, "run-scanner-plugins"),
 	}
 	// Detect if --copy-config and --now flags are set. If so, limited set of
 	// fly.toml file updates. Helpful for deploying PRs when the project is
@@ -73,7 +74,10 @@ func determineSourceInfo(ctx context.Context, appConfig *appconfig.Config, copyC
 		fmt.Fprintln(io.Out, "Scanning source code")
 	}
 
-	srcInfo, err = scanner.Scan(workingDir, scannerConfig)
+	srcInfo, explanation, err := scanner.ScanAndExplain(workingDir, scannerConfig)
+	if flag.GetBool(ctx, "explain-scan") {
+		printScanExplanation(io, explanation)
+	}
 	if err != nil {
 		return nil, nil, err
 	}
@@ -131,3 +135,31 @@ func articleFor(w string) string {
 
 	return article
 }
+
+// printScanExplanation lists the scanners tried, for --explain-scan.
+func printScanExplanation(io *iostreams.IOStreams, explanation *scanner.ScanExplanation) {
+	colorize := io.ColorScheme()
+
+	fmt.Fprintln(io.Out, "Scanners tried, in order:")
+	for _, check := range explanation.Checks {
+		name := check.Scanner + " (" + check.Kind
+		if check.Path != "" {
+			name += ", " + check.Path
+		}
+		name += ")"
+
+		if check.Matched {
+			fmt.Fprintf(io.Out, "  %s %s\n", colorize.SuccessIcon(), colorize.Bold(name))
+		} else {
+			fmt.Fprintf(io.Out, "  - %s\n", name)
+		}
+
+		for _, reason := range check.Reasons {
+			fmt.Fprintf(io.Out, "      %s\n", reason)
+		}
+	}
+
+	if explanation.Match() == nil {
+		fmt.Fprintln(io.Out, "No scanner matched")
+	}
+}
@@ -30,6 +30,7 @@ func newMachineCordon() *cobra.Command {
 		flag.App(),
 		flag.AppConfig(),
 		selectFlag,
+		whereFlags,
 	)
 
 	cmd.Args = cobra.ArbitraryArgs
@@ -47,6 +48,9 @@ func runMachineCordon(ctx context.Context) (err error) {
 	if err != nil {
 		return err
 	}
+	if dryRun(ctx, machines) {
+		return nil
+	}
 
 	// appName is added to context by selectManyMachines
 	appName := appconfig.NameFromContext(ctx)
@@ -2,9 +2,11 @@ package machine
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
+	"github.com/samber/lo"
 	"github.com/spf13/cobra"
 	fly "github.com/superfly/fly-go"
 	"github.com/superfly/flyctl/internal/appconfig"
@@ -38,6 +40,8 @@ This command requires a machine to be in a stopped or suspended state unless the
 		flag.App(),
 		flag.AppConfig(),
 		selectFlag,
+		whereFlags,
+		flag.Yes(),
 		flag.Bool{
 			Name:        "force",
 			Shorthand:   "f",
@@ -61,6 +65,9 @@ func runMachineDestroy(ctx context.Context) (err error) {
 	if image != "" && appconfig.NameFromContext(ctx) == "" {
 		return fmt.Errorf("--image requires --app flag or must be run from app directory")
 	}
+	if image != "" && flag.GetString(ctx, "where") != "" {
+		return errors.New("--image can't be used with --where; add image=... to the --where conditions instead")
+	}
 
 	var machinesToBeDeleted []*fly.Machine
 
@@ -71,27 +78,13 @@ func runMachineDestroy(ctx context.Context) (err error) {
 			return err
 		}
 
-		var ids []string
 		for _, machine := range machines {
 			if machine.ImageRefWithVersion() == image {
 				machinesToBeDeleted = append(machinesToBeDeleted, machine)
-				ids = append(ids, machine.ID)
 			}
 		}
 
-		confirmed, err := prompt.Confirm(ctx,
-			fmt.Sprintf("%d Machines (%s) will be destroyed, continue?",
-				len(machinesToBeDeleted),
-				strings.Join(ids, ","),
-			))
-		if err != nil {
-			return err
-		}
-		if !confirmed {
-			return nil
-		}
-
-	case len(flag.Args(ctx)) == 0:
+	case len(flag.Args(ctx)) == 0 && flag.GetString(ctx, "where") == "":
 		machine, newCtx, err := selectOneMachine(ctx, "", "", false)
 		if err != nil {
 			return err
@@ -108,6 +101,25 @@ func runMachineDestroy(ctx context.Context) (err error) {
 		machinesToBeDeleted = append(machinesToBeDeleted, machines...)
 	}
 
+	if dryRun(ctx, machinesToBeDeleted) {
+		return nil
+	}
+
+	if image != "" || (flag.GetString(ctx, "where") != "" && !flag.GetYes(ctx)) {
+		ids := lo.Map(machinesToBeDeleted, func(m *fly.Machine, _ int) string { return m.ID })
+		confirmed, err := prompt.Confirm(ctx,
+			fmt.Sprintf("%d Machines (%s) will be destroyed, continue?",
+				len(machinesToBeDeleted),
+				strings.Join(ids, ","),
+			))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return nil
+		}
+	}
+
 	if len(machinesToBeDeleted) == 0 {
 		fmt.Fprint(iostreams.FromContext(ctx).Out, "No machine to destroy, exiting\n")
 
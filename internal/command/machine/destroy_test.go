@@ -0,0 +1,48 @@
+package machine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	fly "github.com/superfly/fly-go"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/internal/flapsutil"
+	"github.com/superfly/flyctl/internal/mock"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func destroyTestContext(t *testing.T, flapsClient flapsutil.FlapsClient, args ...string) (context.Context, *iostreams.IOStreams) {
+	cmd := newDestroy()
+	require.NoError(t, cmd.ParseFlags(args))
+
+	ios, _, _, _ := iostreams.Test()
+	ctx := iostreams.NewContext(flag.NewContext(context.Background(), cmd.Flags()), ios)
+	ctx = appconfig.WithName(ctx, "my-app")
+
+	return flapsutil.NewContextWithClient(ctx, flapsClient), ios
+}
+
+func TestDestroyByImage(t *testing.T) {
+	client := &mock.FlapsClient{
+		ListActiveFunc: func(ctx context.Context, appName string) ([]*fly.Machine, error) {
+			return []*fly.Machine{
+				{ID: "m1", ImageRef: fly.MachineImageRef{Registry: "registry.fly.io", Repository: "my-app", Tag: "v1"}},
+				{ID: "m2", ImageRef: fly.MachineImageRef{Registry: "registry.fly.io", Repository: "my-app", Tag: "v2"}},
+			}, nil
+		},
+	}
+
+	ctx, _ := destroyTestContext(t, client, "--image", "my-app:v1", "--where", "region=ord")
+	assert.ErrorContains(t, runMachineDestroy(ctx), "--image can't be used with --where")
+
+	// --dry-run lists the machines without asking to destroy them
+	ctx, ios := destroyTestContext(t, client, "--image", "my-app:v1", "--dry-run")
+	require.NoError(t, runMachineDestroy(ctx))
+	out := ios.Out.(interface{ String() string }).String()
+	assert.Contains(t, out, "1 machine would be selected")
+	assert.Contains(t, out, "m1")
+	assert.NotContains(t, out, "m2")
+}
@@ -0,0 +1,181 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sourcegraph/conc/pool"
+	"github.com/spf13/cobra"
+	fly "github.com/superfly/fly-go"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/command"
+	"github.com/superfly/flyctl/internal/config"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/internal/flapsutil"
+	mach "github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/internal/render"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func newEvents() *cobra.Command {
+	const (
+		short = "Show the event timeline of Fly machines"
+		long  = `Show the events of one or more Fly machines merged into a
+chronological timeline, and point out crash loops, machines killed for
+running out of memory, and slow starts.
+
+With --chrome-trace, also write the timeline in the Chrome trace event format
+to visualize rollouts in chrome://tracing or https://ui.perfetto.dev.
+`
+		usage = "events [flags] [ID ID ...]"
+	)
+
+	cmd := command.New(usage, short, long, runMachineEvents,
+		command.RequireSession,
+		command.LoadAppNameIfPresent,
+	)
+
+	flag.Add(
+		cmd,
+		flag.App(),
+		flag.AppConfig(),
+		flag.JSONOutput(),
+		selectFlag,
+		flag.Bool{
+			Name:        "all",
+			Description: "Show the events of all machines of the app",
+		},
+		flag.Duration{
+			Name:        "slow-start",
+			Description: "Point out machines taking longer than this to start",
+			Default:     30 * time.Second,
+		},
+		flag.String{
+			Name:        "chrome-trace",
+			Description: "Write the timeline to this file in the Chrome trace event format",
+		},
+	)
+
+	cmd.Args = cobra.ArbitraryArgs
+
+	return cmd
+}
+
+func runMachineEvents(ctx context.Context) error {
+	var (
+		io   = iostreams.FromContext(ctx)
+		args = flag.Args(ctx)
+	)
+
+	var (
+		machines []*fly.Machine
+		err      error
+	)
+	if flag.GetBool(ctx, "all") {
+		if len(args) > 0 {
+			return errors.New("machine IDs can't be used with --all")
+		}
+		machines, err = allMachinesWithEvents(ctx)
+	} else {
+		machines, ctx, err = selectManyMachines(ctx, args)
+	}
+	if err != nil {
+		return err
+	}
+
+	timeline := mach.Timeline(machines)
+	findings := mach.AnalyzeTimeline(timeline, flag.GetDuration(ctx, "slow-start"))
+
+	if path := flag.GetString(ctx, "chrome-trace"); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create Chrome trace: %w", err)
+		}
+		defer f.Close()
+
+		if err := mach.WriteChromeTrace(f, timeline, findings); err != nil {
+			return fmt.Errorf("failed to write Chrome trace: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to write Chrome trace: %w", err)
+		}
+	}
+
+	if config.FromContext(ctx).JSONOutput {
+		return render.JSON(io.Out, struct {
+			Events   []mach.TimelineEvent `json:"events"`
+			Findings []mach.EventFinding  `json:"findings"`
+		}{timeline, findings})
+	}
+
+	rows := make([][]string, 0, len(timeline))
+	for _, ev := range timeline {
+		info := ""
+		if ev.Exit != nil {
+			info = fmt.Sprintf("exit_code=%d,oom_killed=%t,requested_stop=%t", ev.Exit.ExitCode, ev.Exit.OOMKilled, ev.Exit.RequestedStop)
+			if ev.RestartCount > 0 {
+				info += fmt.Sprintf(",restart_count=%d", ev.RestartCount)
+			}
+		}
+		rows = append(rows, []string{
+			ev.Time.Format(time.RFC3339Nano),
+			ev.MachineID,
+			ev.Region,
+			ev.ProcessGroup,
+			ev.Type,
+			ev.Status,
+			ev.Source,
+			info,
+		})
+	}
+	if err := render.Table(io.Out, "Events", rows, "Timestamp", "Machine", "Region", "Process Group", "Event", "State", "Source", "Info"); err != nil {
+		return err
+	}
+
+	if len(findings) == 0 {
+		fmt.Fprintln(io.Out, "No crash loops, out of memory exits or slow starts found.")
+
+		return nil
+	}
+
+	rows = make([][]string, 0, len(findings))
+	for _, f := range findings {
+		rows = append(rows, []string{f.Time.Format(time.RFC3339), f.MachineID, f.Kind, f.Detail})
+	}
+
+	return render.Table(io.Out, "Findings", rows, "Timestamp", "Machine", "Kind", "Detail")
+}
+
+// allMachinesWithEvents gets every machine of the app, one by one, since
+// listing machines doesn't return all of their events.
+func allMachinesWithEvents(ctx context.Context) ([]*fly.Machine, error) {
+	appName := appconfig.NameFromContext(ctx)
+	if appName == "" {
+		return nil, errors.New("an app name must be specified to use --all")
+	}
+
+	flapsClient := flapsutil.ClientFromContext(ctx)
+	machines, err := flapsClient.List(ctx, appName, "")
+	if err != nil {
+		return nil, fmt.Errorf("could not get a list of machines: %w", err)
+	}
+
+	getPool := pool.NewWithResults[*fly.Machine]().
+		WithErrors().
+		WithMaxGoroutines(10)
+	for _, m := range machines {
+		getPool.Go(func() (*fly.Machine, error) {
+			machine, err := flapsClient.Get(ctx, appName, m.ID)
+			if err != nil {
+				return nil, fmt.Errorf("could not get machine %s: %w", m.ID, err)
+			}
+
+			return machine, nil
+		})
+	}
+
+	return getPool.Wait()
+}
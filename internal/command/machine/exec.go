@@ -33,6 +33,7 @@ func newMachineExec() *cobra.Command {
 		flag.AppConfig(),
 		flag.JSONOutput(),
 		selectFlag,
+		whereFlags,
 		flag.Int{
 			Name:        "timeout",
 			Description: "Timeout in seconds",
@@ -63,13 +64,16 @@ func runMachineExec(ctx context.Context) (err error) {
 		command = args[0]
 	}
 
-	current, ctx, err := selectOneMachine(ctx, "", machineID, haveMachineID)
+	machines, ctx, err := selectMachines(ctx, machineID, haveMachineID)
 	if err != nil {
 		return err
 	}
+	if dryRun(ctx, machines) {
+		return nil
+	}
 	flapsClient := flapsutil.ClientFromContext(ctx)
 
-	// appName is added to context by selectOneMachine
+	// appName is added to context by selectMachines
 	appName := appconfig.NameFromContext(ctx)
 
 	timeout := flag.GetInt(ctx, "timeout")
@@ -79,15 +83,47 @@ func runMachineExec(ctx context.Context) (err error) {
 		Timeout: timeout,
 	}
 
-	out, err := flapsClient.Exec(ctx, appName, current.ID, in)
-	if err != nil {
-		return fmt.Errorf("could not exec command on machine %s: %w", current.ID, err)
+	if len(machines) == 1 {
+		out, err := flapsClient.Exec(ctx, appName, machines[0].ID, in)
+		if err != nil {
+			return fmt.Errorf("could not exec command on machine %s: %w", machines[0].ID, err)
+		}
+
+		if config.JSONOutput {
+			return render.JSON(io.Out, out)
+		}
+		printExecResponse(io, out)
+
+		return nil
+	}
+
+	type machineExecResponse struct {
+		MachineID string `json:"machine_id"`
+		*fly.MachineExecResponse
+	}
+	var outs []machineExecResponse
+	for _, machine := range machines {
+		out, err := flapsClient.Exec(ctx, appName, machine.ID, in)
+		if err != nil {
+			return fmt.Errorf("could not exec command on machine %s: %w", machine.ID, err)
+		}
+
+		if config.JSONOutput {
+			outs = append(outs, machineExecResponse{MachineID: machine.ID, MachineExecResponse: out})
+			continue
+		}
+		fmt.Fprintf(io.Out, "==> %s (%s)\n", machine.ID, machine.Region)
+		printExecResponse(io, out)
 	}
 
 	if config.JSONOutput {
-		return render.JSON(io.Out, out)
+		return render.JSON(io.Out, outs)
 	}
 
+	return nil
+}
+
+func printExecResponse(io *iostreams.IOStreams, out *fly.MachineExecResponse) {
 	if out.ExitCode != 0 {
 		fmt.Fprintf(io.Out, "Exit code: %d\n", out.ExitCode)
 	}
@@ -98,6 +134,4 @@ func runMachineExec(ctx context.Context) (err error) {
 	if out.StdErr != "" {
 		fmt.Fprint(io.ErrOut, out.StdErr)
 	}
-
-	return
 }
@@ -41,6 +41,9 @@ Machines REST fly.`
 		newSuspend(),
 		newEgressIp(),
 		newPlace(),
+		newEvents(),
+		newTemplate(),
+		newMove(),
 	)
 
 	return cmd
@@ -0,0 +1,320 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+	fly "github.com/superfly/fly-go"
+	"github.com/superfly/flyctl/helpers"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/appsecrets"
+	"github.com/superfly/flyctl/internal/command"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/internal/flapsutil"
+	mach "github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/internal/prompt"
+	"github.com/superfly/flyctl/internal/watch"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func newMove() *cobra.Command {
+	const (
+		short = "Move a Fly Machine and its volumes to another region"
+		long  = `Move a Fly Machine and its volumes to another region.
+
+The machine is stopped, its volumes are forked into the new region, and a copy
+of it is started there with the forked volumes attached. Once the new machine
+passes its health checks, the original machine is destroyed. Its volumes are
+kept unless --destroy-volumes is set, in which case they're destroyed once the
+forks have finished copying them. If any step fails, the new machine and
+volumes are destroyed and the original machine is started again.
+`
+		usage = "move [machine_id]"
+	)
+
+	cmd := command.New(usage, short, long, runMachineMove,
+		command.RequireSession,
+		command.RequireAppName,
+	)
+
+	cmd.Args = cobra.RangeArgs(0, 1)
+
+	flag.Add(
+		cmd,
+		flag.App(),
+		flag.AppConfig(),
+		selectFlag,
+		flag.Yes(),
+		flag.Region(),
+		flag.Bool{
+			Name:        "destroy-volumes",
+			Description: "Destroy the original volumes of the machine once their forks have finished copying them",
+		},
+		flag.Bool{
+			Name:        "skip-health-checks",
+			Description: "Destroy the original machine without waiting for the new one to pass its health checks",
+		},
+		flag.Duration{
+			Name:        "wait-timeout",
+			Description: "How long to wait for the machines to stop and start, and for the forks of the volumes to finish copying them",
+			Default:     5 * time.Minute,
+		},
+	)
+
+	return cmd
+}
+
+// moveVolumePollInterval is how often the forks of the volumes of a moved
+// machine are checked for having finished copying.
+var moveVolumePollInterval = 5 * time.Second
+
+// moveRollback undoes the steps of a move that failed, most recent first.
+type moveRollback struct {
+	steps []func(context.Context) error
+}
+
+func (r *moveRollback) add(step func(context.Context) error) {
+	r.steps = append(r.steps, step)
+}
+
+func (r *moveRollback) run(ctx context.Context) error {
+	// Roll back even if the move was interrupted
+	ctx = context.WithoutCancel(ctx)
+
+	var errs []error
+	for i := len(r.steps) - 1; i >= 0; i-- {
+		if err := r.steps[i](ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func runMachineMove(ctx context.Context) error {
+	var (
+		appName = appconfig.NameFromContext(ctx)
+		region  = flag.GetString(ctx, "region")
+	)
+
+	if region == "" {
+		return errors.New("--region is required")
+	}
+
+	machineID := flag.FirstArg(ctx)
+	haveMachineID := len(flag.Args(ctx)) > 0
+	source, ctx, err := selectOneMachine(ctx, appName, machineID, haveMachineID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case source.HostStatus != fly.HostStatusOk:
+		return fmt.Errorf("the machine is on an unreachable host, try again later")
+	case source.Region == region:
+		return fmt.Errorf("machine %s is already in region %s", source.ID, region)
+	case len(source.Config.Standbys) > 0:
+		return fmt.Errorf("machine %s is a standby; clone the machine it's a standby for instead", source.ID)
+	}
+
+	if !flag.GetYes(ctx) {
+		msg := fmt.Sprintf("Move machine %s from %s to %s? It will be stopped while its volumes are copied.", source.ID, source.Region, region)
+		if confirmed, err := prompt.Confirm(ctx, msg); err != nil || !confirmed {
+			return err
+		}
+	}
+
+	source, releaseLease, err := mach.AcquireLease(ctx, appName, source)
+	defer releaseLease()
+	if err != nil {
+		return err
+	}
+
+	return moveMachine(ctx, appName, source, moveOptions{
+		region:           region,
+		waitTimeout:      flag.GetDuration(ctx, "wait-timeout"),
+		skipHealthChecks: flag.GetBool(ctx, "skip-health-checks"),
+		destroyVolumes:   flag.GetBool(ctx, "destroy-volumes"),
+	})
+}
+
+type moveOptions struct {
+	region           string
+	waitTimeout      time.Duration
+	skipHealthChecks bool
+	destroyVolumes   bool
+}
+
+// moveMachine moves source, which must be leased, to another region, rolling
+// back if the new machine doesn't come up.
+func moveMachine(ctx context.Context, appName string, source *fly.Machine, opts moveOptions) (err error) {
+	var (
+		io          = iostreams.FromContext(ctx)
+		colorize    = io.ColorScheme()
+		flapsClient = flapsutil.ClientFromContext(ctx)
+		region      = opts.region
+		waitTimeout = opts.waitTimeout
+	)
+
+	var rollback moveRollback
+	defer func() {
+		if err == nil || len(rollback.steps) == 0 {
+			return
+		}
+
+		fmt.Fprintf(io.ErrOut, "Failed to move machine %s, rolling back\n", source.ID)
+		if rollbackErr := rollback.run(ctx); rollbackErr != nil {
+			err = fmt.Errorf("%w\nrolling back also failed, check the machines and volumes of the app: %w", err, rollbackErr)
+		}
+	}()
+
+	// Stop the machine so its volumes aren't written to while they're forked
+	if source.State == fly.MachineStateStarted {
+		fmt.Fprintf(io.Out, "Stopping machine %s\n", colorize.Bold(source.ID))
+		if err := flapsClient.Stop(ctx, appName, fly.StopMachineInput{ID: source.ID}, source.LeaseNonce); err != nil {
+			return fmt.Errorf("could not stop machine %s: %w", source.ID, err)
+		}
+		rollback.add(func(ctx context.Context) error {
+			fmt.Fprintf(io.Out, "Starting machine %s again\n", source.ID)
+			_, err := flapsClient.Start(ctx, appName, source.ID, source.LeaseNonce)
+
+			return err
+		})
+
+		if err := mach.WaitForStartOrStop(ctx, appName, source, "stop", waitTimeout); err != nil {
+			return err
+		}
+	}
+
+	targetConfig := helpers.Clone(source.Config)
+	targetConfig.Image = source.FullImageRef()
+
+	var forks []*fly.Volume
+	for i, mnt := range source.Config.Mounts {
+		fmt.Fprintf(io.Out, "Forking volume %s into %s\n", colorize.Bold(mnt.Volume), colorize.Bold(region))
+
+		vol, err := flapsClient.CreateVolume(ctx, appName, fly.CreateVolumeRequest{
+			Name:                mnt.Name,
+			Region:              region,
+			SourceVolumeID:      &mnt.Volume,
+			RequireUniqueZone:   new(false),
+			ComputeRequirements: targetConfig.Guest,
+			ComputeImage:        targetConfig.Image,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to fork volume %s: %w", mnt.Volume, err)
+		}
+		rollback.add(func(ctx context.Context) error {
+			fmt.Fprintf(io.Out, "Destroying volume %s\n", vol.ID)
+			_, err := flapsClient.DeleteVolume(ctx, appName, vol.ID)
+
+			return err
+		})
+
+		targetConfig.Mounts[i].Volume = vol.ID
+		forks = append(forks, vol)
+	}
+
+	minvers, err := appsecrets.GetMinvers(appName)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(io.Out, "Launching a copy of machine %s in %s\n", colorize.Bold(source.ID), colorize.Bold(region))
+	target, err := flapsClient.Launch(ctx, appName, fly.LaunchMachineInput{
+		Region:            region,
+		Config:            targetConfig,
+		SkipLaunch:        source.State != fly.MachineStateStarted,
+		MinSecretsVersion: minvers,
+	})
+	if err != nil {
+		return err
+	}
+	rollback.add(func(ctx context.Context) error {
+		fmt.Fprintf(io.Out, "Destroying machine %s\n", target.ID)
+
+		return flapsClient.Destroy(ctx, appName, fly.RemoveMachineInput{ID: target.ID, Kill: true}, "")
+	})
+
+	if source.State == fly.MachineStateStarted {
+		fmt.Fprintf(io.Out, "Waiting for machine %s to start\n", colorize.Bold(target.ID))
+		if err := mach.WaitForStartOrStop(ctx, appName, target, "start", waitTimeout); err != nil {
+			return err
+		}
+
+		if !opts.skipHealthChecks {
+			if err := watch.MachinesChecks(ctx, appName, []*fly.Machine{target}); err != nil {
+				return fmt.Errorf("error while watching health checks: %w", err)
+			}
+		}
+	}
+
+	// The new machine is up, there's no going back from here
+	rollback = moveRollback{}
+
+	fmt.Fprintf(io.Out, "Destroying machine %s\n", colorize.Bold(source.ID))
+	if err := flapsClient.Destroy(ctx, appName, fly.RemoveMachineInput{ID: source.ID, Kill: true}, source.LeaseNonce); err != nil {
+		return fmt.Errorf("machine %s was moved to %s, but the original machine %s could not be destroyed: %w", target.ID, region, source.ID, err)
+	}
+	runOnDeletionHook(ctx, appName, source)
+
+	switch {
+	case len(forks) == 0:
+	case !opts.destroyVolumes:
+		volumeIDs := lo.Map(source.Config.Mounts, func(mnt fly.MachineMount, _ int) string { return mnt.Volume })
+		fmt.Fprintf(io.Out, "The original volumes %s were kept; destroy them with 'fly volumes destroy' once they're no longer needed\n", strings.Join(volumeIDs, ", "))
+	default:
+		if err := waitForVolumeForks(ctx, appName, forks, waitTimeout); err != nil {
+			return fmt.Errorf("machine %s was moved to %s, but the original volumes were kept: %w", target.ID, region, err)
+		}
+
+		for _, mnt := range source.Config.Mounts {
+			fmt.Fprintf(io.Out, "Destroying volume %s\n", colorize.Bold(mnt.Volume))
+			if _, err := flapsClient.DeleteVolume(ctx, appName, mnt.Volume); err != nil {
+				return fmt.Errorf("machine %s was moved to %s, but the original volume %s could not be destroyed: %w", target.ID, region, mnt.Volume, err)
+			}
+		}
+	}
+
+	fmt.Fprintf(io.Out, "Machine %s has been moved to %s as machine %s\n", source.ID, colorize.Bold(region), colorize.Bold(target.ID))
+
+	return nil
+}
+
+// waitForVolumeForks waits for the forks of volumes to finish copying the
+// data of their source volumes, after which the source volumes can go.
+func waitForVolumeForks(ctx context.Context, appName string, forks []*fly.Volume, timeout time.Duration) error {
+	var (
+		io          = iostreams.FromContext(ctx)
+		flapsClient = flapsutil.ClientFromContext(ctx)
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for _, fork := range forks {
+		fmt.Fprintf(io.Out, "Waiting for volume %s to finish copying\n", fork.ID)
+		for {
+			vol, err := flapsClient.GetVolume(ctx, appName, fork.ID)
+			if err != nil {
+				return fmt.Errorf("failed to get volume %s: %w", fork.ID, err)
+			}
+			if vol.State == "created" {
+				break
+			}
+
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("volume %s is still %s after %s", fork.ID, vol.State, timeout)
+			case <-time.After(moveVolumePollInterval):
+			}
+		}
+	}
+
+	return nil
+}
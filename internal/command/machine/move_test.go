@@ -0,0 +1,208 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	fly "github.com/superfly/fly-go"
+	"github.com/superfly/fly-go/flaps"
+	"github.com/superfly/flyctl/internal/flapsutil"
+	"github.com/superfly/flyctl/internal/mock"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func TestMoveRollback(t *testing.T) {
+	var (
+		rollback moveRollback
+		ran      []string
+	)
+	for _, name := range []string{"start source", "delete fork", "destroy target"} {
+		rollback.add(func(ctx context.Context) error {
+			require.NoError(t, ctx.Err(), "rolling back with a canceled context")
+			ran = append(ran, name)
+			if name != "delete fork" {
+				return errors.New(name + " failed")
+			}
+
+			return nil
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := rollback.run(ctx)
+	assert.Equal(t, []string{"destroy target", "delete fork", "start source"}, ran)
+	assert.EqualError(t, err, "destroy target failed\nstart source failed")
+}
+
+// moveMachines records what a move does to machines and volumes, for a mock
+// flaps client.
+type moveMachines struct {
+	mu             sync.Mutex
+	started        []string
+	destroyed      []string
+	deletedVolumes []string
+}
+
+func (mm *moveMachines) record(list *[]string, id string) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	*list = append(*list, id)
+}
+
+func newMoveTest(t *testing.T) (*moveMachines, *mock.FlapsClient, *fly.Machine) {
+	mm := &moveMachines{}
+	source := &fly.Machine{
+		ID:         "source",
+		Region:     "ord",
+		State:      fly.MachineStateStarted,
+		LeaseNonce: "nonce",
+		Config: &fly.MachineConfig{
+			Image:  "registry.fly.io/my-app:v1",
+			Mounts: []fly.MachineMount{{Volume: "vol_source", Name: "data", Path: "/data"}},
+		},
+	}
+
+	client := &mock.FlapsClient{
+		StopFunc: func(ctx context.Context, appName string, in fly.StopMachineInput, nonce string) error {
+			return nil
+		},
+		StartFunc: func(ctx context.Context, appName, machineID, nonce string) (*fly.MachineStartResponse, error) {
+			mm.record(&mm.started, machineID)
+			return &fly.MachineStartResponse{}, nil
+		},
+		WaitFunc: func(ctx context.Context, appName, machineID string, waitOpts ...flaps.WaitOption) error {
+			return nil
+		},
+		CreateVolumeFunc: func(ctx context.Context, appName string, req fly.CreateVolumeRequest) (*fly.Volume, error) {
+			assert.Equal(t, "vol_source", *req.SourceVolumeID)
+			return &fly.Volume{ID: "vol_fork", Region: req.Region, State: "hydrating"}, nil
+		},
+		DeleteVolumeFunc: func(ctx context.Context, appName, volumeID string) (*fly.Volume, error) {
+			mm.record(&mm.deletedVolumes, volumeID)
+			return &fly.Volume{ID: volumeID}, nil
+		},
+		LaunchFunc: func(ctx context.Context, appName string, input fly.LaunchMachineInput) (*fly.Machine, error) {
+			assert.Equal(t, "vol_fork", input.Config.Mounts[0].Volume)
+			return &fly.Machine{ID: "target", Region: input.Region, Config: input.Config}, nil
+		},
+		DestroyFunc: func(ctx context.Context, appName string, input fly.RemoveMachineInput, nonce string) error {
+			mm.record(&mm.destroyed, input.ID)
+			return nil
+		},
+	}
+
+	return mm, client, source
+}
+
+func moveTestContext(flapsClient flapsutil.FlapsClient) context.Context {
+	ios, _, _, _ := iostreams.Test()
+
+	return flapsutil.NewContextWithClient(iostreams.NewContext(context.Background(), ios), flapsClient)
+}
+
+var testMoveOptions = moveOptions{region: "ams", waitTimeout: time.Second, skipHealthChecks: true}
+
+func TestMoveMachineLaunchFails(t *testing.T) {
+	mm, client, source := newMoveTest(t)
+	client.LaunchFunc = func(ctx context.Context, appName string, input fly.LaunchMachineInput) (*fly.Machine, error) {
+		return nil, errors.New("no capacity")
+	}
+
+	err := moveMachine(moveTestContext(client), "my-app", source, testMoveOptions)
+	assert.EqualError(t, err, "no capacity")
+
+	assert.Equal(t, []string{"source"}, mm.started)
+	assert.Equal(t, []string{"vol_fork"}, mm.deletedVolumes)
+	assert.Empty(t, mm.destroyed)
+}
+
+func TestMoveMachineHealthChecksFail(t *testing.T) {
+	mm, client, source := newMoveTest(t)
+	client.LaunchFunc = func(ctx context.Context, appName string, input fly.LaunchMachineInput) (*fly.Machine, error) {
+		return &fly.Machine{ID: "target", Checks: []*fly.MachineCheckStatus{{Name: "http", Status: "critical"}}}, nil
+	}
+	client.GetManyFunc = func(ctx context.Context, appName string, machineIDs []string) ([]*fly.Machine, error) {
+		return []*fly.Machine{{ID: "target", State: fly.MachineStateStarted, Checks: []*fly.MachineCheckStatus{{Name: "http", Status: "critical"}}}}, nil
+	}
+
+	// The move is interrupted while waiting for the checks to pass
+	ctx, cancel := context.WithTimeout(moveTestContext(client), 100*time.Millisecond)
+	defer cancel()
+
+	opts := testMoveOptions
+	opts.skipHealthChecks = false
+	err := moveMachine(ctx, "my-app", source, opts)
+	assert.ErrorContains(t, err, "error while watching health checks")
+
+	assert.Equal(t, []string{"target"}, mm.destroyed)
+	assert.Equal(t, []string{"vol_fork"}, mm.deletedVolumes)
+	assert.Equal(t, []string{"source"}, mm.started)
+}
+
+func TestMoveMachineDestroySourceFails(t *testing.T) {
+	mm, client, source := newMoveTest(t)
+	client.DestroyFunc = func(ctx context.Context, appName string, input fly.RemoveMachineInput, nonce string) error {
+		mm.record(&mm.destroyed, input.ID)
+		return errors.New("host unreachable")
+	}
+
+	opts := testMoveOptions
+	opts.destroyVolumes = true
+	err := moveMachine(moveTestContext(client), "my-app", source, opts)
+	assert.EqualError(t, err, "machine target was moved to ams, but the original machine source could not be destroyed: host unreachable")
+
+	// The new machine is up, so nothing is rolled back
+	assert.Equal(t, []string{"source"}, mm.destroyed)
+	assert.Empty(t, mm.started)
+	assert.Empty(t, mm.deletedVolumes)
+}
+
+func TestMoveMachineVolumes(t *testing.T) {
+	defer func(interval time.Duration) { moveVolumePollInterval = interval }(moveVolumePollInterval)
+	moveVolumePollInterval = time.Millisecond
+
+	// The original volumes are kept by default
+	mm, client, source := newMoveTest(t)
+	require.NoError(t, moveMachine(moveTestContext(client), "my-app", source, testMoveOptions))
+	assert.Equal(t, []string{"source"}, mm.destroyed)
+	assert.Empty(t, mm.deletedVolumes)
+
+	// With --destroy-volumes, they're destroyed once their forks are copied
+	mm, client, source = newMoveTest(t)
+	polls := 0
+	client.GetVolumeFunc = func(ctx context.Context, appName, volumeID string) (*fly.Volume, error) {
+		assert.Empty(t, mm.deletedVolumes, "source volume destroyed while its fork is copying")
+		polls++
+		if polls < 3 {
+			return &fly.Volume{ID: volumeID, State: "hydrating"}, nil
+		}
+
+		return &fly.Volume{ID: volumeID, State: "created"}, nil
+	}
+
+	opts := testMoveOptions
+	opts.destroyVolumes = true
+	require.NoError(t, moveMachine(moveTestContext(client), "my-app", source, opts))
+	assert.Equal(t, 3, polls)
+	assert.Equal(t, []string{"vol_source"}, mm.deletedVolumes)
+
+	// Forks that don't finish copying in time keep the original volumes
+	mm, client, source = newMoveTest(t)
+	client.GetVolumeFunc = func(ctx context.Context, appName, volumeID string) (*fly.Volume, error) {
+		return &fly.Volume{ID: volumeID, State: "hydrating"}, nil
+	}
+
+	opts.waitTimeout = 20 * time.Millisecond
+	err := moveMachine(moveTestContext(client), "my-app", source, opts)
+	assert.EqualError(t, err, "machine target was moved to ams, but the original volumes were kept: volume vol_fork is still hydrating after 20ms")
+	assert.Empty(t, mm.deletedVolumes)
+	assert.Empty(t, mm.started)
+}
@@ -34,6 +34,7 @@ func newRestart() *cobra.Command {
 		flag.App(),
 		flag.AppConfig(),
 		selectFlag,
+		whereFlags,
 		flag.String{
 			Name:        "signal",
 			Shorthand:   "s",
@@ -76,6 +77,9 @@ func runMachineRestart(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	if dryRun(ctx, machines) {
+		return nil
+	}
 
 	// appName is added to context by selectManyMachines
 	appName := appconfig.NameFromContext(ctx)
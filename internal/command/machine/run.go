@@ -200,6 +200,10 @@ var runOrCreateFlags = flag.Set{
 		Name:        "use-zstd",
 		Description: "Enable zstd compression for the image",
 	},
+	flag.String{
+		Name:        "template",
+		Description: "Start from a machine template saved with 'fly machine template save', as NAME or NAME@VERSION. The image argument and other flags override it.",
+	},
 }
 
 func soManyErrors(args ...any) error {
@@ -303,7 +307,7 @@ func newCreate() *cobra.Command {
 		sharedFlags,
 	)
 
-	cmd.Args = cobra.MinimumNArgs(1)
+	cmd.Args = cobra.MinimumNArgs(0)
 
 	return cmd
 }
@@ -393,6 +397,23 @@ func runMachineRun(ctx context.Context) error {
 		},
 	}
 
+	if ref := flag.GetString(ctx, "template"); ref != "" {
+		tmpl, err := getTemplate(ctx, ref)
+		if err != nil {
+			return err
+		}
+		if tmpl.Config != nil {
+			machineConf = mach.CloneConfig(tmpl.Config)
+		}
+		machineConf.AutoDestroy = machineConf.AutoDestroy || destroy
+		if flag.GetBool(ctx, "skip-dns-registration") {
+			if machineConf.DNS == nil {
+				machineConf.DNS = &fly.DNSConfig{}
+			}
+			machineConf.DNS.SkipRegistration = true
+		}
+	}
+
 	minvers, err := appsecrets.GetMinvers(app.Name)
 	if err != nil {
 		return err
@@ -430,10 +451,16 @@ func runMachineRun(ctx context.Context) error {
 		return err
 	}
 
-	if imageOrPath == "" && len(machineConf.Containers) == 0 {
+	if imageOrPath == "" && machineConf.Image == "" && len(machineConf.Containers) == 0 {
 		return fmt.Errorf("image argument can't be an empty string")
 	}
 
+	for _, mount := range machineConf.Mounts {
+		if mount.Volume == "" {
+			return fmt.Errorf("no volume to mount at %s; attach one with --volume <volume_id_or_name>:%s", mount.Path, mount.Path)
+		}
+	}
+
 	if flag.GetBool(ctx, "build-only") {
 		return nil
 	}
@@ -12,7 +12,9 @@ import (
 	"github.com/superfly/flyctl/internal/flag"
 	"github.com/superfly/flyctl/internal/flapsutil"
 	"github.com/superfly/flyctl/internal/flyutil"
+	mach "github.com/superfly/flyctl/internal/machine"
 	"github.com/superfly/flyctl/internal/prompt"
+	"github.com/superfly/flyctl/internal/render"
 	"github.com/superfly/flyctl/iostreams"
 )
 
@@ -24,6 +26,19 @@ var selectFlag = flag.Bool{
 	Hidden:      true,
 }
 
+// whereFlags select machines by their fields instead of by ID, see
+// mach.Selector, and list them instead of acting on them with --dry-run.
+var whereFlags = flag.Set{
+	flag.String{
+		Name:        "where",
+		Description: "Select machines matching comma separated conditions, like 'region=ord|ams,process_group=web,state!=started,metadata.role=primary,image~=v42'",
+	},
+	flag.Bool{
+		Name:        "dry-run",
+		Description: "List the selected machines without acting on them",
+	},
+}
+
 func selectOneMachine(ctx context.Context, appName string, machineID string, haveMachineID bool) (*fly.Machine, context.Context, error) {
 	if err := checkSelectConditions(ctx, haveMachineID); err != nil {
 		return nil, nil, err
@@ -63,6 +78,10 @@ func selectOneMachine(ctx context.Context, appName string, machineID string, hav
 }
 
 func selectManyMachines(ctx context.Context, machineIDs []string) ([]*fly.Machine, context.Context, error) {
+	if where := flag.GetString(ctx, "where"); where != "" {
+		return selectMachinesWhere(ctx, where, machineIDs)
+	}
+
 	haveMachineIDs := len(machineIDs) > 0
 	if err := checkSelectConditions(ctx, haveMachineIDs); err != nil {
 		return nil, nil, err
@@ -99,6 +118,88 @@ func selectManyMachines(ctx context.Context, machineIDs []string) ([]*fly.Machin
 	return machines, ctx, nil
 }
 
+// selectMachines selects the machines a command that acts on one machine at a
+// time should act on: the machines matching --where, or else machineID, or a
+// machine picked from a prompt.
+func selectMachines(ctx context.Context, machineID string, haveMachineID bool) ([]*fly.Machine, context.Context, error) {
+	if flag.GetString(ctx, "where") != "" {
+		var machineIDs []string
+		if haveMachineID {
+			machineIDs = append(machineIDs, machineID)
+		}
+
+		return selectManyMachines(ctx, machineIDs)
+	}
+
+	machine, ctx, err := selectOneMachine(ctx, "", machineID, haveMachineID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return []*fly.Machine{machine}, ctx, nil
+}
+
+func selectMachinesWhere(ctx context.Context, where string, machineIDs []string) ([]*fly.Machine, context.Context, error) {
+	appName := appconfig.NameFromContext(ctx)
+	switch {
+	case len(machineIDs) > 0:
+		return nil, nil, errors.New("machine IDs can't be used with --where")
+	case flag.GetBool(ctx, "select"):
+		return nil, nil, errors.New("--select can't be used with --where")
+	case appName == "":
+		return nil, nil, errors.New("an app name must be specified to use --where")
+	}
+
+	selector, err := mach.ParseSelector(where)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	machines, err := flapsutil.ClientFromContext(ctx).List(ctx, appName, "")
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not get a list of machines: %w", err)
+	}
+
+	machines = selector.Filter(machines)
+	if len(machines) == 0 {
+		return nil, nil, fmt.Errorf("no machines of app %s match '%s'", appName, selector)
+	}
+	sort.Slice(machines, func(i, j int) bool {
+		return machines[i].ID < machines[j].ID
+	})
+
+	return machines, ctx, nil
+}
+
+// dryRun lists machines and returns true if --dry-run is set, in which case
+// the command should stop without acting on them.
+func dryRun(ctx context.Context, machines []*fly.Machine) bool {
+	if !flag.GetBool(ctx, "dry-run") {
+		return false
+	}
+
+	rows := make([][]string, 0, len(machines))
+	for _, machine := range machines {
+		rows = append(rows, []string{
+			machine.ID,
+			machine.Name,
+			machine.State,
+			machine.Region,
+			machine.ProcessGroup(),
+			machine.FullImageRef(),
+		})
+	}
+
+	out := iostreams.FromContext(ctx).Out
+	title := fmt.Sprintf("%d machines would be selected", len(machines))
+	if len(machines) == 1 {
+		title = "1 machine would be selected"
+	}
+	render.Table(out, title, rows, "ID", "Name", "State", "Region", "Process Group", "Image")
+
+	return true
+}
+
 func selectManyMachineIDs(ctx context.Context, machineIDs []string) ([]string, context.Context, error) {
 	haveMachineIDs := len(machineIDs) > 0
 	if err := checkSelectConditions(ctx, haveMachineIDs); err != nil {
@@ -35,6 +35,7 @@ func newStart() *cobra.Command {
 		flag.App(),
 		flag.AppConfig(),
 		selectFlag,
+		whereFlags,
 	)
 
 	return cmd
@@ -50,6 +51,9 @@ func runMachineStart(ctx context.Context) (err error) {
 	if err != nil {
 		return err
 	}
+	if dryRun(ctx, machines) {
+		return nil
+	}
 
 	// appName is added to context by selectManyMachines
 	appName := appconfig.NameFromContext(ctx)
@@ -37,6 +37,7 @@ func newStop() *cobra.Command {
 		flag.App(),
 		flag.AppConfig(),
 		selectFlag,
+		whereFlags,
 		flag.String{
 			Name:        "signal",
 			Shorthand:   "s",
@@ -69,6 +70,9 @@ func runMachineStop(ctx context.Context) (err error) {
 	if err != nil {
 		return err
 	}
+	if dryRun(ctx, machines) {
+		return nil
+	}
 
 	// appName is added to context by selectManyMachines
 	appName := appconfig.NameFromContext(ctx)
@@ -0,0 +1,390 @@
+package machine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	fly "github.com/superfly/fly-go"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/command"
+	"github.com/superfly/flyctl/internal/config"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/internal/render"
+	"github.com/superfly/flyctl/internal/state"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func newTemplate() *cobra.Command {
+	const (
+		short = "Manage machine templates"
+		long  = `Save machine configurations as named templates to run machines from with
+'fly machine run --template NAME'. Templates are kept in the flyctl config
+directory, and every change to one is saved as a new version.
+`
+		usage = "template <command>"
+	)
+
+	cmd := command.New(usage, short, long, nil)
+
+	cmd.Aliases = []string{"templates"}
+
+	cmd.Args = cobra.NoArgs
+
+	cmd.AddCommand(
+		newTemplateSave(),
+		newTemplateList(),
+		newTemplateShow(),
+		newTemplateEdit(),
+		newTemplateHistory(),
+		newTemplateDelete(),
+	)
+
+	return cmd
+}
+
+func newTemplateSave() *cobra.Command {
+	const (
+		short = "Save the configuration of a machine as a template"
+		long  = short + `. The configuration is saved as the next version of the
+template, without the volumes and release of the machine.
+`
+		usage = "save <name>"
+	)
+
+	cmd := command.New(usage, short, long, runTemplateSave,
+		command.RequireSession,
+		command.LoadAppNameIfPresent,
+	)
+
+	cmd.Args = cobra.ExactArgs(1)
+
+	flag.Add(
+		cmd,
+		flag.App(),
+		flag.AppConfig(),
+		flag.String{
+			Name:        "from",
+			Description: "ID of the machine to save the configuration of",
+		},
+		flag.String{
+			Name:        "machine-config",
+			Description: "Machine configuration to save, as a JSON string or a path to a .json file, instead of the configuration of a machine",
+		},
+	)
+
+	return cmd
+}
+
+func runTemplateSave(ctx context.Context) error {
+	var (
+		io    = iostreams.FromContext(ctx)
+		name  = flag.FirstArg(ctx)
+		from  = flag.GetString(ctx, "from")
+		mc    = flag.GetString(ctx, "machine-config")
+		store = newTemplateStore(state.ConfigDirectory(ctx))
+	)
+
+	if err := validateTemplateName(name); err != nil {
+		return err
+	}
+
+	var (
+		machineConf = &fly.MachineConfig{}
+		source      string
+	)
+	switch {
+	case mc != "" && from != "":
+		return errors.New("--from and --machine-config can't be used together")
+	case mc != "":
+		if err := config.ParseConfig(machineConf, mc); err != nil {
+			return err
+		}
+		source = "machine config"
+	default:
+		machine, ctx, err := selectOneMachine(ctx, "", from, from != "")
+		if err != nil {
+			return err
+		}
+		machineConf = templateConfig(machine)
+		source = fmt.Sprintf("machine %s of app %s", machine.ID, appconfig.NameFromContext(ctx))
+	}
+
+	tmpl, err := store.Save(name, source, machineConf)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(io.Out, "Saved version %d of machine template %s from %s\n", tmpl.Version, tmpl.Name, source)
+
+	return nil
+}
+
+func newTemplateList() *cobra.Command {
+	const (
+		short = "List machine templates"
+		long  = short + "\n"
+		usage = "list"
+	)
+
+	cmd := command.New(usage, short, long, runTemplateList)
+
+	cmd.Aliases = []string{"ls"}
+
+	cmd.Args = cobra.NoArgs
+
+	flag.Add(cmd, flag.JSONOutput())
+
+	return cmd
+}
+
+func runTemplateList(ctx context.Context) error {
+	io := iostreams.FromContext(ctx)
+
+	templates, err := newTemplateStore(state.ConfigDirectory(ctx)).List()
+	if err != nil {
+		return err
+	}
+
+	if config.FromContext(ctx).JSONOutput {
+		return render.JSON(io.Out, templates)
+	}
+
+	if len(templates) == 0 {
+		fmt.Fprintln(io.Out, "No machine templates saved yet. Save one with 'fly machine template save NAME --from MACHINE_ID'.")
+
+		return nil
+	}
+
+	rows := make([][]string, 0, len(templates))
+	for _, tmpl := range templates {
+		rows = append(rows, templateRow(tmpl))
+	}
+
+	return render.Table(io.Out, "", rows, "Name", "Version", "Saved", "Image", "Size", "Source")
+}
+
+func templateRow(tmpl *machineTemplate) []string {
+	image, size := "", ""
+	if tmpl.Config != nil {
+		image = tmpl.Config.Image
+		if tmpl.Config.Guest != nil {
+			size = tmpl.Config.Guest.ToSize()
+		}
+	}
+
+	return []string{
+		tmpl.Name,
+		fmt.Sprint(tmpl.Version),
+		tmpl.SavedAt.Format(time.RFC3339),
+		image,
+		size,
+		tmpl.Source,
+	}
+}
+
+func newTemplateShow() *cobra.Command {
+	const (
+		short = "Show the configuration of a machine template"
+		long  = short + `. NAME@VERSION shows an older version.
+`
+		usage = "show <name>[@version]"
+	)
+
+	cmd := command.New(usage, short, long, runTemplateShow)
+
+	cmd.Args = cobra.ExactArgs(1)
+
+	return cmd
+}
+
+func runTemplateShow(ctx context.Context) error {
+	tmpl, err := getTemplate(ctx, flag.FirstArg(ctx))
+	if err != nil {
+		return err
+	}
+
+	return render.JSON(iostreams.FromContext(ctx).Out, tmpl)
+}
+
+func newTemplateEdit() *cobra.Command {
+	const (
+		short = "Edit the configuration of a machine template"
+		long  = short + ` in $VISUAL or $EDITOR, and save it as a new version.
+NAME@VERSION starts from an older version.
+`
+		usage = "edit <name>[@version]"
+	)
+
+	cmd := command.New(usage, short, long, runTemplateEdit)
+
+	cmd.Args = cobra.ExactArgs(1)
+
+	return cmd
+}
+
+func runTemplateEdit(ctx context.Context) error {
+	io := iostreams.FromContext(ctx)
+
+	tmpl, err := getTemplate(ctx, flag.FirstArg(ctx))
+	if err != nil {
+		return err
+	}
+
+	original, err := json.MarshalIndent(tmpl.Config, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	edited, err := editInEditor(ctx, tmpl.Name+"-*.json", original)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(bytes.TrimSpace(edited), bytes.TrimSpace(original)) {
+		fmt.Fprintf(io.Out, "No changes to machine template %s\n", tmpl.Name)
+
+		return nil
+	}
+
+	var machineConf fly.MachineConfig
+	decoder := json.NewDecoder(bytes.NewReader(edited))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&machineConf); err != nil {
+		return fmt.Errorf("invalid machine config, machine template %s is unchanged: %w", tmpl.Name, err)
+	}
+
+	saved, err := newTemplateStore(state.ConfigDirectory(ctx)).Save(tmpl.Name, fmt.Sprintf("edit of version %d", tmpl.Version), &machineConf)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(io.Out, "Saved version %d of machine template %s\n", saved.Version, saved.Name)
+
+	return nil
+}
+
+// editInEditor lets the user edit data in their editor, and returns the
+// result.
+func editInEditor(ctx context.Context, pattern string, data []byte) ([]byte, error) {
+	io := iostreams.FromContext(ctx)
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	args := append(strings.Fields(editor), f.Name())
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = io.In
+	cmd.Stdout = io.Out
+	cmd.Stderr = io.ErrOut
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("editor %s failed: %w", editor, err)
+	}
+
+	return os.ReadFile(f.Name())
+}
+
+func newTemplateHistory() *cobra.Command {
+	const (
+		short = "List the versions of a machine template"
+		long  = short + "\n"
+		usage = "history <name>"
+	)
+
+	cmd := command.New(usage, short, long, runTemplateHistory)
+
+	cmd.Args = cobra.ExactArgs(1)
+
+	flag.Add(cmd, flag.JSONOutput())
+
+	return cmd
+}
+
+func runTemplateHistory(ctx context.Context) error {
+	io := iostreams.FromContext(ctx)
+
+	name := flag.FirstArg(ctx)
+	if err := validateTemplateName(name); err != nil {
+		return err
+	}
+
+	history, err := newTemplateStore(state.ConfigDirectory(ctx)).History(name)
+	if err != nil {
+		return err
+	}
+
+	if config.FromContext(ctx).JSONOutput {
+		return render.JSON(io.Out, history)
+	}
+
+	rows := make([][]string, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		rows = append(rows, templateRow(history[i]))
+	}
+
+	return render.Table(io.Out, "", rows, "Name", "Version", "Saved", "Image", "Size", "Source")
+}
+
+func newTemplateDelete() *cobra.Command {
+	const (
+		short = "Delete a machine template and all of its versions"
+		long  = short + "\n"
+		usage = "delete <name>"
+	)
+
+	cmd := command.New(usage, short, long, runTemplateDelete)
+
+	cmd.Aliases = []string{"rm"}
+
+	cmd.Args = cobra.ExactArgs(1)
+
+	return cmd
+}
+
+func runTemplateDelete(ctx context.Context) error {
+	name := flag.FirstArg(ctx)
+	if err := newTemplateStore(state.ConfigDirectory(ctx)).Delete(name); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(iostreams.FromContext(ctx).Out, "Deleted machine template %s\n", name)
+
+	return nil
+}
+
+// getTemplate gets the template ref refers to, like "gpu-worker" or
+// "gpu-worker@3".
+func getTemplate(ctx context.Context, ref string) (*machineTemplate, error) {
+	name, version, err := parseTemplateRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTemplateStore(state.ConfigDirectory(ctx)).Get(name, version)
+}
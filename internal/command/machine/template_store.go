@@ -0,0 +1,247 @@
+package machine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	fly "github.com/superfly/fly-go"
+	mach "github.com/superfly/flyctl/internal/machine"
+)
+
+// machineTemplate is a version of a named machine configuration saved
+// locally, to run machines from with `fly machine run --template`.
+type machineTemplate struct {
+	Name    string    `json:"name"`
+	Version int       `json:"version"`
+	SavedAt time.Time `json:"saved_at"`
+	// Source describes where the configuration came from, like a machine.
+	Source string             `json:"source,omitempty"`
+	Config *fly.MachineConfig `json:"config"`
+}
+
+var templateNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// errTemplateNotFound is returned for templates, or versions of them, that
+// don't exist.
+var errTemplateNotFound = errors.New("machine template not found")
+
+// templateStore keeps every version of machine templates in a directory, as
+// <name>/<version>.json.
+type templateStore struct {
+	dir string
+}
+
+func newTemplateStore(configDir string) *templateStore {
+	return &templateStore{dir: filepath.Join(configDir, "machine-templates")}
+}
+
+func validateTemplateName(name string) error {
+	if !templateNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid machine template name %q; use lowercase letters, digits, '-' and '_'", name)
+	}
+
+	return nil
+}
+
+// parseTemplateRef splits a template reference like "gpu-worker@3" into a
+// name and a version, which is 0 for the latest version.
+func parseTemplateRef(ref string) (string, int, error) {
+	name, v, hasVersion := strings.Cut(ref, "@")
+	if err := validateTemplateName(name); err != nil {
+		return "", 0, err
+	}
+	if !hasVersion {
+		return name, 0, nil
+	}
+
+	version, err := strconv.Atoi(strings.TrimPrefix(v, "v"))
+	if err != nil || version < 1 {
+		return "", 0, fmt.Errorf("invalid version %q of machine template %s", v, name)
+	}
+
+	return name, version, nil
+}
+
+// Save saves config as the next version of the template name.
+func (s *templateStore) Save(name, source string, config *fly.MachineConfig) (*machineTemplate, error) {
+	versions, err := s.Versions(name)
+	if err != nil && !errors.Is(err, errTemplateNotFound) {
+		return nil, err
+	}
+
+	tmpl := &machineTemplate{
+		Name:    name,
+		Version: 1,
+		SavedAt: time.Now().UTC(),
+		Source:  source,
+		Config:  config,
+	}
+	if len(versions) > 0 {
+		tmpl.Version = versions[len(versions)-1] + 1
+	}
+
+	data, err := json.MarshalIndent(tmpl, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Join(s.dir, name), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to save machine template %s: %w", name, err)
+	}
+	path := filepath.Join(s.dir, name, fmt.Sprintf("%d.json", tmpl.Version))
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to save machine template %s: %w", name, err)
+	}
+
+	return tmpl, nil
+}
+
+// Versions returns the versions of the template name in ascending order.
+func (s *templateStore) Versions(name string) ([]int, error) {
+	if err := validateTemplateName(name); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(s.dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", errTemplateNotFound, name)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read machine template %s: %w", name, err)
+	}
+
+	var versions []int
+	for _, entry := range entries {
+		v, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil || entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: %s", errTemplateNotFound, name)
+	}
+	slices.Sort(versions)
+
+	return versions, nil
+}
+
+// Get returns a version of the template name, or its latest version if
+// version is 0.
+func (s *templateStore) Get(name string, version int) (*machineTemplate, error) {
+	if err := validateTemplateName(name); err != nil {
+		return nil, err
+	}
+
+	if version == 0 {
+		versions, err := s.Versions(name)
+		if err != nil {
+			return nil, err
+		}
+		version = versions[len(versions)-1]
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.dir, name, fmt.Sprintf("%d.json", version)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s@%d", errTemplateNotFound, name, version)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read machine template %s: %w", name, err)
+	}
+
+	var tmpl machineTemplate
+	if err := json.Unmarshal(data, &tmpl); err != nil {
+		return nil, fmt.Errorf("failed to parse machine template %s@%d: %w", name, version, err)
+	}
+
+	return &tmpl, nil
+}
+
+// History returns every version of the template name, oldest first.
+func (s *templateStore) History(name string) ([]*machineTemplate, error) {
+	versions, err := s.Versions(name)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]*machineTemplate, 0, len(versions))
+	for _, v := range versions {
+		tmpl, err := s.Get(name, v)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, tmpl)
+	}
+
+	return history, nil
+}
+
+// List returns the latest version of every template, by name.
+func (s *templateStore) List() ([]*machineTemplate, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read machine templates: %w", err)
+	}
+
+	var templates []*machineTemplate
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		tmpl, err := s.Get(entry.Name(), 0)
+		if errors.Is(err, errTemplateNotFound) || validateTemplateName(entry.Name()) != nil {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		templates = append(templates, tmpl)
+	}
+
+	return templates, nil
+}
+
+// Delete deletes every version of the template name.
+func (s *templateStore) Delete(name string) error {
+	if _, err := s.Versions(name); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(filepath.Join(s.dir, name)); err != nil {
+		return fmt.Errorf("failed to delete machine template %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// templateConfig returns the configuration of machine without what only
+// makes sense for that machine, like its volumes and release.
+func templateConfig(machine *fly.Machine) *fly.MachineConfig {
+	config := mach.CloneConfig(machine.Config)
+	if config == nil {
+		return &fly.MachineConfig{}
+	}
+
+	for i := range config.Mounts {
+		config.Mounts[i].Volume = ""
+	}
+	for _, key := range []string{
+		fly.MachineConfigMetadataKeyFlyReleaseId,
+		fly.MachineConfigMetadataKeyFlyReleaseVersion,
+		fly.MachineConfigMetadataKeyFlyctlVersion,
+		fly.MachineConfigMetadataKeyFlyPreviousAlloc,
+		fly.MachineConfigMetadataKeyFlyctlBGTag,
+	} {
+		delete(config.Metadata, key)
+	}
+
+	return config
+}
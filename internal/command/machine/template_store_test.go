@@ -0,0 +1,94 @@
+package machine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	fly "github.com/superfly/fly-go"
+)
+
+func TestTemplateStore(t *testing.T) {
+	store := newTemplateStore(t.TempDir())
+
+	templates, err := store.List()
+	require.NoError(t, err)
+	assert.Empty(t, templates)
+
+	_, err = store.Get("gpu-worker", 0)
+	assert.ErrorIs(t, err, errTemplateNotFound)
+
+	v1, err := store.Save("gpu-worker", "machine 1", &fly.MachineConfig{Image: "worker:1"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, v1.Version)
+
+	v2, err := store.Save("gpu-worker", "edit of version 1", &fly.MachineConfig{Image: "worker:2"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, v2.Version)
+
+	_, err = store.Save("web", "machine 2", &fly.MachineConfig{Image: "web:1"})
+	require.NoError(t, err)
+
+	latest, err := store.Get("gpu-worker", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "worker:2", latest.Config.Image)
+
+	old, err := store.Get("gpu-worker", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "worker:1", old.Config.Image)
+	assert.Equal(t, "machine 1", old.Source)
+
+	history, err := store.History("gpu-worker")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, 1, history[0].Version)
+
+	templates, err = store.List()
+	require.NoError(t, err)
+	require.Len(t, templates, 2)
+	assert.Equal(t, "gpu-worker", templates[0].Name)
+	assert.Equal(t, 2, templates[0].Version)
+
+	require.NoError(t, store.Delete("gpu-worker"))
+	_, err = store.Get("gpu-worker", 0)
+	assert.ErrorIs(t, err, errTemplateNotFound)
+	assert.ErrorIs(t, store.Delete("gpu-worker"), errTemplateNotFound)
+
+	_, err = store.Save("../escape", "", &fly.MachineConfig{})
+	assert.ErrorContains(t, err, "invalid machine template name")
+	assert.ErrorContains(t, store.Delete(".."), "invalid machine template name")
+}
+
+func TestParseTemplateRef(t *testing.T) {
+	name, version, err := parseTemplateRef("gpu-worker")
+	require.NoError(t, err)
+	assert.Equal(t, "gpu-worker", name)
+	assert.Zero(t, version)
+
+	name, version, err = parseTemplateRef("gpu-worker@v3")
+	require.NoError(t, err)
+	assert.Equal(t, "gpu-worker", name)
+	assert.Equal(t, 3, version)
+
+	_, _, err = parseTemplateRef("gpu-worker@0")
+	assert.Error(t, err)
+	_, _, err = parseTemplateRef("GPU")
+	assert.Error(t, err)
+}
+
+func TestTemplateConfig(t *testing.T) {
+	machine := &fly.Machine{Config: &fly.MachineConfig{
+		Image:  "worker:1",
+		Mounts: []fly.MachineMount{{Volume: "vol_123", Path: "/data"}},
+		Metadata: map[string]string{
+			fly.MachineConfigMetadataKeyFlyProcessGroup:   "worker",
+			fly.MachineConfigMetadataKeyFlyReleaseVersion: "7",
+		},
+	}}
+
+	config := templateConfig(machine)
+	assert.Equal(t, []fly.MachineMount{{Path: "/data"}}, config.Mounts)
+	assert.Equal(t, map[string]string{fly.MachineConfigMetadataKeyFlyProcessGroup: "worker"}, config.Metadata)
+	// The machine is left alone
+	assert.Equal(t, "vol_123", machine.Config.Mounts[0].Volume)
+}
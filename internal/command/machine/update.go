@@ -3,9 +3,12 @@ package machine
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	fly "github.com/superfly/fly-go"
 	"github.com/superfly/flyctl/iostreams"
@@ -37,6 +40,7 @@ func newUpdate() *cobra.Command {
 		sharedFlags,
 		flag.Yes(),
 		selectFlag,
+		whereFlags,
 		flag.Bool{
 			Name:        "skip-start",
 			Description: "Updates machine without starting it.",
@@ -66,6 +70,10 @@ func newUpdate() *cobra.Command {
 			Description: "Container to update with the new image, files, etc; defaults to \"app\" or the first container in the config.",
 			Hidden:      false,
 		},
+		flag.String{
+			Name:        "patch",
+			Description: "Path to a JSON Patch (RFC 6902) or JSON merge patch (RFC 7396) to apply to the config of every selected machine, or '-' to read it from stdin. Machines are updated one at a time.",
+		},
 		flag.BuildkitAddr(),
 		flag.BuildkitImage(),
 		flag.Buildkit(),
@@ -78,31 +86,28 @@ func newUpdate() *cobra.Command {
 
 func runUpdate(ctx context.Context) (err error) {
 	var (
-		io               = iostreams.FromContext(ctx)
-		autoConfirm      = flag.GetBool(ctx, "yes")
-		skipHealthChecks = flag.GetBool(ctx, "skip-health-checks")
-		skipStart        = flag.GetBool(ctx, "skip-start")
-		image            = flag.GetString(ctx, "image")
-		dockerfile       = flag.GetString(ctx, flag.Dockerfile().Name)
+		image      = flag.GetString(ctx, "image")
+		dockerfile = flag.GetString(ctx, flag.Dockerfile().Name)
 	)
 
 	machineID := flag.FirstArg(ctx)
 	haveMachineID := len(flag.Args(ctx)) > 0
-	machine, ctx, err := selectOneMachine(ctx, "", machineID, haveMachineID)
+	machines, ctx, err := selectMachines(ctx, machineID, haveMachineID)
 	if err != nil {
 		return err
 	}
 	appName := appconfig.NameFromContext(ctx)
 
-	if machine.HostStatus != fly.HostStatusOk {
-		return fmt.Errorf("the machine is on an unreachable host, try again later")
+	if patch := flag.GetString(ctx, "patch"); patch != "" {
+		if err := checkUpdatePatchFlags(ctx); err != nil {
+			return err
+		}
+
+		return runUpdatePatch(ctx, appName, machines, patch)
 	}
 
-	// Acquire lease
-	machine, releaseLeaseFunc, err := mach.AcquireLease(ctx, appName, machine)
-	defer releaseLeaseFunc()
-	if err != nil {
-		return err
+	if dryRun(ctx, machines) {
+		return nil
 	}
 
 	var imageOrPath string
@@ -112,6 +117,69 @@ func runUpdate(ctx context.Context) (err error) {
 		imageOrPath = "."
 	}
 
+	// Build the image once when updating several machines
+	if imageOrPath == "." && len(machines) > 1 {
+		img, err := command.DetermineImage(ctx, appName, imageOrPath)
+		if err != nil {
+			return err
+		}
+		imageOrPath = img.String()
+	}
+
+	for _, machine := range machines {
+		if err := updateMachine(ctx, appName, machine, imageOrPath); err != nil {
+			if len(machines) > 1 {
+				return fmt.Errorf("failed to update machine %s: %w", machine.ID, err)
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updatePatchFlags are the flags of fly machine update that go with --patch.
+// The others change the config of machines, which is up to the patch.
+var updatePatchFlags = []string{
+	"patch", "app", "config", "yes", "select", "where", "dry-run",
+	"skip-start", "skip-health-checks", "wait-timeout",
+}
+
+// checkUpdatePatchFlags fails if flags that --patch would ignore are set.
+func checkUpdatePatchFlags(ctx context.Context) error {
+	var ignored []string
+	command.FromContext(ctx).LocalFlags().VisitAll(func(f *pflag.Flag) {
+		if f.Changed && !slices.Contains(updatePatchFlags, f.Name) {
+			ignored = append(ignored, "--"+f.Name)
+		}
+	})
+	if len(ignored) > 0 {
+		return fmt.Errorf("--patch can't be used with %s; put the changes in the patch instead", strings.Join(ignored, ", "))
+	}
+
+	return nil
+}
+
+func updateMachine(ctx context.Context, appName string, machine *fly.Machine, imageOrPath string) error {
+	var (
+		io               = iostreams.FromContext(ctx)
+		autoConfirm      = flag.GetBool(ctx, "yes")
+		skipHealthChecks = flag.GetBool(ctx, "skip-health-checks")
+		skipStart        = flag.GetBool(ctx, "skip-start")
+	)
+
+	if machine.HostStatus != fly.HostStatusOk {
+		return fmt.Errorf("the machine is on an unreachable host, try again later")
+	}
+
+	// Acquire lease
+	machine, releaseLeaseFunc, err := mach.AcquireLease(ctx, appName, machine)
+	defer releaseLeaseFunc()
+	if err != nil {
+		return err
+	}
+
 	// Identify configuration changes
 	machineConf, err := determineMachineConfig(ctx, &determineMachineConfigInput{
 		initialMachineConf: *machine.Config,
@@ -0,0 +1,179 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	fly "github.com/superfly/fly-go"
+	"github.com/superfly/flyctl/internal/appsecrets"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/internal/flapsutil"
+	"github.com/superfly/flyctl/internal/flyerr"
+	mach "github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/internal/prompt"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+// patchedMachine is a machine and its config with a patch applied.
+type patchedMachine struct {
+	machine *fly.Machine
+	config  *fly.MachineConfig
+}
+
+// runUpdatePatch applies the patch at path to the config of machines, and
+// updates them one at a time, waiting for each to be healthy before moving
+// on to the next.
+func runUpdatePatch(ctx context.Context, appName string, machines []*fly.Machine, path string) error {
+	var (
+		io        = iostreams.FromContext(ctx)
+		colorize  = io.ColorScheme()
+		confirmed = flag.GetYes(ctx)
+	)
+
+	data, err := readPatch(io, path)
+	if err != nil {
+		return err
+	}
+
+	patch, err := mach.ParseConfigPatch(data)
+	if err != nil {
+		return err
+	}
+
+	var updates []patchedMachine
+	for _, machine := range machines {
+		if machine.Config == nil {
+			return fmt.Errorf("machine %s has no config to patch", machine.ID)
+		}
+
+		config, err := patch.Apply(machine.Config)
+		if err != nil {
+			return fmt.Errorf("machine %s: %w", machine.ID, err)
+		}
+
+		diff, err := mach.ConfigDiff(ctx, *machine.Config, *config)
+		if err != nil {
+			return err
+		}
+		if diff == "" {
+			fmt.Fprintf(io.Out, "No changes to machine %s\n", machine.ID)
+
+			continue
+		}
+
+		fmt.Fprintf(io.Out, "Changes to machine %s (%s):\n\n%s\n\n", colorize.Bold(machine.ID), machine.Name, diff)
+		updates = append(updates, patchedMachine{machine: machine, config: config})
+	}
+
+	switch {
+	case len(updates) == 0:
+		fmt.Fprintln(io.Out, "No changes to apply")
+
+		return nil
+	case flag.GetBool(ctx, "dry-run"):
+		fmt.Fprintf(io.Out, "Dry run: %d of %d machines would be updated\n", len(updates), len(machines))
+
+		return nil
+	}
+
+	if !confirmed {
+		confirmed, err = prompt.Confirmf(ctx, "Update %d machines one at a time?", len(updates))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return nil
+		}
+	}
+
+	minvers, err := appsecrets.GetMinvers(appName)
+	if err != nil {
+		return err
+	}
+
+	for i, update := range updates {
+		fmt.Fprintf(io.Out, "Updating machine %s (%d/%d)\n", colorize.Bold(update.machine.ID), i+1, len(updates))
+
+		if err := updatePatchedMachine(ctx, appName, update, minvers); err != nil {
+			var timeoutErr mach.WaitTimeoutErr
+			if errors.As(err, &timeoutErr) {
+				return flyerr.GenericErr{
+					Err:      timeoutErr.Error(),
+					Descript: timeoutErr.Description(),
+					Suggest:  "Try increasing the --wait-timeout",
+				}
+			}
+
+			return fmt.Errorf("failed to update machine %s, %d machines were left unchanged: %w", update.machine.ID, len(updates)-i-1, err)
+		}
+	}
+
+	fmt.Fprintf(io.Out, "Updated %d machines\n", len(updates))
+
+	return nil
+}
+
+func updatePatchedMachine(ctx context.Context, appName string, update patchedMachine, minvers *uint64) error {
+	var (
+		io               = iostreams.FromContext(ctx)
+		machine          = update.machine
+		skipHealthChecks = flag.GetBool(ctx, "skip-health-checks")
+		timeout          = time.Duration(flag.GetInt(ctx, "wait-timeout")) * time.Second
+	)
+
+	if machine.HostStatus != fly.HostStatusOk {
+		return fmt.Errorf("the machine is on an unreachable host, try again later")
+	}
+
+	lm := mach.NewLeasableMachine(flapsutil.ClientFromContext(ctx), io, appName, machine, true)
+	// The lease has to outlast waiting for the machine to start and then to
+	// pass its health checks
+	if err := lm.AcquireLease(ctx, 2*timeout); err != nil {
+		return err
+	}
+	defer lm.ReleaseLease(ctx)
+
+	input := fly.LaunchMachineInput{
+		Name:   machine.Name,
+		Region: machine.Region,
+		Config: update.config,
+		// Stopped machines are left stopped
+		SkipLaunch:        machine.State != fly.MachineStateStarted || len(update.config.Standbys) > 0 || flag.GetBool(ctx, "skip-start"),
+		SkipHealthChecks:  skipHealthChecks,
+		Timeout:           flag.GetInt(ctx, "wait-timeout"),
+		MinSecretsVersion: minvers,
+	}
+	if err := lm.Update(ctx, input); err != nil {
+		return err
+	}
+	if input.SkipLaunch {
+		return nil
+	}
+
+	if err := lm.WaitForState(ctx, fly.MachineStateStarted, timeout); err != nil {
+		return err
+	}
+	if skipHealthChecks {
+		return nil
+	}
+
+	return lm.WaitForHealthchecksToPass(ctx, timeout)
+}
+
+// readPatch reads a patch from path, or from stdin if path is "-".
+func readPatch(streams *iostreams.IOStreams, path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(streams.In)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read patch: %w", err)
+	}
+
+	return data, nil
+}
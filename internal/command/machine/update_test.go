@@ -0,0 +1,21 @@
+package machine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/internal/command"
+)
+
+func TestCheckUpdatePatchFlags(t *testing.T) {
+	cmd := newUpdate()
+	require.NoError(t, cmd.ParseFlags([]string{"--patch", "patch.json", "--app", "my-app", "--yes", "--skip-start"}))
+	assert.NoError(t, checkUpdatePatchFlags(command.NewContext(context.Background(), cmd)))
+
+	cmd = newUpdate()
+	require.NoError(t, cmd.ParseFlags([]string{"--patch", "patch.json", "--memory", "512", "--env", "A=b", "--image", "nginx"}))
+	err := checkUpdatePatchFlags(command.NewContext(context.Background(), cmd))
+	assert.EqualError(t, err, "--patch can't be used with --env, --image, --memory; put the changes in the patch instead")
+}
@@ -0,0 +1,269 @@
+package releases
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+	fly "github.com/superfly/fly-go"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/appsecrets"
+	"github.com/superfly/flyctl/internal/command"
+	"github.com/superfly/flyctl/internal/config"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/internal/flapsutil"
+	"github.com/superfly/flyctl/internal/render"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func newDiff() *cobra.Command {
+	const (
+		short = "Show what changed between two releases"
+		long  = `Show what changed between two releases of the app: the image, each section
+of the app config, and the secrets set in between. Versions are given as
+numbers, like 'fly releases diff 12 14' or 'fly releases diff v12 v14'.
+
+Only the digests of the current values of secrets are known, so secrets are
+listed if they were set after the older release and before the newer one.
+`
+		usage = "diff <version> <version>"
+	)
+
+	cmd := command.New(usage, short, long, runDiff,
+		command.RequireSession,
+		command.RequireAppName,
+	)
+
+	cmd.Args = cobra.ExactArgs(2)
+
+	flag.Add(cmd,
+		flag.App(),
+		flag.AppConfig(),
+		flag.JSONOutput(),
+	)
+
+	return cmd
+}
+
+// imageChange is a change of the image of an app between releases.
+type imageChange struct {
+	From       string `json:"from"`
+	FromDigest string `json:"from_digest,omitempty"`
+	To         string `json:"to"`
+	ToDigest   string `json:"to_digest,omitempty"`
+}
+
+// sectionChange is a change of a top-level section of the app config, like
+// "http_service" or "env", between releases.
+type sectionChange struct {
+	Section string `json:"section"`
+	From    any    `json:"from"`
+	To      any    `json:"to"`
+}
+
+// secretChange is a secret set between releases.
+type secretChange struct {
+	Name      string `json:"name"`
+	Digest    string `json:"digest"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// releaseDiff is what changed between two releases.
+type releaseDiff struct {
+	FromVersion int             `json:"from_version"`
+	ToVersion   int             `json:"to_version"`
+	Image       *imageChange    `json:"image"`
+	Config      []sectionChange `json:"config"`
+	Secrets     []secretChange  `json:"secrets"`
+}
+
+func (d *releaseDiff) empty() bool {
+	return d.Image == nil && len(d.Config) == 0 && len(d.Secrets) == 0
+}
+
+func runDiff(ctx context.Context) error {
+	var (
+		io      = iostreams.FromContext(ctx)
+		appName = appconfig.NameFromContext(ctx)
+		args    = flag.Args(ctx)
+	)
+
+	from, err := parseVersion(args[0])
+	if err != nil {
+		return err
+	}
+	to, err := parseVersion(args[1])
+	if err != nil {
+		return err
+	}
+
+	releases, err := getReleases(ctx, appName, from, to)
+	if err != nil {
+		return err
+	}
+
+	secrets, err := appsecrets.List(ctx, flapsutil.ClientFromContext(ctx), appName)
+	if err != nil {
+		return fmt.Errorf("failed retrieving secrets of %s: %w", appName, err)
+	}
+
+	diff := diffReleases(releases[0], releases[1], secrets)
+
+	if config.FromContext(ctx).JSONOutput {
+		return render.JSON(io.Out, diff)
+	}
+
+	return printDiff(io, diff)
+}
+
+// diffReleases returns what changed from release a to release b, with the
+// secrets in secrets that were set between them.
+func diffReleases(a, b *release, secrets []fly.AppSecret) *releaseDiff {
+	diff := &releaseDiff{
+		FromVersion: a.Version,
+		ToVersion:   b.Version,
+	}
+
+	if a.ImageRef != b.ImageRef || a.ImageDigest != b.ImageDigest {
+		diff.Image = &imageChange{
+			From:       a.ImageRef,
+			FromDigest: a.ImageDigest,
+			To:         b.ImageRef,
+			ToDigest:   b.ImageDigest,
+		}
+	}
+
+	sections := lo.Uniq(append(lo.Keys(a.Definition), lo.Keys(b.Definition)...))
+	slices.Sort(sections)
+	for _, section := range sections {
+		if !reflect.DeepEqual(a.Definition[section], b.Definition[section]) {
+			diff.Config = append(diff.Config, sectionChange{
+				Section: section,
+				From:    a.Definition[section],
+				To:      b.Definition[section],
+			})
+		}
+	}
+
+	// Secrets are compared by when they were set as only their current
+	// values are known
+	older, newer := a.CreatedAt, b.CreatedAt
+	if newer.Before(older) {
+		older, newer = newer, older
+	}
+	for _, secret := range secrets {
+		updatedAt := lo.FromPtr(lo.CoalesceOrEmpty(secret.UpdatedAt, secret.CreatedAt))
+		t, err := time.Parse(time.RFC3339, updatedAt)
+		if err != nil || !t.After(older) || t.After(newer) {
+			continue
+		}
+		diff.Secrets = append(diff.Secrets, secretChange{
+			Name:      secret.Name,
+			Digest:    secret.Digest,
+			UpdatedAt: updatedAt,
+		})
+	}
+
+	return diff
+}
+
+func printDiff(io *iostreams.IOStreams, diff *releaseDiff) error {
+	colorize := io.ColorScheme()
+
+	if diff.empty() {
+		fmt.Fprintf(io.Out, "No changes between v%d and v%d\n", diff.FromVersion, diff.ToVersion)
+
+		return nil
+	}
+
+	fmt.Fprintf(io.Out, "Changes from v%d to v%d\n", diff.FromVersion, diff.ToVersion)
+
+	if diff.Image != nil {
+		fmt.Fprintf(io.Out, "\n%s\n", colorize.Bold("Image"))
+		fmt.Fprintln(io.Out, colorize.Red("- "+imageDescription(diff.Image.From, diff.Image.FromDigest)))
+		fmt.Fprintln(io.Out, colorize.Green("+ "+imageDescription(diff.Image.To, diff.Image.ToDigest)))
+	}
+
+	for _, change := range diff.Config {
+		fmt.Fprintf(io.Out, "\n%s\n", colorize.Bold("Config: "+change.Section))
+		if err := writeSectionDiff(io, change); err != nil {
+			return err
+		}
+	}
+
+	if len(diff.Secrets) > 0 {
+		fmt.Fprintf(io.Out, "\n%s\n", colorize.Bold("Secrets set in between"))
+		rows := make([][]string, 0, len(diff.Secrets))
+		for _, secret := range diff.Secrets {
+			rows = append(rows, []string{secret.Name, secret.Digest, secret.UpdatedAt})
+		}
+
+		return render.Table(io.Out, "", rows, "Name", "Digest", "Set")
+	}
+
+	return nil
+}
+
+func imageDescription(ref, digest string) string {
+	switch {
+	case ref == "":
+		return "(none)"
+	case digest == "" || strings.HasSuffix(ref, digest):
+		return ref
+	default:
+		return ref + " (" + digest + ")"
+	}
+}
+
+func writeSectionDiff(io *iostreams.IOStreams, change sectionChange) error {
+	colorize := io.ColorScheme()
+
+	from, err := sectionLines(change.From)
+	if err != nil {
+		return err
+	}
+	to, err := sectionLines(change.To)
+	if err != nil {
+		return err
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{A: from, B: to, Context: 3})
+	if err != nil {
+		return err
+	}
+
+	for _, line := range strings.Split(strings.TrimRight(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			line = colorize.Gray(line)
+		case strings.HasPrefix(line, "-"):
+			line = colorize.Red(line)
+		case strings.HasPrefix(line, "+"):
+			line = colorize.Green(line)
+		}
+		fmt.Fprintln(io.Out, line)
+	}
+
+	return nil
+}
+
+// sectionLines returns the lines of a config section as indented JSON.
+func sectionLines(section any) ([]string, error) {
+	if section == nil {
+		return nil, nil
+	}
+
+	data, err := json.MarshalIndent(section, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return difflib.SplitLines(string(data)), nil
+}
@@ -0,0 +1,99 @@
+package releases
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	fly "github.com/superfly/fly-go"
+)
+
+func testReleases() (*release, *release) {
+	v3 := &release{
+		Version:     3,
+		CreatedAt:   time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		ImageRef:    "registry.fly.io/my-app:deployment-3",
+		ImageDigest: "sha256:333",
+		Definition: map[string]any{
+			"app":            "my-app",
+			"primary_region": "ord",
+			"env":            map[string]any{"LOG_LEVEL": "info"},
+		},
+	}
+	v5 := &release{
+		Version:     5,
+		CreatedAt:   time.Date(2026, 10, 3, 12, 0, 0, 0, time.UTC),
+		ImageRef:    "registry.fly.io/my-app:deployment-5",
+		ImageDigest: "sha256:555",
+		Definition: map[string]any{
+			"app":            "my-app",
+			"primary_region": "ord",
+			"env":            map[string]any{"LOG_LEVEL": "debug"},
+			"http_service":   map[string]any{"internal_port": float64(8080)},
+		},
+	}
+
+	return v3, v5
+}
+
+func TestDiffReleases(t *testing.T) {
+	v3, v5 := testReleases()
+	secrets := []fly.AppSecret{
+		{Name: "BEFORE", Digest: "aaa", CreatedAt: new("2026-09-30T00:00:00Z")},
+		{Name: "BETWEEN", Digest: "bbb", CreatedAt: new("2026-09-30T00:00:00Z"), UpdatedAt: new("2026-10-02T00:00:00Z")},
+		{Name: "AFTER", Digest: "ccc", CreatedAt: new("2026-10-04T00:00:00Z")},
+	}
+
+	diff := diffReleases(v3, v5, secrets)
+
+	assert.Equal(t, &imageChange{
+		From: v3.ImageRef, FromDigest: "sha256:333",
+		To: v5.ImageRef, ToDigest: "sha256:555",
+	}, diff.Image)
+
+	require.Len(t, diff.Config, 2)
+	assert.Equal(t, "env", diff.Config[0].Section)
+	assert.Equal(t, "http_service", diff.Config[1].Section)
+	assert.Nil(t, diff.Config[1].From)
+
+	assert.Equal(t, []secretChange{{Name: "BETWEEN", Digest: "bbb", UpdatedAt: "2026-10-02T00:00:00Z"}}, diff.Secrets)
+
+	// Releases can be given newest first
+	assert.Len(t, diffReleases(v5, v3, secrets).Secrets, 1)
+	assert.True(t, diffReleases(v3, v3, secrets).empty())
+}
+
+func TestParseVersion(t *testing.T) {
+	v, err := parseVersion("v12")
+	require.NoError(t, err)
+	assert.Equal(t, 12, v)
+
+	v, err = parseVersion("7")
+	require.NoError(t, err)
+	assert.Equal(t, 7, v)
+
+	_, err = parseVersion("latest")
+	assert.Error(t, err)
+	_, err = parseVersion("0")
+	assert.Error(t, err)
+}
+
+func TestRollbackConfig(t *testing.T) {
+	_, v5 := testReleases()
+	v5.Definition["build"] = map[string]any{"dockerfile": "Dockerfile.prod"}
+
+	cfg, err := rollbackConfig(v5)
+	require.NoError(t, err)
+	assert.Equal(t, "registry.fly.io/my-app:deployment-5", cfg.Build.Image)
+	assert.Empty(t, cfg.Build.Dockerfile)
+	assert.Equal(t, "debug", cfg.Env["LOG_LEVEL"])
+	assert.Equal(t, 8080, cfg.HTTPService.InternalPort)
+
+	v5.ImageRef = ""
+	_, err = rollbackConfig(v5)
+	assert.ErrorContains(t, err, "no image")
+
+	_, err = rollbackConfig(&release{Version: 2, ImageRef: "img"})
+	assert.ErrorContains(t, err, "no app config")
+}
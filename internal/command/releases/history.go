@@ -0,0 +1,136 @@
+package releases
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	fly "github.com/superfly/fly-go"
+	"github.com/superfly/flyctl/gql"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/flyutil"
+)
+
+// release is a release of an app with the config and image it deployed.
+type release struct {
+	Version     int
+	Description string
+	Reason      string
+	Status      string
+	User        string
+	CreatedAt   time.Time
+	ImageRef    string
+	ImageDigest string
+	// Definition is the app config of the release as stored by the API.
+	Definition map[string]any
+}
+
+// Config returns the app config of r.
+func (r *release) Config() (*appconfig.Config, error) {
+	if r.Definition == nil {
+		return nil, fmt.Errorf("release v%d has no app config", r.Version)
+	}
+
+	return appconfig.FromDefinition(fly.DefinitionPtr(r.Definition))
+}
+
+// parseVersion parses release versions like "12" or "v12".
+func parseVersion(s string) (int, error) {
+	version, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(s), "v"))
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid release version %q", s)
+	}
+
+	return version, nil
+}
+
+// getReleases returns the releases of appName with the given versions, in
+// the same order.
+func getReleases(ctx context.Context, appName string, versions ...int) ([]*release, error) {
+	_ = `# @genqlient
+	query FlyctlReleasesUnprocessed(
+		$appName: String!,
+		# @genqlient(omitempty: true)
+		$after: String,
+	) {
+		app(name:$appName) {
+			releasesUnprocessed(first: 50, after: $after) {
+				pageInfo {
+					hasNextPage
+					endCursor
+				}
+				nodes {
+					version
+					description
+					reason
+					status
+					createdAt
+					imageRef
+					image {
+						digest
+					}
+					user {
+						email
+					}
+					configDefinition
+				}
+			}
+		}
+	}
+	`
+	client := flyutil.ClientFromContext(ctx)
+
+	found := make(map[int]*release, len(versions))
+	var after string
+	for len(found) < len(versions) {
+		resp, err := gql.FlyctlReleasesUnprocessed(ctx, client.GenqClient(), appName, after)
+		if err != nil {
+			return nil, fmt.Errorf("failed retrieving releases of %s: %w", appName, err)
+		}
+
+		conn := resp.App.ReleasesUnprocessed
+		for _, node := range conn.Nodes {
+			for _, v := range versions {
+				if node.Version == v {
+					found[v] = releaseFromNode(node)
+				}
+			}
+		}
+
+		if !conn.PageInfo.HasNextPage {
+			break
+		}
+		after = conn.PageInfo.EndCursor
+	}
+
+	releases := make([]*release, 0, len(versions))
+	for _, v := range versions {
+		r, ok := found[v]
+		if !ok {
+			return nil, fmt.Errorf("release v%d of %s not found", v, appName)
+		}
+		releases = append(releases, r)
+	}
+
+	return releases, nil
+}
+
+func releaseFromNode(node gql.FlyctlReleasesUnprocessedAppReleasesUnprocessedReleaseUnprocessedConnectionNodesReleaseUnprocessed) *release {
+	r := &release{
+		Version:     node.Version,
+		Description: node.Description,
+		Reason:      node.Reason,
+		Status:      node.Status,
+		User:        node.User.Email,
+		CreatedAt:   node.CreatedAt,
+		ImageRef:    node.ImageRef,
+		ImageDigest: node.Image.Digest,
+	}
+	if definition, ok := node.ConfigDefinition.(map[string]any); ok {
+		r.Definition = definition
+	}
+
+	return r
+}
@@ -8,5 +8,12 @@ import (
 
 // TODO: deprecate
 func New() *cobra.Command {
-	return apps.NewReleases()
+	cmd := apps.NewReleases()
+
+	cmd.AddCommand(
+		newDiff(),
+		newRollback(),
+	)
+
+	return cmd
 }
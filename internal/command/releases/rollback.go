@@ -0,0 +1,139 @@
+package releases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/command"
+	"github.com/superfly/flyctl/internal/command/deploy"
+	"github.com/superfly/flyctl/internal/command/deploycontext"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/internal/flyutil"
+	"github.com/superfly/flyctl/internal/prompt"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func newRollback() *cobra.Command {
+	const (
+		short = "Redeploy the config and image of an earlier release"
+		long  = `Roll the app back to an earlier release by deploying the app config and image
+of that release again. The rollback is a new release made with the usual
+deployment strategy, and records which release it rolled back to and why.
+
+Secrets aren't rolled back, as only their current values are known.
+`
+		usage = "rollback <version>"
+	)
+
+	cmd := command.New(usage, short, long, runRollback,
+		command.RequireSession,
+		command.RequireAppName,
+	)
+
+	cmd.Args = cobra.ExactArgs(1)
+
+	flag.Add(cmd,
+		deploy.CommonFlags,
+		flag.App(),
+		flag.AppConfig(),
+		flag.String{
+			Name:        "reason",
+			Description: "Why the app is rolled back, recorded with the release",
+		},
+	)
+
+	return cmd
+}
+
+func runRollback(ctx context.Context) error {
+	var (
+		io      = iostreams.FromContext(ctx)
+		appName = appconfig.NameFromContext(ctx)
+	)
+
+	if flag.GetString(ctx, "image") != "" || flag.GetString(ctx, flag.Dockerfile().Name) != "" {
+		return errors.New("a rollback deploys the image of the release it rolls back to; --image and --dockerfile can't be used")
+	}
+
+	version, err := parseVersion(flag.FirstArg(ctx))
+	if err != nil {
+		return err
+	}
+
+	current, err := flyutil.ClientFromContext(ctx).GetAppCurrentReleaseMachines(ctx, appName)
+	if err != nil {
+		return fmt.Errorf("failed retrieving the current release of %s: %w", appName, err)
+	}
+	if current.Version == version {
+		return fmt.Errorf("v%d is already the current release of %s", version, appName)
+	}
+
+	releases, err := getReleases(ctx, appName, version)
+	if err != nil {
+		return err
+	}
+	target := releases[0]
+
+	cfg, err := rollbackConfig(target)
+	if err != nil {
+		return err
+	}
+	cfg.AppName = appName
+
+	err, extraInfo := cfg.Validate(ctx)
+	if extraInfo != "" {
+		fmt.Fprint(io.Out, extraInfo)
+	}
+	if err != nil {
+		return fmt.Errorf("the app config of v%d is no longer valid: %w", version, err)
+	}
+
+	rollback := &deploycontext.Rollback{
+		FromVersion: current.Version,
+		ToVersion:   version,
+		Reason:      flag.GetString(ctx, "reason"),
+	}
+	if rollback.Reason == "" {
+		rollback.Reason = fmt.Sprintf("rollback to v%d", version)
+	}
+
+	fmt.Fprintf(io.Out, "Rolling back %s from v%d to v%d (%s, deployed %s by %s)\n",
+		appName, current.Version, version, target.ImageRef, target.CreatedAt.Format("2006-01-02 15:04"), target.User)
+
+	if !flag.GetYes(ctx) {
+		switch confirmed, err := prompt.Confirmf(ctx, "Deploy v%d of %s again?", version, appName); {
+		case err != nil:
+			return err
+		case !confirmed:
+			return nil
+		}
+	}
+
+	ctx = appconfig.WithConfig(ctx, cfg)
+	ctx = context.WithValue(ctx, deploycontext.RollbackKey, rollback)
+
+	return deploy.DeployWithConfig(ctx, cfg, 0, flag.GetYes(ctx))
+}
+
+// rollbackConfig returns the app config to deploy to roll back to r, which
+// deploys the image of r instead of building one.
+func rollbackConfig(r *release) (*appconfig.Config, error) {
+	if r.ImageRef == "" {
+		return nil, fmt.Errorf("release v%d has no image to roll back to", r.Version)
+	}
+
+	cfg, err := r.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.SetMachinesPlatform(); err != nil {
+		return nil, err
+	}
+	cfg.Build = &appconfig.Build{Image: r.ImageRef}
+
+	return cfg, nil
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/superfly/flyctl/internal/command/agent"
 	"github.com/superfly/flyctl/internal/command/apps"
 	"github.com/superfly/flyctl/internal/command/auth"
+	"github.com/superfly/flyctl/internal/command/build"
 	"github.com/superfly/flyctl/internal/command/certificates"
 	"github.com/superfly/flyctl/internal/command/checks"
 	"github.com/superfly/flyctl/internal/command/config"
@@ -122,6 +123,7 @@ func New() *cobra.Command {
 		group(lfsc.New(), "dbs_and_extensions"),
 		agent.New(),
 		group(image.New(), "configuring"),
+		group(build.New(), "configuring"),
 		group(incidents.New(), "upkeep"),
 		group(ping.New(), "upkeep"),
 		group(proxy.New(), "upkeep"),
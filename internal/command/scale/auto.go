@@ -0,0 +1,458 @@
+package scale
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+	fly "github.com/superfly/fly-go"
+	"github.com/superfly/flyctl/helpers"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/appsecrets"
+	"github.com/superfly/flyctl/internal/command"
+	"github.com/superfly/flyctl/internal/config"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/internal/flag/completion"
+	"github.com/superfly/flyctl/internal/flapsutil"
+	mach "github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/internal/render"
+	"github.com/superfly/flyctl/iostreams"
+	"github.com/superfly/flyctl/terminal"
+)
+
+func newScaleAuto() *cobra.Command {
+	const (
+		short = "Scale an app's machines automatically from metrics"
+		long  = `Run a controller that scales an app's machines from metrics.
+
+Every interval, the controller runs a PromQL query that returns one value per
+region, like the number of concurrent requests, and scales the process group
+in each region to the number of machines that brings the value per machine
+to the target, within min and max. It starts stopped machines before creating
+new ones, copying the config of an existing machine of the group, and stops
+machines to scale down. Cooldowns keep it from scaling a region again too
+soon.
+
+Queries go to the Fly metrics API of the app's organization, or to the
+Prometheus-compatible API at --metrics-url. The default query is the
+concurrency of the whole app by region, so rules files with more than one
+rule need a query for each.
+
+To scale more than one process group, list rules in a TOML file and pass it
+with --rules:
+
+  [[rules]]
+  process_group = "web"
+  regions = ["ord", "ams"]
+  query = 'sum by (region) (fly_app_concurrency{app="my-app"})'
+  target = 20.0
+  min = 1
+  max = 10
+  scale_up_cooldown = "1m"
+  scale_down_cooldown = "5m"
+
+The controller runs until interrupted. To run it on a machine rather than
+locally, run flyctl there with a FLY_API_TOKEN that can manage the app.
+
+With --simulate, the controller replays the query over the past --since and
+prints the decisions it would have made without changing any machines.`
+	)
+	cmd := command.New("auto", short, long, runScaleAuto,
+		command.RequireSession,
+		command.RequireAppName,
+	)
+	cmd.Args = cobra.NoArgs
+	flag.Add(cmd,
+		flag.App(),
+		flag.AppConfig(),
+		flag.ProcessGroup("The process group to scale"),
+		flag.String{Name: "region", Shorthand: "r", Description: "Comma separated list of regions to scale. Defaults to the regions where the process group has machines", CompletionFn: completion.CompleteRegions},
+		flag.String{Name: "query", Description: "PromQL query returning one value per region, each with a region label"},
+		flag.Float64{Name: "target", Description: "Target value of the query per started machine"},
+		flag.Int{Name: "min", Description: "Minimum number of started machines per region", Default: 1},
+		flag.Int{Name: "max", Description: "Maximum number of started machines per region"},
+		flag.Duration{Name: "scale-up-cooldown", Description: "Time to wait after scaling a region before scaling it up", Default: defaultScaleUpCooldown},
+		flag.Duration{Name: "scale-down-cooldown", Description: "Time to wait after scaling a region before scaling it down", Default: defaultScaleDownCooldown},
+		flag.String{Name: "rules", Description: "Path to a TOML file of scaling rules, used instead of the rule flags"},
+		flag.Duration{Name: "interval", Description: "Time between runs of the controller", Default: 30 * time.Second},
+		flag.String{Name: "metrics-url", Description: "URL of a Prometheus-compatible API to query. Defaults to the Fly metrics API of the app's organization"},
+		flag.Bool{Name: "once", Description: "Run the controller once and exit"},
+		flag.Bool{Name: "simulate", Description: "Replay past metrics and print the decisions the controller would have made, without changing any machines"},
+		flag.Duration{Name: "since", Description: "How far back to replay metrics with --simulate", Default: 24 * time.Hour},
+		flag.Duration{Name: "step", Description: "Time between replayed samples with --simulate", Default: time.Minute},
+		flag.JSONOutput(),
+	)
+
+	return cmd
+}
+
+func runScaleAuto(ctx context.Context) error {
+	appName := appconfig.NameFromContext(ctx)
+	flapsClient := flapsutil.ClientFromContext(ctx)
+
+	defaultQuery := fmt.Sprintf(`sum by (region) (fly_app_concurrency{app=%q})`, appName)
+	rules, err := autoscaleRulesFromFlags(ctx, defaultQuery)
+	if err != nil {
+		return err
+	}
+
+	metricsURL := flag.GetString(ctx, "metrics-url")
+	authorization := ""
+	if metricsURL == "" {
+		app, err := flapsClient.GetApp(ctx, appName)
+		if err != nil {
+			return fmt.Errorf("failed to get app: %w", err)
+		}
+		metricsURL = fmt.Sprintf("%s/prometheus/%s", strings.TrimSuffix(config.FromContext(ctx).APIBaseURL, "/"), app.Organization.Slug)
+		authorization = config.Tokens(ctx).GraphQLHeader()
+	}
+	metrics, err := newMetricsClient(metricsURL, authorization)
+	if err != nil {
+		return err
+	}
+
+	if flag.GetBool(ctx, "simulate") {
+		return runAutoscaleSimulation(ctx, appName, rules, metrics)
+	}
+
+	io := iostreams.FromContext(ctx)
+	scaler := newAutoscaler()
+	interval := flag.GetDuration(ctx, "interval")
+	if interval <= 0 {
+		return errors.New("--interval must be greater than 0")
+	}
+
+	if !flag.GetBool(ctx, "once") {
+		fmt.Fprintf(io.ErrOut, "Autoscaling %s every %s; press Ctrl+C to stop\n", appName, interval)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := runAutoscaleOnce(ctx, appName, rules, scaler, metrics)
+		if flag.GetBool(ctx, "once") {
+			return err
+		}
+		if err != nil {
+			terminal.Warnf("Autoscaling failed: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// autoscaleRulesFromFlags returns the rules in --rules, or else the rule set
+// with flags.
+func autoscaleRulesFromFlags(ctx context.Context, defaultQuery string) ([]autoscaleRule, error) {
+	if rulesPath := flag.GetString(ctx, "rules"); rulesPath != "" {
+		return loadAutoscaleRules(rulesPath, defaultQuery)
+	}
+
+	rule := autoscaleRule{
+		ProcessGroup:      flag.GetProcessGroup(ctx),
+		Query:             flag.GetString(ctx, "query"),
+		Target:            flag.GetFloat64(ctx, "target"),
+		Min:               flag.GetInt(ctx, "min"),
+		Max:               flag.GetInt(ctx, "max"),
+		ScaleUpCooldown:   flag.GetDuration(ctx, "scale-up-cooldown"),
+		ScaleDownCooldown: flag.GetDuration(ctx, "scale-down-cooldown"),
+	}
+	if rule.ProcessGroup == "" {
+		rule.ProcessGroup = fly.MachineProcessGroupApp
+	}
+	if v := flag.GetRegion(ctx); v != "" {
+		rule.Regions = strings.Split(v, ",")
+	}
+	if rule.Query == "" {
+		rule.Query = defaultQuery
+	}
+	if err := rule.validate(); err != nil {
+		return nil, fmt.Errorf("%w; set --target and --max, or --rules", err)
+	}
+
+	return []autoscaleRule{rule}, nil
+}
+
+// runAutoscaleOnce scales every process group of rules once.
+func runAutoscaleOnce(ctx context.Context, appName string, rules []autoscaleRule, scaler *autoscaler, metrics *metricsClient) error {
+	io := iostreams.FromContext(ctx)
+	flapsClient := flapsutil.ClientFromContext(ctx)
+
+	machines, _, err := flapsClient.ListFlyAppsMachines(ctx, appName)
+	if err != nil {
+		return fmt.Errorf("failed to list machines: %w", err)
+	}
+
+	var errs []error
+	now := time.Now()
+	for _, rule := range rules {
+		values, err := metrics.query(ctx, rule.Query, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("process group %s: %w", rule.ProcessGroup, err))
+
+			continue
+		}
+
+		groupMachines := lo.Filter(machines, func(m *fly.Machine, _ int) bool {
+			return m.Config != nil && m.ProcessGroup() == rule.ProcessGroup
+		})
+		for _, region := range autoscaleRegions(rule, groupMachines) {
+			value, ok := values[region]
+			d := scaler.decide(rule, region, value, ok, len(startedMachines(groupMachines, region)), now)
+
+			fmt.Fprintf(io.Out, "%s %s/%s: %d -> %d machines (%s)\n",
+				d.Time.Format(time.TimeOnly), d.ProcessGroup, d.Region, d.Current, d.Desired, d.Reason)
+			if d.Delta() == 0 {
+				continue
+			}
+			if err := applyAutoscaleDecision(ctx, appName, groupMachines, d); err != nil {
+				errs = append(errs, fmt.Errorf("process group %s in %s: %w", d.ProcessGroup, d.Region, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func autoscaleRegions(rule autoscaleRule, groupMachines []*fly.Machine) []string {
+	if len(rule.Regions) > 0 {
+		return rule.Regions
+	}
+
+	regions := lo.Uniq(lo.Map(groupMachines, func(m *fly.Machine, _ int) string { return m.Region }))
+	slices.Sort(regions)
+
+	return regions
+}
+
+func startedMachines(machines []*fly.Machine, region string) []*fly.Machine {
+	return lo.Filter(machines, func(m *fly.Machine, _ int) bool {
+		return m.Region == region && (m.State == fly.MachineStateStarted || m.State == "starting")
+	})
+}
+
+// autoscalePlan is how to carry out a decision: machines to start or stop,
+// and how many to create.
+type autoscalePlan struct {
+	Start  []*fly.Machine
+	Stop   []*fly.Machine
+	Create int
+}
+
+// planAutoscale picks the machines of a process group to carry out d.
+// Scaling up starts stopped machines first and creates the rest; scaling
+// down stops the newest started machines.
+func planAutoscale(groupMachines []*fly.Machine, d autoscaleDecision) autoscalePlan {
+	var plan autoscalePlan
+
+	switch delta := d.Delta(); {
+	case delta > 0:
+		stopped := lo.Filter(groupMachines, func(m *fly.Machine, _ int) bool {
+			return m.Region == d.Region && (m.State == fly.MachineStateStopped || m.State == fly.MachineStateSuspended)
+		})
+		slices.SortFunc(stopped, func(a, b *fly.Machine) int { return strings.Compare(a.ID, b.ID) })
+		plan.Start = stopped[:min(delta, len(stopped))]
+		plan.Create = delta - len(plan.Start)
+	case delta < 0:
+		started := startedMachines(groupMachines, d.Region)
+		slices.SortFunc(started, func(a, b *fly.Machine) int {
+			if c := strings.Compare(b.CreatedAt, a.CreatedAt); c != 0 {
+				return c
+			}
+
+			return strings.Compare(a.ID, b.ID)
+		})
+		plan.Stop = started[:min(-delta, len(started))]
+	}
+
+	return plan
+}
+
+func applyAutoscaleDecision(ctx context.Context, appName string, groupMachines []*fly.Machine, d autoscaleDecision) error {
+	io := iostreams.FromContext(ctx)
+	flapsClient := flapsutil.ClientFromContext(ctx)
+	plan := planAutoscale(groupMachines, d)
+
+	if leased := slices.Concat(plan.Start, plan.Stop); len(leased) > 0 {
+		leased, release, err := mach.AcquireLeases(ctx, appName, leased)
+		defer release()
+		if err != nil {
+			return err
+		}
+
+		for _, m := range leased {
+			if slices.ContainsFunc(plan.Start, func(s *fly.Machine) bool { return s.ID == m.ID }) {
+				if _, err := flapsutil.Start(ctx, flapsClient, appName, m.ID, m.LeaseNonce); err != nil {
+					return fmt.Errorf("failed to start machine %s: %w", m.ID, err)
+				}
+				fmt.Fprintf(io.Out, "  Started %s\n", m.ID)
+			} else {
+				if err := flapsClient.Stop(ctx, appName, fly.StopMachineInput{ID: m.ID}, m.LeaseNonce); err != nil {
+					return fmt.Errorf("failed to stop machine %s: %w", m.ID, err)
+				}
+				fmt.Fprintf(io.Out, "  Stopped %s\n", m.ID)
+			}
+		}
+	}
+
+	if plan.Create == 0 {
+		return nil
+	}
+
+	template := autoscaleTemplate(groupMachines, d.Region)
+	if template == nil {
+		return fmt.Errorf("there are no machines in process group %s to copy for new machines", d.ProcessGroup)
+	}
+	mConfig := helpers.Clone(template.Config)
+	if len(mConfig.Mounts) > 0 {
+		return fmt.Errorf("can't create machines with volumes; create stopped machines with `fly scale count` for the autoscaler to start")
+	}
+	// Nullify standbys, no point on having more than one
+	mConfig.Standbys = nil
+	delete(mConfig.Env, "FLY_STANDBY_FOR")
+
+	minvers, err := appsecrets.GetMinvers(appName)
+	if err != nil {
+		return err
+	}
+
+	for range plan.Create {
+		m, err := flapsutil.Launch(ctx, flapsClient, appName, fly.LaunchMachineInput{
+			Region:            d.Region,
+			Config:            mConfig,
+			MinSecretsVersion: minvers,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create machine: %w", err)
+		}
+		fmt.Fprintf(io.Out, "  Created %s\n", m.ID)
+	}
+
+	return nil
+}
+
+// autoscaleTemplate returns the machine whose config new machines in region
+// copy, preferring one in region.
+func autoscaleTemplate(groupMachines []*fly.Machine, region string) *fly.Machine {
+	if m, ok := lo.Find(groupMachines, func(m *fly.Machine) bool { return m.Region == region }); ok {
+		return m
+	}
+	if len(groupMachines) > 0 {
+		return groupMachines[0]
+	}
+
+	return nil
+}
+
+func runAutoscaleSimulation(ctx context.Context, appName string, rules []autoscaleRule, metrics *metricsClient) error {
+	io := iostreams.FromContext(ctx)
+	flapsClient := flapsutil.ClientFromContext(ctx)
+
+	step := flag.GetDuration(ctx, "step")
+	since := flag.GetDuration(ctx, "since")
+	if step <= 0 || since <= 0 {
+		return errors.New("--since and --step must be greater than 0")
+	}
+
+	machines, _, err := flapsClient.ListFlyAppsMachines(ctx, appName)
+	if err != nil {
+		return fmt.Errorf("failed to list machines: %w", err)
+	}
+
+	end := time.Now().Truncate(step)
+	start := end.Add(-since)
+
+	var decisions []autoscaleDecision
+	for _, rule := range rules {
+		samples, err := metrics.queryRange(ctx, rule.Query, start, end, step)
+		if err != nil {
+			return fmt.Errorf("process group %s: %w", rule.ProcessGroup, err)
+		}
+
+		groupMachines := lo.Filter(machines, func(m *fly.Machine, _ int) bool {
+			return m.Config != nil && m.ProcessGroup() == rule.ProcessGroup
+		})
+		current := map[string]int{}
+		for _, region := range autoscaleRegions(rule, groupMachines) {
+			current[region] = len(startedMachines(groupMachines, region))
+		}
+
+		ruleDecisions, err := simulateAutoscale(rule, samples, current)
+		if err != nil {
+			return fmt.Errorf("process group %s: %w", rule.ProcessGroup, err)
+		}
+		decisions = append(decisions, ruleDecisions...)
+	}
+
+	if config.FromContext(ctx).JSONOutput {
+		return render.JSON(io.Out, decisions)
+	}
+
+	var rows [][]string
+	for _, d := range decisions {
+		if d.Delta() == 0 {
+			continue
+		}
+		rows = append(rows, []string{
+			d.Time.Local().Format(time.DateTime),
+			d.ProcessGroup,
+			d.Region,
+			strconv.FormatFloat(d.Value, 'g', 4, 64),
+			fmt.Sprintf("%d -> %d", d.Current, d.Desired),
+			d.Reason,
+		})
+	}
+	if len(rows) == 0 {
+		fmt.Fprintf(io.Out, "The controller would not have scaled %s between %s and %s\n", appName, start.Local().Format(time.DateTime), end.Local().Format(time.DateTime))
+	} else if err := render.Table(io.Out, "Simulated scaling", rows, "Time", "Group", "Region", "Value", "Machines", "Reason"); err != nil {
+		return err
+	}
+
+	return render.Table(io.Out, "Summary", autoscaleSummary(decisions), "Group", "Region", "Scale ups", "Scale downs", "Fewest", "Most")
+}
+
+// autoscaleSummary counts the scaling in decisions and the range of
+// machines by process group and region.
+func autoscaleSummary(decisions []autoscaleDecision) [][]string {
+	type summary struct {
+		ups, downs, fewest, most int
+	}
+	var keys []autoscaleKey
+	summaries := map[autoscaleKey]*summary{}
+	for _, d := range decisions {
+		key := autoscaleKey{d.ProcessGroup, d.Region}
+		s, ok := summaries[key]
+		if !ok {
+			s = &summary{fewest: d.Desired, most: d.Desired}
+			summaries[key] = s
+			keys = append(keys, key)
+		}
+		switch {
+		case d.Delta() > 0:
+			s.ups++
+		case d.Delta() < 0:
+			s.downs++
+		}
+		s.fewest = min(s.fewest, d.Current, d.Desired)
+		s.most = max(s.most, d.Current, d.Desired)
+	}
+
+	rows := make([][]string, 0, len(keys))
+	for _, key := range keys {
+		s := summaries[key]
+		rows = append(rows, []string{key.group, key.region, strconv.Itoa(s.ups), strconv.Itoa(s.downs), strconv.Itoa(s.fewest), strconv.Itoa(s.most)})
+	}
+
+	return rows
+}
@@ -0,0 +1,272 @@
+package scale
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	fly "github.com/superfly/fly-go"
+)
+
+const (
+	defaultScaleUpCooldown   = time.Minute
+	defaultScaleDownCooldown = 5 * time.Minute
+)
+
+// autoscaleRule scales the machines of a process group so that the value of
+// Query, split by region, stays near Target per started machine.
+type autoscaleRule struct {
+	ProcessGroup string
+	// Regions to scale; empty means the regions the group has machines in.
+	Regions []string
+	// Query is a PromQL query returning one series per region, each with a
+	// region label.
+	Query             string
+	Target            float64
+	Min               int
+	Max               int
+	ScaleUpCooldown   time.Duration
+	ScaleDownCooldown time.Duration
+}
+
+func (r autoscaleRule) validate() error {
+	switch {
+	case r.Query == "":
+		return fmt.Errorf("autoscale rule for process group %q has no query", r.ProcessGroup)
+	case r.Target <= 0:
+		return fmt.Errorf("autoscale rule for process group %q needs a target greater than 0", r.ProcessGroup)
+	case r.Min < 0:
+		return fmt.Errorf("autoscale rule for process group %q has a negative min", r.ProcessGroup)
+	case r.Max < 1 || r.Max < r.Min:
+		return fmt.Errorf("autoscale rule for process group %q needs a max of at least 1 and no less than min", r.ProcessGroup)
+	}
+
+	return nil
+}
+
+// autoscaleRulesFile is the TOML file of rules read by --rules:
+//
+//	[[rules]]
+//	process_group = "web"
+//	regions = ["ord", "ams"]
+//	query = 'sum by (region) (fly_app_concurrency{app="my-app"})'
+//	target = 20.0
+//	min = 1
+//	max = 10
+//	scale_up_cooldown = "1m"
+//	scale_down_cooldown = "5m"
+type autoscaleRulesFile struct {
+	Rules []struct {
+		ProcessGroup      string   `toml:"process_group"`
+		Regions           []string `toml:"regions"`
+		Query             string   `toml:"query"`
+		Target            float64  `toml:"target"`
+		Min               *int     `toml:"min"`
+		Max               int      `toml:"max"`
+		ScaleUpCooldown   string   `toml:"scale_up_cooldown"`
+		ScaleDownCooldown string   `toml:"scale_down_cooldown"`
+	} `toml:"rules"`
+}
+
+// loadAutoscaleRules reads the rules in rulesPath. A single rule without a
+// query uses defaultQuery, which is about the whole app and so can't tell the
+// process groups of several rules apart.
+func loadAutoscaleRules(rulesPath string, defaultQuery string) ([]autoscaleRule, error) {
+	data, err := os.ReadFile(rulesPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read autoscale rules: %w", err)
+	}
+
+	var file autoscaleRulesFile
+	decoder := toml.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to parse autoscale rules %s: %w", rulesPath, err)
+	}
+	if len(file.Rules) == 0 {
+		return nil, fmt.Errorf("autoscale rules %s has no [[rules]]", rulesPath)
+	}
+
+	var rules []autoscaleRule
+	for _, r := range file.Rules {
+		rule := autoscaleRule{
+			ProcessGroup:      r.ProcessGroup,
+			Regions:           r.Regions,
+			Query:             r.Query,
+			Target:            r.Target,
+			Min:               1,
+			Max:               r.Max,
+			ScaleUpCooldown:   defaultScaleUpCooldown,
+			ScaleDownCooldown: defaultScaleDownCooldown,
+		}
+		if rule.ProcessGroup == "" {
+			rule.ProcessGroup = fly.MachineProcessGroupApp
+		}
+		if rule.Query == "" {
+			if len(file.Rules) > 1 {
+				return nil, fmt.Errorf("autoscale rule for process group %q in %s needs a query: the default query covers the whole app, so it only fits a single rule", rule.ProcessGroup, rulesPath)
+			}
+			rule.Query = defaultQuery
+		}
+		if r.Min != nil {
+			rule.Min = *r.Min
+		}
+		if r.ScaleUpCooldown != "" {
+			if rule.ScaleUpCooldown, err = time.ParseDuration(r.ScaleUpCooldown); err != nil {
+				return nil, fmt.Errorf("invalid scale_up_cooldown in autoscale rules %s: %w", rulesPath, err)
+			}
+		}
+		if r.ScaleDownCooldown != "" {
+			if rule.ScaleDownCooldown, err = time.ParseDuration(r.ScaleDownCooldown); err != nil {
+				return nil, fmt.Errorf("invalid scale_down_cooldown in autoscale rules %s: %w", rulesPath, err)
+			}
+		}
+		if err := rule.validate(); err != nil {
+			return nil, err
+		}
+		if slices.ContainsFunc(rules, func(other autoscaleRule) bool { return other.ProcessGroup == rule.ProcessGroup }) {
+			return nil, fmt.Errorf("autoscale rules %s has more than one rule for process group %q", rulesPath, rule.ProcessGroup)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// autoscaleDecision is what the autoscaler decided for the machines of a
+// process group in a region at some time.
+type autoscaleDecision struct {
+	Time         time.Time `json:"time"`
+	ProcessGroup string    `json:"process_group"`
+	Region       string    `json:"region"`
+	// Value is the value of the query, if HasValue.
+	Value    float64 `json:"value"`
+	HasValue bool    `json:"has_value"`
+	Current  int     `json:"current"`
+	Desired  int     `json:"desired"`
+	Reason   string  `json:"reason"`
+}
+
+// Delta is the number of machines to start, or stop if negative.
+func (d autoscaleDecision) Delta() int {
+	return d.Desired - d.Current
+}
+
+type autoscaleKey struct {
+	group  string
+	region string
+}
+
+// autoscaler decides how many machines each process group should run in
+// each region, and keeps track of cooldowns between scaling.
+type autoscaler struct {
+	lastScaled map[autoscaleKey]time.Time
+}
+
+func newAutoscaler() *autoscaler {
+	return &autoscaler{lastScaled: map[autoscaleKey]time.Time{}}
+}
+
+// decide returns how many machines rule wants in region at now, given the
+// value of its query there and the number of started machines. A decision
+// to scale starts a cooldown for the region. Cooldowns don't hold back
+// scaling into [Min, Max].
+func (a *autoscaler) decide(rule autoscaleRule, region string, value float64, hasValue bool, current int, now time.Time) autoscaleDecision {
+	d := autoscaleDecision{
+		Time:         now,
+		ProcessGroup: rule.ProcessGroup,
+		Region:       region,
+		Value:        value,
+		HasValue:     hasValue,
+		Current:      current,
+		Desired:      current,
+	}
+
+	want := min(max(current, rule.Min), rule.Max)
+	if hasValue {
+		needed := int(math.Ceil(value / rule.Target))
+		want = min(max(needed, rule.Min), rule.Max)
+		d.Reason = fmt.Sprintf("%.4g needs %d machines at %.4g each", value, needed, rule.Target)
+		if want != needed {
+			d.Reason += fmt.Sprintf(", limited to %d-%d", rule.Min, rule.Max)
+		}
+	} else {
+		d.Reason = "no metric data"
+	}
+	if want == current {
+		return d
+	}
+
+	key := autoscaleKey{rule.ProcessGroup, region}
+	cooldown := rule.ScaleDownCooldown
+	if want > current {
+		cooldown = rule.ScaleUpCooldown
+	}
+	inBounds := current >= rule.Min && current <= rule.Max
+	if last, ok := a.lastScaled[key]; ok && inBounds && now.Sub(last) < cooldown {
+		d.Reason += fmt.Sprintf("; cooling down until %s", last.Add(cooldown).Format(time.TimeOnly))
+
+		return d
+	}
+
+	d.Desired = want
+	a.lastScaled[key] = now
+
+	return d
+}
+
+// autoscaleSample holds the values of a query by region at some time.
+type autoscaleSample struct {
+	Time   time.Time
+	Values map[string]float64
+}
+
+// simulateAutoscale replays samples of the query of rule, starting from
+// the started machines in each region in current, and returns every
+// decision made. The simulation assumes machines start and stop as soon as
+// they're asked to.
+func simulateAutoscale(rule autoscaleRule, samples []autoscaleSample, current map[string]int) ([]autoscaleDecision, error) {
+	if len(samples) == 0 {
+		return nil, errors.New("the query returned no data to simulate")
+	}
+
+	regions := rule.Regions
+	if len(regions) == 0 {
+		seen := map[string]bool{}
+		for region := range current {
+			seen[region] = true
+		}
+		for _, s := range samples {
+			for region := range s.Values {
+				seen[region] = true
+			}
+		}
+		for region := range seen {
+			regions = append(regions, region)
+		}
+		slices.Sort(regions)
+	}
+
+	counts := map[string]int{}
+	for _, region := range regions {
+		counts[region] = current[region]
+	}
+
+	scaler := newAutoscaler()
+	var decisions []autoscaleDecision
+	for _, s := range samples {
+		for _, region := range regions {
+			value, ok := s.Values[region]
+			d := scaler.decide(rule, region, value, ok, counts[region], s.Time)
+			counts[region] = d.Desired
+			decisions = append(decisions, d)
+		}
+	}
+
+	return decisions, nil
+}
@@ -0,0 +1,125 @@
+package scale
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/superfly/flyctl/terminal"
+)
+
+// metricsClient queries a Prometheus-compatible API, like the Fly metrics
+// API of an organization, for the values of autoscale rules by region.
+type metricsClient struct {
+	api promv1.API
+}
+
+func newMetricsClient(address, authorization string) (*metricsClient, error) {
+	client, err := api.NewClient(api.Config{
+		Address:      address,
+		RoundTripper: &authorizationRoundTripper{authorization: authorization, next: api.DefaultRoundTripper},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid metrics URL %q: %w", address, err)
+	}
+
+	return &metricsClient{api: promv1.NewAPI(client)}, nil
+}
+
+// query returns the value of query at t by region.
+func (c *metricsClient) query(ctx context.Context, query string, t time.Time) (map[string]float64, error) {
+	value, warnings, err := c.api.Query(ctx, query, t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	logMetricsWarnings(warnings)
+
+	vector, ok := value.(model.Vector)
+	if !ok {
+		return nil, fmt.Errorf("autoscale query must return an instant vector, not a %s", value.Type())
+	}
+
+	values := map[string]float64{}
+	for _, sample := range vector {
+		region, err := sampleRegion(sample.Metric)
+		if err != nil {
+			return nil, err
+		}
+		values[region] += float64(sample.Value)
+	}
+
+	return values, nil
+}
+
+// queryRange returns the values of query by region every step between start
+// and end.
+func (c *metricsClient) queryRange(ctx context.Context, query string, start, end time.Time, step time.Duration) ([]autoscaleSample, error) {
+	value, warnings, err := c.api.QueryRange(ctx, query, promv1.Range{Start: start, End: end, Step: step})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	logMetricsWarnings(warnings)
+
+	matrix, ok := value.(model.Matrix)
+	if !ok {
+		return nil, fmt.Errorf("autoscale query must return a range vector, not a %s", value.Type())
+	}
+
+	byTime := map[model.Time]map[string]float64{}
+	for _, stream := range matrix {
+		region, err := sampleRegion(stream.Metric)
+		if err != nil {
+			return nil, err
+		}
+		for _, point := range stream.Values {
+			if byTime[point.Timestamp] == nil {
+				byTime[point.Timestamp] = map[string]float64{}
+			}
+			byTime[point.Timestamp][region] += float64(point.Value)
+		}
+	}
+
+	samples := make([]autoscaleSample, 0, len(byTime))
+	for t, values := range byTime {
+		samples = append(samples, autoscaleSample{Time: t.Time(), Values: values})
+	}
+	slices.SortFunc(samples, func(a, b autoscaleSample) int {
+		return a.Time.Compare(b.Time)
+	})
+
+	return samples, nil
+}
+
+func sampleRegion(metric model.Metric) (string, error) {
+	region := string(metric["region"])
+	if region == "" {
+		return "", fmt.Errorf("autoscale query returned %s without a region label; aggregate it with sum by (region) (...)", metric)
+	}
+
+	return region, nil
+}
+
+func logMetricsWarnings(warnings promv1.Warnings) {
+	for _, w := range warnings {
+		terminal.Warnf("metrics query: %s\n", w)
+	}
+}
+
+type authorizationRoundTripper struct {
+	authorization string
+	next          http.RoundTripper
+}
+
+func (t *authorizationRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.authorization != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", t.authorization)
+	}
+
+	return t.next.RoundTrip(req)
+}
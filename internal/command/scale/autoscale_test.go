@@ -0,0 +1,211 @@
+package scale
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	fly "github.com/superfly/fly-go"
+)
+
+var testRule = autoscaleRule{
+	ProcessGroup:      "app",
+	Query:             "q",
+	Target:            10,
+	Min:               1,
+	Max:               5,
+	ScaleUpCooldown:   time.Minute,
+	ScaleDownCooldown: 5 * time.Minute,
+}
+
+func TestAutoscalerDecide(t *testing.T) {
+	scaler := newAutoscaler()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	d := scaler.decide(testRule, "ord", 35, true, 2, now)
+	assert.Equal(t, 4, d.Desired)
+	assert.Equal(t, "35 needs 4 machines at 10 each", d.Reason)
+
+	// Scaling down within the cooldown is held back.
+	d = scaler.decide(testRule, "ord", 5, true, 4, now.Add(time.Minute))
+	assert.Equal(t, 4, d.Desired)
+	assert.Contains(t, d.Reason, "cooling down until 12:05:00")
+
+	// Other regions have their own cooldowns.
+	d = scaler.decide(testRule, "ams", 5, true, 3, now.Add(time.Minute))
+	assert.Equal(t, 1, d.Desired)
+
+	d = scaler.decide(testRule, "ord", 5, true, 4, now.Add(5*time.Minute))
+	assert.Equal(t, 1, d.Desired)
+
+	// Max limits scaling up, and being out of bounds skips the cooldown.
+	d = scaler.decide(testRule, "ord", 1000, true, 1, now.Add(6*time.Minute))
+	assert.Equal(t, 5, d.Desired)
+	assert.Equal(t, "1000 needs 100 machines at 10 each, limited to 1-5", d.Reason)
+	d = scaler.decide(testRule, "ord", 0, false, 7, now.Add(6*time.Minute+time.Second))
+	assert.Equal(t, 5, d.Desired)
+
+	// Without data, machines within bounds are left alone.
+	d = scaler.decide(testRule, "ord", 0, false, 3, now.Add(time.Hour))
+	assert.Equal(t, 3, d.Desired)
+	assert.Equal(t, "no metric data", d.Reason)
+}
+
+func TestSimulateAutoscale(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var samples []autoscaleSample
+	for i, value := range []float64{10, 45, 45, 45, 10, 10, 10, 10, 10, 10} {
+		samples = append(samples, autoscaleSample{
+			Time:   start.Add(time.Duration(i) * time.Minute),
+			Values: map[string]float64{"ord": value},
+		})
+	}
+
+	decisions, err := simulateAutoscale(testRule, samples, map[string]int{"ord": 1})
+	require.NoError(t, err)
+	require.Len(t, decisions, len(samples))
+
+	var counts []int
+	for _, d := range decisions {
+		counts = append(counts, d.Desired)
+	}
+	// Up as soon as the load arrives, down once the 5m cooldown is over.
+	assert.Equal(t, []int{1, 5, 5, 5, 5, 5, 1, 1, 1, 1}, counts)
+
+	assert.Equal(t, [][]string{{"app", "ord", "1", "1", "1", "5"}}, autoscaleSummary(decisions))
+
+	_, err = simulateAutoscale(testRule, nil, nil)
+	assert.Error(t, err)
+}
+
+func TestPlanAutoscale(t *testing.T) {
+	machines := []*fly.Machine{
+		{ID: "a", Region: "ord", State: fly.MachineStateStarted, CreatedAt: "2026-01-01T00:00:00Z"},
+		{ID: "b", Region: "ord", State: fly.MachineStateStarted, CreatedAt: "2026-01-02T00:00:00Z"},
+		{ID: "c", Region: "ord", State: fly.MachineStateStopped},
+		{ID: "d", Region: "ams", State: fly.MachineStateStopped},
+	}
+
+	plan := planAutoscale(machines, autoscaleDecision{Region: "ord", Current: 2, Desired: 4})
+	assert.Equal(t, []string{"c"}, machineIDs(plan.Start))
+	assert.Equal(t, 1, plan.Create)
+
+	plan = planAutoscale(machines, autoscaleDecision{Region: "ord", Current: 2, Desired: 1})
+	assert.Equal(t, []string{"b"}, machineIDs(plan.Stop))
+	assert.Zero(t, plan.Create)
+}
+
+func machineIDs(machines []*fly.Machine) []string {
+	var ids []string
+	for _, m := range machines {
+		ids = append(ids, m.ID)
+	}
+
+	return ids
+}
+
+func TestLoadAutoscaleRules(t *testing.T) {
+	dir := t.TempDir()
+	rulesPath := filepath.Join(dir, "autoscale.toml")
+	require.NoError(t, os.WriteFile(rulesPath, []byte(`
+[[rules]]
+process_group = "web"
+regions = ["ord"]
+query = 'sum by (region) (fly_app_concurrency{app="my-app"})'
+target = 20.0
+max = 10
+scale_up_cooldown = "30s"
+
+[[rules]]
+process_group = "worker"
+query = "sum by (region) (queue_depth)"
+target = 100.0
+min = 0
+max = 3
+`), 0o644))
+
+	rules, err := loadAutoscaleRules(rulesPath, "default")
+	require.NoError(t, err)
+	assert.Equal(t, []autoscaleRule{
+		{
+			ProcessGroup: "web", Regions: []string{"ord"}, Query: `sum by (region) (fly_app_concurrency{app="my-app"})`, Target: 20, Min: 1, Max: 10,
+			ScaleUpCooldown: 30 * time.Second, ScaleDownCooldown: defaultScaleDownCooldown,
+		},
+		{
+			ProcessGroup: "worker", Query: "sum by (region) (queue_depth)", Target: 100, Min: 0, Max: 3,
+			ScaleUpCooldown: defaultScaleUpCooldown, ScaleDownCooldown: defaultScaleDownCooldown,
+		},
+	}, rules)
+
+	// The default query is about the whole app, so it only fits a single rule
+	require.NoError(t, os.WriteFile(rulesPath, []byte(`
+[[rules]]
+process_group = "web"
+target = 20.0
+max = 10
+
+[[rules]]
+process_group = "worker"
+query = "sum by (region) (queue_depth)"
+target = 100.0
+max = 3
+`), 0o644))
+	_, err = loadAutoscaleRules(rulesPath, "default")
+	assert.ErrorContains(t, err, `autoscale rule for process group "web" in `+rulesPath+` needs a query`)
+
+	require.NoError(t, os.WriteFile(rulesPath, []byte("[[rules]]\nprocess_group = \"web\"\ntarget = 20.0\nmax = 10\n"), 0o644))
+	rules, err = loadAutoscaleRules(rulesPath, "default")
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, "default", rules[0].Query)
+
+	require.NoError(t, os.WriteFile(rulesPath, []byte("[[rules]]\ntarget = 1.0\nmax = 0\n"), 0o644))
+	_, err = loadAutoscaleRules(rulesPath, "q")
+	assert.ErrorContains(t, err, `process group "app" needs a max of at least 1`)
+}
+
+func TestMetricsClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "sum by (region) (load)", r.Form.Get("query"))
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/query":
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"region":"ord"},"value":[1767225600,"12.5"]},
+				{"metric":{"region":"ams"},"value":[1767225600,"3"]}
+			]}}`))
+		case "/api/v1/query_range":
+			w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[
+				{"metric":{"region":"ord"},"values":[[1767225660,"2"],[1767225600,"1"]]},
+				{"metric":{"region":"ams"},"values":[[1767225600,"5"]]}
+			]}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client, err := newMetricsClient(server.URL, "Bearer secret")
+	require.NoError(t, err)
+
+	values, err := client.query(context.Background(), "sum by (region) (load)", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"ord": 12.5, "ams": 3}, values)
+
+	start := time.Unix(1767225600, 0)
+	samples, err := client.queryRange(context.Background(), "sum by (region) (load)", start, start.Add(time.Minute), time.Minute)
+	require.NoError(t, err)
+	require.Len(t, samples, 2)
+	assert.True(t, samples[0].Time.Equal(start))
+	assert.Equal(t, map[string]float64{"ord": 1, "ams": 5}, samples[0].Values)
+	assert.Equal(t, map[string]float64{"ord": 2}, samples[1].Values)
+}
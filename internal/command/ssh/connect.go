@@ -41,13 +41,28 @@ type OrganizationImpl interface {
 func Connect(p *ConnectParams, addr string) (*ssh.Client, error) {
 	terminal.Debugf("Fetching certificate for %s\n", addr)
 
+	cert, pemkey, err := issueConnectCertificate(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return ConnectWithCertificate(p, addr, cert, pemkey)
+}
+
+// issueConnectCertificate issues a single-use certificate for p that can be
+// shared by several connections through ConnectWithCertificate.
+func issueConnectCertificate(p *ConnectParams) (*fly.IssuedCertificate, []byte, error) {
 	cert, pk, err := singleUseSSHCertificate(p.Ctx, p.Org, p.AppNames, p.Username)
 	if err != nil {
-		return nil, fmt.Errorf("create ssh certificate: %w (if you haven't created a key for your org yet, try `flyctl ssh issue`)", err)
+		return nil, nil, fmt.Errorf("create ssh certificate: %w (if you haven't created a key for your org yet, try `flyctl ssh issue`)", err)
 	}
 
-	pemkey := ssh.MarshalED25519PrivateKey(pk, "single-use certificate")
+	return cert, ssh.MarshalED25519PrivateKey(pk, "single-use certificate"), nil
+}
 
+// ConnectWithCertificate is like Connect but uses an already issued
+// certificate and its PEM-encoded private key.
+func ConnectWithCertificate(p *ConnectParams, addr string, cert *fly.IssuedCertificate, pemkey []byte) (*ssh.Client, error) {
 	terminal.Debugf("Keys for %s configured; connecting...\n", addr)
 
 	sshClient := &ssh.Client{
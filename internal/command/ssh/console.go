@@ -84,19 +84,19 @@ func quiet(ctx context.Context) bool {
 	return flag.GetBool(ctx, "quiet")
 }
 
-func lookupAddressAndContainer(ctx context.Context, cli *agent.Client, dialer agent.Dialer, app *fly.AppCompact, console bool) (addr string, container string, err error) {
+func lookupAddressAndContainer(ctx context.Context, cli *agent.Client, dialer agent.Dialer, app *fly.AppCompact, console bool) (addr string, container string, machine *fly.Machine, err error) {
 	selectedMachine, err := selectMachine(ctx, app)
 	if err != nil {
-		return "", "", err
+		return "", "", nil, err
 	}
 
 	container, err = selectContainer(ctx, selectedMachine)
 	if err != nil {
-		return "", "", err
+		return "", "", nil, err
 	}
 
 	if addr = flag.GetString(ctx, "address"); addr != "" {
-		return addr, container, nil
+		return addr, container, selectedMachine, nil
 	}
 
 	if addr == "" {
@@ -112,11 +112,11 @@ func lookupAddressAndContainer(ctx context.Context, cli *agent.Client, dialer ag
 		if err := cli.WaitForDNS(ctx, dialer, app.Organization.Slug, addr, ""); err != nil {
 			captureError(ctx, err, app)
 
-			return "", "", errors.Wrapf(err, "host unavailable at %s", addr)
+			return "", "", nil, errors.Wrapf(err, "host unavailable at %s", addr)
 		}
 	}
 
-	return
+	return addr, container, selectedMachine, nil
 }
 
 func newConsole() *cobra.Command {
@@ -137,6 +137,34 @@ func newConsole() *cobra.Command {
 			Name:        "no-container",
 			Description: "Connect to the machine itself rather than to one of its containers",
 		},
+		flag.StringArray{
+			Name:        "local-forward",
+			Shorthand:   "L",
+			Description: "Forward a local port to a host and port reachable from the machine, as [bind_address:]port:host:hostport",
+		},
+		flag.StringArray{
+			Name:        "remote-forward",
+			Shorthand:   "R",
+			Description: "Forward a port on the machine to a host and port reachable from here, as [bind_address:]port:host:hostport",
+		},
+		flag.StringArray{
+			Name:        "dynamic-forward",
+			Shorthand:   "D",
+			Description: "Run a local SOCKS5 proxy that connects through the machine, as [bind_address:]port",
+		},
+		flag.Bool{
+			Name:        "forward-only",
+			Shorthand:   "N",
+			Description: "Only set up port forwards; don't run a shell or command",
+		},
+		flag.String{
+			Name:        "record",
+			Description: "Record the session to this file in asciicast v2 format. If it is a directory, a file name is generated",
+		},
+		flag.Bool{
+			Name:        "record-input",
+			Description: "Also record what is typed into the session. Input may contain secrets such as passwords",
+		},
 	)
 
 	return cmd
@@ -188,7 +216,12 @@ func runConsole(ctx context.Context) error {
 		return err
 	}
 
-	addr, container, err := lookupAddressAndContainer(ctx, agentclient, dialer, app, true)
+	addr, container, machine, err := lookupAddressAndContainer(ctx, agentclient, dialer, app, true)
+	if err != nil {
+		return err
+	}
+
+	forwards, err := parseForwards(ctx)
 	if err != nil {
 		return err
 	}
@@ -221,18 +254,72 @@ func runConsole(ctx context.Context) error {
 		return err
 	}
 
+	if !forwards.empty() {
+		fwdCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		running, err := forwards.start(fwdCtx, sshc)
+		if err != nil {
+			captureError(ctx, err, app)
+
+			return err
+		}
+
+		if flag.GetBool(ctx, "forward-only") {
+			fmt.Fprintln(iostreams.FromContext(ctx).ErrOut, "Forwarding ports; press Ctrl+C to stop")
+
+			return waitForwards(ctx, running)
+		}
+	} else if flag.GetBool(ctx, "forward-only") {
+		return errors.New("--forward-only requires at least one of --local-forward, --remote-forward or --dynamic-forward")
+	}
+
 	target := SessionTarget{Container: params.Container, Machine: params.Machine}
 
-	if err := Console(ctx, sshc, cmd, allocPTY, target); err != nil {
+	var recorder *ssh.Recorder
+	if path := flag.GetString(ctx, "record"); path != "" {
+		meta := ssh.RecordingMetadata{
+			App:       app.Name,
+			Machine:   machine.ID,
+			Container: params.Container,
+			User:      params.Username,
+			StartedAt: time.Now(),
+		}
+
+		f, err := createRecording(path, meta)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		recorder = ssh.NewRecorder(f, cmd, meta)
+		recorder.RecordInput = flag.GetBool(ctx, "record-input")
+
+		defer fmt.Fprintf(iostreams.FromContext(ctx).ErrOut, "Session recorded to %s\n", f.Name())
+	}
+
+	if err := ConsoleWithRecorder(ctx, sshc, cmd, allocPTY, target, recorder); err != nil {
 		captureError(ctx, err, app)
 
 		return err
 	}
 
+	if recorder != nil {
+		if err := recorder.Err(); err != nil {
+			return fmt.Errorf("recording session: %w", err)
+		}
+	}
+
 	return nil
 }
 
 func Console(ctx context.Context, sshClient *ssh.Client, cmd string, allocPTY bool, target SessionTarget) error {
+	return ConsoleWithRecorder(ctx, sshClient, cmd, allocPTY, target, nil)
+}
+
+// ConsoleWithRecorder is like Console but records the session with recorder
+// when it's not nil.
+func ConsoleWithRecorder(ctx context.Context, sshClient *ssh.Client, cmd string, allocPTY bool, target SessionTarget, recorder *ssh.Recorder) error {
 	currentStdin, currentStdout, currentStderr, err := setupConsole()
 	defer func() error {
 		if err := cleanupConsole(currentStdin, currentStdout, currentStderr); err != nil {
@@ -252,6 +339,7 @@ func Console(ctx context.Context, sshClient *ssh.Client, cmd string, allocPTY bo
 		Stderr:   ioutils.NewWriteCloserWrapper(colorable.NewColorableStderr(), func() error { return nil }),
 		AllocPTY: allocPTY,
 		TermEnv:  determineTermEnv(),
+		Recorder: recorder,
 	}
 
 	if err := sshClient.Shell(ctx, sessIO, cmd, target); err != nil {
@@ -0,0 +1,114 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/iostreams"
+	"github.com/superfly/flyctl/ssh"
+)
+
+// consoleForwards holds the port forwards requested on the command line.
+type consoleForwards struct {
+	local   []ssh.Forward
+	remote  []ssh.Forward
+	dynamic []string
+}
+
+func parseForwards(ctx context.Context) (*consoleForwards, error) {
+	var fwds consoleForwards
+
+	for _, spec := range flag.GetStringArray(ctx, "local-forward") {
+		fwd, err := ssh.ParseForward(spec)
+		if err != nil {
+			return nil, fmt.Errorf("--local-forward: %w", err)
+		}
+		fwds.local = append(fwds.local, fwd)
+	}
+
+	for _, spec := range flag.GetStringArray(ctx, "remote-forward") {
+		fwd, err := ssh.ParseForward(spec)
+		if err != nil {
+			return nil, fmt.Errorf("--remote-forward: %w", err)
+		}
+		fwds.remote = append(fwds.remote, fwd)
+	}
+
+	for _, spec := range flag.GetStringArray(ctx, "dynamic-forward") {
+		addr, err := ssh.ParseDynamicForward(spec)
+		if err != nil {
+			return nil, fmt.Errorf("--dynamic-forward: %w", err)
+		}
+		fwds.dynamic = append(fwds.dynamic, addr)
+	}
+
+	return &fwds, nil
+}
+
+func (f *consoleForwards) empty() bool {
+	return len(f.local) == 0 && len(f.remote) == 0 && len(f.dynamic) == 0
+}
+
+// start sets up every forward over sshc. Forwards keep running until ctx is
+// cancelled; if any of them can't be set up, the ones already running are
+// left for the caller to stop by cancelling ctx.
+func (f *consoleForwards) start(ctx context.Context, sshc *ssh.Client) ([]*ssh.Forwarding, error) {
+	var (
+		errOut  = iostreams.FromContext(ctx).ErrOut
+		running []*ssh.Forwarding
+	)
+
+	for _, fwd := range f.local {
+		r, err := sshc.LocalForward(ctx, fwd)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(errOut, "Forwarding local %s to %s on the machine\n", r.Addr, fwd.TargetAddr())
+		running = append(running, r)
+	}
+
+	for _, fwd := range f.remote {
+		r, err := sshc.RemoteForward(ctx, fwd)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(errOut, "Forwarding %s on the machine to local %s\n", fwd.ListenAddr(), fwd.TargetAddr())
+		running = append(running, r)
+	}
+
+	for _, addr := range f.dynamic {
+		r, err := sshc.DynamicForward(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(errOut, "SOCKS5 proxy listening on %s\n", r.Addr)
+		running = append(running, r)
+	}
+
+	return running, nil
+}
+
+// waitForwards blocks until ctx is cancelled or any forward stops with an
+// error.
+func waitForwards(ctx context.Context, running []*ssh.Forwarding) error {
+	errCh := make(chan error, len(running))
+	for _, r := range running {
+		go func() {
+			errCh <- r.Wait()
+		}()
+	}
+
+	for range running {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errCh:
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/superfly/flyctl/internal/command"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/iostreams"
+	"github.com/superfly/flyctl/ssh"
+)
+
+func newReplay() *cobra.Command {
+	const (
+		long = `Play back a session recorded with 'fly ssh console --record'.
+Recordings are in asciicast v2 format, so other asciinema-compatible players
+can play them too.`
+		short = "Play back a recorded SSH session"
+		usage = "replay <file>"
+	)
+
+	cmd := command.New(usage, short, long, runReplay)
+
+	cmd.Args = cobra.ExactArgs(1)
+
+	flag.Add(cmd,
+		flag.Float64{
+			Name:        "speed",
+			Default:     1,
+			Description: "Playback speed multiplier",
+		},
+		flag.Duration{
+			Name:        "idle-limit",
+			Description: "Shorten pauses longer than this, e.g. 2s",
+		},
+		flag.Bool{
+			Name:        "info",
+			Description: "Print the recording's metadata instead of playing it",
+		},
+	)
+
+	return cmd
+}
+
+func runReplay(ctx context.Context) error {
+	io := iostreams.FromContext(ctx)
+
+	f, err := os.Open(flag.FirstArg(ctx))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	header, events, err := ssh.ReadRecording(f)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", f.Name(), err)
+	}
+
+	if flag.GetBool(ctx, "info") {
+		printRecordingInfo(io, header, events)
+
+		return nil
+	}
+
+	speed := flag.GetFloat64(ctx, "speed")
+	if speed <= 0 {
+		return errors.New("--speed must be greater than 0")
+	}
+
+	return ssh.Replay(ctx, io.Out, events, ssh.ReplayOptions{
+		Speed:     speed,
+		IdleLimit: flag.GetDuration(ctx, "idle-limit"),
+	})
+}
+
+func printRecordingInfo(io *iostreams.IOStreams, header *ssh.RecordingHeader, events []ssh.RecordingEvent) {
+	if meta := header.Fly; meta != nil {
+		fmt.Fprintf(io.Out, "App:        %s\n", meta.App)
+		fmt.Fprintf(io.Out, "Machine:    %s\n", meta.Machine)
+		if meta.Container != "" {
+			fmt.Fprintf(io.Out, "Container:  %s\n", meta.Container)
+		}
+		fmt.Fprintf(io.Out, "User:       %s\n", meta.User)
+		fmt.Fprintf(io.Out, "Started:    %s\n", meta.StartedAt.Local().Format("2006-01-02 15:04:05 MST"))
+	}
+
+	if header.Command != "" {
+		fmt.Fprintf(io.Out, "Command:    %s\n", header.Command)
+	}
+
+	fmt.Fprintf(io.Out, "Terminal:   %dx%d\n", header.Width, header.Height)
+
+	if len(events) > 0 {
+		fmt.Fprintf(io.Out, "Duration:   %s\n", events[len(events)-1].Time.Round(100*time.Millisecond))
+	}
+}
+
+// createRecording creates the file a session is recorded to. If path is a
+// directory, the file name is generated from meta.
+func createRecording(path string, meta ssh.RecordingMetadata) (*os.File, error) {
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		name := strings.Join([]string{
+			meta.App,
+			meta.Machine,
+			meta.StartedAt.UTC().Format("20060102T150405Z"),
+		}, "-") + ".cast"
+		path = filepath.Join(path, name)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("create recording: %w", err)
+	}
+
+	return f, nil
+}
@@ -0,0 +1,524 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/kballard/go-shellquote"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+	fly "github.com/superfly/fly-go"
+	"github.com/superfly/flyctl/agent"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/command"
+	"github.com/superfly/flyctl/internal/config"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/internal/flapsutil"
+	"github.com/superfly/flyctl/internal/flyutil"
+	"github.com/superfly/flyctl/internal/render"
+	"github.com/superfly/flyctl/internal/statuslogger"
+	"github.com/superfly/flyctl/iostreams"
+	"github.com/superfly/flyctl/ssh"
+	cryptossh "golang.org/x/crypto/ssh"
+)
+
+func newRun() *cobra.Command {
+	const (
+		long = `Run a command on several Machines at once. Select every started Machine
+with --all, or list them with --machine; either can be narrowed down by
+region, process group and metadata. Output from each Machine is printed
+prefixed with its ID once it finishes, or written to one file per Machine
+with --output-dir.`
+		short = "Run a command on several Machines at once"
+		usage = "run [flags] -- <command> [args...]"
+	)
+
+	cmd := command.New(usage, short, long, runRun, command.RequireSession, command.RequireAppName)
+
+	cmd.Args = cobra.ArbitraryArgs
+
+	flag.Add(cmd,
+		flag.Org(),
+		flag.App(),
+		flag.AppConfig(),
+		flag.Region(),
+		flag.ProcessGroup(""),
+		flag.JSONOutput(),
+		flag.String{
+			Name:        "command",
+			Shorthand:   "C",
+			Description: "Command to run; alternatively pass it after --",
+		},
+		flag.Bool{
+			Name:        "all",
+			Description: "Run on every started Machine of the app",
+		},
+		flag.StringSlice{
+			Name:        "machine",
+			Description: "IDs of the Machines to run on",
+		},
+		flag.StringArray{
+			Name:        "metadata",
+			Description: "Only run on Machines whose metadata matches, as key=value. Can be repeated",
+		},
+		flag.Int{
+			Name:        "concurrency",
+			Default:     8,
+			Description: "Maximum number of Machines to run on at the same time",
+		},
+		flag.Duration{
+			Name:        "timeout",
+			Description: "Give up on a Machine if the command hasn't finished after this long",
+		},
+		flag.String{
+			Name:        "output-dir",
+			Description: "Write each Machine's stdout and stderr to <machine-id>.stdout and <machine-id>.stderr in this directory",
+		},
+		flag.String{
+			Name:        "container",
+			Description: "Container to run in on each Machine",
+		},
+		flag.Bool{
+			Name:        "quiet",
+			Shorthand:   "q",
+			Description: "Don't print progress indicators for WireGuard",
+		},
+		flag.String{
+			Name:        "user",
+			Shorthand:   "u",
+			Description: "Unix username to connect as",
+			Default:     DefaultSshUsername,
+		},
+	)
+
+	return cmd
+}
+
+// runResult is the outcome of the command on a single machine.
+type runResult struct {
+	MachineID    string `json:"machine_id"`
+	Region       string `json:"region"`
+	ProcessGroup string `json:"process_group"`
+	// ExitCode is the remote command's exit status, or -1 if it never ran or
+	// didn't report one.
+	ExitCode   int     `json:"exit_code"`
+	Error      string  `json:"error,omitempty"`
+	DurationMS int64   `json:"duration_ms"`
+	Stdout     *string `json:"stdout,omitempty"`
+	Stderr     *string `json:"stderr,omitempty"`
+	StdoutFile string  `json:"stdout_file,omitempty"`
+	StderrFile string  `json:"stderr_file,omitempty"`
+}
+
+func (r *runResult) failed() bool {
+	return r.ExitCode != 0
+}
+
+// runSummary is printed with --json.
+type runSummary struct {
+	App       string       `json:"app"`
+	Command   string       `json:"command"`
+	Total     int          `json:"total"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Results   []*runResult `json:"results"`
+}
+
+func runRun(ctx context.Context) error {
+	var (
+		io       = iostreams.FromContext(ctx)
+		client   = flyutil.ClientFromContext(ctx)
+		appName  = appconfig.NameFromContext(ctx)
+		jsonOut  = config.FromContext(ctx).JSONOutput
+		outDir   = flag.GetString(ctx, "output-dir")
+		parallel = flag.GetInt(ctx, "concurrency")
+	)
+
+	remoteCmd, err := runCommandLine(flag.GetString(ctx, "command"), flag.Args(ctx))
+	if err != nil {
+		return err
+	}
+
+	if parallel < 1 {
+		return errors.New("--concurrency must be at least 1")
+	}
+
+	metadata, err := parseMetadataFilters(flag.GetStringArray(ctx, "metadata"))
+	if err != nil {
+		return err
+	}
+
+	app, err := client.GetAppCompact(ctx, appName)
+	if err != nil {
+		return fmt.Errorf("get app: %w", err)
+	}
+
+	machines, err := flapsutil.ClientFromContext(ctx).ListActive(ctx, app.Name)
+	if err != nil {
+		return fmt.Errorf("list machines: %w", err)
+	}
+
+	machines, err = filterRunMachines(machines, runMachineFilter{
+		all:          flag.GetBool(ctx, "all"),
+		ids:          flag.GetStringSlice(ctx, "machine"),
+		region:       flag.GetRegion(ctx),
+		processGroup: flag.GetProcessGroup(ctx),
+		metadata:     metadata,
+	})
+	if err != nil {
+		return err
+	}
+
+	if outDir != "" {
+		if err := os.MkdirAll(outDir, 0o755); err != nil {
+			return fmt.Errorf("create output directory: %w", err)
+		}
+	}
+
+	network, err := client.GetAppNetwork(ctx, app.Name)
+	if err != nil {
+		return fmt.Errorf("get app network: %w", err)
+	}
+
+	_, dialer, err := agent.BringUpAgent(ctx, client, app, *network, quiet(ctx) || jsonOut)
+	if err != nil {
+		return err
+	}
+
+	params := &ConnectParams{
+		Ctx:            ctx,
+		Org:            app.Organization,
+		Dialer:         dialer,
+		Username:       flag.GetString(ctx, "user"),
+		DisableSpinner: true,
+		Container:      flag.GetString(ctx, "container"),
+		AppNames:       []string{app.Name},
+	}
+
+	// One certificate covers every connection.
+	cert, pemkey, err := issueConnectCertificate(params)
+	if err != nil {
+		return err
+	}
+
+	// Progress goes to stderr so that stdout only carries command output or
+	// the JSON summary.
+	logIO := *io
+	logIO.Out = io.ErrOut
+	logCtx := iostreams.NewContext(ctx, &logIO)
+
+	var (
+		results = make([]*runResult, len(machines))
+		slots   = make(chan struct{}, parallel)
+		timeout = flag.GetDuration(ctx, "timeout")
+	)
+
+	err = statuslogger.AsyncIterateWithErr(logCtx, false, "", machines, func(ctx context.Context, i int, m *fly.Machine) error {
+		statuslogger.Logf(ctx, "Waiting to run on %s", m.ID)
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			results[i] = newRunResult(m)
+			results[i].Error = ctx.Err().Error()
+			return nil
+		}
+		defer func() { <-slots }()
+
+		statuslogger.Logf(ctx, "Running on %s", m.ID)
+
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		res := runOnMachine(ctx, params, cert, pemkey, m, remoteCmd, outDir)
+		results[i] = res
+
+		switch {
+		case res.Error != "":
+			statuslogger.LogStatus(ctx, statuslogger.StatusFailure, fmt.Sprintf("%s: %s", m.ID, res.Error))
+		case res.failed():
+			statuslogger.LogStatus(ctx, statuslogger.StatusFailure, fmt.Sprintf("%s: exited with code %d", m.ID, res.ExitCode))
+		default:
+			statuslogger.LogStatus(ctx, statuslogger.StatusSuccess, fmt.Sprintf("%s: done", m.ID))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	summary := &runSummary{
+		App:     app.Name,
+		Command: remoteCmd,
+		Total:   len(results),
+		Results: results,
+	}
+	for _, r := range results {
+		if r.failed() {
+			summary.Failed++
+		} else {
+			summary.Succeeded++
+		}
+	}
+
+	if jsonOut {
+		if err := render.JSON(io.Out, summary); err != nil {
+			return err
+		}
+	} else {
+		printRunResults(io, summary)
+	}
+
+	if summary.Failed > 0 {
+		return fmt.Errorf("command failed on %d of %d machines", summary.Failed, summary.Total)
+	}
+
+	return nil
+}
+
+// runCommandLine picks the remote command from either --command or the
+// positional arguments, which are quoted as they were given.
+func runCommandLine(flagCmd string, args []string) (string, error) {
+	switch {
+	case flagCmd != "" && len(args) > 0:
+		return "", errors.New("pass the command either with --command or after --, not both")
+	case flagCmd != "":
+		return flagCmd, nil
+	case len(args) > 0:
+		return shellquote.Join(args...), nil
+	default:
+		return "", errors.New("no command given; pass it with --command or after --")
+	}
+}
+
+func parseMetadataFilters(specs []string) (map[string]string, error) {
+	metadata := make(map[string]string, len(specs))
+
+	for _, spec := range specs {
+		k, v, ok := strings.Cut(spec, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid --metadata %q: expected key=value", spec)
+		}
+		metadata[k] = v
+	}
+
+	return metadata, nil
+}
+
+type runMachineFilter struct {
+	all          bool
+	ids          []string
+	region       string
+	processGroup string
+	metadata     map[string]string
+}
+
+// filterRunMachines narrows machines down to the started ones matching f.
+func filterRunMachines(machines []*fly.Machine, f runMachineFilter) ([]*fly.Machine, error) {
+	switch {
+	case f.all && len(f.ids) > 0:
+		return nil, errors.New("--all and --machine are mutually exclusive")
+	case !f.all && len(f.ids) == 0:
+		return nil, errors.New("select Machines with --all or --machine")
+	}
+
+	if len(f.ids) > 0 {
+		byID := lo.KeyBy(machines, func(m *fly.Machine) string { return m.ID })
+
+		var selected []*fly.Machine
+		var stopped []string
+		for _, id := range f.ids {
+			m, ok := byID[id]
+			if !ok {
+				return nil, fmt.Errorf("machine %s not found", id)
+			}
+			if m.State != fly.MachineStateStarted {
+				stopped = append(stopped, id)
+			}
+			selected = append(selected, m)
+		}
+		if len(stopped) > 0 {
+			return nil, fmt.Errorf("machines %s aren't started; start them with 'fly machine start' first", strings.Join(stopped, ", "))
+		}
+		machines = selected
+	}
+
+	machines = lo.Filter(machines, func(m *fly.Machine, _ int) bool {
+		if m.State != fly.MachineStateStarted {
+			return false
+		}
+
+		if f.region != "" && m.Region != f.region {
+			return false
+		}
+
+		if f.processGroup != "" && m.ProcessGroup() != f.processGroup {
+			return false
+		}
+
+		for k, v := range f.metadata {
+			if m.Config == nil || m.Config.Metadata[k] != v {
+				return false
+			}
+		}
+
+		return true
+	})
+
+	if len(machines) == 0 {
+		return nil, errors.New("no started Machines match the given filters")
+	}
+
+	return machines, nil
+}
+
+func newRunResult(m *fly.Machine) *runResult {
+	return &runResult{
+		MachineID:    m.ID,
+		Region:       m.Region,
+		ProcessGroup: m.ProcessGroup(),
+		ExitCode:     -1,
+	}
+}
+
+func runOnMachine(ctx context.Context, params *ConnectParams, cert *fly.IssuedCertificate, pemkey []byte, m *fly.Machine, remoteCmd, outDir string) *runResult {
+	res := newRunResult(m)
+	start := time.Now()
+	defer func() {
+		res.DurationMS = time.Since(start).Milliseconds()
+	}()
+
+	stdout, stderr, finish, err := runOutputs(res, outDir)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer finish()
+
+	p := *params
+	p.Ctx = ctx
+
+	sshc, err := ConnectWithCertificate(&p, m.PrivateIP, cert, pemkey)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer sshc.Close()
+
+	sessIO := &ssh.SessionIO{
+		Stdout: stdout,
+		Stderr: stderr,
+	}
+
+	err = sshc.Shell(ctx, sessIO, remoteCmd, ssh.SessionTarget{Container: p.Container})
+
+	var exitErr *cryptossh.ExitError
+	switch {
+	case err == nil:
+		res.ExitCode = 0
+	case errors.As(err, &exitErr):
+		res.ExitCode = exitErr.ExitStatus()
+	default:
+		res.Error = err.Error()
+	}
+
+	return res
+}
+
+// runOutputs returns where a machine's output goes: files in outDir, or
+// buffers that finish copies into res.
+func runOutputs(res *runResult, outDir string) (stdout, stderr io.WriteCloser, finish func(), err error) {
+	if outDir == "" {
+		var outBuf, errBuf bytes.Buffer
+
+		finish = func() {
+			out, errOut := outBuf.String(), errBuf.String()
+			res.Stdout, res.Stderr = &out, &errOut
+		}
+
+		return nopWriteCloser(&outBuf), nopWriteCloser(&errBuf), finish, nil
+	}
+
+	res.StdoutFile = filepath.Join(outDir, res.MachineID+".stdout")
+	res.StderrFile = filepath.Join(outDir, res.MachineID+".stderr")
+
+	outFile, err := os.Create(res.StdoutFile)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	errFile, err := os.Create(res.StderrFile)
+	if err != nil {
+		outFile.Close()
+		return nil, nil, nil, err
+	}
+
+	finish = func() {
+		outFile.Close()
+		errFile.Close()
+	}
+
+	return nopWriteCloser(outFile), nopWriteCloser(errFile), finish, nil
+}
+
+// nopWriteCloser keeps the session from closing outputs it doesn't own.
+func nopWriteCloser(w io.Writer) io.WriteCloser {
+	return ioutils.NewWriteCloserWrapper(w, func() error { return nil })
+}
+
+func printRunResults(io *iostreams.IOStreams, summary *runSummary) {
+	cs := io.ColorScheme()
+
+	for _, r := range summary.Results {
+		prefix := cs.Bold(fmt.Sprintf("%s %s", r.MachineID, r.Region))
+
+		if r.Stdout != nil {
+			writePrefixed(io.Out, prefix+" | ", *r.Stdout)
+		}
+		if r.Stderr != nil {
+			writePrefixed(io.ErrOut, prefix+" ! ", *r.Stderr)
+		}
+	}
+
+	fmt.Fprintln(io.ErrOut)
+
+	for _, r := range summary.Results {
+		switch {
+		case r.Error != "":
+			fmt.Fprintf(io.ErrOut, "%s %s: %s\n", cs.FailureIcon(), r.MachineID, r.Error)
+		case r.failed():
+			fmt.Fprintf(io.ErrOut, "%s %s: exited with code %d\n", cs.FailureIcon(), r.MachineID, r.ExitCode)
+		default:
+			fmt.Fprintf(io.ErrOut, "%s %s: exited with code 0\n", cs.SuccessIcon(), r.MachineID)
+		}
+
+		if r.StdoutFile != "" {
+			fmt.Fprintf(io.ErrOut, "    output in %s and %s\n", r.StdoutFile, r.StderrFile)
+		}
+	}
+
+	fmt.Fprintf(io.ErrOut, "\n%d of %d machines succeeded\n", summary.Succeeded, summary.Total)
+}
+
+// writePrefixed writes every line of s to w with prefix in front of it.
+func writePrefixed(w io.Writer, prefix, s string) {
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	scanner.Buffer(nil, len(s)+1)
+
+	for scanner.Scan() {
+		fmt.Fprintf(w, "%s%s\n", prefix, scanner.Text())
+	}
+}
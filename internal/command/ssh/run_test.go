@@ -0,0 +1,138 @@
+package ssh
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	fly "github.com/superfly/fly-go"
+)
+
+func TestFilterRunMachines(t *testing.T) {
+	machines := []*fly.Machine{
+		{
+			ID:     "web-ord",
+			State:  fly.MachineStateStarted,
+			Region: "ord",
+			Config: &fly.MachineConfig{Metadata: map[string]string{"fly_process_group": "web", "role": "primary"}},
+		},
+		{
+			ID:     "web-ams",
+			State:  fly.MachineStateStarted,
+			Region: "ams",
+			Config: &fly.MachineConfig{Metadata: map[string]string{"fly_process_group": "web", "role": "replica"}},
+		},
+		{
+			ID:     "worker-ord",
+			State:  fly.MachineStateStarted,
+			Region: "ord",
+			Config: &fly.MachineConfig{Metadata: map[string]string{"fly_process_group": "worker"}},
+		},
+		{
+			ID:     "stopped-ord",
+			State:  fly.MachineStateStopped,
+			Region: "ord",
+			Config: &fly.MachineConfig{Metadata: map[string]string{"fly_process_group": "web"}},
+		},
+	}
+
+	for _, tc := range []struct {
+		name      string
+		filter    runMachineFilter
+		expect    []string
+		expectErr string
+	}{
+		{
+			name:   "all started",
+			filter: runMachineFilter{all: true},
+			expect: []string{"web-ord", "web-ams", "worker-ord"},
+		},
+		{
+			name:   "region",
+			filter: runMachineFilter{all: true, region: "ord"},
+			expect: []string{"web-ord", "worker-ord"},
+		},
+		{
+			name:   "process group",
+			filter: runMachineFilter{all: true, processGroup: "web"},
+			expect: []string{"web-ord", "web-ams"},
+		},
+		{
+			name:   "metadata",
+			filter: runMachineFilter{all: true, metadata: map[string]string{"role": "primary"}},
+			expect: []string{"web-ord"},
+		},
+		{
+			name:   "explicit IDs keep their order",
+			filter: runMachineFilter{ids: []string{"worker-ord", "web-ams"}},
+			expect: []string{"worker-ord", "web-ams"},
+		},
+		{
+			name:      "stopped ID",
+			filter:    runMachineFilter{ids: []string{"web-ord", "stopped-ord"}},
+			expectErr: "machines stopped-ord aren't started; start them with 'fly machine start' first",
+		},
+		{
+			name:      "unknown ID",
+			filter:    runMachineFilter{ids: []string{"nope"}},
+			expectErr: "machine nope not found",
+		},
+		{
+			name:      "nothing selected",
+			filter:    runMachineFilter{},
+			expectErr: "select Machines with --all or --machine",
+		},
+		{
+			name:      "all and IDs",
+			filter:    runMachineFilter{all: true, ids: []string{"web-ord"}},
+			expectErr: "--all and --machine are mutually exclusive",
+		},
+		{
+			name:      "no match",
+			filter:    runMachineFilter{all: true, region: "syd"},
+			expectErr: "no started Machines match the given filters",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := filterRunMachines(machines, tc.filter)
+			if tc.expectErr != "" {
+				require.EqualError(t, err, tc.expectErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expect, lo.Map(got, func(m *fly.Machine, _ int) string { return m.ID }))
+		})
+	}
+}
+
+func TestRunCommandLine(t *testing.T) {
+	cmd, err := runCommandLine("", []string{"uname", "-a"})
+	require.NoError(t, err)
+	assert.Equal(t, "uname -a", cmd)
+
+	// Quoting survives
+	cmd, err = runCommandLine("", []string{"sh", "-c", "echo a b"})
+	require.NoError(t, err)
+	assert.Equal(t, `sh -c 'echo a b'`, cmd)
+
+	cmd, err = runCommandLine("df -h", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "df -h", cmd)
+
+	_, err = runCommandLine("df -h", []string{"uname"})
+	require.Error(t, err)
+
+	_, err = runCommandLine("", nil)
+	require.Error(t, err)
+}
+
+func TestWritePrefixed(t *testing.T) {
+	var buf bytes.Buffer
+
+	writePrefixed(&buf, "abc | ", "one\ntwo\nthree without newline")
+
+	assert.Equal(t, "abc | one\nabc | two\nabc | three without newline\n", buf.String())
+}
@@ -147,7 +147,7 @@ func newSFTPConnection(ctx context.Context) (*sftp.Client, error) {
 		return nil, err
 	}
 
-	addr, container, err := lookupAddressAndContainer(ctx, agentclient, dialer, app, false)
+	addr, container, _, err := lookupAddressAndContainer(ctx, agentclient, dialer, app, false)
 	if err != nil {
 		return nil, err
 	}
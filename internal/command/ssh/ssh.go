@@ -18,6 +18,8 @@ func New() *cobra.Command {
 		newConsole(),
 		newIssue(),
 		newLog(),
+		newRun(),
+		newReplay(),
 		NewSFTP(),
 	)
 
@@ -0,0 +1,484 @@
+package status
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	fly "github.com/superfly/fly-go"
+
+	"github.com/superfly/flyctl/iostreams"
+	"github.com/superfly/flyctl/logs"
+
+	"github.com/superfly/flyctl/internal/flapsutil"
+	"github.com/superfly/flyctl/internal/flyutil"
+	mach "github.com/superfly/flyctl/internal/machine"
+	"github.com/superfly/flyctl/internal/render"
+)
+
+const (
+	dashboardEvents   = 50
+	dashboardLogLines = 500
+	dashboardKeys     = "[yellow]↑/↓[-] select  [yellow]r[-] restart  [yellow]s[-] stop  [yellow]c[-] cordon  [yellow]x[-] ssh console  [yellow]q[-] quit"
+)
+
+var dashboardColumns = []string{"Process", "Region", "ID", "Version", "State", "Role", "Checks", "Last Updated"}
+
+// dashboard is the terminal UI of `fly status --watch`: a live table of the
+// machines of an app grouped by process group and region, their recent
+// events, and the logs of the highlighted machine, which can be restarted,
+// stopped, cordoned or ssh'd into.
+type dashboard struct {
+	ctx      context.Context
+	appName  string
+	interval time.Duration
+
+	app    *tview.Application
+	pages  *tview.Pages
+	header *tview.TextView
+	table  *tview.Table
+	events *tview.TextView
+	logs   *tview.TextView
+	footer *tview.TextView
+
+	// refreshNow asks for the machines to be listed again before the next
+	// interval, like after acting on one.
+	refreshNow chan struct{}
+
+	// These are only used from the event loop of app.
+
+	// machines are the machines shown in the table, the first one in row 1.
+	machines []*fly.Machine
+	// selected is the ID of the highlighted machine.
+	selected string
+	stopLogs context.CancelFunc
+}
+
+func newDashboard(ctx context.Context, appName string, interval time.Duration) *dashboard {
+	d := &dashboard{
+		ctx:        ctx,
+		appName:    appName,
+		interval:   interval,
+		app:        tview.NewApplication(),
+		pages:      tview.NewPages(),
+		header:     tview.NewTextView().SetDynamicColors(true),
+		table:      tview.NewTable().SetSelectable(true, false).SetFixed(1, 0),
+		events:     tview.NewTextView().SetDynamicColors(true).SetMaxLines(dashboardEvents),
+		logs:       tview.NewTextView().SetDynamicColors(true).SetMaxLines(dashboardLogLines),
+		footer:     tview.NewTextView().SetDynamicColors(true).SetText(dashboardKeys),
+		refreshNow: make(chan struct{}, 1),
+	}
+
+	d.table.SetBorder(true).SetTitle(" Machines ")
+	d.events.SetBorder(true).SetTitle(" Recent Events ")
+	d.logs.SetBorder(true).SetTitle(" Logs ")
+	d.table.SetSelectionChangedFunc(func(row, _ int) {
+		d.highlight(row)
+	})
+
+	panes := tview.NewFlex().
+		AddItem(d.events, 0, 1, false).
+		AddItem(d.logs, 0, 2, false)
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(d.header, 1, 0, false).
+		AddItem(d.table, 0, 1, true).
+		AddItem(panes, 0, 1, false).
+		AddItem(d.footer, 1, 0, false)
+	d.pages.AddPage("dashboard", layout, true, true)
+
+	d.app.SetRoot(d.pages, true).SetInputCapture(d.handleKey)
+
+	return d
+}
+
+func (d *dashboard) run() error {
+	ctx, cancel := context.WithCancel(d.ctx)
+	defer cancel()
+	d.ctx = ctx
+
+	go d.poll()
+
+	return d.app.Run()
+}
+
+// poll lists the machines of the app every interval.
+func (d *dashboard) poll() {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		machines, err := flapsutil.ClientFromContext(d.ctx).ListActive(d.ctx, d.appName)
+		if d.ctx.Err() != nil {
+			return
+		}
+		d.app.QueueUpdateDraw(func() {
+			if err != nil {
+				d.setStatus("[red]failed to list machines: %s", err)
+
+				return
+			}
+			d.update(machines)
+		})
+
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.refreshNow:
+		}
+	}
+}
+
+func (d *dashboard) update(machines []*fly.Machine) {
+	d.header.SetText(fmt.Sprintf("[::b]%s[::-] at [::b]%s[::-]", tview.Escape(d.appName), time.Now().UTC().Format(time.TimeOnly)))
+
+	var rows [][]string
+	d.machines, rows = dashboardTable(machines)
+
+	d.table.Clear()
+	for col, name := range dashboardColumns {
+		d.table.SetCell(0, col, tview.NewTableCell(name).SetAttributes(tcell.AttrBold).SetSelectable(false))
+	}
+	for i, row := range rows {
+		for col, text := range row {
+			cell := tview.NewTableCell(tview.Escape(text))
+			switch dashboardColumns[col] {
+			case "State":
+				cell.SetTextColor(stateColor(d.machines[i]))
+			case "Checks":
+				cell.SetTextColor(checksColor(d.machines[i]))
+			}
+			d.table.SetCell(i+1, col, cell)
+		}
+	}
+
+	d.events.SetText(tview.Escape(strings.Join(recentEvents(machines, dashboardEvents), "\n")))
+
+	if len(d.machines) == 0 {
+		d.table.SetCell(1, 0, tview.NewTableCell("No machines").SetSelectable(false))
+		d.highlight(0)
+
+		return
+	}
+
+	// Keep the same machine highlighted
+	row := 1
+	for i, m := range d.machines {
+		if m.ID == d.selected {
+			row = i + 1
+		}
+	}
+	d.table.Select(row, 0)
+}
+
+// highlight follows the machine in row of the table with the logs pane.
+func (d *dashboard) highlight(row int) {
+	machine := d.machineAt(row)
+	id := ""
+	if machine != nil {
+		id = machine.ID
+	}
+	if id == d.selected {
+		return
+	}
+	d.selected = id
+
+	if d.stopLogs != nil {
+		d.stopLogs()
+		d.stopLogs = nil
+	}
+	d.logs.Clear()
+	if machine == nil {
+		d.logs.SetTitle(" Logs ")
+
+		return
+	}
+	d.logs.SetTitle(fmt.Sprintf(" Logs: %s ", id))
+
+	ctx, cancel := context.WithCancel(d.ctx)
+	d.stopLogs = cancel
+	go d.tailLogs(ctx, id)
+}
+
+// tailLogs appends the logs of a machine to the logs pane until ctx is done.
+func (d *dashboard) tailLogs(ctx context.Context, machineID string) {
+	entries := make(chan logs.LogEntry)
+	go func() {
+		defer close(entries)
+
+		opts := &logs.LogOptions{AppName: d.appName, VMID: machineID}
+		if err := logs.Poll(ctx, entries, flyutil.ClientFromContext(ctx), opts); err != nil && ctx.Err() == nil {
+			d.app.QueueUpdateDraw(func() {
+				fmt.Fprintf(d.logs, "[red]failed to get logs: %s[-]\n", tview.Escape(err.Error()))
+			})
+		}
+	}()
+
+	for entry := range entries {
+		if ctx.Err() != nil {
+			continue
+		}
+		line := fmt.Sprintf("[gray]%s[-] %s\n", tview.Escape(entry.Timestamp), tview.Escape(entry.Message))
+		d.app.QueueUpdateDraw(func() {
+			if ctx.Err() == nil {
+				io.WriteString(d.logs, line)
+			}
+		})
+	}
+}
+
+func (d *dashboard) machineAt(row int) *fly.Machine {
+	if row < 1 || row > len(d.machines) {
+		return nil
+	}
+
+	return d.machines[row-1]
+}
+
+func (d *dashboard) handleKey(event *tcell.EventKey) *tcell.EventKey {
+	// Let the confirmation dialog have its keys
+	if front, _ := d.pages.GetFrontPage(); front != "dashboard" {
+		return event
+	}
+
+	row, _ := d.table.GetSelection()
+	machine := d.machineAt(row)
+
+	switch event.Rune() {
+	case 'q':
+		d.app.Stop()
+	case 'r':
+		d.confirm(machine, restartAction)
+	case 's':
+		d.confirm(machine, stopAction)
+	case 'c':
+		d.confirm(machine, cordonAction)
+	case 'x':
+		d.ssh(machine)
+	default:
+		return event
+	}
+
+	return nil
+}
+
+// dashboardAction is something the dashboard can do to a machine.
+type dashboardAction struct {
+	verb, doing, done string
+	run               func(ctx context.Context, appName string, m *fly.Machine) error
+}
+
+var (
+	restartAction = dashboardAction{"Restart", "Restarting", "restarted", func(ctx context.Context, appName string, m *fly.Machine) error {
+		return flapsutil.ClientFromContext(ctx).Restart(ctx, appName, fly.RestartMachineInput{ID: m.ID}, m.LeaseNonce)
+	}}
+	stopAction = dashboardAction{"Stop", "Stopping", "stopped", func(ctx context.Context, appName string, m *fly.Machine) error {
+		return flapsutil.ClientFromContext(ctx).Stop(ctx, appName, fly.StopMachineInput{ID: m.ID}, m.LeaseNonce)
+	}}
+	cordonAction = dashboardAction{"Cordon", "Cordoning", "cordoned", func(ctx context.Context, appName string, m *fly.Machine) error {
+		return flapsutil.ClientFromContext(ctx).Cordon(ctx, appName, m.ID, m.LeaseNonce)
+	}}
+)
+
+// confirm asks whether to do action to machine, and then does so in the
+// background.
+func (d *dashboard) confirm(machine *fly.Machine, action dashboardAction) {
+	if machine == nil {
+		return
+	}
+
+	modal := tview.NewModal().
+		SetText(fmt.Sprintf("%s machine %s (%s, %s)?", action.verb, machine.ID, machine.ProcessGroup(), machine.Region)).
+		AddButtons([]string{action.verb, "Cancel"}).
+		SetDoneFunc(func(_ int, label string) {
+			d.pages.RemovePage("confirm")
+			if label != action.verb {
+				return
+			}
+
+			d.setStatus("%s machine %s...", action.doing, machine.ID)
+			go func() {
+				err := d.act(machine, action)
+				d.app.QueueUpdateDraw(func() {
+					if err != nil {
+						d.setStatus("[red]failed to %s machine %s: %s", strings.ToLower(action.verb), machine.ID, err)
+					} else {
+						d.setStatus("[green]machine %s %s", machine.ID, action.done)
+					}
+				})
+			}()
+		})
+	d.pages.AddPage("confirm", modal, false, true)
+}
+
+// act does action to machine with a lease on it.
+func (d *dashboard) act(machine *fly.Machine, action dashboardAction) error {
+	// Leases report waiting on them to the terminal, which is the dashboard's
+	ctx := iostreams.NewContext(d.ctx, &iostreams.IOStreams{
+		In:     io.NopCloser(strings.NewReader("")),
+		Out:    io.Discard,
+		ErrOut: io.Discard,
+	})
+
+	machine, release, err := mach.AcquireLease(ctx, d.appName, machine)
+	defer release()
+	if err != nil {
+		return err
+	}
+	err = action.run(ctx, d.appName, machine)
+
+	select {
+	case d.refreshNow <- struct{}{}:
+	default:
+	}
+
+	return err
+}
+
+// ssh suspends the dashboard for a `fly ssh console` session on machine.
+func (d *dashboard) ssh(machine *fly.Machine) {
+	if machine == nil {
+		return
+	}
+	if machine.State != fly.MachineStateStarted {
+		d.setStatus("[red]machine %s is %s; start it to ssh into it", machine.ID, machine.State)
+
+		return
+	}
+
+	flyctl, err := os.Executable()
+	if err != nil {
+		d.setStatus("[red]failed to find flyctl: %s", err)
+
+		return
+	}
+
+	streams := iostreams.FromContext(d.ctx)
+	d.app.Suspend(func() {
+		cmd := exec.CommandContext(d.ctx, flyctl, "ssh", "console", "--app", d.appName, "--machine", machine.ID)
+		cmd.Stdin = streams.In
+		cmd.Stdout = streams.Out
+		cmd.Stderr = streams.ErrOut
+		err = cmd.Run()
+	})
+	if err != nil {
+		d.setStatus("[red]ssh console on machine %s failed: %s", machine.ID, err)
+	} else {
+		d.setStatus("")
+	}
+}
+
+// setStatus shows a message next to the key bindings.
+func (d *dashboard) setStatus(format string, a ...any) {
+	text := dashboardKeys
+	if format != "" {
+		text += "  │  " + fmt.Sprintf(format, a...) + "[-]"
+	}
+	d.footer.SetText(text)
+}
+
+// dashboardTable sorts machines by process group, region and ID, and returns
+// them with the rows of the machines table. The process group and region of
+// a row are left out when they're those of the row above.
+func dashboardTable(machines []*fly.Machine) ([]*fly.Machine, [][]string) {
+	sorted := slices.Clone(machines)
+	slices.SortFunc(sorted, func(a, b *fly.Machine) int {
+		return cmp.Or(
+			cmp.Compare(a.ProcessGroup(), b.ProcessGroup()),
+			cmp.Compare(a.Region, b.Region),
+			cmp.Compare(a.ID, b.ID),
+		)
+	})
+
+	rows := make([][]string, 0, len(sorted))
+	for i, machine := range sorted {
+		group, region := getProcessgroup(machine), machine.Region
+		if i > 0 && sorted[i-1].ProcessGroup() == machine.ProcessGroup() {
+			// Keep the standby and unreachable host marks of the group
+			if getProcessgroup(sorted[i-1]) == group {
+				group = ""
+			}
+			if sorted[i-1].Region == region {
+				region = ""
+			}
+		}
+
+		state := machine.State
+		if machine.Cordoned {
+			state += " (cordoned)"
+		}
+
+		rows = append(rows, []string{
+			group,
+			region,
+			machine.ID,
+			getReleaseVersion(machine),
+			state,
+			machine.GetConfig().Metadata["role"],
+			render.MachineHealthChecksSummary(machine),
+			machine.UpdatedAt,
+		})
+	}
+
+	return sorted, rows
+}
+
+// recentEvents returns up to n of the latest events of machines, newest
+// first.
+func recentEvents(machines []*fly.Machine, n int) []string {
+	type machineEvent struct {
+		machineID string
+		event     *fly.MachineEvent
+	}
+
+	var events []machineEvent
+	for _, machine := range machines {
+		for _, event := range machine.Events {
+			events = append(events, machineEvent{machine.ID, event})
+		}
+	}
+	slices.SortStableFunc(events, func(a, b machineEvent) int {
+		return cmp.Compare(b.event.Timestamp, a.event.Timestamp)
+	})
+
+	lines := make([]string, 0, min(n, len(events)))
+	for _, e := range events[:min(n, len(events))] {
+		lines = append(lines, fmt.Sprintf("%s %s %s %s (%s)",
+			e.event.Time().UTC().Format(time.TimeOnly), e.machineID, e.event.Type, e.event.Status, e.event.Source))
+	}
+
+	return lines
+}
+
+func stateColor(machine *fly.Machine) tcell.Color {
+	switch machine.State {
+	case fly.MachineStateStarted:
+		return tcell.ColorGreen
+	case fly.MachineStateStopped, "suspended", "created":
+		return tcell.ColorYellow
+	default:
+		return tcell.ColorRed
+	}
+}
+
+func checksColor(machine *fly.Machine) tcell.Color {
+	color := tcell.ColorGreen
+	for _, check := range machine.Checks {
+		switch check.Status {
+		case fly.Critical:
+			return tcell.ColorRed
+		case fly.Warning:
+			color = tcell.ColorYellow
+		}
+	}
+
+	return color
+}
@@ -0,0 +1,68 @@
+package status
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	fly "github.com/superfly/fly-go"
+)
+
+func dashboardMachine(id, group, region string, events ...*fly.MachineEvent) *fly.Machine {
+	return &fly.Machine{
+		ID:         id,
+		Region:     region,
+		State:      fly.MachineStateStarted,
+		HostStatus: fly.HostStatusOk,
+		Events:     events,
+		Config: &fly.MachineConfig{
+			Metadata: map[string]string{
+				fly.MachineConfigMetadataKeyFlyProcessGroup:   group,
+				fly.MachineConfigMetadataKeyFlyReleaseVersion: "3",
+			},
+		},
+	}
+}
+
+func TestDashboardTable(t *testing.T) {
+	machines := []*fly.Machine{
+		dashboardMachine("d", "worker", "ord"),
+		dashboardMachine("c", "app", "ord"),
+		dashboardMachine("b", "app", "ams"),
+		dashboardMachine("a", "app", "ord"),
+	}
+	machines[1].Cordoned = true
+	machines[3].HostStatus = fly.HostStatusUnreachable
+
+	sorted, rows := dashboardTable(machines)
+
+	var ids []string
+	for _, m := range sorted {
+		ids = append(ids, m.ID)
+	}
+	require.Equal(t, []string{"b", "a", "c", "d"}, ids)
+
+	require.Equal(t, [][]string{
+		{"app", "ams", "b", "3", "started", "", "", ""},
+		{"app💀", "ord", "a", "3", "started", "", "", ""},
+		{"app", "", "c", "3", "started (cordoned)", "", "", ""},
+		{"worker", "ord", "d", "3", "started", "", "", ""},
+	}, rows)
+}
+
+func TestRecentEvents(t *testing.T) {
+	machines := []*fly.Machine{
+		dashboardMachine("a", "app", "ord",
+			&fly.MachineEvent{Type: "start", Status: "started", Source: "flyd", Timestamp: 3_000},
+			&fly.MachineEvent{Type: "launch", Status: "created", Source: "user", Timestamp: 1_000},
+		),
+		dashboardMachine("b", "app", "ord",
+			&fly.MachineEvent{Type: "exit", Status: "stopped", Source: "flyd", Timestamp: 2_000},
+		),
+	}
+
+	require.Equal(t, []string{
+		"00:00:03 a start started (flyd)",
+		"00:00:02 b exit stopped (flyd)",
+	}, recentEvents(machines, 2))
+	require.Len(t, recentEvents(machines, 10), 3)
+}
@@ -2,16 +2,12 @@
 package status
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
-	"strings"
 	"time"
 
-	"github.com/azazeal/pause"
-	"github.com/inancgumus/screen"
 	"github.com/spf13/cobra"
 
 	"github.com/superfly/flyctl/iostreams"
@@ -53,7 +49,7 @@ currently allocated.
 		},
 		flag.Bool{
 			Name:        "watch",
-			Description: "Refresh details",
+			Description: "Show a live dashboard of the app's machines, their events and logs, to restart, stop, cordon or ssh into them",
 		},
 		flag.Int{
 			Name:        "rate",
@@ -103,7 +99,6 @@ func runWatch(ctx context.Context) (err error) {
 
 		return
 	}
-	colorize := streams.ColorScheme()
 
 	sleep := flag.GetInt(ctx, "rate")
 	if sleep < 1 || sleep > 3600 {
@@ -114,27 +109,7 @@ func runWatch(ctx context.Context) (err error) {
 
 	appName := appconfig.NameFromContext(ctx)
 
-	var buf bytes.Buffer
-
-	for err == nil {
-		buf.Reset()
-
-		if err = once(ctx, &buf); err != nil {
-			break
-		}
-
-		header := fmt.Sprintf("%s %s %s\n\n", colorize.Bold(appName), "at:", colorize.Bold(time.Now().UTC().Format("15:04:05")))
-
-		screen.Clear()
-		screen.MoveTopLeft()
-
-		io.Copy(streams.Out, io.MultiReader(
-			strings.NewReader(header),
-			&buf,
-		))
-
-		pause.For(ctx, time.Duration(sleep)*time.Second)
-	}
+	err = newDashboard(ctx, appName, time.Duration(sleep)*time.Second).run()
 
 	// Interrupted with Ctrl-C
 	if errors.Is(ctx.Err(), context.Canceled) {
@@ -0,0 +1,383 @@
+package volumes
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Volume archives are zstd-compressed tarballs. The volume's files live under
+// data/, and a manifest with per-file checksums is written last as
+// manifest.json, since the checksums are only known once every file has been
+// read.
+const (
+	archiveDataDir      = "data"
+	archiveManifestName = "manifest.json"
+	archiveVersion      = 1
+)
+
+// archiveManifest describes where an archive came from and what it holds.
+type archiveManifest struct {
+	Version    int            `json:"version"`
+	App        string         `json:"app"`
+	VolumeID   string         `json:"volume_id"`
+	VolumeName string         `json:"volume_name"`
+	Region     string         `json:"region"`
+	SizeGb     int            `json:"size_gb"`
+	SourcePath string         `json:"source_path"`
+	CreatedAt  time.Time      `json:"created_at"`
+	Files      []archivedFile `json:"files"`
+}
+
+type archivedFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// archiveFS is the set of file operations needed to archive or restore a
+// volume. Paths are slash-separated. It is implemented over SFTP for volumes
+// mounted in a Machine.
+type archiveFS interface {
+	Lstat(p string) (fs.FileInfo, error)
+	ReadDir(p string) ([]fs.FileInfo, error)
+	ReadLink(p string) (string, error)
+	Open(p string) (io.ReadCloser, error)
+	Create(p string, mode fs.FileMode) (io.WriteCloser, error)
+	Mkdir(p string, mode fs.FileMode) error
+	Symlink(target, p string) error
+	Lchown(p string, uid, gid int) error
+	Chtimes(p string, mtime time.Time) error
+	// Owner returns the numeric owner of the file fi describes.
+	Owner(fi fs.FileInfo) (uid, gid int)
+}
+
+// writeVolumeArchive archives everything below root in fsys into w. The
+// manifest's Files and CreatedAt are filled in as the archive is written.
+func writeVolumeArchive(w io.Writer, fsys archiveFS, root string, manifest *archiveManifest) error {
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(zw)
+
+	manifest.Version = archiveVersion
+	manifest.SourcePath = root
+	manifest.CreatedAt = time.Now().UTC()
+	manifest.Files = nil
+
+	if err := archiveTree(tw, fsys, root, "", manifest); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     archiveManifestName,
+		Mode:     0o644,
+		Size:     int64(len(data)),
+		ModTime:  manifest.CreatedAt,
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return err
+	}
+
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// archiveTree writes dir, relative to root, and everything below it.
+func archiveTree(tw *tar.Writer, fsys archiveFS, root, dir string, manifest *archiveManifest) error {
+	entries, err := fsys.ReadDir(path.Join(root, dir))
+	if err != nil {
+		return fmt.Errorf("read %s: %w", path.Join(root, dir), err)
+	}
+
+	for _, fi := range entries {
+		rel := path.Join(dir, fi.Name())
+		full := path.Join(root, rel)
+
+		// ext4 creates this at the root of every volume; restoring it would
+		// only get in the way.
+		if dir == "" && fi.Name() == "lost+found" {
+			continue
+		}
+
+		var link string
+		if fi.Mode()&fs.ModeSymlink != 0 {
+			if link, err = fsys.ReadLink(full); err != nil {
+				return fmt.Errorf("read link %s: %w", full, err)
+			}
+		}
+
+		if !fi.Mode().IsRegular() && !fi.IsDir() && fi.Mode()&fs.ModeSymlink == 0 {
+			// Sockets, devices and the like can't be meaningfully copied.
+			continue
+		}
+
+		hdr, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return fmt.Errorf("archive %s: %w", full, err)
+		}
+		hdr.Name = path.Join(archiveDataDir, rel)
+		if fi.IsDir() {
+			hdr.Name += "/"
+		}
+		hdr.Uid, hdr.Gid = fsys.Owner(fi)
+		hdr.Uname, hdr.Gname = "", ""
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		switch {
+		case fi.IsDir():
+			if err := archiveTree(tw, fsys, root, rel, manifest); err != nil {
+				return err
+			}
+		case fi.Mode().IsRegular():
+			sum, err := archiveFile(tw, fsys, full)
+			if err != nil {
+				return err
+			}
+			manifest.Files = append(manifest.Files, archivedFile{Path: rel, Size: fi.Size(), SHA256: sum})
+		}
+	}
+
+	return nil
+}
+
+func archiveFile(w io.Writer, fsys archiveFS, p string) (string, error) {
+	f, err := fsys.Open(p)
+	if err != nil {
+		return "", fmt.Errorf("open %s: %w", p, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, h), f); err != nil {
+		return "", fmt.Errorf("read %s: %w", p, err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// readArchiveManifest returns the manifest of the archive in r without
+// extracting anything.
+func readArchiveManifest(r io.Reader) (*archiveManifest, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("archive has no manifest; was it created by `fly volumes export`?")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if hdr.Name == archiveManifestName {
+			var manifest archiveManifest
+			if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+				return nil, fmt.Errorf("read manifest: %w", err)
+			}
+
+			return &manifest, nil
+		}
+	}
+}
+
+// extractVolumeArchive restores the archive in r below root in fsys and
+// checks every file against the manifest.
+func extractVolumeArchive(r io.Reader, fsys archiveFS, root string) (*archiveManifest, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	var (
+		tr       = tar.NewReader(zr)
+		sums     = map[string]string{}
+		dirTimes = map[string]time.Time{}
+		manifest *archiveManifest
+	)
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if hdr.Name == archiveManifestName {
+			manifest = &archiveManifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("read manifest: %w", err)
+			}
+
+			continue
+		}
+
+		rel, ok := archiveRelPath(hdr.Name)
+		if !ok {
+			return nil, fmt.Errorf("unexpected path %q in archive", hdr.Name)
+		}
+		if rel == "" {
+			continue
+		}
+
+		target := path.Join(root, rel)
+		mode := fs.FileMode(hdr.Mode).Perm()
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := fsys.Mkdir(target, mode); err != nil {
+				return nil, fmt.Errorf("create %s: %w", target, err)
+			}
+			dirTimes[target] = hdr.ModTime
+		case tar.TypeSymlink:
+			if err := fsys.Symlink(hdr.Linkname, target); err != nil {
+				return nil, fmt.Errorf("create %s: %w", target, err)
+			}
+		case tar.TypeReg:
+			sum, err := extractFile(tr, fsys, target, mode)
+			if err != nil {
+				return nil, err
+			}
+			sums[rel] = sum
+		default:
+			continue
+		}
+
+		if err := fsys.Lchown(target, hdr.Uid, hdr.Gid); err != nil {
+			return nil, fmt.Errorf("chown %s: %w", target, err)
+		}
+
+		if hdr.Typeflag == tar.TypeReg {
+			if err := fsys.Chtimes(target, hdr.ModTime); err != nil {
+				return nil, fmt.Errorf("set times on %s: %w", target, err)
+			}
+		}
+	}
+
+	// Directory times change as their contents are written, so set them last.
+	for dir, mtime := range dirTimes {
+		if err := fsys.Chtimes(dir, mtime); err != nil {
+			return nil, fmt.Errorf("set times on %s: %w", dir, err)
+		}
+	}
+
+	if manifest == nil {
+		return nil, errors.New("archive has no manifest; was it created by `fly volumes export`?")
+	}
+
+	if err := verifyArchivedFiles(manifest, sums); err != nil {
+		return manifest, err
+	}
+
+	return manifest, nil
+}
+
+func extractFile(r io.Reader, fsys archiveFS, target string, mode fs.FileMode) (string, error) {
+	f, err := fsys.Create(target, mode)
+	if err != nil {
+		return "", fmt.Errorf("create %s: %w", target, err)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), r); err != nil {
+		f.Close()
+		return "", fmt.Errorf("write %s: %w", target, err)
+	}
+
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("write %s: %w", target, err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// archiveRelPath maps an archive entry name to a path relative to the data
+// directory, rejecting anything that would land outside of it.
+func archiveRelPath(name string) (string, bool) {
+	name = strings.TrimSuffix(name, "/")
+	if name == archiveDataDir {
+		return "", true
+	}
+
+	rel, ok := strings.CutPrefix(name, archiveDataDir+"/")
+	if !ok || !fs.ValidPath(rel) {
+		return "", false
+	}
+
+	return rel, true
+}
+
+func verifyArchivedFiles(manifest *archiveManifest, sums map[string]string) error {
+	var problems []string
+
+	for _, f := range manifest.Files {
+		got, ok := sums[f.Path]
+		switch {
+		case !ok:
+			problems = append(problems, f.Path+" is missing")
+		case got != f.SHA256:
+			problems = append(problems, f.Path+" has the wrong checksum")
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("archive is corrupt: %s", strings.Join(problems, ", "))
+	}
+
+	return nil
+}
+
+// checksumLine formats sum the way sha256sum does, so that the checksum file
+// written next to an archive can be checked with `sha256sum -c`.
+func checksumLine(sum []byte, name string) string {
+	return fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum), name)
+}
+
+// parseChecksumLine reads the checksum out of a line written by checksumLine.
+func parseChecksumLine(line string) (string, error) {
+	sum, _, ok := strings.Cut(strings.TrimSpace(line), " ")
+	if !ok || len(sum) != sha256.Size*2 {
+		return "", errors.New("malformed checksum file")
+	}
+
+	if _, err := hex.DecodeString(sum); err != nil {
+		return "", errors.New("malformed checksum file")
+	}
+
+	return sum, nil
+}
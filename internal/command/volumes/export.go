@@ -0,0 +1,249 @@
+package volumes
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+	fly "github.com/superfly/fly-go"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/command"
+	"github.com/superfly/flyctl/internal/config"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/internal/flapsutil"
+	"github.com/superfly/flyctl/internal/flyutil"
+	"github.com/superfly/flyctl/internal/render"
+	"github.com/superfly/flyctl/iostreams"
+	"github.com/superfly/flyctl/terminal"
+)
+
+func newExport() *cobra.Command {
+	const (
+		short = "Export the contents of a volume to a local archive."
+
+		long = short + ` The archive is a zstd-compressed tarball with a manifest
+of per-file checksums, and a sha256sum-compatible checksum file is written next
+to it. Restore it with 'fly volumes import'.
+
+If the volume is attached to a running Machine, files are copied from it over
+SFTP while it keeps running. Use --fork to copy from a fork of the volume
+instead, which gives a consistent copy without touching the running Machine.
+Volumes that aren't attached are mounted in a temporary Machine.`
+
+		usage = "export <volume id>"
+	)
+
+	cmd := command.New(usage, short, long, runExport,
+		command.RequireSession,
+		command.RequireAppName,
+	)
+
+	cmd.Args = cobra.MaximumNArgs(1)
+
+	flag.Add(cmd,
+		flag.App(),
+		flag.AppConfig(),
+		flag.JSONOutput(),
+		flag.String{
+			Name:        "to",
+			Description: "Path of the archive to write. Defaults to <volume id>.tar.zst",
+		},
+		flag.Bool{
+			Name:        "fork",
+			Description: "Fork the volume and export the fork from a temporary Machine, leaving the attached Machine alone",
+		},
+		flag.String{
+			Name:        "image",
+			Description: "Image for the temporary Machine. Defaults to the app's current image",
+		},
+	)
+
+	return cmd
+}
+
+func runExport(ctx context.Context) error {
+	var (
+		io          = iostreams.FromContext(ctx)
+		cfg         = config.FromContext(ctx)
+		appName     = appconfig.NameFromContext(ctx)
+		volID       = flag.FirstArg(ctx)
+		flapsClient = flapsutil.ClientFromContext(ctx)
+	)
+
+	var (
+		vol *fly.Volume
+		err error
+	)
+	if volID == "" {
+		app, err := flyutil.ClientFromContext(ctx).GetAppBasic(ctx, appName)
+		if err != nil {
+			return err
+		}
+		if vol, err = selectVolume(ctx, flapsClient, app); err != nil {
+			return err
+		}
+	} else if vol, err = flapsClient.GetVolume(ctx, appName, volID); err != nil {
+		return fmt.Errorf("failed to get volume: %w", err)
+	}
+
+	dest := flag.GetString(ctx, "to")
+	if dest == "" {
+		dest = vol.ID + ".tar.zst"
+	}
+
+	m, root, cleanup, err := exportSource(ctx, appName, vol)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	ftp, closeFTP, err := connectSFTP(ctx, appName, m)
+	if err != nil {
+		return err
+	}
+	defer closeFTP()
+
+	if !cfg.JSONOutput {
+		fmt.Fprintf(io.ErrOut, "Exporting %s from Machine %s to %s\n", root, m.ID, dest)
+	}
+
+	manifest := &archiveManifest{
+		App:        appName,
+		VolumeID:   vol.ID,
+		VolumeName: vol.Name,
+		Region:     vol.Region,
+		SizeGb:     vol.SizeGb,
+	}
+
+	sum, size, err := writeArchiveFile(dest, sftpFS{ftp}, root, manifest)
+	if err != nil {
+		return err
+	}
+
+	if cfg.JSONOutput {
+		return render.JSON(io.Out, map[string]any{
+			"archive":  dest,
+			"sha256":   sum,
+			"size":     size,
+			"manifest": manifest,
+		})
+	}
+
+	fmt.Fprintf(io.Out, "Exported %d files from volume %s to %s (%s)\n", len(manifest.Files), vol.ID, dest, humanize.IBytes(uint64(size)))
+	fmt.Fprintf(io.Out, "SHA-256: %s\n", sum)
+
+	return nil
+}
+
+// exportSource returns a running Machine with the data to export mounted, and
+// where. The returned function tears down anything created for the export.
+func exportSource(ctx context.Context, appName string, vol *fly.Volume) (*fly.Machine, string, func(), error) {
+	flapsClient := flapsutil.ClientFromContext(ctx)
+	noop := func() {}
+
+	if vol.AttachedMachine != nil && !flag.GetBool(ctx, "fork") {
+		m, err := flapsClient.Get(ctx, appName, *vol.AttachedMachine)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		if err := requireStarted(m); err != nil {
+			return nil, "", nil, err
+		}
+
+		root, err := attachedMountPath(m, vol)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		return m, root, noop, nil
+	}
+
+	image, err := helperImage(ctx, appName, flag.GetString(ctx, "image"))
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	source := vol
+	removeFork := noop
+
+	if flag.GetBool(ctx, "fork") {
+		fork, err := flapsClient.CreateVolume(ctx, appName, fly.CreateVolumeRequest{
+			Name:              vol.Name,
+			Region:            vol.Region,
+			SourceVolumeID:    &vol.ID,
+			RequireUniqueZone: new(false),
+			ComputeImage:      image,
+		})
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to fork volume: %w", err)
+		}
+
+		source = fork
+		removeFork = func() {
+			if _, err := flapsClient.DeleteVolume(context.WithoutCancel(ctx), appName, fork.ID); err != nil {
+				terminal.Warnf("Failed to delete forked volume %s: %v\n", fork.ID, err)
+				terminal.Warn("You may need to destroy it manually (`fly volumes destroy`).")
+			}
+		}
+	}
+
+	m, destroy, err := launchVolumeHelper(ctx, appName, source, image, "to export volume "+vol.ID)
+	if err != nil {
+		removeFork()
+		return nil, "", nil, err
+	}
+
+	return m, helperMountPath, func() {
+		destroy()
+		removeFork()
+	}, nil
+}
+
+// writeArchiveFile writes the archive to dest, along with a checksum file,
+// and returns the archive's checksum and size. Nothing is left at dest if
+// the export fails.
+func writeArchiveFile(dest string, fsys archiveFS, root string, manifest *archiveManifest) (string, int64, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(dest), filepath.Base(dest)+".partial-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	counter := &countingWriter{}
+
+	if err := writeVolumeArchive(io.MultiWriter(tmp, h, counter), fsys, root, manifest); err != nil {
+		tmp.Close()
+		return "", 0, err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return "", 0, err
+	}
+
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return "", 0, err
+	}
+
+	sum := h.Sum(nil)
+	if err := os.WriteFile(dest+".sha256", []byte(checksumLine(sum, filepath.Base(dest))), 0o644); err != nil {
+		return "", 0, fmt.Errorf("write checksum file: %w", err)
+	}
+
+	return fmt.Sprintf("%x", sum), counter.n, nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
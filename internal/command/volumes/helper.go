@@ -0,0 +1,205 @@
+package volumes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/pkg/sftp"
+	fly "github.com/superfly/fly-go"
+	"github.com/superfly/flyctl/agent"
+	"github.com/superfly/flyctl/internal/command/ssh"
+	"github.com/superfly/flyctl/internal/flyutil"
+	"github.com/superfly/flyctl/internal/machine"
+)
+
+// helperMountPath is where helper Machines mount the volume they work on.
+const helperMountPath = "/data"
+
+// launchVolumeHelper starts an ephemeral Machine with vol mounted at
+// helperMountPath, so its contents can be reached over SFTP. The returned
+// function destroys the Machine.
+func launchVolumeHelper(ctx context.Context, appName string, vol *fly.Volume, image, what string) (*fly.Machine, func(), error) {
+	input := &machine.EphemeralInput{
+		LaunchInput: fly.LaunchMachineInput{
+			Region: vol.Region,
+			Config: &fly.MachineConfig{
+				Image: image,
+				Init: fly.MachineInit{
+					Exec: []string{"sleep", "inf"},
+				},
+				Guest: &fly.MachineGuest{
+					CPUKind:  "shared",
+					CPUs:     1,
+					MemoryMB: 256,
+				},
+				Mounts: []fly.MachineMount{{
+					Volume: vol.ID,
+					Path:   helperMountPath,
+				}},
+				Restart: &fly.MachineRestart{
+					Policy: fly.MachineRestartPolicyNo,
+				},
+				AutoDestroy: true,
+				DNS: &fly.DNSConfig{
+					SkipRegistration: true,
+				},
+			},
+		},
+		What: what,
+	}
+
+	return machine.LaunchEphemeral(ctx, appName, input)
+}
+
+// helperImage picks the image for a helper Machine: the one given with
+// --image, or the image of the app's current release.
+func helperImage(ctx context.Context, appName, override string) (string, error) {
+	if override != "" {
+		return override, nil
+	}
+
+	release, err := flyutil.ClientFromContext(ctx).GetAppCurrentReleaseMachines(ctx, appName)
+	if err != nil {
+		return "", err
+	}
+
+	if release == nil || release.ImageRef == "" {
+		return "", errors.New("the app has not been deployed yet; pass an image for the helper Machine with --image")
+	}
+
+	return release.ImageRef, nil
+}
+
+// attachedMountPath returns where m mounts vol.
+func attachedMountPath(m *fly.Machine, vol *fly.Volume) (string, error) {
+	if m.Config != nil {
+		for _, mount := range m.Config.Mounts {
+			if mount.Volume == vol.ID {
+				return mount.Path, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("machine %s does not mount volume %s", m.ID, vol.ID)
+}
+
+// connectSFTP opens an SFTP session to m. The returned function closes it.
+func connectSFTP(ctx context.Context, appName string, m *fly.Machine) (*sftp.Client, func(), error) {
+	client := flyutil.ClientFromContext(ctx)
+
+	app, err := client.GetAppCompact(ctx, appName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get app: %w", err)
+	}
+
+	network, err := client.GetAppNetwork(ctx, app.Name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get app network: %w", err)
+	}
+
+	_, dialer, err := agent.BringUpAgent(ctx, client, app, *network, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, err := ssh.Connect(&ssh.ConnectParams{
+		Ctx:            ctx,
+		Org:            app.Organization,
+		Dialer:         dialer,
+		Username:       ssh.DefaultSshUsername,
+		DisableSpinner: true,
+		AppNames:       []string{app.Name},
+	}, m.PrivateIP)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ftp, err := sftp.NewClient(conn.Client,
+		sftp.UseConcurrentReads(true),
+		sftp.UseConcurrentWrites(true),
+	)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	return ftp, func() {
+		ftp.Close()
+		conn.Close()
+	}, nil
+}
+
+// requireStarted checks that a Machine whose volume is exported in place is
+// running, since SFTP needs a running Machine.
+func requireStarted(m *fly.Machine) error {
+	if m.State == fly.MachineStateStarted {
+		return nil
+	}
+
+	return fmt.Errorf("machine %s is %s; start it or use --fork to export from a copy of the volume", m.ID, m.State)
+}
+
+// sftpFS implements archiveFS over an SFTP session.
+type sftpFS struct {
+	c *sftp.Client
+}
+
+func (s sftpFS) Lstat(p string) (fs.FileInfo, error)     { return s.c.Lstat(p) }
+func (s sftpFS) ReadDir(p string) ([]fs.FileInfo, error) { return s.c.ReadDir(p) }
+func (s sftpFS) ReadLink(p string) (string, error)       { return s.c.ReadLink(p) }
+
+func (s sftpFS) Open(p string) (io.ReadCloser, error) {
+	return s.c.Open(p)
+}
+
+func (s sftpFS) Create(p string, mode fs.FileMode) (io.WriteCloser, error) {
+	f, err := s.c.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (s sftpFS) Mkdir(p string, mode fs.FileMode) error {
+	if err := s.c.MkdirAll(p); err != nil {
+		return err
+	}
+
+	return s.c.Chmod(p, mode)
+}
+
+func (s sftpFS) Symlink(target, p string) error {
+	return s.c.Symlink(target, p)
+}
+
+func (s sftpFS) Lchown(p string, uid, gid int) error {
+	// SFTP has no lchown, and chown would follow the link.
+	if fi, err := s.c.Lstat(p); err == nil && fi.Mode()&fs.ModeSymlink != 0 {
+		return nil
+	}
+
+	return s.c.Chown(p, uid, gid)
+}
+
+func (s sftpFS) Chtimes(p string, mtime time.Time) error {
+	return s.c.Chtimes(p, mtime, mtime)
+}
+
+func (s sftpFS) Owner(fi fs.FileInfo) (int, int) {
+	if st, ok := fi.Sys().(*sftp.FileStat); ok {
+		return int(st.UID), int(st.GID)
+	}
+
+	return 0, 0
+}
@@ -0,0 +1,220 @@
+package volumes
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	fly "github.com/superfly/fly-go"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/command"
+	"github.com/superfly/flyctl/internal/config"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/internal/flapsutil"
+	"github.com/superfly/flyctl/internal/render"
+	"github.com/superfly/flyctl/iostreams"
+	"github.com/superfly/flyctl/terminal"
+)
+
+func newImport() *cobra.Command {
+	const (
+		short = "Restore an archive made by 'fly volumes export' into a new volume."
+
+		long = short + ` The archive is checked against its checksum file, if
+there is one next to it, before anything is created. A new volume is then
+created, mounted in a temporary Machine, and filled over SFTP; every file is
+checked against the archive's manifest as it is written.
+
+The new volume's name, region and size default to those of the exported
+volume.`
+
+		usage = "import <archive>"
+	)
+
+	cmd := command.New(usage, short, long, runImport,
+		command.RequireSession,
+		command.RequireAppName,
+	)
+
+	cmd.Args = cobra.ExactArgs(1)
+
+	flag.Add(cmd,
+		flag.App(),
+		flag.AppConfig(),
+		flag.JSONOutput(),
+		flag.String{
+			Name:        "name",
+			Shorthand:   "n",
+			Description: "Name of the new volume. Defaults to the name of the exported volume",
+		},
+		flag.String{
+			Name:        "region",
+			Shorthand:   "r",
+			Description: "Region of the new volume. Defaults to the region of the exported volume",
+		},
+		flag.Int{
+			Name:        "size",
+			Shorthand:   "s",
+			Description: "Size of the new volume in GB. Defaults to the size of the exported volume",
+		},
+		flag.String{
+			Name:        "image",
+			Description: "Image for the temporary Machine. Defaults to the app's current image",
+		},
+		flag.Bool{
+			Name:        "no-verify",
+			Description: "Don't check the archive against its checksum file",
+		},
+	)
+
+	return cmd
+}
+
+func runImport(ctx context.Context) error {
+	var (
+		io          = iostreams.FromContext(ctx)
+		cfg         = config.FromContext(ctx)
+		appName     = appconfig.NameFromContext(ctx)
+		archive     = flag.FirstArg(ctx)
+		flapsClient = flapsutil.ClientFromContext(ctx)
+	)
+
+	if !flag.GetBool(ctx, "no-verify") {
+		if err := verifyArchiveChecksum(archive); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	manifest, err := readArchiveManifest(f)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", archive, err)
+	}
+
+	req := importVolumeRequest(ctx, manifest)
+	if req.Region == "" {
+		return errors.New("the archive doesn't record a region; pass one with --region")
+	}
+
+	image, err := helperImage(ctx, appName, flag.GetString(ctx, "image"))
+	if err != nil {
+		return err
+	}
+	req.ComputeImage = image
+
+	vol, err := flapsClient.CreateVolume(ctx, appName, req)
+	if err != nil {
+		return fmt.Errorf("failed to create volume: %w", err)
+	}
+
+	if err := fillVolume(ctx, appName, vol, image, f); err != nil {
+		terminal.Warnf("Import failed; volume %s may be incomplete. Destroy it with `fly volumes destroy %s`.\n", vol.ID, vol.ID)
+
+		return err
+	}
+
+	if cfg.JSONOutput {
+		return render.JSON(io.Out, vol)
+	}
+
+	fmt.Fprintf(io.Out, "Restored %d files from %s into volume %s\n\n", len(manifest.Files), archive, vol.ID)
+
+	return printVolume(io.Out, vol, appName)
+}
+
+// importVolumeRequest builds the request for the new volume from the flags,
+// falling back to what the manifest recorded about the exported volume.
+func importVolumeRequest(ctx context.Context, manifest *archiveManifest) fly.CreateVolumeRequest {
+	req := fly.CreateVolumeRequest{
+		Name:              manifest.VolumeName,
+		Region:            manifest.Region,
+		RequireUniqueZone: new(false),
+	}
+
+	if name := flag.GetString(ctx, "name"); name != "" {
+		req.Name = name
+	}
+
+	if region := flag.GetString(ctx, "region"); region != "" {
+		req.Region = region
+	}
+
+	size := manifest.SizeGb
+	if flag.IsSpecified(ctx, "size") {
+		size = flag.GetInt(ctx, "size")
+	}
+	if size > 0 {
+		req.SizeGb = &size
+	}
+
+	return req
+}
+
+// fillVolume extracts the archive in f into vol from a temporary Machine.
+func fillVolume(ctx context.Context, appName string, vol *fly.Volume, image string, f *os.File) error {
+	m, destroy, err := launchVolumeHelper(ctx, appName, vol, image, "to import into volume "+vol.ID)
+	if err != nil {
+		return err
+	}
+	defer destroy()
+
+	ftp, closeFTP, err := connectSFTP(ctx, appName, m)
+	if err != nil {
+		return err
+	}
+	defer closeFTP()
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	_, err = extractVolumeArchive(f, sftpFS{ftp}, helperMountPath)
+
+	return err
+}
+
+// verifyArchiveChecksum checks archive against the checksum file written
+// next to it by export. A missing checksum file is only warned about.
+func verifyArchiveChecksum(archive string) error {
+	want, err := os.ReadFile(archive + ".sha256")
+	if errors.Is(err, os.ErrNotExist) {
+		terminal.Warnf("No checksum file found for %s; skipping verification\n", archive)
+
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	wantSum, err := parseChecksumLine(string(want))
+	if err != nil {
+		return fmt.Errorf("%s.sha256: %w", archive, err)
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+
+	if got := hex.EncodeToString(h.Sum(nil)); got != wantSum {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", archive, wantSum, got)
+	}
+
+	return nil
+}
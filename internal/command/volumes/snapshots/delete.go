@@ -0,0 +1,90 @@
+package snapshots
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/superfly/flyctl/internal/command"
+	"github.com/superfly/flyctl/internal/config"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/internal/flapsutil"
+	"github.com/superfly/flyctl/internal/prompt"
+	"github.com/superfly/flyctl/internal/render"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func newDelete() *cobra.Command {
+	const (
+		short = "Delete volume snapshots."
+		long  = short + " Deleting a snapshot is not reversible."
+		usage = "delete <volume id> <snapshot id> ..."
+	)
+
+	cmd := command.New(usage, short, long, runDelete,
+		command.RequireSession,
+		command.LoadAppNameIfPresent,
+	)
+
+	cmd.Aliases = []string{"destroy", "rm"}
+
+	cmd.Args = cobra.MinimumNArgs(2)
+
+	flag.Add(cmd, flag.App(), flag.Yes(), flag.JSONOutput())
+
+	return cmd
+}
+
+func runDelete(ctx context.Context) error {
+	var (
+		io          = iostreams.FromContext(ctx)
+		cfg         = config.FromContext(ctx)
+		args        = flag.Args(ctx)
+		volID       = args[0]
+		snapshotIDs = args[1:]
+	)
+
+	appName, err := appNameForVolume(ctx, volID)
+	if err != nil {
+		return err
+	}
+
+	if !flag.GetYes(ctx) {
+		msg := fmt.Sprintf("Delete %d snapshot(s) of volume %s? This is not reversible.", len(snapshotIDs), volID)
+
+		switch confirmed, err := prompt.Confirm(ctx, msg); {
+		case err == nil:
+			if !confirmed {
+				return nil
+			}
+		case prompt.IsNonInteractive(err):
+			return prompt.NonInteractiveError("yes flag must be specified when not running interactively")
+		default:
+			return err
+		}
+	}
+
+	flapsClient := flapsutil.ClientFromContext(ctx)
+
+	deleted := make([]string, 0, len(snapshotIDs))
+	for _, id := range snapshotIDs {
+		if err := flapsutil.DeleteVolumeSnapshot(ctx, flapsClient, appName, volID, id); err != nil {
+			return err
+		}
+
+		deleted = append(deleted, id)
+
+		if !cfg.JSONOutput {
+			fmt.Fprintf(io.Out, "Deleted snapshot %s of volume %s\n", id, volID)
+		}
+	}
+
+	if cfg.JSONOutput {
+		return render.JSON(io.Out, map[string]any{
+			"volume_id": volID,
+			"deleted":   deleted,
+		})
+	}
+
+	return nil
+}
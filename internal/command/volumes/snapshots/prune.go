@@ -0,0 +1,285 @@
+package snapshots
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+	"github.com/superfly/flyctl/internal/appconfig"
+	"github.com/superfly/flyctl/internal/command"
+	"github.com/superfly/flyctl/internal/config"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/internal/flapsutil"
+	"github.com/superfly/flyctl/internal/prompt"
+	"github.com/superfly/flyctl/internal/render"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func newPrune() *cobra.Command {
+	const (
+		short = "Apply a retention policy to the snapshots of an app's volumes."
+
+		long = short + ` Snapshots of every volume of the app that
+no rule keeps are deleted. Rules are applied to each volume separately:
+
+  --keep-last N     keeps the newest N snapshots
+  --keep-daily N    keeps the newest snapshot of each of the last N days
+  --keep-weekly N   keeps the newest snapshot of each of the last N weeks
+
+Days and weeks only count when they have a snapshot, and are in UTC. Snapshots
+that haven't finished are always kept.
+
+Use --dry-run to see what would be deleted without deleting anything. With
+--json, a report of every snapshot and what happened to it is printed, which
+suits scheduled jobs; the command fails if any snapshot couldn't be deleted.`
+
+		usage = "prune"
+	)
+
+	cmd := command.New(usage, short, long, runPrune,
+		command.RequireSession,
+		command.RequireAppName,
+	)
+
+	cmd.Aliases = []string{"policy"}
+
+	cmd.Args = cobra.NoArgs
+
+	flag.Add(cmd,
+		flag.App(),
+		flag.AppConfig(),
+		flag.Yes(),
+		flag.JSONOutput(),
+		flag.Int{
+			Name:        "keep-last",
+			Description: "Keep the newest N snapshots of each volume",
+		},
+		flag.Int{
+			Name:        "keep-daily",
+			Description: "Keep the newest snapshot of each of the last N days",
+		},
+		flag.Int{
+			Name:        "keep-weekly",
+			Description: "Keep the newest snapshot of each of the last N weeks",
+		},
+		flag.StringSlice{
+			Name:        "volume",
+			Description: "Only prune snapshots of these volumes. Defaults to every volume of the app",
+		},
+		flag.Bool{
+			Name:        "dry-run",
+			Description: "List the snapshots that would be deleted without deleting them",
+		},
+	)
+
+	return cmd
+}
+
+// pruneReport is what prune did, or would do, to an app's snapshots.
+type pruneReport struct {
+	App     string          `json:"app"`
+	Policy  retentionPolicy `json:"policy"`
+	DryRun  bool            `json:"dry_run"`
+	Volumes []volumePrune   `json:"volumes"`
+}
+
+type volumePrune struct {
+	VolumeID   string           `json:"volume_id"`
+	VolumeName string           `json:"volume_name"`
+	Snapshots  []prunedSnapshot `json:"snapshots"`
+}
+
+type prunedSnapshot struct {
+	snapshotDecision
+	Deleted bool   `json:"deleted"`
+	Error   string `json:"error,omitempty"`
+}
+
+// counts returns how many snapshots the policy prunes, and how many of those
+// failed to be deleted.
+func (r *pruneReport) counts() (pruned, failed int) {
+	for _, v := range r.Volumes {
+		for _, s := range v.Snapshots {
+			if !s.Keep {
+				pruned++
+			}
+			if s.Error != "" {
+				failed++
+			}
+		}
+	}
+
+	return pruned, failed
+}
+
+func runPrune(ctx context.Context) error {
+	var (
+		io          = iostreams.FromContext(ctx)
+		cfg         = config.FromContext(ctx)
+		appName     = appconfig.NameFromContext(ctx)
+		flapsClient = flapsutil.ClientFromContext(ctx)
+	)
+
+	policy := retentionPolicy{
+		KeepLast:   flag.GetInt(ctx, "keep-last"),
+		KeepDaily:  flag.GetInt(ctx, "keep-daily"),
+		KeepWeekly: flag.GetInt(ctx, "keep-weekly"),
+	}
+	if err := policy.validate(); err != nil {
+		return err
+	}
+
+	report, err := planPrune(ctx, appName, policy)
+	if err != nil {
+		return err
+	}
+
+	pruned, _ := report.counts()
+
+	if pruned > 0 && !report.DryRun && !flag.GetYes(ctx) {
+		msg := fmt.Sprintf("Delete %d snapshot(s) across %d volume(s) of %s (%s)?", pruned, len(report.Volumes), appName, policy)
+
+		switch confirmed, err := prompt.Confirm(ctx, msg); {
+		case err == nil:
+			if !confirmed {
+				return nil
+			}
+		case prompt.IsNonInteractive(err):
+			return prompt.NonInteractiveError("yes flag must be specified when not running interactively")
+		default:
+			return err
+		}
+	}
+
+	if !report.DryRun {
+		for i := range report.Volumes {
+			v := &report.Volumes[i]
+
+			for j := range v.Snapshots {
+				s := &v.Snapshots[j]
+				if s.Keep {
+					continue
+				}
+
+				if err := flapsutil.DeleteVolumeSnapshot(ctx, flapsClient, appName, v.VolumeID, s.Snapshot.ID); err != nil {
+					s.Error = err.Error()
+					continue
+				}
+
+				s.Deleted = true
+			}
+		}
+	}
+
+	if cfg.JSONOutput {
+		if err := render.JSON(io.Out, report); err != nil {
+			return err
+		}
+	} else {
+		printPruneReport(io, report)
+	}
+
+	if _, failed := report.counts(); failed > 0 {
+		return fmt.Errorf("failed to delete %d of %d snapshot(s)", failed, pruned)
+	}
+
+	return nil
+}
+
+// planPrune applies policy to the snapshots of the app's volumes, without
+// deleting anything.
+func planPrune(ctx context.Context, appName string, policy retentionPolicy) (*pruneReport, error) {
+	flapsClient := flapsutil.ClientFromContext(ctx)
+
+	volumes, err := flapsClient.GetVolumes(ctx, appName)
+	if err != nil {
+		return nil, fmt.Errorf("failed retrieving volumes: %w", err)
+	}
+
+	only := flag.GetStringSlice(ctx, "volume")
+
+	report := &pruneReport{
+		App:    appName,
+		Policy: policy,
+		DryRun: flag.GetBool(ctx, "dry-run"),
+	}
+
+	for _, vol := range volumes {
+		if len(only) > 0 && !slices.Contains(only, vol.ID) {
+			continue
+		}
+
+		snapshots, err := flapsClient.GetVolumeSnapshots(ctx, appName, vol.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed retrieving snapshots of volume %s: %w", vol.ID, err)
+		}
+
+		decisions := policy.apply(snapshots, time.UTC)
+
+		v := volumePrune{
+			VolumeID:   vol.ID,
+			VolumeName: vol.Name,
+			Snapshots:  make([]prunedSnapshot, 0, len(decisions)),
+		}
+		for _, d := range decisions {
+			v.Snapshots = append(v.Snapshots, prunedSnapshot{snapshotDecision: d})
+		}
+
+		report.Volumes = append(report.Volumes, v)
+	}
+
+	if len(only) > 0 && len(report.Volumes) < len(only) {
+		return nil, fmt.Errorf("not every volume given with --volume belongs to %s", appName)
+	}
+
+	return report, nil
+}
+
+func printPruneReport(io *iostreams.IOStreams, report *pruneReport) {
+	if len(report.Volumes) == 0 {
+		fmt.Fprintf(io.ErrOut, "App %s has no volumes\n", report.App)
+		return
+	}
+
+	var rows [][]string
+	for _, v := range report.Volumes {
+		for _, s := range v.Snapshots {
+			rows = append(rows, []string{
+				v.VolumeID,
+				s.Snapshot.ID,
+				timeToString(s.Snapshot.CreatedAt),
+				humanize.IBytes(uint64(s.Snapshot.Size)),
+				pruneAction(report.DryRun, s),
+			})
+		}
+	}
+
+	render.Table(io.Out, "", rows, "Volume", "Snapshot", "Created At", "Stored Size", "Action") //nolint:errcheck
+
+	pruned, failed := report.counts()
+	switch {
+	case report.DryRun:
+		fmt.Fprintf(io.Out, "\nDry run: %d snapshot(s) would be deleted (%s)\n", pruned, report.Policy)
+	case failed > 0:
+		fmt.Fprintf(io.Out, "\nDeleted %d of %d snapshot(s) (%s)\n", pruned-failed, pruned, report.Policy)
+	default:
+		fmt.Fprintf(io.Out, "\nDeleted %d snapshot(s) (%s)\n", pruned, report.Policy)
+	}
+}
+
+func pruneAction(dryRun bool, s prunedSnapshot) string {
+	switch {
+	case s.Keep:
+		return "keep (" + strings.Join(s.Reasons, ", ") + ")"
+	case dryRun:
+		return "would delete"
+	case s.Error != "":
+		return "failed: " + s.Error
+	default:
+		return "deleted"
+	}
+}
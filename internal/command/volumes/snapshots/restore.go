@@ -0,0 +1,142 @@
+package snapshots
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	fly "github.com/superfly/fly-go"
+	"github.com/superfly/flyctl/internal/command"
+	"github.com/superfly/flyctl/internal/config"
+	"github.com/superfly/flyctl/internal/flag"
+	"github.com/superfly/flyctl/internal/flapsutil"
+	"github.com/superfly/flyctl/internal/render"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func newRestore() *cobra.Command {
+	const (
+		short = "Restore a volume snapshot into a new volume."
+
+		long = short + ` The snapshotted volume is left untouched; attach the new
+volume to a Machine in its place to roll back to the snapshot.
+
+The new volume's name, region and size default to those of the snapshotted
+volume. Pass "latest" as the snapshot ID to restore its newest snapshot.`
+
+		usage = "restore <volume id> <snapshot id>"
+	)
+
+	cmd := command.New(usage, short, long, runRestore,
+		command.RequireSession,
+		command.LoadAppNameIfPresent,
+	)
+
+	cmd.Args = cobra.ExactArgs(2)
+
+	flag.Add(cmd,
+		flag.App(),
+		flag.JSONOutput(),
+		flag.String{
+			Name:        "name",
+			Shorthand:   "n",
+			Description: "Name of the new volume. Defaults to the name of the snapshotted volume",
+		},
+		flag.String{
+			Name:        "region",
+			Shorthand:   "r",
+			Description: "Region of the new volume. Defaults to the region of the snapshotted volume",
+		},
+		flag.Int{
+			Name:        "size",
+			Shorthand:   "s",
+			Description: "Size of the new volume in GB. Defaults to the size of the snapshotted volume",
+		},
+	)
+
+	return cmd
+}
+
+func runRestore(ctx context.Context) error {
+	var (
+		io          = iostreams.FromContext(ctx)
+		cfg         = config.FromContext(ctx)
+		volID       = flag.Args(ctx)[0]
+		snapshotID  = flag.Args(ctx)[1]
+		flapsClient = flapsutil.ClientFromContext(ctx)
+	)
+
+	appName, err := appNameForVolume(ctx, volID)
+	if err != nil {
+		return err
+	}
+
+	vol, err := flapsClient.GetVolume(ctx, appName, volID)
+	if err != nil {
+		return fmt.Errorf("failed to get volume: %w", err)
+	}
+
+	if snapshotID == "latest" {
+		if snapshotID, err = latestSnapshotID(ctx, appName, volID); err != nil {
+			return err
+		}
+	}
+
+	input := fly.CreateVolumeRequest{
+		Name:              vol.Name,
+		Region:            vol.Region,
+		SizeGb:            &vol.SizeGb,
+		SnapshotID:        &snapshotID,
+		RequireUniqueZone: new(false),
+	}
+
+	if name := flag.GetString(ctx, "name"); name != "" {
+		input.Name = name
+	}
+
+	if region := flag.GetString(ctx, "region"); region != "" {
+		input.Region = region
+	}
+
+	if flag.IsSpecified(ctx, "size") {
+		input.SizeGb = new(flag.GetInt(ctx, "size"))
+	}
+
+	restored, err := flapsClient.CreateVolume(ctx, appName, input)
+	if err != nil {
+		return fmt.Errorf("failed to restore snapshot %s: %w", snapshotID, err)
+	}
+
+	if cfg.JSONOutput {
+		return render.JSON(io.Out, restored)
+	}
+
+	fmt.Fprintf(io.Out, "Restored snapshot %s of volume %s into new volume %s (%s, %s)\n", snapshotID, volID, restored.ID, restored.Name, restored.Region)
+
+	return nil
+}
+
+// latestSnapshotID returns the newest finished snapshot of a volume.
+func latestSnapshotID(ctx context.Context, appName, volID string) (string, error) {
+	snapshots, err := flapsutil.ClientFromContext(ctx).GetVolumeSnapshots(ctx, appName, volID)
+	if err != nil {
+		return "", fmt.Errorf("failed retrieving snapshots: %w", err)
+	}
+
+	var latest *fly.VolumeSnapshot
+	for i, s := range snapshots {
+		if s.ID == "" || s.Status != "created" {
+			continue
+		}
+
+		if latest == nil || s.CreatedAt.After(latest.CreatedAt) {
+			latest = &snapshots[i]
+		}
+	}
+
+	if latest == nil {
+		return "", fmt.Errorf("volume %s has no finished snapshots", volID)
+	}
+
+	return latest.ID, nil
+}
@@ -0,0 +1,113 @@
+package snapshots
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	fly "github.com/superfly/fly-go"
+)
+
+// retentionPolicy says which snapshots of a volume to keep. Every rule keeps
+// snapshots on its own; a snapshot survives if any rule keeps it.
+type retentionPolicy struct {
+	// KeepLast keeps the newest KeepLast snapshots.
+	KeepLast int `json:"keep_last"`
+	// KeepDaily keeps the newest snapshot of each of the last KeepDaily days
+	// that have one.
+	KeepDaily int `json:"keep_daily"`
+	// KeepWeekly keeps the newest snapshot of each of the last KeepWeekly ISO
+	// weeks that have one.
+	KeepWeekly int `json:"keep_weekly"`
+}
+
+func (p retentionPolicy) validate() error {
+	if p.KeepLast < 0 || p.KeepDaily < 0 || p.KeepWeekly < 0 {
+		return errors.New("retention counts can't be negative")
+	}
+
+	if p.KeepLast == 0 && p.KeepDaily == 0 && p.KeepWeekly == 0 {
+		return errors.New("a retention policy keeps nothing unless at least one of --keep-last, --keep-daily or --keep-weekly is set")
+	}
+
+	return nil
+}
+
+func (p retentionPolicy) String() string {
+	var rules []string
+	if p.KeepLast > 0 {
+		rules = append(rules, fmt.Sprintf("last %d", p.KeepLast))
+	}
+	if p.KeepDaily > 0 {
+		rules = append(rules, fmt.Sprintf("%d daily", p.KeepDaily))
+	}
+	if p.KeepWeekly > 0 {
+		rules = append(rules, fmt.Sprintf("%d weekly", p.KeepWeekly))
+	}
+
+	return "keep " + strings.Join(rules, ", ")
+}
+
+// snapshotDecision is what a retention policy decided for one snapshot.
+type snapshotDecision struct {
+	Snapshot fly.VolumeSnapshot `json:"snapshot"`
+	Keep     bool               `json:"keep"`
+	// Reasons lists the rules that keep the snapshot.
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// apply decides, for each snapshot, whether the policy keeps it. Days and
+// weeks are counted in loc. Snapshots that aren't finished yet are always
+// kept, since they can't be deleted. Decisions are returned newest first.
+func (p retentionPolicy) apply(snapshots []fly.VolumeSnapshot, loc *time.Location) []snapshotDecision {
+	decisions := make([]snapshotDecision, len(snapshots))
+	for i, s := range snapshots {
+		decisions[i] = snapshotDecision{Snapshot: s}
+	}
+
+	sort.SliceStable(decisions, func(i, j int) bool {
+		return decisions[i].Snapshot.CreatedAt.After(decisions[j].Snapshot.CreatedAt)
+	})
+
+	var (
+		last  int
+		days  = map[string]bool{}
+		weeks = map[string]bool{}
+	)
+
+	for i := range decisions {
+		d := &decisions[i]
+
+		if d.Snapshot.ID == "" || d.Snapshot.Status != "created" {
+			d.keep("pending")
+			continue
+		}
+
+		created := d.Snapshot.CreatedAt.In(loc)
+
+		if last < p.KeepLast {
+			last++
+			d.keep("last")
+		}
+
+		if day := created.Format(time.DateOnly); !days[day] && len(days) < p.KeepDaily {
+			days[day] = true
+			d.keep("daily")
+		}
+
+		year, week := created.ISOWeek()
+		if key := fmt.Sprintf("%d-W%02d", year, week); !weeks[key] && len(weeks) < p.KeepWeekly {
+			weeks[key] = true
+			d.keep("weekly")
+		}
+	}
+
+	return decisions
+}
+
+func (d *snapshotDecision) keep(reason string) {
+	d.Keep = true
+	d.Reasons = append(d.Reasons, reason)
+}
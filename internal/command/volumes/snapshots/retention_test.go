@@ -0,0 +1,112 @@
+package snapshots
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	fly "github.com/superfly/fly-go"
+)
+
+func snapshotAt(id, at string) fly.VolumeSnapshot {
+	t, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		panic(err)
+	}
+
+	return fly.VolumeSnapshot{ID: id, Status: "created", CreatedAt: t}
+}
+
+// kept returns the IDs of the kept snapshots with the reasons they were kept.
+func kept(decisions []snapshotDecision) map[string][]string {
+	out := map[string][]string{}
+	for _, d := range decisions {
+		if d.Keep {
+			out[d.Snapshot.ID] = d.Reasons
+		}
+	}
+
+	return out
+}
+
+func TestRetentionPolicyApply(t *testing.T) {
+	snapshots := []fly.VolumeSnapshot{
+		// Monday 2024-03-04 is the start of ISO week 10.
+		snapshotAt("mon-early", "2024-03-04T01:00:00Z"),
+		snapshotAt("mon-late", "2024-03-04T23:00:00Z"),
+		snapshotAt("tue", "2024-03-05T12:00:00Z"),
+		snapshotAt("wed", "2024-03-06T12:00:00Z"),
+		// Week 9.
+		snapshotAt("prev-fri", "2024-03-01T12:00:00Z"),
+		snapshotAt("prev-sun", "2024-03-03T12:00:00Z"),
+		// Week 8.
+		snapshotAt("old", "2024-02-20T12:00:00Z"),
+	}
+
+	t.Run("daily", func(t *testing.T) {
+		decisions := retentionPolicy{KeepDaily: 3}.apply(snapshots, time.UTC)
+
+		assert.Equal(t, map[string][]string{
+			"wed":      {"daily"},
+			"tue":      {"daily"},
+			"mon-late": {"daily"},
+		}, kept(decisions))
+		assert.Equal(t, "wed", decisions[0].Snapshot.ID, "decisions are newest first")
+	})
+
+	t.Run("weekly", func(t *testing.T) {
+		decisions := retentionPolicy{KeepWeekly: 2}.apply(snapshots, time.UTC)
+
+		assert.Equal(t, map[string][]string{
+			"wed":      {"weekly"},
+			"prev-sun": {"weekly"},
+		}, kept(decisions))
+	})
+
+	t.Run("combined", func(t *testing.T) {
+		decisions := retentionPolicy{KeepLast: 1, KeepDaily: 2, KeepWeekly: 3}.apply(snapshots, time.UTC)
+
+		assert.Equal(t, map[string][]string{
+			"wed":      {"last", "daily", "weekly"},
+			"tue":      {"daily"},
+			"prev-sun": {"weekly"},
+			"old":      {"weekly"},
+		}, kept(decisions))
+	})
+
+	t.Run("days follow the location", func(t *testing.T) {
+		// In UTC-5, mon-early falls on Sunday, which is in week 9.
+		loc := time.FixedZone("UTC-5", -5*60*60)
+		decisions := retentionPolicy{KeepWeekly: 2}.apply(snapshots, loc)
+
+		assert.Equal(t, map[string][]string{
+			"wed":       {"weekly"},
+			"mon-early": {"weekly"},
+		}, kept(decisions))
+	})
+}
+
+func TestRetentionPolicyKeepsPendingSnapshots(t *testing.T) {
+	pending := snapshotAt("", "2024-03-01T00:00:00Z")
+	pending.Status = "running"
+
+	decisions := retentionPolicy{KeepLast: 1}.apply([]fly.VolumeSnapshot{
+		pending,
+		snapshotAt("a", "2024-02-01T00:00:00Z"),
+		snapshotAt("b", "2024-01-01T00:00:00Z"),
+	}, time.UTC)
+
+	require.Len(t, decisions, 3)
+	assert.True(t, decisions[0].Keep)
+	assert.Equal(t, []string{"pending"}, decisions[0].Reasons)
+	assert.True(t, decisions[1].Keep, "pending snapshots don't count towards --keep-last")
+	assert.False(t, decisions[2].Keep)
+}
+
+func TestRetentionPolicyValidate(t *testing.T) {
+	assert.Error(t, retentionPolicy{}.validate())
+	assert.Error(t, retentionPolicy{KeepDaily: -1, KeepWeekly: 2}.validate())
+	assert.NoError(t, retentionPolicy{KeepWeekly: 2}.validate())
+	assert.Equal(t, "keep last 1, 7 daily, 4 weekly", retentionPolicy{KeepLast: 1, KeepDaily: 7, KeepWeekly: 4}.String())
+}
@@ -1,9 +1,14 @@
 package snapshots
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/spf13/cobra"
 
+	"github.com/superfly/flyctl/internal/appconfig"
 	"github.com/superfly/flyctl/internal/command"
+	"github.com/superfly/flyctl/internal/flyutil"
 )
 
 func New() *cobra.Command {
@@ -24,7 +29,25 @@ func New() *cobra.Command {
 	snapshots.AddCommand(
 		newList(),
 		newCreate(),
+		newDelete(),
+		newRestore(),
+		newPrune(),
 	)
 
 	return snapshots
 }
+
+// appNameForVolume returns the app given with --app or found in fly.toml,
+// falling back to asking the API which app owns the volume.
+func appNameForVolume(ctx context.Context, volID string) (string, error) {
+	if appName := appconfig.NameFromContext(ctx); appName != "" {
+		return appName, nil
+	}
+
+	n, err := flyutil.ClientFromContext(ctx).GetAppNameFromVolume(ctx, volID)
+	if err != nil {
+		return "", fmt.Errorf("failed getting app name from volume: %w", err)
+	}
+
+	return *n, nil
+}
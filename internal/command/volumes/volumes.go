@@ -43,6 +43,8 @@ func New() *cobra.Command {
 		newExtend(),
 		newShow(),
 		newFork(),
+		newExport(),
+		newImport(),
 		snapshots.New(),
 	)
 
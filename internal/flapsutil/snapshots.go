@@ -0,0 +1,102 @@
+package flapsutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"sync"
+
+	clientsignals "github.com/superfly/client-signals/go"
+	fly "github.com/superfly/fly-go"
+	"github.com/superfly/fly-go/flaps"
+	"github.com/superfly/flyctl/internal/buildinfo"
+	"github.com/superfly/flyctl/internal/logger"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// DeleteVolumeSnapshot deletes a snapshot of a volume. fly-go doesn't wrap
+// this endpoint yet, so the request is built with the client's NewRequest,
+// which takes care of the base URL and authentication, and sent through an
+// HTTP client set up like the one of flaps clients.
+func DeleteVolumeSnapshot(ctx context.Context, client FlapsClient, appName, volumeID, snapshotID string) error {
+	endpoint := fmt.Sprintf("/apps/%s/volumes/%s/snapshots/%s", appName, volumeID, snapshotID)
+
+	req, err := client.NewRequest(ctx, http.MethodDelete, endpoint, nil, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", buildinfo.UserAgent())
+
+	httpClient, err := snapshotHTTPClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to delete snapshot %s: %w", snapshotID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 300 {
+		return nil
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+
+	return &flaps.FlapsError{
+		OriginalError:      fmt.Errorf("failed to delete snapshot %s: %s (status %d)", snapshotID, apiErrorMessage(body), resp.StatusCode),
+		ResponseStatusCode: resp.StatusCode,
+		ResponseBody:       body,
+		FlyRequestId:       resp.Header.Get("fly-request-id"),
+	}
+}
+
+var (
+	snapshotClientOnce sync.Once
+	snapshotClient     *http.Client
+	snapshotClientErr  error
+)
+
+// snapshotHTTPClient returns the HTTP client that requests flaps clients
+// build but can't send go through. It's set up once, like the one
+// flaps.NewWithOptions sets up for flyctl, with retries, tracing, logging,
+// client signals and a cookie jar.
+func snapshotHTTPClient(ctx context.Context) (*http.Client, error) {
+	snapshotClientOnce.Do(func() {
+		transport := clientsignals.DetectOnce().WrapTransport(http.DefaultTransport)
+
+		var log fly.Logger
+		if v := logger.MaybeFromContext(ctx); v != nil {
+			log = v
+		}
+
+		snapshotClient, snapshotClientErr = fly.NewHTTPClient(log, otelhttp.NewTransport(transport))
+		if snapshotClientErr == nil {
+			snapshotClient.Jar, snapshotClientErr = cookiejar.New(nil)
+		}
+	})
+
+	return snapshotClient, snapshotClientErr
+}
+
+// apiErrorMessage pulls the message out of a Machines API error body, falling
+// back to the body itself.
+func apiErrorMessage(body []byte) string {
+	var apiErr struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &apiErr); err == nil && apiErr.Error != "" {
+		return apiErr.Error
+	}
+
+	if msg := strings.TrimSpace(string(body)); msg != "" {
+		return msg
+	}
+
+	return "no response body"
+}
@@ -0,0 +1,52 @@
+package flapsutil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/fly-go/flaps"
+	"github.com/superfly/fly-go/tokens"
+)
+
+func TestDeleteVolumeSnapshot(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.NotEmpty(t, r.Header.Get("User-Agent"))
+
+		switch r.URL.Path {
+		case "/v1/apps/my-app/volumes/vol_1/snapshots/snap_1":
+			w.WriteHeader(http.StatusOK)
+		case "/v1/apps/my-app/volumes/vol_1/snapshots/snap_2":
+			w.Header().Set("fly-request-id", "req-42")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"snapshot not found"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("FLY_FLAPS_BASE_URL", server.URL)
+	client, err := flaps.NewWithOptions(context.Background(), flaps.NewClientOpts{Tokens: tokens.Parse("secret")})
+	require.NoError(t, err)
+
+	require.NoError(t, DeleteVolumeSnapshot(context.Background(), client, "my-app", "vol_1", "snap_1"))
+
+	err = DeleteVolumeSnapshot(context.Background(), client, "my-app", "vol_1", "snap_2")
+	var flapsErr *flaps.FlapsError
+	require.ErrorAs(t, err, &flapsErr)
+	assert.Equal(t, http.StatusNotFound, flapsErr.ResponseStatusCode)
+	assert.Equal(t, "req-42", flapsErr.FlyRequestId)
+	assert.ErrorContains(t, err, "failed to delete snapshot snap_2: snapshot not found (status 404)")
+
+	assert.Equal(t, []string{
+		"DELETE /v1/apps/my-app/volumes/vol_1/snapshots/snap_1",
+		"DELETE /v1/apps/my-app/volumes/vol_1/snapshots/snap_2",
+	}, requests)
+}
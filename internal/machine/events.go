@@ -0,0 +1,274 @@
+package machine
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"time"
+
+	fly "github.com/superfly/fly-go"
+)
+
+// TimelineEvent is an event of one of the machines on a Timeline.
+type TimelineEvent struct {
+	Time         time.Time `json:"time"`
+	MachineID    string    `json:"machine_id"`
+	Region       string    `json:"region"`
+	ProcessGroup string    `json:"process_group"`
+	Type         string    `json:"type"`
+	Status       string    `json:"status"`
+	Source       string    `json:"source"`
+
+	// Exit is set for exit events.
+	Exit         *fly.MachineExitEvent `json:"exit,omitempty"`
+	RestartCount int                   `json:"restart_count,omitempty"`
+	CrashLoop    bool                  `json:"crash_loop,omitempty"`
+}
+
+// Timeline merges the events of machines into chronological order.
+func Timeline(machines []*fly.Machine) []TimelineEvent {
+	var timeline []TimelineEvent
+	for _, m := range machines {
+		for _, ev := range m.Events {
+			event := TimelineEvent{
+				Time:         ev.Time().UTC(),
+				MachineID:    m.ID,
+				Region:       m.Region,
+				ProcessGroup: m.ProcessGroup(),
+				Type:         ev.Type,
+				Status:       ev.Status,
+				Source:       ev.Source,
+				CrashLoop:    IsCrashLoopExit(ev),
+			}
+			if ev.Request != nil {
+				event.Exit = exitEvent(ev.Request)
+				event.RestartCount = ev.Request.RestartCount
+			}
+			timeline = append(timeline, event)
+		}
+	}
+
+	slices.SortStableFunc(timeline, func(a, b TimelineEvent) int {
+		return cmp.Or(a.Time.Compare(b.Time), cmp.Compare(a.MachineID, b.MachineID))
+	})
+
+	return timeline
+}
+
+func exitEvent(req *fly.MachineRequest) *fly.MachineExitEvent {
+	if req.MonitorEvent != nil && req.MonitorEvent.ExitEvent != nil {
+		return req.MonitorEvent.ExitEvent
+	}
+
+	return req.ExitEvent
+}
+
+// IsCrashLoopExit reports whether ev is a machine exiting on its own with an
+// error, to be restarted once more.
+func IsCrashLoopExit(ev *fly.MachineEvent) bool {
+	if ev == nil || ev.Type != "exit" || ev.Request == nil || ev.Request.ExitEvent == nil {
+		return false
+	}
+
+	return !ev.Request.ExitEvent.RequestedStop &&
+		ev.Request.ExitEvent.Restarting &&
+		ev.Request.RestartCount > 1 &&
+		ev.Request.ExitEvent.ExitCode != 0
+}
+
+// IsConstantlyRestarting reports whether the latest exit of machine is a
+// crash loop exit.
+func IsConstantlyRestarting(machine *fly.Machine) bool {
+	for _, ev := range machine.Events {
+		if ev.Type == "exit" {
+			return IsCrashLoopExit(ev)
+		}
+	}
+
+	return false
+}
+
+// Kinds of EventFinding.
+const (
+	FindingCrashLoop = "crash_loop"
+	FindingOOM       = "oom"
+	FindingSlowStart = "slow_start"
+)
+
+// EventFinding is something worth a look in the events of a machine.
+type EventFinding struct {
+	Kind      string    `json:"kind"`
+	MachineID string    `json:"machine_id"`
+	Time      time.Time `json:"time"`
+	Detail    string    `json:"detail"`
+}
+
+// AnalyzeTimeline finds crash loops, machines killed for running out of
+// memory, and machines that took longer than slowStart to start after being
+// asked to in timeline.
+func AnalyzeTimeline(timeline []TimelineEvent, slowStart time.Duration) []EventFinding {
+	var (
+		findings []EventFinding
+		// crashLoops are the indexes in findings of the crash loop of each
+		// machine, to count its exits.
+		crashLoops = map[string]int{}
+		crashes    = map[string]int{}
+		// startRequested is when each machine was last asked to start.
+		startRequested = map[string]time.Time{}
+	)
+
+	for _, ev := range timeline {
+		switch {
+		case ev.Type == "exit" && ev.Exit != nil:
+			if ev.Exit.OOMKilled {
+				findings = append(findings, EventFinding{
+					Kind:      FindingOOM,
+					MachineID: ev.MachineID,
+					Time:      ev.Time,
+					Detail:    fmt.Sprintf("killed for running out of memory (exit code %d)", ev.Exit.ExitCode),
+				})
+			}
+
+			if ev.CrashLoop {
+				crashes[ev.MachineID]++
+				i, ok := crashLoops[ev.MachineID]
+				if !ok {
+					i = len(findings)
+					crashLoops[ev.MachineID] = i
+					findings = append(findings, EventFinding{
+						Kind:      FindingCrashLoop,
+						MachineID: ev.MachineID,
+						Time:      ev.Time,
+					})
+				}
+				findings[i].Detail = fmt.Sprintf("exited with code %d and was restarted %d times (%d crashes seen)",
+					ev.Exit.ExitCode, ev.RestartCount, crashes[ev.MachineID])
+			}
+
+		case ev.Source == "user" && slices.Contains([]string{"launch", "start", "update", "restart"}, ev.Type):
+			startRequested[ev.MachineID] = ev.Time
+
+		case ev.Type == "start" && ev.Status == fly.MachineStateStarted:
+			requested, ok := startRequested[ev.MachineID]
+			if !ok {
+				continue
+			}
+			delete(startRequested, ev.MachineID)
+
+			if took := ev.Time.Sub(requested); took > slowStart {
+				findings = append(findings, EventFinding{
+					Kind:      FindingSlowStart,
+					MachineID: ev.MachineID,
+					Time:      ev.Time,
+					Detail:    fmt.Sprintf("took %s to start", took.Round(time.Millisecond)),
+				})
+			}
+		}
+	}
+
+	slices.SortStableFunc(findings, func(a, b EventFinding) int {
+		return a.Time.Compare(b.Time)
+	})
+
+	return findings
+}
+
+// chromeTraceEvent is an event of the Chrome trace event format, see
+// https://docs.google.com/document/d/1CvAClvFfyA5R-PhYUmn5OOQtYMH4h6I0nSsKchNAySU
+type chromeTraceEvent struct {
+	Name  string         `json:"name"`
+	Phase string         `json:"ph"`
+	Time  int64          `json:"ts"`
+	Dur   int64          `json:"dur,omitempty"`
+	PID   int            `json:"pid"`
+	TID   int            `json:"tid"`
+	Scope string         `json:"s,omitempty"`
+	Args  map[string]any `json:"args,omitempty"`
+}
+
+// WriteChromeTrace writes timeline in the Chrome trace event format, for
+// chrome://tracing or Perfetto. Each process group is a process and each
+// machine a thread, with a slice for every state the machine was in and an
+// instant event for every finding.
+func WriteChromeTrace(w io.Writer, timeline []TimelineEvent, findings []EventFinding) error {
+	var (
+		events []chromeTraceEvent
+		pids   = map[string]int{}
+		tids   = map[string]int{}
+		last   = map[string]TimelineEvent{}
+		end    time.Time
+	)
+
+	for _, ev := range timeline {
+		pid, ok := pids[ev.ProcessGroup]
+		if !ok {
+			pid = len(pids) + 1
+			pids[ev.ProcessGroup] = pid
+			events = append(events, chromeTraceEvent{
+				Name: "process_name", Phase: "M", PID: pid,
+				Args: map[string]any{"name": cmp.Or(ev.ProcessGroup, "(no process group)")},
+			})
+		}
+		tid, ok := tids[ev.MachineID]
+		if !ok {
+			tid = len(tids) + 1
+			tids[ev.MachineID] = tid
+			events = append(events, chromeTraceEvent{
+				Name: "thread_name", Phase: "M", PID: pid, TID: tid,
+				Args: map[string]any{"name": fmt.Sprintf("%s (%s)", ev.MachineID, ev.Region)},
+			})
+		}
+
+		if prev, ok := last[ev.MachineID]; ok {
+			events = append(events, stateSlice(prev, ev.Time, pids, tids))
+		}
+		last[ev.MachineID] = ev
+		end = ev.Time
+	}
+
+	// The last state of each machine lasts until the end of the timeline
+	for _, machineID := range slices.Sorted(maps.Keys(last)) {
+		if prev := last[machineID]; prev.Time.Before(end) {
+			events = append(events, stateSlice(prev, end, pids, tids))
+		}
+	}
+
+	for _, f := range findings {
+		events = append(events, chromeTraceEvent{
+			Name: f.Kind, Phase: "i", Scope: "t",
+			Time: f.Time.UnixMicro(),
+			PID:  pids[last[f.MachineID].ProcessGroup],
+			TID:  tids[f.MachineID],
+			Args: map[string]any{"detail": f.Detail},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(map[string]any{
+		"traceEvents":     events,
+		"displayTimeUnit": "ms",
+	})
+}
+
+func stateSlice(ev TimelineEvent, until time.Time, pids, tids map[string]int) chromeTraceEvent {
+	args := map[string]any{"event": ev.Type, "source": ev.Source}
+	if ev.Exit != nil {
+		args["exit_code"] = ev.Exit.ExitCode
+		args["oom_killed"] = ev.Exit.OOMKilled
+	}
+
+	return chromeTraceEvent{
+		Name:  ev.Status,
+		Phase: "X",
+		Time:  ev.Time.UnixMicro(),
+		Dur:   until.Sub(ev.Time).Microseconds(),
+		PID:   pids[ev.ProcessGroup],
+		TID:   tids[ev.MachineID],
+		Args:  args,
+	}
+}
@@ -0,0 +1,107 @@
+package machine
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	fly "github.com/superfly/fly-go"
+)
+
+func exitEv(ts int64, exitCode, restarts int, oom bool) *fly.MachineEvent {
+	return &fly.MachineEvent{
+		Type: "exit", Status: "stopped", Source: "flyd", Timestamp: ts,
+		Request: &fly.MachineRequest{
+			ExitEvent:    &fly.MachineExitEvent{ExitCode: exitCode, OOMKilled: oom, Restarting: restarts > 0},
+			RestartCount: restarts,
+		},
+	}
+}
+
+func timelineMachines() []*fly.Machine {
+	return []*fly.Machine{
+		{
+			ID: "a", Region: "ord",
+			Config: &fly.MachineConfig{Metadata: map[string]string{fly.MachineConfigMetadataKeyFlyProcessGroup: "app"}},
+			// Newest first, like the API returns them
+			Events: []*fly.MachineEvent{
+				exitEv(40_000, 1, 3, false),
+				exitEv(30_000, 1, 2, false),
+				{Type: "start", Status: "started", Source: "flyd", Timestamp: 20_000},
+				{Type: "launch", Status: "created", Source: "user", Timestamp: 1_000},
+			},
+		},
+		{
+			ID: "b", Region: "ams",
+			Config: &fly.MachineConfig{Metadata: map[string]string{fly.MachineConfigMetadataKeyFlyProcessGroup: "worker"}},
+			Events: []*fly.MachineEvent{
+				exitEv(25_000, 137, 0, true),
+				{Type: "start", Status: "started", Source: "flyd", Timestamp: 5_000},
+				{Type: "start", Status: "start", Source: "user", Timestamp: 2_000},
+			},
+		},
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	timeline := Timeline(timelineMachines())
+	require.Len(t, timeline, 7)
+
+	var order []string
+	for _, ev := range timeline {
+		order = append(order, ev.MachineID+":"+ev.Type)
+	}
+	assert.Equal(t, []string{"a:launch", "b:start", "b:start", "a:start", "b:exit", "a:exit", "a:exit"}, order)
+	assert.Equal(t, "worker", timeline[1].ProcessGroup)
+	assert.True(t, timeline[6].CrashLoop)
+	assert.False(t, timeline[4].CrashLoop)
+}
+
+func TestAnalyzeTimeline(t *testing.T) {
+	findings := AnalyzeTimeline(Timeline(timelineMachines()), 10*time.Second)
+
+	require.Len(t, findings, 3)
+	assert.Equal(t, EventFinding{
+		Kind: FindingSlowStart, MachineID: "a", Time: time.UnixMilli(20_000).UTC(), Detail: "took 19s to start",
+	}, findings[0])
+	assert.Equal(t, FindingOOM, findings[1].Kind)
+	assert.Equal(t, "b", findings[1].MachineID)
+	assert.Equal(t, EventFinding{
+		Kind: FindingCrashLoop, MachineID: "a", Time: time.UnixMilli(30_000).UTC(),
+		Detail: "exited with code 1 and was restarted 3 times (2 crashes seen)",
+	}, findings[2])
+}
+
+func TestIsConstantlyRestarting(t *testing.T) {
+	machines := timelineMachines()
+	assert.True(t, IsConstantlyRestarting(machines[0]))
+	assert.False(t, IsConstantlyRestarting(machines[1]))
+	assert.False(t, IsConstantlyRestarting(&fly.Machine{Events: []*fly.MachineEvent{{Type: "exit"}}}))
+}
+
+func TestWriteChromeTrace(t *testing.T) {
+	timeline := Timeline(timelineMachines())
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteChromeTrace(&buf, timeline, AnalyzeTimeline(timeline, time.Minute)))
+
+	var trace struct {
+		TraceEvents []chromeTraceEvent `json:"traceEvents"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &trace))
+
+	phases := map[string]int{}
+	for _, ev := range trace.TraceEvents {
+		phases[ev.Phase]++
+	}
+	// 2 processes and 2 threads, a slice per event but the last, and the
+	// OOM and crash loop findings.
+	assert.Equal(t, map[string]int{"M": 4, "X": 6, "i": 2}, phases)
+
+	first := trace.TraceEvents[1]
+	assert.Equal(t, "thread_name", first.Name)
+	assert.Equal(t, "a (ord)", first.Args["name"])
+}
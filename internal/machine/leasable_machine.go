@@ -290,27 +290,6 @@ func (lm *leasableMachine) WaitForState(ctx context.Context, desiredState string
 	}
 }
 
-func (lm *leasableMachine) isConstantlyRestarting(machine *fly.Machine) bool {
-	var ev *fly.MachineEvent
-
-	for _, mev := range machine.Events {
-		if mev.Type == "exit" {
-			ev = mev
-
-			break
-		}
-	}
-
-	if ev == nil {
-		return false
-	}
-
-	return !ev.Request.ExitEvent.RequestedStop &&
-		ev.Request.ExitEvent.Restarting &&
-		ev.Request.RestartCount > 1 &&
-		ev.Request.ExitEvent.ExitCode != 0
-}
-
 func (lm *leasableMachine) WaitForSmokeChecksToPass(ctx context.Context) error {
 	ctx, span := tracing.GetTracer().Start(ctx, "wait_for_smoke_checks")
 	defer span.End()
@@ -337,7 +316,7 @@ func (lm *leasableMachine) WaitForSmokeChecksToPass(ctx context.Context) error {
 			uptime = time.Since(startedAt)
 		}
 		switch {
-		case uptime > 10*time.Second && !lm.isConstantlyRestarting(machine):
+		case uptime > 10*time.Second && !IsConstantlyRestarting(machine):
 			return nil
 		case errors.Is(waitCtx.Err(), context.Canceled):
 			return err
@@ -350,7 +329,7 @@ func (lm *leasableMachine) WaitForSmokeChecksToPass(ctx context.Context) error {
 		}
 
 		switch {
-		case lm.isConstantlyRestarting(machine):
+		case IsConstantlyRestarting(machine):
 			err := fmt.Errorf("the app appears to be crashing")
 			span.RecordError(err)
 
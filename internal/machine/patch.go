@@ -0,0 +1,80 @@
+package machine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	fly "github.com/superfly/fly-go"
+)
+
+// ConfigPatch is a patch of machine configs, either a JSON Patch (RFC 6902)
+// or a JSON merge patch (RFC 7396).
+type ConfigPatch struct {
+	jsonPatch  jsonpatch.Patch
+	mergePatch []byte
+}
+
+// ParseConfigPatch parses data as a JSON Patch if it's an array of
+// operations, or as a JSON merge patch if it's an object.
+func ParseConfigPatch(data []byte) (*ConfigPatch, error) {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(data, []byte("[")):
+		patch, err := jsonpatch.DecodePatch(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON Patch: %w", err)
+		}
+
+		return &ConfigPatch{jsonPatch: patch}, nil
+	case bytes.HasPrefix(data, []byte("{")):
+		if !json.Valid(data) {
+			return nil, errors.New("invalid JSON merge patch: not valid JSON")
+		}
+
+		return &ConfigPatch{mergePatch: data}, nil
+	default:
+		return nil, errors.New("a machine config patch must be a JSON Patch array of operations or a JSON merge patch object")
+	}
+}
+
+// Apply returns a patched copy of config. Patches can't add fields machine
+// configs don't have.
+func (p *ConfigPatch) Apply(config *fly.MachineConfig) (*fly.MachineConfig, error) {
+	original, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var patched []byte
+	if p.jsonPatch != nil {
+		// Empty fields are left out of machine configs, so adding to them
+		// needs their parents created
+		opts := jsonpatch.NewApplyOptions()
+		opts.EnsurePathExistsOnAdd = true
+		patched, err = p.jsonPatch.ApplyWithOptions(original, opts)
+	} else {
+		patched, err = jsonpatch.MergePatch(original, p.mergePatch)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply patch: %w", err)
+	}
+
+	var result fly.MachineConfig
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&result); err != nil {
+		return nil, fmt.Errorf("patched machine config is invalid: %w", err)
+	}
+
+	return &result, nil
+}
+
+// ConfigDiff returns the differences between two machine configs, as shown
+// before updating machines, or "" if there are none.
+func ConfigDiff(ctx context.Context, original, new fly.MachineConfig) (string, error) {
+	return configCompare(ctx, original, new)
+}
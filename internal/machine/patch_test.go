@@ -0,0 +1,83 @@
+package machine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	fly "github.com/superfly/fly-go"
+	"github.com/superfly/flyctl/iostreams"
+)
+
+func patchConfig() *fly.MachineConfig {
+	return &fly.MachineConfig{
+		Image: "app:1",
+		Env:   map[string]string{"LOG_LEVEL": "info", "DEBUG": "1"},
+		Guest: &fly.MachineGuest{CPUKind: "shared", CPUs: 1, MemoryMB: 256},
+	}
+}
+
+func TestConfigPatchJSONPatch(t *testing.T) {
+	patch, err := ParseConfigPatch([]byte(`[
+		{"op": "replace", "path": "/guest/memory_mb", "value": 1024},
+		{"op": "remove", "path": "/env/DEBUG"},
+		{"op": "add", "path": "/metadata/team", "value": "infra"}
+	]`))
+	require.NoError(t, err)
+
+	original := patchConfig()
+	patched, err := patch.Apply(original)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1024, patched.Guest.MemoryMB)
+	assert.Equal(t, map[string]string{"LOG_LEVEL": "info"}, patched.Env)
+	assert.Equal(t, map[string]string{"team": "infra"}, patched.Metadata)
+	// The original is left alone
+	assert.Equal(t, 256, original.Guest.MemoryMB)
+	assert.Contains(t, original.Env, "DEBUG")
+}
+
+func TestConfigPatchMergePatch(t *testing.T) {
+	patch, err := ParseConfigPatch([]byte(`{"image": "app:2", "env": {"DEBUG": null, "REGION": "ord"}}`))
+	require.NoError(t, err)
+
+	patched, err := patch.Apply(patchConfig())
+	require.NoError(t, err)
+
+	assert.Equal(t, "app:2", patched.Image)
+	assert.Equal(t, map[string]string{"LOG_LEVEL": "info", "REGION": "ord"}, patched.Env)
+	assert.Equal(t, 256, patched.Guest.MemoryMB)
+
+	ios, _, _, _ := iostreams.Test()
+	ctx := iostreams.NewContext(context.Background(), ios)
+
+	diff, err := ConfigDiff(ctx, *patchConfig(), *patched)
+	require.NoError(t, err)
+	assert.Contains(t, diff, "app:2")
+
+	diff, err = ConfigDiff(ctx, *patchConfig(), *patchConfig())
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+}
+
+func TestConfigPatchErrors(t *testing.T) {
+	_, err := ParseConfigPatch([]byte(`"image"`))
+	assert.Error(t, err)
+
+	_, err = ParseConfigPatch([]byte(`{"image": `))
+	assert.ErrorContains(t, err, "invalid JSON merge patch")
+
+	_, err = ParseConfigPatch([]byte(`[{"op": "replace"`))
+	assert.ErrorContains(t, err, "invalid JSON Patch")
+
+	patch, err := ParseConfigPatch([]byte(`{"imagee": "app:2"}`))
+	require.NoError(t, err)
+	_, err = patch.Apply(patchConfig())
+	assert.ErrorContains(t, err, "patched machine config is invalid")
+
+	patch, err = ParseConfigPatch([]byte(`[{"op": "test", "path": "/image", "value": "app:3"}]`))
+	require.NoError(t, err)
+	_, err = patch.Apply(patchConfig())
+	assert.ErrorContains(t, err, "failed to apply patch")
+}
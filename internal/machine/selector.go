@@ -0,0 +1,162 @@
+package machine
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+	fly "github.com/superfly/fly-go"
+)
+
+// selectorFields are the machine fields a Selector can match, besides
+// metadata.KEY.
+var selectorFields = map[string]func(m *fly.Machine) string{
+	"id":            func(m *fly.Machine) string { return m.ID },
+	"name":          func(m *fly.Machine) string { return m.Name },
+	"region":        func(m *fly.Machine) string { return m.Region },
+	"state":         func(m *fly.Machine) string { return m.State },
+	"process_group": func(m *fly.Machine) string { return m.ProcessGroup() },
+	"image":         func(m *fly.Machine) string { return m.FullImageRef() },
+	"host_status":   func(m *fly.Machine) string { return string(m.HostStatus) },
+}
+
+// Selector matches machines against a comma separated list of conditions,
+// all of which must hold, like:
+//
+//	region=ord|ams,process_group=web,state!=destroyed,metadata.role=primary,image~=v42
+//
+// FIELD=VALUE matches if the field is VALUE, or any of the |-separated
+// values. FIELD!=VALUE is its opposite. FIELD~=REGEXP matches if the field
+// matches the regular expression, which may have commas, like image~=v[0-9]{1,2},
+// as long as they aren't followed by another condition.
+type Selector struct {
+	conditions []selectorCondition
+	source     string
+}
+
+type selectorCondition struct {
+	value  func(m *fly.Machine) string
+	negate bool
+	values []string
+	regexp *regexp.Regexp
+}
+
+// ParseSelector parses a selector like "region=ord,state=stopped".
+func ParseSelector(s string) (*Selector, error) {
+	selector := &Selector{source: s}
+
+	for _, part := range splitSelector(s) {
+		condition, err := parseSelectorCondition(part)
+		if err != nil {
+			return nil, err
+		}
+		selector.conditions = append(selector.conditions, condition)
+	}
+
+	if len(selector.conditions) == 0 {
+		return nil, fmt.Errorf("machine selector %q has no conditions", s)
+	}
+
+	return selector, nil
+}
+
+var selectorConditionPattern = regexp.MustCompile(`^([\w.-]+)\s*(!=|~=|=)\s*(.*)$`)
+
+// splitSelector splits s into its conditions at commas, except for those in
+// the regular expression of a FIELD~=REGEXP condition, which don't start
+// another condition.
+func splitSelector(s string) []string {
+	var parts []string
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+
+		if n := len(parts); n > 0 && !selectorConditionPattern.MatchString(strings.TrimSpace(part)) {
+			if match := selectorConditionPattern.FindStringSubmatch(parts[n-1]); match != nil && match[2] == "~=" {
+				parts[n-1] += "," + part
+
+				continue
+			}
+		}
+		parts = append(parts, strings.TrimSpace(part))
+	}
+
+	return parts
+}
+
+func parseSelectorCondition(part string) (selectorCondition, error) {
+	var c selectorCondition
+
+	match := selectorConditionPattern.FindStringSubmatch(part)
+	if match == nil {
+		return c, fmt.Errorf("machine selector condition %q must look like FIELD=VALUE, FIELD!=VALUE or FIELD~=REGEXP", part)
+	}
+	field, op, v := match[1], match[2], match[3]
+
+	if key, ok := strings.CutPrefix(field, "metadata."); ok && key != "" {
+		c.value = func(m *fly.Machine) string {
+			if m.Config == nil {
+				return ""
+			}
+
+			return m.Config.Metadata[key]
+		}
+	} else if value, ok := selectorFields[field]; ok {
+		c.value = value
+	} else {
+		fields := append(lo.Keys(selectorFields), "metadata.KEY")
+		slices.Sort(fields)
+
+		return c, fmt.Errorf("unknown machine selector field %q; use one of %s", field, strings.Join(fields, ", "))
+	}
+
+	switch op {
+	case "~=":
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return c, fmt.Errorf("invalid regular expression in machine selector condition %q: %w", part, err)
+		}
+		c.regexp = re
+	case "!=":
+		c.negate = true
+		c.values = strings.Split(v, "|")
+	default:
+		c.values = strings.Split(v, "|")
+	}
+
+	return c, nil
+}
+
+func (c selectorCondition) matches(m *fly.Machine) bool {
+	value := c.value(m)
+	if c.regexp != nil {
+		return c.regexp.MatchString(value)
+	}
+
+	return slices.Contains(c.values, value) != c.negate
+}
+
+// Matches reports whether m meets every condition of s.
+func (s *Selector) Matches(m *fly.Machine) bool {
+	for _, c := range s.conditions {
+		if !c.matches(m) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Filter returns the machines s matches.
+func (s *Selector) Filter(machines []*fly.Machine) []*fly.Machine {
+	return lo.Filter(machines, func(m *fly.Machine, _ int) bool {
+		return s.Matches(m)
+	})
+}
+
+func (s *Selector) String() string {
+	return s.source
+}
@@ -0,0 +1,75 @@
+package machine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	fly "github.com/superfly/fly-go"
+)
+
+func TestSelector(t *testing.T) {
+	machines := []*fly.Machine{
+		{
+			ID: "a", Region: "ord", State: fly.MachineStateStarted,
+			ImageRef: fly.MachineImageRef{Registry: "registry.fly.io", Repository: "app", Tag: "deployment-v42"},
+			Config: &fly.MachineConfig{Metadata: map[string]string{
+				fly.MachineConfigMetadataKeyFlyProcessGroup: "web",
+				"role": "primary",
+			}},
+		},
+		{
+			ID: "b", Region: "ams", State: fly.MachineStateStopped,
+			ImageRef: fly.MachineImageRef{Registry: "registry.fly.io", Repository: "app", Tag: "deployment-v41"},
+			Config: &fly.MachineConfig{Metadata: map[string]string{
+				fly.MachineConfigMetadataKeyFlyProcessGroup: "web",
+			}},
+		},
+		{
+			ID: "c", Region: "ord", State: fly.MachineStateStopped,
+			Config: &fly.MachineConfig{Metadata: map[string]string{
+				fly.MachineConfigMetadataKeyFlyProcessGroup: "worker",
+			}},
+		},
+	}
+
+	cases := map[string][]string{
+		"region=ord":                        {"a", "c"},
+		"region=ord|ams,state=stopped":      {"b", "c"},
+		"process_group=web, state!=started": {"b"},
+		"metadata.role=primary":             {"a"},
+		"metadata.role!=primary":            {"b", "c"},
+		"image~=v4[12]$":                    {"a", "b"},
+		"image~=v42,region=ams":             nil,
+		"image~=v[0-9]{1,2}$,region=ord":    {"a"},
+		"image~=v4[0-9]{1,},state=stopped":  {"b"},
+	}
+	for where, want := range cases {
+		selector, err := ParseSelector(where)
+		require.NoError(t, err, where)
+
+		var ids []string
+		for _, m := range selector.Filter(machines) {
+			ids = append(ids, m.ID)
+		}
+		assert.Equal(t, want, ids, where)
+	}
+}
+
+func TestParseSelectorErrors(t *testing.T) {
+	_, err := ParseSelector("")
+	assert.ErrorContains(t, err, "has no conditions")
+
+	_, err = ParseSelector("region")
+	assert.ErrorContains(t, err, "must look like FIELD=VALUE")
+
+	// Only regular expressions have commas
+	_, err = ParseSelector("region=ord,ams")
+	assert.ErrorContains(t, err, `condition "ams" must look like FIELD=VALUE`)
+
+	_, err = ParseSelector("zone=ord")
+	assert.ErrorContains(t, err, `unknown machine selector field "zone"`)
+
+	_, err = ParseSelector("image~=(")
+	assert.ErrorContains(t, err, "invalid regular expression")
+}
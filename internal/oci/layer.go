@@ -0,0 +1,175 @@
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+// File is a local file or directory to copy into an image.
+type File struct {
+	LocalPath string
+	ImagePath string
+}
+
+// AppendFiles returns base with a layer holding files on top. The result
+// only depends on the content and executable bits of files and on created,
+// which is used for every timestamp, so building it twice gives the same
+// digest.
+func AppendFiles(base v1.Image, files []File, created time.Time) (v1.Image, error) {
+	layer, err := NewLayer(files, created)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := mutate.Append(base, mutate.Addendum{
+		Layer: layer,
+		History: v1.History{
+			Created:   v1.Time{Time: created},
+			CreatedBy: "flyctl",
+			Comment:   "files copied by flyctl",
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mutate.CreatedAt(img, v1.Time{Time: created})
+}
+
+// NewLayer returns a layer holding files, with entries sorted by path, owned
+// by root and timestamped with modTime.
+func NewLayer(files []File, modTime time.Time) (v1.Layer, error) {
+	entries := map[string]layerEntry{}
+	for _, f := range files {
+		if err := addLayerEntries(entries, f); err != nil {
+			return nil, err
+		}
+	}
+
+	paths := make([]string, 0, len(entries))
+	for p := range entries {
+		paths = append(paths, p)
+	}
+	slices.Sort(paths)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, p := range paths {
+		if err := entries[p].write(tw, p, modTime); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+
+	data := buf.Bytes()
+
+	return tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	})
+}
+
+type layerEntry struct {
+	typeflag  byte
+	mode      int64
+	localPath string
+	linkname  string
+}
+
+func (e layerEntry) write(tw *tar.Writer, name string, modTime time.Time) error {
+	hdr := &tar.Header{
+		Typeflag: e.typeflag,
+		Name:     strings.TrimPrefix(name, "/"),
+		Linkname: e.linkname,
+		Mode:     e.mode,
+		ModTime:  modTime,
+		Format:   tar.FormatPAX,
+	}
+	if e.typeflag == tar.TypeDir {
+		hdr.Name += "/"
+	}
+
+	if e.typeflag != tar.TypeReg {
+		return tw.WriteHeader(hdr)
+	}
+
+	data, err := os.ReadFile(e.localPath)
+	if err != nil {
+		return err
+	}
+	hdr.Size = int64(len(data))
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = tw.Write(data)
+
+	return err
+}
+
+// addLayerEntries adds f, the directories it's in and, if it's a directory,
+// everything in it to entries.
+func addLayerEntries(entries map[string]layerEntry, f File) error {
+	if !path.IsAbs(f.ImagePath) {
+		return fmt.Errorf("image path %q must be absolute", f.ImagePath)
+	}
+	imagePath := path.Clean(f.ImagePath)
+
+	for dir := path.Dir(imagePath); dir != "/"; dir = path.Dir(dir) {
+		if _, ok := entries[dir]; !ok {
+			entries[dir] = layerEntry{typeflag: tar.TypeDir, mode: 0o755}
+		}
+	}
+
+	return filepath.WalkDir(f.LocalPath, func(localPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(f.LocalPath, localPath)
+		if err != nil {
+			return err
+		}
+		name := path.Join(imagePath, filepath.ToSlash(rel))
+
+		switch {
+		case d.IsDir():
+			entries[name] = layerEntry{typeflag: tar.TypeDir, mode: 0o755}
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(localPath)
+			if err != nil {
+				return err
+			}
+			entries[name] = layerEntry{typeflag: tar.TypeSymlink, mode: 0o777, linkname: filepath.ToSlash(target)}
+		case d.Type().IsRegular():
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			// Only the executable bit is kept, so the layer doesn't depend
+			// on the umask of the machine the files were made on.
+			mode := int64(0o644)
+			if info.Mode().Perm()&0o111 != 0 {
+				mode = 0o755
+			}
+			entries[name] = layerEntry{typeflag: tar.TypeReg, mode: mode, localPath: localPath}
+		default:
+			return fmt.Errorf("can't copy %s into the image: not a regular file, directory or symlink", localPath)
+		}
+
+		return nil
+	})
+}
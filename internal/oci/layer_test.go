@@ -0,0 +1,83 @@
+package oci
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLayer(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "server"), []byte("binary"), 0o700))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "public", "css"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "public", "css", "app.css"), []byte("body{}"), 0o600))
+
+	files := []File{
+		{LocalPath: filepath.Join(dir, "server"), ImagePath: "/app/server"},
+		{LocalPath: filepath.Join(dir, "public"), ImagePath: "/app/public"},
+	}
+	layer, err := NewLayer(files, time.Unix(0, 0))
+	require.NoError(t, err)
+
+	rc, err := layer.Uncompressed()
+	require.NoError(t, err)
+	defer rc.Close()
+
+	type entry struct {
+		name string
+		mode int64
+	}
+	var entries []entry
+	tr := tar.NewReader(rc)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		assert.Equal(t, 0, hdr.Uid)
+		assert.True(t, hdr.ModTime.Equal(time.Unix(0, 0)))
+		entries = append(entries, entry{hdr.Name, hdr.Mode})
+	}
+	assert.Equal(t, []entry{
+		{"app/", 0o755},
+		{"app/public/", 0o755},
+		{"app/public/css/", 0o755},
+		{"app/public/css/app.css", 0o644},
+		{"app/server", 0o755},
+	}, entries)
+
+	_, err = NewLayer([]File{{LocalPath: dir, ImagePath: "app"}}, time.Unix(0, 0))
+	assert.ErrorContains(t, err, `image path "app" must be absolute`)
+}
+
+func TestAppendFilesIsReproducible(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "server")
+	require.NoError(t, os.WriteFile(path, []byte("binary"), 0o755))
+	files := []File{{LocalPath: path, ImagePath: "/server"}}
+
+	first, err := AppendFiles(empty.Image, files, time.Unix(1700000000, 0))
+	require.NoError(t, err)
+
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now()))
+	second, err := AppendFiles(empty.Image, files, time.Unix(1700000000, 0))
+	require.NoError(t, err)
+
+	firstDigest, err := first.Digest()
+	require.NoError(t, err)
+	secondDigest, err := second.Digest()
+	require.NoError(t, err)
+	assert.Equal(t, firstDigest, secondDigest)
+
+	cfg, err := first.ConfigFile()
+	require.NoError(t, err)
+	assert.True(t, cfg.Created.Equal(time.Unix(1700000000, 0)))
+}
@@ -78,6 +78,7 @@ func AsyncIterate[T any](ctx context.Context, clearAfter bool, items []T, cb fun
 // a context with a StatusLine for each item. If any callback returns an error,
 // the first error is returned and the remaining goroutines are canceled.
 // If doneText is non-empty, each line will have its status set to this after its task successfully finishes.
+// A callback may mark its own line as failed without returning an error; such lines are left as they are.
 func AsyncIterateWithErr[T any](ctx context.Context, clearAfter bool, doneText string, items []T, cb func(context.Context, int, T) error) error {
 	logger := Create(ctx, len(items), true)
 	defer logger.Destroy(clearAfter)
@@ -85,14 +86,24 @@ func AsyncIterateWithErr[T any](ctx context.Context, clearAfter bool, doneText s
 	cancelableCtx, done := context.WithCancel(ctx)
 	defer done()
 
-	firstErr := make(chan error, 1)
+	var (
+		errOnce  sync.Once
+		firstErr error
+	)
 	asyncIter(cancelableCtx, logger, clearAfter, items, func(ctx context.Context, i int, item T) {
 		FromContext(ctx).setStatus(StatusRunning)
 		err := cb(ctx, i, item)
 		if err != nil {
 			FromContext(ctx).LogStatus(StatusFailure, err.Error())
-			cancelableCtx.Done()
-			firstErr <- err
+			errOnce.Do(func() {
+				firstErr = err
+				done()
+			})
+
+			return
+		}
+		if FromContext(ctx).currentStatus() == StatusFailure {
+			return
 		}
 		if doneText != "" {
 			FromContext(ctx).LogStatus(StatusSuccess, doneText)
@@ -101,7 +112,7 @@ func AsyncIterateWithErr[T any](ctx context.Context, clearAfter bool, doneText s
 		}
 	})
 
-	return nil
+	return firstErr
 }
 
 // SingleLine returns a single StatusLine and a function to destroy it.
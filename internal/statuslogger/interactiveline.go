@@ -56,6 +56,12 @@ func (line *interactiveLine) Failed(e error) {
 	line.LogfStatus(StatusFailure, "Failed: %s", e.Error())
 }
 
+func (line *interactiveLine) currentStatus() Status {
+	line.logger.lock.Lock()
+	defer line.logger.lock.Unlock()
+	return line.status
+}
+
 func (line *interactiveLine) setStatus(s Status) {
 	line.logger.lock.Lock()
 	defer line.logger.lock.Unlock()
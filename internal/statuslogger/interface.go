@@ -23,4 +23,6 @@ type StatusLine interface {
 	// Private because it won't redraw on non-interactive loggers.
 	// For outside use, use LogStatus or LogfStatus.
 	setStatus(s Status)
+	// currentStatus reports the status last set on the line.
+	currentStatus() Status
 }
@@ -68,6 +68,10 @@ func (line *noninteractiveLine) Failed(e error) {
 	line.LogfStatus(StatusFailure, "Failed: %s", firstLine)
 }
 
+func (line *noninteractiveLine) currentStatus() Status {
+	return line.status
+}
+
 func (line *noninteractiveLine) setStatus(s Status) {
 	line.status = s
 }
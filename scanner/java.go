@@ -0,0 +1,233 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/superfly/flyctl/internal/command/launch/plan"
+)
+
+// defaultJDKVersion is used when the build doesn't say which Java it targets.
+const defaultJDKVersion = "21"
+
+// jvmFramework describes how a JVM framework is built, served and health
+// checked.
+type jvmFramework struct {
+	family string
+	// markers are strings that appear in a build file using the framework.
+	markers []string
+	// healthMarker is the dependency that adds a health endpoint, served at
+	// healthPath.
+	healthMarker string
+	healthPath   string
+	// portProperty is the key in application.properties that sets the port.
+	portProperty string
+	gradleTask   string
+	// quarkusApp is set when the build produces a quarkus-app directory
+	// rather than a runnable jar.
+	quarkusApp bool
+}
+
+var jvmFrameworks = []jvmFramework{
+	{
+		family:       "Spring Boot",
+		markers:      []string{"org.springframework.boot", "spring-boot"},
+		healthMarker: "spring-boot-starter-actuator",
+		healthPath:   "/actuator/health",
+		portProperty: "server.port",
+		gradleTask:   "bootJar",
+	},
+	{
+		family:       "Quarkus",
+		markers:      []string{"io.quarkus"},
+		healthMarker: "quarkus-smallrye-health",
+		healthPath:   "/q/health",
+		portProperty: "quarkus.http.port",
+		gradleTask:   "quarkusBuild",
+		quarkusApp:   true,
+	},
+	{
+		family:       "Micronaut",
+		markers:      []string{"io.micronaut"},
+		healthMarker: "micronaut-management",
+		healthPath:   "/health",
+		portProperty: "micronaut.server.port",
+		gradleTask:   "shadowJar",
+	},
+}
+
+var (
+	mavenJavaVersionRegex = regexp.MustCompile(`<(?:java\.version|maven\.compiler\.release|maven\.compiler\.target|maven\.compiler\.source|release)>\s*([\d.]+)\s*<`)
+
+	gradleJavaVersionRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`JavaLanguageVersion\.of\(\s*['"]?(\d+)`),
+		regexp.MustCompile(`jvmToolchain\(\s*(\d+)`),
+		regexp.MustCompile(`(?:source|target)Compatibility\s*=\s*(?:JavaVersion\.VERSION_)?['"]?([\d_.]+)`),
+	}
+
+	sdkmanJavaRegex = regexp.MustCompile(`(?m)^java=([\d.]+)`)
+)
+
+func configureJava(sourceDir string, _ *ScannerConfig) (*SourceInfo, error) {
+	var (
+		tool       string
+		buildFiles []string
+	)
+
+	switch {
+	case checksPass(sourceDir, fileExists("pom.xml")):
+		tool = "maven"
+		buildFiles = []string{"pom.xml"}
+	case checksPass(sourceDir, fileExists("build.gradle", "build.gradle.kts")):
+		tool = "gradle"
+		buildFiles = []string{"build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts", "gradle/libs.versions.toml"}
+	default:
+		return nil, nil
+	}
+
+	var build strings.Builder
+	for _, name := range buildFiles {
+		if data, err := os.ReadFile(filepath.Join(sourceDir, name)); err == nil {
+			build.Write(data)
+			build.WriteString("\n")
+		}
+	}
+	buildText := build.String()
+
+	jdkVersion := detectJDKVersion(sourceDir, tool, buildText)
+
+	s := &SourceInfo{
+		Family:  "Java",
+		Port:    8080,
+		Runtime: plan.RuntimeStruct{Language: "java", Version: jdkVersion},
+		Env: map[string]string{
+			"PORT": "8080",
+		},
+		SkipDatabase: true,
+	}
+
+	if strings.Contains(buildText, "org.jetbrains.kotlin") || strings.Contains(buildText, `kotlin("jvm")`) || strings.Contains(buildText, "kotlin-maven-plugin") {
+		s.Family = "Kotlin"
+	}
+
+	var framework *jvmFramework
+	for i, fw := range jvmFrameworks {
+		for _, marker := range fw.markers {
+			if strings.Contains(buildText, marker) {
+				framework = &jvmFrameworks[i]
+				break
+			}
+		}
+		if framework != nil {
+			break
+		}
+	}
+
+	vars := map[string]any{
+		"jdkVersion": jdkVersion,
+		"maven":      tool == "maven",
+		"wrapper":    false,
+		"quarkusApp": false,
+	}
+
+	if framework != nil {
+		s.Family = framework.family
+		s.Env = nil
+		vars["quarkusApp"] = framework.quarkusApp
+
+		if strings.Contains(buildText, framework.healthMarker) {
+			s.HttpCheckPath = framework.healthPath
+		}
+
+		if port := propertiesPort(sourceDir, framework.portProperty); port > 0 {
+			s.Port = port
+		}
+	}
+
+	switch tool {
+	case "maven":
+		vars["wrapper"] = checksPass(sourceDir, fileExists("mvnw"))
+		vars["outputDir"] = "target"
+	case "gradle":
+		vars["wrapper"] = checksPass(sourceDir, fileExists("gradlew"))
+		vars["outputDir"] = "build/libs"
+		if framework != nil && framework.quarkusApp {
+			vars["outputDir"] = "build"
+		}
+
+		task := "assemble"
+		if framework != nil && (framework.gradleTask != "shadowJar" || strings.Contains(buildText, "shadow")) {
+			task = framework.gradleTask
+		}
+		vars["gradleTask"] = task
+	}
+
+	s.Files = templatesExecute("templates/java", vars)
+
+	return s, nil
+}
+
+// detectJDKVersion finds the Java version the build targets, from the build
+// files or a version manager's file.
+func detectJDKVersion(sourceDir, tool, buildText string) string {
+	if tool == "maven" {
+		if m := mavenJavaVersionRegex.FindStringSubmatch(buildText); m != nil {
+			return normalizeJavaVersion(m[1])
+		}
+	} else {
+		for _, re := range gradleJavaVersionRegexes {
+			if m := re.FindStringSubmatch(buildText); m != nil {
+				return normalizeJavaVersion(m[1])
+			}
+		}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(sourceDir, ".java-version")); err == nil {
+		if v := normalizeJavaVersion(strings.TrimSpace(string(data))); v != "" {
+			return v
+		}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(sourceDir, ".sdkmanrc")); err == nil {
+		if m := sdkmanJavaRegex.FindSubmatch(data); m != nil {
+			return normalizeJavaVersion(string(m[1]))
+		}
+	}
+
+	return defaultJDKVersion
+}
+
+// normalizeJavaVersion turns the ways builds spell a Java version, such as
+// 1.8, 1_8, 17.0.2 or 21, into the feature release number images are tagged
+// with.
+func normalizeJavaVersion(v string) string {
+	v = strings.ReplaceAll(v, "_", ".")
+	v = strings.TrimPrefix(v, "1.")
+
+	major, _, _ := strings.Cut(v, ".")
+	if _, err := strconv.Atoi(major); err != nil {
+		return defaultJDKVersion
+	}
+
+	return major
+}
+
+// propertiesPort reads the port set with key in the application's
+// application.properties, if any.
+func propertiesPort(sourceDir, key string) int {
+	data, err := os.ReadFile(filepath.Join(sourceDir, "src", "main", "resources", "application.properties"))
+	if err != nil {
+		return 0
+	}
+
+	re := regexp.MustCompile(`(?m)^\s*` + regexp.QuoteMeta(key) + `\s*[=:]\s*(\d+)\s*$`)
+	if m := re.FindSubmatch(data); m != nil {
+		port, _ := strconv.Atoi(string(m[1]))
+		return port
+	}
+
+	return 0
+}
@@ -0,0 +1,149 @@
+package scanner
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dockerfileOf(t *testing.T, si *SourceInfo) string {
+	t.Helper()
+
+	for _, f := range si.Files {
+		if f.Path == "Dockerfile" {
+			return string(f.Contents)
+		}
+	}
+
+	t.Fatal("no Dockerfile generated")
+	return ""
+}
+
+func TestConfigureJavaSpringBootMaven(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "pom.xml"), `<project>
+  <parent>
+    <groupId>org.springframework.boot</groupId>
+    <artifactId>spring-boot-starter-parent</artifactId>
+  </parent>
+  <properties>
+    <java.version>17</java.version>
+  </properties>
+  <dependencies>
+    <dependency>
+      <groupId>org.springframework.boot</groupId>
+      <artifactId>spring-boot-starter-actuator</artifactId>
+    </dependency>
+  </dependencies>
+</project>`, 0o644)
+	writeFile(t, filepath.Join(dir, "mvnw"), "#!/bin/sh\n", 0o755)
+	writeFile(t, filepath.Join(dir, "src", "main", "resources", "application.properties"), "server.port = 8081\n", 0o644)
+
+	si, err := configureJava(dir, &ScannerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, si)
+
+	assert.Equal(t, "Spring Boot", si.Family)
+	assert.Equal(t, 8081, si.Port)
+	assert.Equal(t, "/actuator/health", si.HttpCheckPath)
+	assert.Equal(t, "17", si.Runtime.Version)
+
+	dockerfile := dockerfileOf(t, si)
+	assert.Contains(t, dockerfile, "ARG JDK_VERSION=17")
+	assert.Contains(t, dockerfile, "FROM eclipse-temurin:${JDK_VERSION}-jdk AS build")
+	assert.Contains(t, dockerfile, "./mvnw -B -DskipTests package")
+	assert.Contains(t, dockerfile, "find target -maxdepth 1")
+	assert.Contains(t, dockerfile, `CMD ["java", "-jar", "/app/app.jar"]`)
+}
+
+func TestConfigureJavaQuarkusGradle(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "build.gradle.kts"), `plugins {
+    kotlin("jvm") version "1.9.22"
+    id("io.quarkus")
+}
+
+dependencies {
+    implementation("io.quarkus:quarkus-resteasy-reactive")
+}
+
+java {
+    sourceCompatibility = JavaVersion.VERSION_21
+}
+`, 0o644)
+
+	si, err := configureJava(dir, &ScannerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, si)
+
+	assert.Equal(t, "Quarkus", si.Family)
+	assert.Equal(t, 8080, si.Port)
+	assert.Empty(t, si.HttpCheckPath, "no health extension")
+	assert.Equal(t, "21", si.Runtime.Version)
+
+	dockerfile := dockerfileOf(t, si)
+	assert.Contains(t, dockerfile, "FROM gradle:jdk${JDK_VERSION} AS build")
+	assert.Contains(t, dockerfile, "gradle --no-daemon quarkusBuild")
+	assert.Contains(t, dockerfile, "COPY --from=build /src/build/quarkus-app/ /app/")
+	assert.NotContains(t, dockerfile, "find ")
+}
+
+func TestConfigureJavaMicronautGradle(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "build.gradle"), `plugins {
+    id("com.github.johnrengelman.shadow") version "8.1.1"
+    id("io.micronaut.application") version "4.2.1"
+}
+dependencies {
+    implementation("io.micronaut:micronaut-management")
+}
+java {
+    toolchain { languageVersion = JavaLanguageVersion.of(11) }
+}
+`, 0o644)
+	writeFile(t, filepath.Join(dir, "gradlew"), "#!/bin/sh\n", 0o755)
+
+	si, err := configureJava(dir, &ScannerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, si)
+
+	assert.Equal(t, "Micronaut", si.Family)
+	assert.Equal(t, "/health", si.HttpCheckPath)
+	assert.Equal(t, "11", si.Runtime.Version)
+	assert.Contains(t, dockerfileOf(t, si), "./gradlew --no-daemon shadowJar")
+}
+
+func TestConfigureJavaPlain(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "pom.xml"), "<project><properties><maven.compiler.source>1.8</maven.compiler.source></properties></project>", 0o644)
+
+	si, err := configureJava(dir, &ScannerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, si)
+
+	assert.Equal(t, "Java", si.Family)
+	assert.Equal(t, "8", si.Runtime.Version)
+	assert.Equal(t, map[string]string{"PORT": "8080"}, si.Env)
+	assert.Contains(t, dockerfileOf(t, si), "FROM maven:3-eclipse-temurin-${JDK_VERSION} AS build")
+}
+
+func TestConfigureJavaNotJava(t *testing.T) {
+	si, err := configureJava(t.TempDir(), &ScannerConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, si)
+}
+
+func TestNormalizeJavaVersion(t *testing.T) {
+	for in, want := range map[string]string{
+		"1.8":    "8",
+		"1_8":    "8",
+		"17":     "17",
+		"17.0.2": "17",
+		"21.0.2": "21",
+		"latest": defaultJDKVersion,
+	} {
+		assert.Equal(t, want, normalizeJavaVersion(in), in)
+	}
+}
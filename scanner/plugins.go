@@ -0,0 +1,435 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/superfly/flyctl/internal/command/launch/plan"
+)
+
+// PluginDir is where a project keeps scanners of its own, relative to its
+// source directory. Scanners found there are tried, in file name order, before
+// the built-in ones.
+//
+// A file ending in .toml is a rules file: it describes the files that identify
+// a framework and the SourceInfo to use when they're found. An executable is
+// run with the source directory as its only argument, and prints the
+// SourceInfo as JSON when it recognizes the project, or nothing when it
+// doesn't. Anything else in the directory, such as templates referenced from
+// rules files, is ignored.
+const PluginDir = ".fly/scanners"
+
+// pluginTimeout bounds how long an executable scanner may run.
+const pluginTimeout = 30 * time.Second
+
+// Kinds of scanners, as reported in a ScanExplanation.
+const (
+	ScannerKindRules      = "rules"
+	ScannerKindExecutable = "executable"
+	ScannerKindBuiltin    = "built-in"
+)
+
+// ScanCheck records one scanner that Scan tried.
+type ScanCheck struct {
+	Scanner string   `json:"scanner"`
+	Kind    string   `json:"kind"`
+	Path    string   `json:"path,omitempty"`
+	Matched bool     `json:"matched"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// ScanExplanation lists the scanners Scan tried, in order. When a scanner
+// matched, it is the last one.
+type ScanExplanation struct {
+	Checks []ScanCheck `json:"checks"`
+}
+
+// Match returns the scanner that matched, if any.
+func (e *ScanExplanation) Match() *ScanCheck {
+	if n := len(e.Checks); n > 0 && e.Checks[n-1].Matched {
+		return &e.Checks[n-1]
+	}
+
+	return nil
+}
+
+// pluginScanner is a scanner loaded from PluginDir.
+type pluginScanner struct {
+	name string
+	kind string
+	path string
+	// scan returns nil when the scanner doesn't match, along with the reasons
+	// it did or didn't.
+	scan func(sourceDir string, config *ScannerConfig) (*SourceInfo, []string, error)
+}
+
+// loadPlugins returns the scanners in sourceDir's PluginDir.
+func loadPlugins(sourceDir string) ([]pluginScanner, error) {
+	dir := filepath.Join(sourceDir, PluginDir)
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", PluginDir, err)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var plugins []pluginScanner
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+
+		switch {
+		case filepath.Ext(entry.Name()) == ".toml":
+			rules, err := loadRules(path)
+			if err != nil {
+				return nil, err
+			}
+			plugins = append(plugins, pluginScanner{
+				name: rules.Name,
+				kind: ScannerKindRules,
+				path: path,
+				scan: rules.scan,
+			})
+		case isExecutable(entry):
+			plugins = append(plugins, pluginScanner{
+				name: strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())),
+				kind: ScannerKindExecutable,
+				path: path,
+				scan: func(sourceDir string, config *ScannerConfig) (*SourceInfo, []string, error) {
+					return runExecutablePlugin(path, sourceDir, config)
+				},
+			})
+		}
+	}
+
+	return plugins, nil
+}
+
+func isExecutable(entry os.DirEntry) bool {
+	if runtime.GOOS == "windows" {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".exe", ".bat", ".cmd":
+			return true
+		}
+
+		return false
+	}
+
+	info, err := entry.Info()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode().IsRegular() && info.Mode().Perm()&0o111 != 0
+}
+
+// runExecutablePlugin runs the executable scanner at path against sourceDir.
+func runExecutablePlugin(path, sourceDir string, config *ScannerConfig) (*SourceInfo, []string, error) {
+	if config == nil || !config.RunPlugins {
+		return nil, []string{"not run; executable scanners only run when allowed with --run-scanner-plugins"}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, path, sourceDir)
+	cmd.Dir = sourceDir
+	cmd.Env = append(os.Environ(),
+		"FLY_SCANNER_SOURCE_DIR="+sourceDir,
+		"FLY_SCANNER_MODE="+config.Mode,
+	)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("timed out after %s", pluginTimeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+
+		return nil, nil, fmt.Errorf("scanner %s failed: %w", path, err)
+	}
+
+	out := bytes.TrimSpace(stdout.Bytes())
+	if len(out) == 0 || string(out) == "null" {
+		return nil, []string{"printed no source info"}, nil
+	}
+
+	var info pluginSourceInfo
+	dec := json.NewDecoder(bytes.NewReader(out))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&info); err != nil {
+		return nil, nil, fmt.Errorf("scanner %s printed invalid source info: %w", path, err)
+	}
+
+	si, err := info.toSourceInfo(filepath.Dir(path))
+	if err != nil {
+		return nil, nil, fmt.Errorf("scanner %s: %w", path, err)
+	}
+
+	reasons := info.Reasons
+	if len(reasons) == 0 {
+		reasons = []string{"printed source info for " + si.Family}
+	}
+
+	return si, reasons, nil
+}
+
+// scannerRules is the contents of a rules file. The scanner matches when at
+// least one of the Files globs matches a file, and every Contains rule holds.
+type scannerRules struct {
+	Name  string `toml:"name"`
+	Match struct {
+		Files    []string      `toml:"files"`
+		Contains []rulePattern `toml:"contains"`
+	} `toml:"match"`
+	Source pluginSourceInfo `toml:"source"`
+
+	dir string
+}
+
+// rulePattern holds when a file matching the Files glob has a line matching
+// Pattern.
+type rulePattern struct {
+	Files   string `toml:"files"`
+	Pattern string `toml:"pattern"`
+
+	re *regexp.Regexp
+}
+
+func loadRules(path string) (*scannerRules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules scannerRules
+	dec := toml.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("failed to parse scanner rules %s: %w", path, err)
+	}
+
+	if rules.Name == "" {
+		rules.Name = strings.TrimSuffix(filepath.Base(path), ".toml")
+	}
+	rules.dir = filepath.Dir(path)
+
+	if len(rules.Match.Files) == 0 && len(rules.Match.Contains) == 0 {
+		return nil, fmt.Errorf("scanner rules %s match nothing; add [match] files or contains", path)
+	}
+
+	for i := range rules.Match.Contains {
+		p := &rules.Match.Contains[i]
+		if p.Files == "" {
+			return nil, fmt.Errorf("scanner rules %s: every contains rule needs files", path)
+		}
+
+		if p.re, err = regexp.Compile(p.Pattern); err != nil {
+			return nil, fmt.Errorf("scanner rules %s: bad pattern %q: %w", path, p.Pattern, err)
+		}
+	}
+
+	return &rules, nil
+}
+
+func (r *scannerRules) scan(sourceDir string, _ *ScannerConfig) (*SourceInfo, []string, error) {
+	var reasons []string
+
+	if len(r.Match.Files) > 0 {
+		found := ""
+		for _, glob := range r.Match.Files {
+			if matches, _ := filepath.Glob(filepath.Join(sourceDir, glob)); len(matches) > 0 {
+				found = glob
+				break
+			}
+		}
+
+		if found == "" {
+			return nil, []string{"none of " + strings.Join(r.Match.Files, ", ") + " exist"}, nil
+		}
+
+		reasons = append(reasons, "found "+found)
+	}
+
+	for _, p := range r.Match.Contains {
+		file := p.matchingFile(sourceDir)
+		if file == "" {
+			return nil, []string{fmt.Sprintf("no %s matches /%s/", p.Files, p.Pattern)}, nil
+		}
+
+		reasons = append(reasons, fmt.Sprintf("%s matches /%s/", file, p.Pattern))
+	}
+
+	si, err := r.Source.toSourceInfo(r.dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("scanner rules %s: %w", r.Name, err)
+	}
+
+	return si, reasons, nil
+}
+
+// matchingFile returns the first file, relative to sourceDir, that matches the
+// rule.
+func (p *rulePattern) matchingFile(sourceDir string) string {
+	matches, _ := filepath.Glob(filepath.Join(sourceDir, p.Files))
+	for _, path := range matches {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		for _, line := range strings.Split(string(data), "\n") {
+			if p.re.MatchString(line) {
+				rel, _ := filepath.Rel(sourceDir, path)
+				return rel
+			}
+		}
+	}
+
+	return ""
+}
+
+// pluginSourceInfo is the part of SourceInfo a plugin can describe, as JSON
+// printed by an executable scanner or as the [source] table of a rules file.
+type pluginSourceInfo struct {
+	Family           string            `json:"family" toml:"family"`
+	Version          string            `json:"version,omitempty" toml:"version"`
+	DockerfilePath   string            `json:"dockerfile_path,omitempty" toml:"dockerfile_path"`
+	Builder          string            `json:"builder,omitempty" toml:"builder"`
+	Buildpacks       []string          `json:"buildpacks,omitempty" toml:"buildpacks"`
+	BuildArgs        map[string]string `json:"build_args,omitempty" toml:"build_args"`
+	ReleaseCmd       string            `json:"release_cmd,omitempty" toml:"release_cmd"`
+	SeedCmd          string            `json:"seed_cmd,omitempty" toml:"seed_cmd"`
+	DockerCommand    string            `json:"docker_command,omitempty" toml:"docker_command"`
+	DockerEntrypoint string            `json:"docker_entrypoint,omitempty" toml:"docker_entrypoint"`
+	KillSignal       string            `json:"kill_signal,omitempty" toml:"kill_signal"`
+	SwapSizeMB       int               `json:"swap_size_mb,omitempty" toml:"swap_size_mb"`
+	Port             int               `json:"port,omitempty" toml:"port"`
+	Env              map[string]string `json:"env,omitempty" toml:"env"`
+	Processes        map[string]string `json:"processes,omitempty" toml:"processes"`
+	Statics          []Static          `json:"statics,omitempty" toml:"statics"`
+	Volumes          []Volume          `json:"volumes,omitempty" toml:"volumes"`
+	Files            []pluginFile      `json:"files,omitempty" toml:"files"`
+	HttpCheckPath    string            `json:"http_check_path,omitempty" toml:"http_check_path"`
+	HttpCheckHeaders map[string]string `json:"http_check_headers,omitempty" toml:"http_check_headers"`
+	ConsoleCommand   string            `json:"console_command,omitempty" toml:"console_command"`
+	Notice           string            `json:"notice,omitempty" toml:"notice"`
+	DeployDocs       string            `json:"deploy_docs,omitempty" toml:"deploy_docs"`
+	SkipDeploy       bool              `json:"skip_deploy,omitempty" toml:"skip_deploy"`
+	Database         string            `json:"database,omitempty" toml:"database"`
+	Redis            bool              `json:"redis,omitempty" toml:"redis"`
+	ObjectStorage    bool              `json:"object_storage,omitempty" toml:"object_storage"`
+	Runtime          *pluginRuntime    `json:"runtime,omitempty" toml:"runtime"`
+
+	// Reasons explain, for --explain-scan, why an executable scanner matched.
+	Reasons []string `json:"reasons,omitempty" toml:"-"`
+}
+
+type pluginRuntime struct {
+	Language string `json:"language" toml:"language"`
+	Version  string `json:"version,omitempty" toml:"version"`
+}
+
+// pluginFile is a file to write into the source directory. Its contents are
+// given inline, or read from From, relative to the plugin directory.
+type pluginFile struct {
+	Path     string `json:"path" toml:"path"`
+	Contents string `json:"contents,omitempty" toml:"contents"`
+	From     string `json:"from,omitempty" toml:"from"`
+}
+
+var pluginDatabases = map[string]DatabaseKind{
+	"":         DatabaseKindNone,
+	"postgres": DatabaseKindPostgres,
+	"mysql":    DatabaseKindMySQL,
+	"sqlite":   DatabaseKindSqlite,
+}
+
+// toSourceInfo converts p to a SourceInfo. Files given with From are read
+// relative to dir.
+func (p *pluginSourceInfo) toSourceInfo(dir string) (*SourceInfo, error) {
+	if p.Family == "" {
+		return nil, fmt.Errorf("source info needs a family")
+	}
+
+	database, ok := pluginDatabases[p.Database]
+	if !ok {
+		return nil, fmt.Errorf("unknown database %q; use postgres, mysql or sqlite", p.Database)
+	}
+
+	si := &SourceInfo{
+		Family:               p.Family,
+		Version:              p.Version,
+		DockerfilePath:       p.DockerfilePath,
+		Builder:              p.Builder,
+		Buildpacks:           p.Buildpacks,
+		BuildArgs:            p.BuildArgs,
+		ReleaseCmd:           p.ReleaseCmd,
+		SeedCmd:              p.SeedCmd,
+		DockerCommand:        p.DockerCommand,
+		DockerEntrypoint:     p.DockerEntrypoint,
+		KillSignal:           p.KillSignal,
+		SwapSizeMB:           p.SwapSizeMB,
+		Port:                 p.Port,
+		Env:                  p.Env,
+		Processes:            p.Processes,
+		Statics:              p.Statics,
+		Volumes:              p.Volumes,
+		HttpCheckPath:        p.HttpCheckPath,
+		HttpCheckHeaders:     p.HttpCheckHeaders,
+		ConsoleCommand:       p.ConsoleCommand,
+		Notice:               p.Notice,
+		DeployDocs:           p.DeployDocs,
+		SkipDeploy:           p.SkipDeploy,
+		SkipDatabase:         database == DatabaseKindNone,
+		DatabaseDesired:      database,
+		RedisDesired:         p.Redis,
+		ObjectStorageDesired: p.ObjectStorage,
+	}
+
+	if p.Runtime != nil {
+		si.Runtime = plan.RuntimeStruct{Language: p.Runtime.Language, Version: p.Runtime.Version}
+	}
+
+	for _, f := range p.Files {
+		if f.Path == "" || filepath.IsAbs(f.Path) || strings.HasPrefix(filepath.Clean(f.Path), "..") {
+			return nil, fmt.Errorf("file path %q must be relative to the source directory", f.Path)
+		}
+
+		contents := []byte(f.Contents)
+		if f.From != "" {
+			data, err := os.ReadFile(filepath.Join(dir, f.From))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", f.From, err)
+			}
+			contents = data
+		}
+
+		si.Files = append(si.Files, SourceFile{Path: f.Path, Contents: contents})
+	}
+
+	return si, nil
+}
@@ -0,0 +1,152 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, contents string, mode os.FileMode) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(contents), mode))
+}
+
+const acmeRules = `
+name = "acme"
+
+[match]
+files = ["acme.yaml", "acme.yml"]
+contains = [{ files = "go.mod", pattern = "acme\\.dev/framework" }]
+
+[source]
+family = "Acme"
+port = 9000
+http_check_path = "/_acme/health"
+database = "postgres"
+env = { ACME_ENV = "production" }
+
+[[source.files]]
+path = "Dockerfile"
+from = "acme/Dockerfile"
+`
+
+func TestScanRulesPlugin(t *testing.T) {
+	t.Setenv("OPT_OUT_GITHUB_ACTIONS", "1")
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, PluginDir, "acme.toml"), acmeRules, 0o644)
+	writeFile(t, filepath.Join(dir, PluginDir, "acme", "Dockerfile"), "FROM acme\n", 0o644)
+	writeFile(t, filepath.Join(dir, "acme.yml"), "", 0o644)
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example\n\nrequire acme.dev/framework v1.2.0\n", 0o644)
+	// A Dockerfile would match a built-in scanner, but plugins go first.
+	writeFile(t, filepath.Join(dir, "Dockerfile"), "FROM scratch\n", 0o644)
+
+	si, explanation, err := ScanAndExplain(dir, &ScannerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, si)
+
+	assert.Equal(t, "Acme", si.Family)
+	assert.Equal(t, 9000, si.Port)
+	assert.Equal(t, "/_acme/health", si.HttpCheckPath)
+	assert.Equal(t, DatabaseKindPostgres, si.DatabaseDesired)
+	assert.Equal(t, map[string]string{"ACME_ENV": "production"}, si.Env)
+	assert.Equal(t, []SourceFile{{Path: "Dockerfile", Contents: []byte("FROM acme\n")}}, si.Files)
+
+	match := explanation.Match()
+	require.NotNil(t, match)
+	assert.Equal(t, "acme", match.Scanner)
+	assert.Equal(t, ScannerKindRules, match.Kind)
+	assert.Equal(t, []string{"found acme.yml", "go.mod matches /acme\\.dev/framework/"}, match.Reasons)
+}
+
+func TestScanRulesPluginFallsThrough(t *testing.T) {
+	t.Setenv("OPT_OUT_GITHUB_ACTIONS", "1")
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, PluginDir, "acme.toml"), acmeRules, 0o644)
+	writeFile(t, filepath.Join(dir, "acme.yml"), "", 0o644)
+	writeFile(t, filepath.Join(dir, "Dockerfile"), "FROM scratch\nEXPOSE 3000\n", 0o644)
+
+	si, explanation, err := ScanAndExplain(dir, &ScannerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, si)
+	assert.Equal(t, "Dockerfile", si.Family)
+
+	require.NotEmpty(t, explanation.Checks)
+	assert.Equal(t, ScanCheck{
+		Scanner: "acme",
+		Kind:    ScannerKindRules,
+		Path:    filepath.Join(dir, PluginDir, "acme.toml"),
+		Reasons: []string{"no go.mod matches /acme\\.dev/framework/"},
+	}, explanation.Checks[0])
+
+	match := explanation.Match()
+	require.NotNil(t, match)
+	assert.Equal(t, "dockerfile", match.Scanner)
+	assert.Equal(t, ScannerKindBuiltin, match.Kind)
+}
+
+func TestScanRejectsBadRules(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, PluginDir, "bad.toml"), "name = \"bad\"\n[source]\nfamily = \"Bad\"\n", 0o644)
+
+	_, _, err := ScanAndExplain(dir, &ScannerConfig{})
+	assert.ErrorContains(t, err, "match nothing")
+}
+
+func TestScanExecutablePlugin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+
+	t.Setenv("OPT_OUT_GITHUB_ACTIONS", "1")
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, PluginDir, "internal-fw"), `#!/bin/sh
+test -f "$1/internal.json" || exit 0
+echo '{"family": "Internal", "port": 4000, "reasons": ["found internal.json"]}'
+`, 0o755)
+	writeFile(t, filepath.Join(dir, "internal.json"), "{}", 0o644)
+
+	t.Run("not allowed", func(t *testing.T) {
+		si, explanation, err := ScanAndExplain(dir, &ScannerConfig{})
+		require.NoError(t, err)
+		assert.Nil(t, si)
+		assert.False(t, explanation.Checks[0].Matched)
+		assert.Contains(t, explanation.Checks[0].Reasons[0], "--run-scanner-plugins")
+	})
+
+	t.Run("allowed", func(t *testing.T) {
+		si, explanation, err := ScanAndExplain(dir, &ScannerConfig{RunPlugins: true})
+		require.NoError(t, err)
+		require.NotNil(t, si)
+		assert.Equal(t, "Internal", si.Family)
+		assert.Equal(t, 4000, si.Port)
+
+		match := explanation.Match()
+		require.NotNil(t, match)
+		assert.Equal(t, "internal-fw", match.Scanner)
+		assert.Equal(t, ScannerKindExecutable, match.Kind)
+		assert.Equal(t, []string{"found internal.json"}, match.Reasons)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		require.NoError(t, os.Remove(filepath.Join(dir, "internal.json")))
+
+		si, explanation, err := ScanAndExplain(dir, &ScannerConfig{RunPlugins: true})
+		require.NoError(t, err)
+		assert.Nil(t, si)
+		assert.Equal(t, []string{"printed no source info"}, explanation.Checks[0].Reasons)
+	})
+}
+
+func TestBuiltinName(t *testing.T) {
+	assert.Equal(t, "rails", builtinName(configureRails))
+	assert.Equal(t, "jsframework", builtinName(configureJsFramework))
+}
@@ -5,6 +5,8 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
 	"strings"
 	"text/template"
 
@@ -105,6 +107,7 @@ type ScannerConfig struct {
 	ExistingPort    int
 	Colorize        *iostreams.ColorScheme
 	SkipHealthcheck bool // Skip healthcheck goroutine (primarily for tests)
+	RunPlugins      bool // Run executable scanners found in PluginDir
 }
 
 type GitHubActionsStruct struct {
@@ -114,6 +117,40 @@ type GitHubActionsStruct struct {
 }
 
 func Scan(sourceDir string, config *ScannerConfig) (*SourceInfo, error) {
+	si, _, err := ScanAndExplain(sourceDir, config)
+	return si, err
+}
+
+// ScanAndExplain is Scan, but also reports which scanners were tried and why
+// the one that matched did.
+func ScanAndExplain(sourceDir string, config *ScannerConfig) (*SourceInfo, *ScanExplanation, error) {
+	explanation := &ScanExplanation{}
+
+	plugins, err := loadPlugins(sourceDir)
+	if err != nil {
+		return nil, explanation, err
+	}
+
+	for _, plugin := range plugins {
+		si, reasons, err := plugin.scan(sourceDir, config)
+		if err != nil {
+			return nil, explanation, err
+		}
+
+		explanation.Checks = append(explanation.Checks, ScanCheck{
+			Scanner: plugin.name,
+			Kind:    plugin.kind,
+			Path:    plugin.path,
+			Matched: si != nil,
+			Reasons: reasons,
+		})
+
+		if si != nil {
+			addGithubActions(sourceDir, si)
+			return si, explanation, nil
+		}
+	}
+
 	scanners := []sourceScanner{
 		configureDjango,
 		configureLaravel,
@@ -129,6 +166,7 @@ func Scan(sourceDir string, config *ScannerConfig) (*SourceInfo, error) {
 		configureLucky,
 		configureRuby,
 		configureGo,
+		configureJava,
 		configureElixir,
 		configureFlask,
 		configurePython,
@@ -144,23 +182,45 @@ func Scan(sourceDir string, config *ScannerConfig) (*SourceInfo, error) {
 	for _, scanner := range scanners {
 		si, err := scanner(sourceDir, config)
 		if err != nil {
-			return nil, err
+			return nil, explanation, err
+		}
+
+		check := ScanCheck{
+			Scanner: builtinName(scanner),
+			Kind:    ScannerKindBuiltin,
+			Matched: si != nil,
 		}
-		optOutGithubActions := os.Getenv("OPT_OUT_GITHUB_ACTIONS")
 		if si != nil {
-			if optOutGithubActions == "" {
-				github_actions(sourceDir, &si.GitHubActions)
-			}
+			check.Reasons = []string{"detected " + si.Family}
+		}
+		explanation.Checks = append(explanation.Checks, check)
 
-			return si, nil
+		if si != nil {
+			addGithubActions(sourceDir, si)
+			return si, explanation, nil
 		}
 	}
 
-	return nil, nil
+	return nil, explanation, nil
+}
+
+func addGithubActions(sourceDir string, si *SourceInfo) {
+	if os.Getenv("OPT_OUT_GITHUB_ACTIONS") == "" {
+		github_actions(sourceDir, &si.GitHubActions)
+	}
 }
 
 type sourceScanner func(sourceDir string, config *ScannerConfig) (*SourceInfo, error)
 
+// builtinName names a built-in scanner after its function, so configureRails
+// is "rails".
+func builtinName(scanner sourceScanner) string {
+	name := runtime.FuncForPC(reflect.ValueOf(scanner).Pointer()).Name()
+	name = name[strings.LastIndex(name, ".")+1:]
+
+	return strings.ToLower(strings.TrimPrefix(name, "configure"))
+}
+
 // templates recursively returns files from the templates directory within the named directory
 // will panic on errors since these files are embedded and should work
 func templates(name string) (files []SourceFile) {
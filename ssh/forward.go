@@ -0,0 +1,458 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Forward describes a single port forward, in the same shape as OpenSSH's
+// -L and -R arguments: [bind_address:]port:host:hostport.
+type Forward struct {
+	// BindAddr and BindPort are where the listening side accepts connections.
+	// For a local forward that is this machine, for a remote forward it is
+	// the remote machine.
+	BindAddr string
+	BindPort int
+
+	// Host and HostPort are dialed from the other side for each accepted
+	// connection.
+	Host     string
+	HostPort int
+}
+
+// ListenAddr returns the address the forward listens on.
+func (f Forward) ListenAddr() string {
+	return net.JoinHostPort(f.BindAddr, strconv.Itoa(f.BindPort))
+}
+
+// TargetAddr returns the address the forward dials for each connection.
+func (f Forward) TargetAddr() string {
+	return net.JoinHostPort(f.Host, strconv.Itoa(f.HostPort))
+}
+
+func (f Forward) String() string {
+	return fmt.Sprintf("%s -> %s", f.ListenAddr(), f.TargetAddr())
+}
+
+// ParseForward parses a forward spec of the form
+// [bind_address:]port:host:hostport. IPv6 addresses must be enclosed in
+// square brackets. The bind address defaults to localhost.
+func ParseForward(spec string) (Forward, error) {
+	parts, err := splitForwardSpec(spec)
+	if err != nil {
+		return Forward{}, err
+	}
+
+	fwd := Forward{BindAddr: "localhost"}
+
+	switch len(parts) {
+	case 3:
+	case 4:
+		if parts[0] != "" {
+			fwd.BindAddr = parts[0]
+		}
+		parts = parts[1:]
+	default:
+		return Forward{}, fmt.Errorf("invalid forward %q: expected [bind_address:]port:host:hostport", spec)
+	}
+
+	if fwd.BindPort, err = parsePort(parts[0]); err != nil {
+		return Forward{}, fmt.Errorf("invalid forward %q: %w", spec, err)
+	}
+
+	if fwd.Host = parts[1]; fwd.Host == "" {
+		return Forward{}, fmt.Errorf("invalid forward %q: missing host", spec)
+	}
+
+	if fwd.HostPort, err = parsePort(parts[2]); err != nil {
+		return Forward{}, fmt.Errorf("invalid forward %q: %w", spec, err)
+	}
+
+	return fwd, nil
+}
+
+// ParseDynamicForward parses a dynamic forward spec of the form
+// [bind_address:]port and returns the address to listen on.
+func ParseDynamicForward(spec string) (string, error) {
+	parts, err := splitForwardSpec(spec)
+	if err != nil {
+		return "", err
+	}
+
+	bindAddr := "localhost"
+
+	switch len(parts) {
+	case 1:
+	case 2:
+		if parts[0] != "" {
+			bindAddr = parts[0]
+		}
+		parts = parts[1:]
+	default:
+		return "", fmt.Errorf("invalid dynamic forward %q: expected [bind_address:]port", spec)
+	}
+
+	port, err := parsePort(parts[0])
+	if err != nil {
+		return "", fmt.Errorf("invalid dynamic forward %q: %w", spec, err)
+	}
+
+	return net.JoinHostPort(bindAddr, strconv.Itoa(port)), nil
+}
+
+// splitForwardSpec splits on colons, keeping bracketed IPv6 addresses intact.
+func splitForwardSpec(spec string) ([]string, error) {
+	var (
+		parts []string
+		cur   strings.Builder
+		inV6  bool
+	)
+
+	for _, r := range spec {
+		switch {
+		case r == '[' && !inV6:
+			inV6 = true
+		case r == ']' && inV6:
+			inV6 = false
+		case r == ':' && !inV6:
+			parts = append(parts, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+
+	if inV6 {
+		return nil, fmt.Errorf("invalid forward %q: unterminated '['", spec)
+	}
+
+	return append(parts, cur.String()), nil
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 0 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+
+	return port, nil
+}
+
+// Forwarding is a running forward. It stops when the context it was started
+// with is cancelled.
+type Forwarding struct {
+	// Addr is the address the forward is listening on.
+	Addr net.Addr
+
+	done chan struct{}
+	err  error
+}
+
+func startForwarding(ln net.Listener, serve func(net.Listener) error) *Forwarding {
+	f := &Forwarding{
+		Addr: ln.Addr(),
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(f.done)
+		f.err = serve(ln)
+	}()
+
+	return f
+}
+
+// Wait blocks until the forward stops and returns the error, if any, that
+// stopped it.
+func (f *Forwarding) Wait() error {
+	<-f.done
+	return f.err
+}
+
+// LocalForward listens on the local side and forwards every accepted
+// connection through the SSH connection to the forward's target, which is
+// dialed from the remote machine. Connections are served until ctx is
+// cancelled.
+func (c *Client) LocalForward(ctx context.Context, fwd Forward) (*Forwarding, error) {
+	if err := c.ensureConnected(ctx); err != nil {
+		return nil, err
+	}
+
+	var lc net.ListenConfig
+	ln, err := lc.Listen(ctx, "tcp", fwd.ListenAddr())
+	if err != nil {
+		return nil, fmt.Errorf("local forward %s: %w", fwd, err)
+	}
+
+	return startForwarding(ln, func(ln net.Listener) error {
+		return serveForward(ctx, ln, func() (net.Conn, error) {
+			return c.Client.Dial("tcp", fwd.TargetAddr())
+		})
+	}), nil
+}
+
+// RemoteForward asks the remote machine to listen on the forward's bind
+// address and forwards every connection it accepts to the target, which is
+// dialed locally. Connections are served until ctx is cancelled.
+func (c *Client) RemoteForward(ctx context.Context, fwd Forward) (*Forwarding, error) {
+	if err := c.ensureConnected(ctx); err != nil {
+		return nil, err
+	}
+
+	ln, err := c.Client.Listen("tcp", fwd.ListenAddr())
+	if err != nil {
+		return nil, fmt.Errorf("remote forward %s: %w", fwd, err)
+	}
+
+	var d net.Dialer
+
+	return startForwarding(ln, func(ln net.Listener) error {
+		return serveForward(ctx, ln, func() (net.Conn, error) {
+			return d.DialContext(ctx, "tcp", fwd.TargetAddr())
+		})
+	}), nil
+}
+
+// DynamicForward runs a SOCKS5 proxy on addr. Each CONNECT request is dialed
+// from the remote machine. Connections are served until ctx is cancelled.
+func (c *Client) DynamicForward(ctx context.Context, addr string) (*Forwarding, error) {
+	if err := c.ensureConnected(ctx); err != nil {
+		return nil, err
+	}
+
+	var lc net.ListenConfig
+	ln, err := lc.Listen(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("dynamic forward %s: %w", addr, err)
+	}
+
+	return startForwarding(ln, func(ln net.Listener) error {
+		return serveSOCKS5(ctx, ln, func(network, addr string) (net.Conn, error) {
+			return c.Client.Dial(network, addr)
+		})
+	}), nil
+}
+
+func (c *Client) ensureConnected(ctx context.Context) error {
+	if c.Client != nil {
+		return nil
+	}
+
+	return c.Connect(ctx)
+}
+
+// serveForward accepts connections on ln until ctx is cancelled, pairing each
+// with a connection from dial.
+func serveForward(ctx context.Context, ln net.Listener, dial func() (net.Conn, error)) error {
+	return acceptLoop(ctx, ln, func(conn net.Conn) {
+		defer conn.Close()
+
+		upstream, err := dial()
+		if err != nil {
+			return
+		}
+		defer upstream.Close()
+
+		pipe(conn, upstream)
+	})
+}
+
+func acceptLoop(ctx context.Context, ln net.Listener, handle func(net.Conn)) error {
+	stop := context.AfterFunc(ctx, func() {
+		ln.Close()
+	})
+	defer stop()
+
+	var handlers sync.WaitGroup
+	defer handlers.Wait()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		handlers.Add(1)
+		go func() {
+			defer handlers.Done()
+
+			stop := context.AfterFunc(ctx, func() {
+				conn.Close()
+			})
+			defer stop()
+
+			handle(conn)
+		}()
+	}
+}
+
+// pipe copies in both directions until either side is done.
+func pipe(a, b io.ReadWriteCloser) {
+	done := make(chan struct{}, 2)
+
+	cp := func(dst io.WriteCloser, src io.Reader) {
+		_, _ = io.Copy(dst, src)
+		// Half-close so the other direction can finish draining; fall
+		// back to a full close when the connection can't do that.
+		if cw, ok := dst.(interface{ CloseWrite() error }); ok {
+			_ = cw.CloseWrite()
+		} else {
+			_ = dst.Close()
+		}
+		done <- struct{}{}
+	}
+
+	go cp(a, b)
+	go cp(b, a)
+
+	<-done
+	<-done
+}
+
+const (
+	socks5Version = 0x05
+
+	socks5AuthNone         = 0x00
+	socks5AuthUnacceptable = 0xff
+
+	socks5CmdConnect = 0x01
+
+	socks5AddrIPv4   = 0x01
+	socks5AddrDomain = 0x03
+	socks5AddrIPv6   = 0x04
+
+	socks5ReplySucceeded           = 0x00
+	socks5ReplyGeneralFailure      = 0x01
+	socks5ReplyCommandNotSupported = 0x07
+	socks5ReplyAddrNotSupported    = 0x08
+)
+
+var errSOCKS5Unsupported = errors.New("unsupported SOCKS request")
+
+// serveSOCKS5 implements the subset of RFC 1928 needed by common clients:
+// no authentication and the CONNECT command.
+func serveSOCKS5(ctx context.Context, ln net.Listener, dial func(network, addr string) (net.Conn, error)) error {
+	return acceptLoop(ctx, ln, func(conn net.Conn) {
+		defer conn.Close()
+
+		target, err := socks5Handshake(conn)
+		if err != nil {
+			return
+		}
+
+		upstream, err := dial("tcp", target)
+		if err != nil {
+			_ = socks5Reply(conn, socks5ReplyGeneralFailure)
+			return
+		}
+		defer upstream.Close()
+
+		if err := socks5Reply(conn, socks5ReplySucceeded); err != nil {
+			return
+		}
+
+		pipe(conn, upstream)
+	})
+}
+
+// socks5Handshake negotiates authentication and reads a CONNECT request,
+// returning the requested target address.
+func socks5Handshake(rw io.ReadWriter) (string, error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(rw, hdr[:]); err != nil {
+		return "", err
+	}
+
+	if hdr[0] != socks5Version {
+		return "", fmt.Errorf("%w: version %d", errSOCKS5Unsupported, hdr[0])
+	}
+
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(rw, methods); err != nil {
+		return "", err
+	}
+
+	method := byte(socks5AuthUnacceptable)
+	for _, m := range methods {
+		if m == socks5AuthNone {
+			method = socks5AuthNone
+			break
+		}
+	}
+
+	if _, err := rw.Write([]byte{socks5Version, method}); err != nil {
+		return "", err
+	}
+
+	if method == socks5AuthUnacceptable {
+		return "", fmt.Errorf("%w: no acceptable authentication method", errSOCKS5Unsupported)
+	}
+
+	var req [4]byte
+	if _, err := io.ReadFull(rw, req[:]); err != nil {
+		return "", err
+	}
+
+	if req[0] != socks5Version {
+		return "", fmt.Errorf("%w: version %d", errSOCKS5Unsupported, req[0])
+	}
+
+	if req[1] != socks5CmdConnect {
+		_ = socks5Reply(rw, socks5ReplyCommandNotSupported)
+		return "", fmt.Errorf("%w: command %d", errSOCKS5Unsupported, req[1])
+	}
+
+	var host string
+
+	switch req[3] {
+	case socks5AddrIPv4, socks5AddrIPv6:
+		size := net.IPv4len
+		if req[3] == socks5AddrIPv6 {
+			size = net.IPv6len
+		}
+
+		ip := make(net.IP, size)
+		if _, err := io.ReadFull(rw, ip); err != nil {
+			return "", err
+		}
+		host = ip.String()
+	case socks5AddrDomain:
+		var n [1]byte
+		if _, err := io.ReadFull(rw, n[:]); err != nil {
+			return "", err
+		}
+
+		name := make([]byte, n[0])
+		if _, err := io.ReadFull(rw, name); err != nil {
+			return "", err
+		}
+		host = string(name)
+	default:
+		_ = socks5Reply(rw, socks5ReplyAddrNotSupported)
+		return "", fmt.Errorf("%w: address type %d", errSOCKS5Unsupported, req[3])
+	}
+
+	var port [2]byte
+	if _, err := io.ReadFull(rw, port[:]); err != nil {
+		return "", err
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(int(port[0])<<8|int(port[1]))), nil
+}
+
+// socks5Reply writes a reply with an unspecified bound address; clients only
+// look at the status for CONNECT.
+func socks5Reply(w io.Writer, status byte) error {
+	_, err := w.Write([]byte{socks5Version, status, 0x00, socks5AddrIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
@@ -0,0 +1,114 @@
+package ssh
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/proxy"
+)
+
+func TestParseForward(t *testing.T) {
+	for _, tc := range []struct {
+		spec      string
+		expect    Forward
+		expectErr bool
+	}{
+		{
+			spec:   "5432:localhost:5432",
+			expect: Forward{BindAddr: "localhost", BindPort: 5432, Host: "localhost", HostPort: 5432},
+		},
+		{
+			spec:   "0.0.0.0:8080:sidecar:80",
+			expect: Forward{BindAddr: "0.0.0.0", BindPort: 8080, Host: "sidecar", HostPort: 80},
+		},
+		{
+			spec:   "[::1]:8080:[fdaa::3]:80",
+			expect: Forward{BindAddr: "::1", BindPort: 8080, Host: "fdaa::3", HostPort: 80},
+		},
+		{
+			// An empty bind address falls back to the default.
+			spec:   ":8080:localhost:80",
+			expect: Forward{BindAddr: "localhost", BindPort: 8080, Host: "localhost", HostPort: 80},
+		},
+		{spec: "5432", expectErr: true},
+		{spec: "5432:localhost", expectErr: true},
+		{spec: "nope:localhost:5432", expectErr: true},
+		{spec: "5432::5432", expectErr: true},
+		{spec: "5432:localhost:70000", expectErr: true},
+		{spec: "[::1:8080:localhost:80", expectErr: true},
+	} {
+		t.Run(tc.spec, func(t *testing.T) {
+			fwd, err := ParseForward(tc.spec)
+			if tc.expectErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expect, fwd)
+		})
+	}
+}
+
+func TestParseDynamicForward(t *testing.T) {
+	addr, err := ParseDynamicForward("1080")
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:1080", addr)
+
+	addr, err = ParseDynamicForward("0.0.0.0:1080")
+	require.NoError(t, err)
+	assert.Equal(t, "0.0.0.0:1080", addr)
+
+	_, err = ParseDynamicForward("a:b:c")
+	require.Error(t, err)
+}
+
+func TestServeSOCKS5(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	dialed := make(chan string, 1)
+	served := make(chan error, 1)
+
+	go func() {
+		served <- serveSOCKS5(ctx, ln, func(network, addr string) (net.Conn, error) {
+			dialed <- addr
+
+			client, server := net.Pipe()
+			go func() {
+				defer server.Close()
+				_, _ = io.Copy(server, server)
+			}()
+
+			return client, nil
+		})
+	}()
+
+	dialer, err := proxy.SOCKS5("tcp", ln.Addr().String(), nil, proxy.Direct)
+	require.NoError(t, err)
+
+	conn, err := dialer.Dial("tcp", "postgres.internal:5432")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	assert.Equal(t, "postgres.internal:5432", <-dialed)
+
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(buf))
+
+	conn.Close()
+	cancel()
+	require.NoError(t, <-served)
+}
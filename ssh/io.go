@@ -34,6 +34,9 @@ type SessionIO struct {
 
 	AllocPTY bool
 	TermEnv  string
+
+	// Recorder, when set, records the session's terminal I/O.
+	Recorder *Recorder
 }
 
 func getFd(reader io.Reader) (fd int, ok bool) {
@@ -48,10 +51,9 @@ func getFd(reader io.Reader) (fd int, ok bool) {
 }
 
 func (s *SessionIO) attach(ctx context.Context, sess *ssh.Session, cmd string) error {
+	width, height := DefaultWidth, DefaultHeight
 
 	if s.AllocPTY {
-		width, height := DefaultWidth, DefaultHeight
-
 		if fd, ok := getFd(s.Stdin); ok {
 			state, err := term.MakeRaw(fd)
 			if err != nil {
@@ -69,6 +71,12 @@ func (s *SessionIO) attach(ctx context.Context, sess *ssh.Session, cmd string) e
 		}
 	}
 
+	if s.Recorder != nil {
+		if err := s.Recorder.begin(width, height, s.TermEnv); err != nil {
+			return err
+		}
+	}
+
 	stdin, err := sess.StdinPipe()
 	if err != nil {
 		return err
@@ -93,7 +101,27 @@ func (s *SessionIO) attach(ctx context.Context, sess *ssh.Session, cmd string) e
 	})
 }
 
+// windowChanged is called after the remote terminal has been resized.
+func (s *SessionIO) windowChanged(width, height int) {
+	if s.Recorder != nil {
+		s.Recorder.resize(width, height)
+	}
+}
+
 func (s *SessionIO) attachPipes(ctx context.Context, stdin io.WriteCloser, stdout, stderr io.Reader, run func() error) error {
+	localStdin, localStdout, localStderr := s.Stdin, s.Stdout, s.Stderr
+	if s.Recorder != nil {
+		if localStdin != nil && s.Recorder.RecordInput {
+			localStdin = &recordingReader{Reader: localStdin, r: s.Recorder}
+		}
+		if localStdout != nil {
+			localStdout = &recordingWriter{WriteCloser: localStdout, r: s.Recorder, kind: EventOutput}
+		}
+		if localStderr != nil {
+			localStderr = &recordingWriter{WriteCloser: localStderr, r: s.Recorder, kind: EventOutput}
+		}
+	}
+
 	var closeStdin sync.Once
 	defer closeStdin.Do(func() {
 		stdin.Close()
@@ -103,8 +131,8 @@ func (s *SessionIO) attachPipes(ctx context.Context, stdin io.WriteCloser, stdou
 		defer closeStdin.Do(func() {
 			stdin.Close()
 		})
-		if s.Stdin != nil {
-			io.Copy(stdin, s.Stdin)
+		if localStdin != nil {
+			io.Copy(stdin, localStdin)
 		}
 	}()
 	var outputCopies sync.WaitGroup
@@ -112,14 +140,14 @@ func (s *SessionIO) attachPipes(ctx context.Context, stdin io.WriteCloser, stdou
 		defer outputCopies.Done()
 		_, _ = io.Copy(dst, src)
 	}
-	if s.Stdout != nil {
+	if localStdout != nil {
 		outputCopies.Add(1)
-		go copyOutput(s.Stdout, stdout)
+		go copyOutput(localStdout, stdout)
 	}
 
-	if s.Stderr != nil {
+	if localStderr != nil {
 		outputCopies.Add(1)
-		go copyOutput(s.Stderr, stderr)
+		go copyOutput(localStderr, stderr)
 	}
 
 	cmdC := make(chan error, 1)
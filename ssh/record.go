@@ -0,0 +1,325 @@
+package ssh
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Session recordings use the asciicast v2 format
+// (https://docs.asciinema.org/manual/asciicast/v2/), so they can also be
+// played back with asciinema and compatible players.
+
+const asciicastVersion = 2
+
+// Asciicast event types.
+const (
+	EventOutput = "o"
+	EventInput  = "i"
+	EventResize = "r"
+)
+
+// RecordingMetadata describes where a recorded session ran. It is stored in
+// the recording header under "fly"; other players ignore it.
+type RecordingMetadata struct {
+	App       string    `json:"app,omitempty"`
+	Machine   string    `json:"machine,omitempty"`
+	Container string    `json:"container,omitempty"`
+	User      string    `json:"user,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// RecordingHeader is the first line of an asciicast v2 recording.
+type RecordingHeader struct {
+	Version   int                `json:"version"`
+	Width     int                `json:"width"`
+	Height    int                `json:"height"`
+	Timestamp int64              `json:"timestamp"`
+	Command   string             `json:"command,omitempty"`
+	Title     string             `json:"title,omitempty"`
+	Env       map[string]string  `json:"env,omitempty"`
+	Fly       *RecordingMetadata `json:"fly,omitempty"`
+}
+
+// RecordingEvent is a single line of a recording after the header.
+type RecordingEvent struct {
+	// Time is the offset from the start of the recording.
+	Time time.Duration
+	Type string
+	Data string
+}
+
+func (e RecordingEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{e.Time.Seconds(), e.Type, e.Data})
+}
+
+func (e *RecordingEvent) UnmarshalJSON(b []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	if len(raw) != 3 {
+		return fmt.Errorf("expected 3 fields in event, got %d", len(raw))
+	}
+
+	var secs float64
+	if err := json.Unmarshal(raw[0], &secs); err != nil {
+		return fmt.Errorf("event time: %w", err)
+	}
+
+	if err := json.Unmarshal(raw[1], &e.Type); err != nil {
+		return fmt.Errorf("event type: %w", err)
+	}
+
+	if err := json.Unmarshal(raw[2], &e.Data); err != nil {
+		return fmt.Errorf("event data: %w", err)
+	}
+
+	e.Time = time.Duration(secs * float64(time.Second))
+
+	return nil
+}
+
+// Recorder writes the terminal I/O of a session as an asciicast v2
+// recording. Attach it to a session through SessionIO.Recorder.
+type Recorder struct {
+	// RecordInput also records what is typed into the session. Input often
+	// contains passwords and other secrets, so it is off by default.
+	RecordInput bool
+
+	mu      sync.Mutex
+	w       io.Writer
+	enc     *json.Encoder
+	meta    RecordingMetadata
+	command string
+	start   time.Time
+	err     error
+
+	// partial holds the trailing bytes of an incomplete UTF-8 sequence for
+	// each event type, so that multi-byte characters split across reads
+	// aren't mangled.
+	partial map[string][]byte
+}
+
+// NewRecorder returns a Recorder that writes to w. The header is written
+// when the session starts.
+func NewRecorder(w io.Writer, command string, meta RecordingMetadata) *Recorder {
+	return &Recorder{
+		w:       w,
+		enc:     json.NewEncoder(w),
+		meta:    meta,
+		command: command,
+		partial: map[string][]byte{},
+	}
+}
+
+// begin writes the recording header for a terminal of the given size.
+func (r *Recorder) begin(width, height int, term string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.start.IsZero() {
+		return errors.New("recording already started")
+	}
+
+	r.start = time.Now()
+	if r.meta.StartedAt.IsZero() {
+		r.meta.StartedAt = r.start
+	}
+
+	header := RecordingHeader{
+		Version:   asciicastVersion,
+		Width:     width,
+		Height:    height,
+		Timestamp: r.meta.StartedAt.Unix(),
+		Command:   r.command,
+		Title:     recordingTitle(r.meta),
+		Fly:       &r.meta,
+	}
+
+	if term != "" {
+		header.Env = map[string]string{"TERM": term}
+	}
+
+	r.err = r.enc.Encode(header)
+
+	return r.err
+}
+
+func recordingTitle(meta RecordingMetadata) string {
+	switch {
+	case meta.App != "" && meta.Machine != "":
+		return meta.App + "/" + meta.Machine
+	default:
+		return meta.App
+	}
+}
+
+// event records data of the given type. Recording errors are kept for Err
+// rather than interrupting the session.
+func (r *Recorder) event(kind string, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil || r.start.IsZero() {
+		return
+	}
+
+	buf := append(r.partial[kind], data...)
+
+	// Hold back an incomplete rune at the end for the next write.
+	cut := len(buf)
+	for i := len(buf) - 1; i >= 0 && i >= len(buf)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(buf[i]) {
+			if !utf8.FullRune(buf[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+
+	r.partial[kind] = append([]byte(nil), buf[cut:]...)
+
+	if cut == 0 {
+		return
+	}
+
+	r.err = r.enc.Encode(RecordingEvent{
+		Time: time.Since(r.start),
+		Type: kind,
+		Data: string(buf[:cut]),
+	})
+}
+
+// resize records a change in terminal size.
+func (r *Recorder) resize(width, height int) {
+	r.event(EventResize, fmt.Appendf(nil, "%dx%d", width, height))
+}
+
+// Err returns the first error encountered while writing the recording.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+type recordingWriter struct {
+	io.WriteCloser
+	r    *Recorder
+	kind string
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	n, err := w.WriteCloser.Write(p)
+	w.r.event(w.kind, p[:n])
+
+	return n, err
+}
+
+type recordingReader struct {
+	io.Reader
+	r *Recorder
+}
+
+func (rr *recordingReader) Read(p []byte) (int, error) {
+	n, err := rr.Reader.Read(p)
+	rr.r.event(EventInput, p[:n])
+
+	return n, err
+}
+
+// ReadRecording reads an asciicast v2 recording.
+func ReadRecording(r io.Reader) (*RecordingHeader, []RecordingEvent, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		return nil, nil, errors.New("recording is empty")
+	}
+
+	var header RecordingHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return nil, nil, fmt.Errorf("recording header: %w", err)
+	}
+
+	if header.Version != asciicastVersion {
+		return nil, nil, fmt.Errorf("unsupported recording version %d", header.Version)
+	}
+
+	var events []RecordingEvent
+	for line := 2; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var ev RecordingEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			return nil, nil, fmt.Errorf("recording line %d: %w", line, err)
+		}
+		events = append(events, ev)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return &header, events, nil
+}
+
+// ReplayOptions controls the pace of Replay.
+type ReplayOptions struct {
+	// Speed multiplies the playback speed. Zero means 1.
+	Speed float64
+
+	// IdleLimit caps the pause between two events. Zero means no cap.
+	IdleLimit time.Duration
+}
+
+// Replay writes the output events to w, pausing between them as they were
+// recorded. Input and resize events are skipped.
+func Replay(ctx context.Context, w io.Writer, events []RecordingEvent, opts ReplayOptions) error {
+	speed := opts.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+
+	var last time.Duration
+	for _, ev := range events {
+		if ev.Type != EventOutput {
+			continue
+		}
+
+		wait := ev.Time - last
+		last = ev.Time
+
+		if opts.IdleLimit > 0 && wait > opts.IdleLimit {
+			wait = opts.IdleLimit
+		}
+
+		if wait = time.Duration(float64(wait) / speed); wait > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+
+		if _, err := io.WriteString(w, ev.Data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
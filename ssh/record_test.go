@@ -0,0 +1,143 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorderRoundTrip(t *testing.T) {
+	var (
+		recording bytes.Buffer
+		stdout    bytes.Buffer
+	)
+
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	rec := NewRecorder(&recording, "uptime", RecordingMetadata{
+		App:       "my-app",
+		Machine:   "3d8d9d16f14683",
+		User:      "root",
+		StartedAt: started,
+	})
+	require.NoError(t, rec.begin(120, 30, "xterm-256color"))
+
+	sessIO := &SessionIO{
+		Stdout:   testWriteCloser{Writer: &stdout},
+		Stderr:   testWriteCloser{Writer: io.Discard},
+		Recorder: rec,
+	}
+
+	err := sessIO.attachPipes(
+		context.Background(),
+		testWriteCloser{Writer: io.Discard},
+		strings.NewReader(" 12:00:00 up 3 days\n"),
+		strings.NewReader(""),
+		func() error { return nil },
+	)
+	require.NoError(t, err)
+	require.NoError(t, rec.Err())
+
+	assert.Equal(t, " 12:00:00 up 3 days\n", stdout.String())
+
+	header, events, err := ReadRecording(&recording)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, header.Version)
+	assert.Equal(t, 120, header.Width)
+	assert.Equal(t, 30, header.Height)
+	assert.Equal(t, started.Unix(), header.Timestamp)
+	assert.Equal(t, "uptime", header.Command)
+	assert.Equal(t, "my-app/3d8d9d16f14683", header.Title)
+	assert.Equal(t, map[string]string{"TERM": "xterm-256color"}, header.Env)
+	require.NotNil(t, header.Fly)
+	assert.Equal(t, "my-app", header.Fly.App)
+	assert.Equal(t, "root", header.Fly.User)
+	assert.True(t, started.Equal(header.Fly.StartedAt))
+
+	var replayed bytes.Buffer
+	require.NoError(t, Replay(context.Background(), &replayed, events, ReplayOptions{Speed: 100}))
+	assert.Equal(t, " 12:00:00 up 3 days\n", replayed.String())
+}
+
+func TestRecorderKeepsSplitRunesTogether(t *testing.T) {
+	var recording bytes.Buffer
+
+	rec := NewRecorder(&recording, "", RecordingMetadata{})
+	require.NoError(t, rec.begin(80, 24, ""))
+
+	snowman := []byte("☃")
+	rec.event(EventOutput, append([]byte("a"), snowman[:1]...))
+	rec.event(EventOutput, snowman[1:])
+	rec.resize(100, 40)
+	require.NoError(t, rec.Err())
+
+	_, events, err := ReadRecording(&recording)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	assert.Equal(t, "a", events[0].Data)
+	assert.Equal(t, "☃", events[1].Data)
+	assert.Equal(t, EventResize, events[2].Type)
+	assert.Equal(t, "100x40", events[2].Data)
+}
+
+func TestRecorderSkipsInputUnlessAsked(t *testing.T) {
+	for _, recordInput := range []bool{false, true} {
+		var recording bytes.Buffer
+
+		rec := NewRecorder(&recording, "", RecordingMetadata{})
+		rec.RecordInput = recordInput
+		require.NoError(t, rec.begin(80, 24, ""))
+
+		sessIO := &SessionIO{
+			Stdin:    strings.NewReader("hunter2\n"),
+			Recorder: rec,
+		}
+
+		var remoteStdin bytes.Buffer
+		err := sessIO.attachPipes(
+			context.Background(),
+			testWriteCloser{Writer: &remoteStdin},
+			strings.NewReader(""),
+			strings.NewReader(""),
+			func() error {
+				// Give the stdin copy a chance to run before the session ends.
+				time.Sleep(50 * time.Millisecond)
+				return nil
+			},
+		)
+		require.NoError(t, err)
+
+		_, events, err := ReadRecording(&recording)
+		require.NoError(t, err)
+
+		if recordInput {
+			require.Len(t, events, 1)
+			assert.Equal(t, EventInput, events[0].Type)
+			assert.Equal(t, "hunter2\n", events[0].Data)
+		} else {
+			assert.Empty(t, events)
+		}
+	}
+}
+
+func TestReplayIdleLimit(t *testing.T) {
+	events := []RecordingEvent{
+		{Time: 0, Type: EventOutput, Data: "a"},
+		{Time: time.Hour, Type: EventOutput, Data: "b"},
+		{Time: time.Hour, Type: EventInput, Data: "ignored"},
+	}
+
+	var out bytes.Buffer
+	start := time.Now()
+	require.NoError(t, Replay(context.Background(), &out, events, ReplayOptions{IdleLimit: 10 * time.Millisecond}))
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, "ab", out.String())
+}